@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	accessLogFlag     = flag.String("log.access", "", "Format of the HTTP and websocket access log written to stdout (common, json; empty = disabled)")
+	accessLogSaltFlag = flag.String("log.access.salt", "", "Salt of the client IP hashes in the access log (empty = random, unlinkable across restarts)")
+)
+
+// newAccessLogSalt returns the configured key of the client IP hashes in the
+// access log, or a random one if none was set.
+func newAccessLogSalt() []byte {
+	if *accessLogSaltFlag != "" {
+		return []byte(*accessLogSaltFlag)
+	}
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		panic(err)
+	}
+	return salt
+}
+
+// accessLog serializes the lines written to the access log.
+var accessLog = struct {
+	lock sync.Mutex
+	out  io.Writer
+	salt []byte // Key of the client IP hashes
+}{
+	out: os.Stdout,
+}
+
+// accessEntry is a single request in the JSON access log.
+type accessEntry struct {
+	Time    time.Time `json:"time"`
+	Client  string    `json:"client"`         // Salted hash of the client IP
+	User    string    `json:"user,omitempty"` // Identity of the client certificate
+	Method  string    `json:"method"`
+	Path    string    `json:"path"`
+	Proto   string    `json:"proto"`
+	Status  int       `json:"status"`
+	Bytes   int64     `json:"bytes"`
+	Latency float64   `json:"latency"` // Seconds
+}
+
+// hashClient derives a stable pseudonym of a client IP, allowing requests of the
+// same client to be correlated without logging the IP itself.
+func hashClient(addr string) string {
+	mac := hmac.New(sha256.New, accessLog.salt)
+	mac.Write([]byte(remoteHost(addr)))
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// logAccess wraps a handler, recording every request in the access log once it
+// completed, if enabled. Websocket requests are logged when the socket closes.
+func logAccess(handler http.Handler) http.Handler {
+	switch *accessLogFlag {
+	case "":
+		return handler
+	case "common", "json":
+	default:
+		log.Fatal("Unknown access log format: ", *accessLogFlag)
+	}
+	accessLog.salt = newAccessLogSalt()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		handler.ServeHTTP(rec, r)
+
+		entry := &accessEntry{
+			Time:    start,
+			Client:  hashClient(r.RemoteAddr),
+			User:    certIdentity(r),
+			Method:  r.Method,
+			Path:    r.URL.Path, // Queries may carry addresses, leave them out
+			Proto:   r.Proto,
+			Status:  rec.status,
+			Bytes:   rec.bytes,
+			Latency: time.Since(start).Seconds(),
+		}
+		if entry.Status == 0 {
+			entry.Status = http.StatusOK
+		}
+		var line string
+		if *accessLogFlag == "json" {
+			blob, _ := json.Marshal(entry)
+			line = string(blob)
+		} else {
+			user := "-"
+			if entry.User != "" {
+				user = strings.ReplaceAll(entry.User, " ", "_")
+			}
+			line = fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %d %.3f", entry.Client, user, start.Format("02/Jan/2006:15:04:05 -0700"),
+				entry.Method, entry.Path, entry.Proto, entry.Status, entry.Bytes, entry.Latency)
+		}
+		accessLog.lock.Lock()
+		fmt.Fprintln(accessLog.out, line)
+		accessLog.lock.Unlock()
+	})
+}
+
+// statusRecorder captures the status and size of a response, while still
+// allowing websocket upgrades to take over the connection.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(data)
+	r.bytes += int64(n)
+	return n, err
+}
+
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("connection cannot be hijacked")
+	}
+	r.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
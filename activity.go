@@ -0,0 +1,603 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	activityFlag          = flag.Bool("activity.enabled", true, "Serve the public activity page and feed")
+	activityAnonymizeFlag = flag.Bool("activity.anonymize", true, "Mask recipient addresses on the public activity page and feed, disabling lookups by address")
+	activityRetainFlag    = flag.Int("activity.retain", 10000, "Claims to keep in the funding history, dropping the oldest beyond (0 = unlimited)")
+)
+
+// Store buckets holding the funding history.
+const (
+	claimsBucket     = "claims"     // sequence number -> claim record
+	recipientsBucket = "recipients" // address -> cumulative recipient record
+	activityBucket   = "activity"   // "summary" -> activity summary
+
+	legacyDailyBucket   = "daily"             // YYYY-MM-DD -> wei dispensed, before summaries were kept
+	legacyAddressBucket = "claims-by-address" // address -> claim keys, before recipients indexed them
+)
+
+const (
+	activityPageSize = 25 // Default number of recent claims per page
+	activityMaxPage  = 100
+	activityTopCount = 10 // Number of biggest recipients to list
+	activityDays     = 30 // Number of days of dispensing history to list and keep
+)
+
+// claimRecord is a funding event in the persisted history.
+type claimRecord struct {
+	Time    time.Time `json:"time"`
+	Address string    `json:"address"`
+	Amount  string    `json:"amount"` // Payout in wei, decimal
+	Tier    uint      `json:"tier"`
+	Tx      string    `json:"tx"`
+	NFT     string    `json:"nft,omitempty"` // ID of the minted NFT, if any
+
+	Fields map[string]string `json:"fields,omitempty"` // Extra form fields of the claim
+}
+
+// claimKey is the store key of the claim record with the given sequence number,
+// ordering the records by age.
+func claimKey(seq uint64) string {
+	return fmt.Sprintf("%020d", seq)
+}
+
+// recipientRecord is the cumulative funding of a single address.
+type recipientRecord struct {
+	Address  string    `json:"address"`
+	Total    string    `json:"total"` // Total payout in wei, decimal
+	Claims   int       `json:"claims"`
+	Last     time.Time `json:"last"`
+	Retained []uint64  `json:"retained,omitempty"` // Sequence numbers of the retained claims, oldest first
+}
+
+// activitySummary holds the aggregates of the whole funding history, kept up to
+// date as claims are recorded, so the activity feed is served without scanning
+// the history and survives the pruning of old records.
+type activitySummary struct {
+	Total string             `json:"total"` // Total ever dispensed in wei, decimal
+	Top   []*recipientRecord `json:"top"`   // Biggest cumulative recipients, biggest first
+	Daily map[string]string  `json:"daily"` // YYYY-MM-DD -> wei dispensed that day, for the listed days
+	First uint64             `json:"first"` // Sequence number of the oldest retained claim
+	Next  uint64             `json:"next"`  // Sequence number of the next claim, 0 before summaries were kept
+}
+
+// loadSummary loads the activity summary, or an empty one if there's none.
+func loadSummary() (*activitySummary, error) {
+	summary := &activitySummary{Total: "0", Daily: make(map[string]string), First: 1, Next: 1}
+	if err := getJSON(store, activityBucket, "summary", summary); err != nil && err != errNotFound {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// recordStage appends every sent funding transaction to the persisted history.
+func recordStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if c.Tx != nil {
+			recordClaim(c)
+		}
+		return next(c)
+	}
+}
+
+// recordClaim persists a funding event along with the aggregate statistics, in
+// a single write to the store.
+func recordClaim(c *Claim) {
+	now := time.Now()
+	rec := &claimRecord{Time: now, Address: c.Address, Amount: c.Amount.String(), Tier: c.Tier, Tx: c.Tx.ID(), Fields: c.Fields}
+	if c.TokenID != nil {
+		rec.NFT = c.TokenID.String()
+	}
+	err := store.Batch(func(tx Store) error {
+		summary := &activitySummary{Total: "0", Daily: make(map[string]string), First: 1, Next: 1}
+		if err := getJSON(tx, activityBucket, "summary", summary); err != nil && err != errNotFound {
+			return err
+		}
+		seq := summary.Next
+		summary.Next++
+		if err := putJSON(tx, claimsBucket, claimKey(seq), rec); err != nil {
+			return err
+		}
+		r := &recipientRecord{Address: rec.Address, Total: "0"}
+		if err := getJSON(tx, recipientsBucket, rec.Address, r); err != nil && err != errNotFound {
+			return err
+		}
+		total, _ := new(big.Int).SetString(r.Total, 10)
+		r.Total = total.Add(total, c.Amount).String()
+		r.Claims++
+		r.Last = now
+		r.Retained = append(r.Retained, seq)
+		if err := putJSON(tx, recipientsBucket, rec.Address, r); err != nil {
+			return err
+		}
+		total, _ = new(big.Int).SetString(summary.Total, 10)
+		summary.Total = total.Add(total, c.Amount).String()
+		summary.Daily = addDaily(summary.Daily, now.UTC().Format("2006-01-02"), c.Amount)
+		summary.Top = rankRecipient(summary.Top, r, c.Amount)
+		return putJSON(tx, activityBucket, "summary", summary)
+	})
+	if err != nil {
+		log.Error("Failed to record claim err: ", err)
+	}
+	if *activityFlag {
+		hub.broadcast(&wsFundedMessage{Funded: &activityClaim{
+			Time:    now,
+			Address: anonymize(rec.Address),
+			Amount:  fromWei(c.Amount),
+			Tx:      rec.Tx,
+			NFT:     rec.NFT,
+		}})
+	}
+}
+
+// addDaily adds an amount to the total of a day, dropping the days beyond the
+// listed ones.
+func addDaily(daily map[string]string, day string, amount *big.Int) map[string]string {
+	if daily == nil {
+		daily = make(map[string]string)
+	}
+	total, ok := new(big.Int).SetString(daily[day], 10)
+	if !ok {
+		total = new(big.Int)
+	}
+	daily[day] = total.Add(total, amount).String()
+
+	if len(daily) > activityDays {
+		days := make([]string, 0, len(daily))
+		for day := range daily {
+			days = append(days, day)
+		}
+		sort.Strings(days)
+		for _, day := range days[:len(days)-activityDays] {
+			delete(daily, day)
+		}
+	}
+	return daily
+}
+
+// rankRecipient updates the biggest cumulative recipients with the just updated
+// record of a recipient funded with the given amount.
+func rankRecipient(top []*recipientRecord, r *recipientRecord, amount *big.Int) []*recipientRecord {
+	r = &recipientRecord{Address: r.Address, Total: r.Total, Claims: r.Claims, Last: r.Last}
+	for i, ranked := range top {
+		if ranked.Address == r.Address {
+			// Count on from the ranked total, the records of the recipient may
+			// have been pruned and started over meanwhile
+			total, _ := new(big.Int).SetString(ranked.Total, 10)
+			if amount != nil {
+				total.Add(total, amount)
+			}
+			r.Total, r.Claims = total.String(), ranked.Claims+1
+			top = append(top[:i], top[i+1:]...)
+			break
+		}
+	}
+	top = append(top, r)
+	sort.SliceStable(top, func(i, j int) bool {
+		a, _ := new(big.Int).SetString(top[i].Total, 10)
+		b, _ := new(big.Int).SetString(top[j].Total, 10)
+		return a.Cmp(b) > 0
+	})
+	if len(top) > activityTopCount {
+		top = top[:activityTopCount]
+	}
+	return top
+}
+
+// startActivity migrates the funding history recorded before summaries were
+// kept, and starts pruning the history beyond --activity.retain.
+func startActivity() {
+	summary := new(activitySummary)
+	switch err := getJSON(store, activityBucket, "summary", summary); {
+	case err == errNotFound:
+		migrateActivity(nil)
+	case err != nil:
+		log.Error("Failed to load activity summary err: ", err)
+	case summary.Next == 0:
+		migrateActivity(summary)
+	}
+	go func() {
+		for range time.Tick(time.Minute) {
+			pruneActivity()
+		}
+	}()
+}
+
+// migrateActivity numbers the claim records of a history recorded before they
+// were, indexes them in the records of their recipients and completes the
+// summary of the history, if any, from the recipient and daily records.
+func migrateActivity(summary *activitySummary) {
+	var migrated int
+	err := store.Batch(func(tx Store) error {
+		var keys []string
+		tx.Iterate(claimsBucket, func(key string, _ []byte) bool {
+			keys = append(keys, key)
+			return true
+		})
+		retained := make(map[string][]uint64)
+		next := uint64(1)
+		for _, key := range keys {
+			var rec claimRecord
+			if err := getJSON(tx, claimsBucket, key, &rec); err != nil {
+				return err
+			}
+			tx.Delete(claimsBucket, key)
+			if err := putJSON(tx, claimsBucket, claimKey(next), &rec); err != nil {
+				return err
+			}
+			retained[rec.Address] = append(retained[rec.Address], next)
+			next++
+		}
+		migrated = len(keys)
+
+		var top []*recipientRecord
+		recipients := make(map[string]*recipientRecord)
+		tx.Iterate(recipientsBucket, func(address string, blob []byte) bool {
+			r := new(recipientRecord)
+			if err := json.Unmarshal(blob, r); err == nil {
+				recipients[address] = r
+			}
+			return true
+		})
+		for address, r := range recipients {
+			r.Retained = retained[address]
+			if err := putJSON(tx, recipientsBucket, address, r); err != nil {
+				return err
+			}
+			top = rankRecipient(top, r, nil)
+		}
+		total, daily := new(big.Int), make(map[string]string)
+		var days, addresses []string
+		tx.Iterate(legacyDailyBucket, func(day string, blob []byte) bool {
+			if amount, ok := new(big.Int).SetString(string(blob), 10); ok {
+				total.Add(total, amount)
+				daily = addDaily(daily, day, amount)
+			}
+			days = append(days, day)
+			return true
+		})
+		for _, day := range days {
+			tx.Delete(legacyDailyBucket, day)
+		}
+		tx.Iterate(legacyAddressBucket, func(address string, _ []byte) bool {
+			addresses = append(addresses, address)
+			return true
+		})
+		for _, address := range addresses {
+			tx.Delete(legacyAddressBucket, address)
+		}
+		// Keep the aggregates of a summary already kept, which survived pruning
+		if summary == nil {
+			summary = &activitySummary{Total: total.String(), Top: top}
+		}
+		summary.Daily, summary.First, summary.Next = daily, 1, next
+		return putJSON(tx, activityBucket, "summary", summary)
+	})
+	if err != nil {
+		log.Error("Failed to migrate the funding history err: ", err)
+		return
+	}
+	log.Info("Migrated the funding history of ", migrated, " claims")
+}
+
+// pruneActivity drops the oldest claim records beyond --activity.retain along
+// with their entries in the records of their recipients, and the records of
+// recipients left without any.
+func pruneActivity() {
+	if *activityRetainFlag <= 0 {
+		return
+	}
+	err := store.Batch(func(tx Store) error {
+		summary := new(activitySummary)
+		if err := getJSON(tx, activityBucket, "summary", summary); err != nil {
+			return err
+		}
+		if summary.Next == 0 || summary.Next-summary.First <= uint64(*activityRetainFlag) {
+			return nil
+		}
+		first := summary.Next - uint64(*activityRetainFlag)
+		for seq := summary.First; seq < first; seq++ {
+			var rec claimRecord
+			if err := getJSON(tx, claimsBucket, claimKey(seq), &rec); err == nil {
+				if err := unindexClaim(tx, rec.Address, seq); err != nil {
+					return err
+				}
+			}
+			tx.Delete(claimsBucket, claimKey(seq))
+		}
+		summary.First = first
+		return putJSON(tx, activityBucket, "summary", summary)
+	})
+	if err != nil && err != errNotFound {
+		log.Error("Failed to prune activity err: ", err)
+	}
+}
+
+// unindexClaim drops a claim record from the record of its recipient, and the
+// record itself with its last claim.
+func unindexClaim(tx Store, address string, seq uint64) error {
+	r := new(recipientRecord)
+	switch err := getJSON(tx, recipientsBucket, address, r); err {
+	case errNotFound:
+		return nil
+	case nil:
+	default:
+		return err
+	}
+	for i, retained := range r.Retained {
+		if retained == seq {
+			r.Retained = append(r.Retained[:i], r.Retained[i+1:]...)
+			break
+		}
+	}
+	if len(r.Retained) == 0 {
+		return tx.Delete(recipientsBucket, address)
+	}
+	return putJSON(tx, recipientsBucket, address, r)
+}
+
+// activityClaim is a funding event as shown publicly.
+type activityClaim struct {
+	Time    time.Time `json:"time"`
+	Address string    `json:"address"`
+	Amount  string    `json:"amount"` // Payout in token units
+	Tx      string    `json:"tx"`
+	NFT     string    `json:"nft,omitempty"` // ID of the minted NFT, if any
+}
+
+// activityRecipient is a cumulative recipient as shown publicly.
+type activityRecipient struct {
+	Address string `json:"address"`
+	Total   string `json:"total"` // Total payout in token units
+	Claims  int    `json:"claims"`
+}
+
+// activityDay is the amount dispensed on a single day.
+type activityDay struct {
+	Date   string `json:"date"`
+	Amount string `json:"amount"` // Payout in token units
+}
+
+// activityReport is the public view of the funding history.
+type activityReport struct {
+	Name   string              `json:"name"`
+	Unit   string              `json:"unit"`
+	Page   int                 `json:"page"`
+	Pages  int                 `json:"pages"`
+	Recent []activityClaim     `json:"recent"`
+	Top    []activityRecipient `json:"top"`
+	Daily  []activityDay       `json:"daily"`
+	Total  string              `json:"total"` // Total ever dispensed in token units
+}
+
+// anonymize masks the middle of an address if requested by the operator.
+func anonymize(address string) string {
+	if !*activityAnonymizeFlag || len(address) < 10 {
+		return address
+	}
+	return address[:6] + "…" + address[len(address)-4:]
+}
+
+// collectActivity assembles one page of the public activity report from the
+// activity summary, reading only the claim records on the page.
+func collectActivity(page, limit int) (*activityReport, error) {
+	report := &activityReport{Name: *apiName, Unit: *UnitFlag, Page: page}
+
+	summary, err := loadSummary()
+	if err != nil {
+		return nil, err
+	}
+	// Gather the requested page of recent claims, newest first
+	var seqs []uint64
+	if summary.Next > summary.First {
+		report.Pages = int((summary.Next - summary.First + uint64(limit) - 1) / uint64(limit))
+		for i := uint64(page*limit + 1); i <= uint64((page+1)*limit) && i <= summary.Next-summary.First; i++ {
+			seqs = append(seqs, summary.Next-i)
+		}
+	}
+	report.Recent = loadActivityClaims(seqs, true)
+
+	// List the biggest cumulative recipients and the total
+	for _, r := range summary.Top {
+		total, _ := new(big.Int).SetString(r.Total, 10)
+		report.Top = append(report.Top, activityRecipient{
+			Address: anonymize(r.Address),
+			Total:   fromWei(total),
+			Claims:  r.Claims,
+		})
+	}
+	total, _ := new(big.Int).SetString(summary.Total, 10)
+	report.Total = fromWei(total)
+
+	// List the dispensed amounts of the recent days
+	days := make([]string, 0, len(summary.Daily))
+	for day := range summary.Daily {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+	for _, day := range days {
+		amount, _ := new(big.Int).SetString(summary.Daily[day], 10)
+		report.Daily = append(report.Daily, activityDay{Date: day, Amount: fromWei(amount)})
+	}
+	return report, nil
+}
+
+// collectAddressActivity assembles one page of the retained claims of a single
+// address, looked up in its recipient record.
+func collectAddressActivity(address string, page, limit int) (*activityReport, error) {
+	report := &activityReport{Name: *apiName, Unit: *UnitFlag, Page: page, Recent: []activityClaim{}}
+
+	var r recipientRecord
+	switch err := getJSON(store, recipientsBucket, address, &r); err {
+	case errNotFound:
+		return report, nil
+	case nil:
+	default:
+		return nil, err
+	}
+	report.Pages = (len(r.Retained) + limit - 1) / limit
+
+	var seqs []uint64
+	for i := len(r.Retained) - 1 - page*limit; i >= 0 && i > len(r.Retained)-1-(page+1)*limit; i-- {
+		seqs = append(seqs, r.Retained[i])
+	}
+	report.Recent = loadActivityClaims(seqs, false)
+
+	total, _ := new(big.Int).SetString(r.Total, 10)
+	report.Top = []activityRecipient{{Address: r.Address, Total: fromWei(total), Claims: r.Claims}}
+	report.Total = fromWei(total)
+	return report, nil
+}
+
+// loadActivityClaims loads the claim records of the given sequence numbers as
+// shown publicly, skipping those pruned meanwhile.
+func loadActivityClaims(seqs []uint64, masked bool) []activityClaim {
+	claims := []activityClaim{}
+	for _, seq := range seqs {
+		var rec claimRecord
+		if err := getJSON(store, claimsBucket, claimKey(seq), &rec); err != nil {
+			continue
+		}
+		if masked {
+			rec.Address = anonymize(rec.Address)
+		}
+		amount, _ := new(big.Int).SetString(rec.Amount, 10)
+		claims = append(claims, activityClaim{
+			Time:    rec.Time,
+			Address: rec.Address,
+			Amount:  fromWei(amount),
+			Tx:      rec.Tx,
+			NFT:     rec.NFT,
+		})
+	}
+	return claims
+}
+
+// parseActivityQuery extracts the pagination parameters of an activity request.
+func parseActivityQuery(r *http.Request) (page int, limit int) {
+	page, _ = strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 0 {
+		page = 0
+	}
+	limit, _ = strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > activityMaxPage {
+		limit = activityPageSize
+	}
+	return page, limit
+}
+
+// onActivityAPI serves the activity report as JSON, or the claims of a single
+// address given as the address query parameter.
+func (s *Server) onActivityAPI(w http.ResponseWriter, r *http.Request) {
+	page, limit := parseActivityQuery(r)
+
+	var (
+		report *activityReport
+		err    error
+	)
+	if address := r.URL.Query().Get("address"); address != "" {
+		if *activityAnonymizeFlag {
+			writeJSONError(w, http.StatusBadRequest, errors.New("address lookups are disabled on anonymized activity"))
+			return
+		}
+		if s.chain != nil {
+			if address, err = s.chain.ParseAddress(address); err != nil {
+				writeJSONError(w, http.StatusBadRequest, errors.New("invalid address"))
+				return
+			}
+		}
+		report, err = collectAddressActivity(address, page, limit)
+	} else {
+		report, err = collectActivity(page, limit)
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// activityTemplate renders the activity report as a web page.
+var activityTemplate = template.Must(template.New("activity").Funcs(template.FuncMap{
+	"inc": func(i int) int { return i + 1 },
+	"dec": func(i int) int { return i - 1 },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+  <head>
+    <meta charset="utf-8" />
+    <meta name="viewport" content="width=device-width, initial-scale=1" />
+    <title>{{ .Name }} Faucet Activity</title>
+    <link href="https://cdnjs.cloudflare.com/ajax/libs/twitter-bootstrap/3.3.7/css/bootstrap.min.css" rel="stylesheet" />
+  </head>
+  <body>
+    <div class="container">
+      <h1>{{ .Name }} Faucet Activity</h1>
+      <p class="lead">Total dispensed: {{ .Total }} {{ .Unit }}</p>
+
+      <h2>Recent claims</h2>
+      <table class="table table-condensed">
+        <thead><tr><th>Time</th><th>Recipient</th><th>Amount</th><th>Transaction</th></tr></thead>
+        <tbody>
+          {{range .Recent}}<tr><td><time datetime="{{ .Time.UTC.Format "2006-01-02T15:04:05Z07:00" }}">{{ .Time.UTC.Format "2006-01-02 15:04:05 UTC" }}</time></td><td><code>{{ .Address }}</code></td><td>{{ .Amount }} {{ $.Unit }}{{with .NFT}} + NFT #{{ . }}{{end}}</td><td><code>{{ .Tx }}</code></td></tr>
+          {{else}}<tr><td colspan="4">No claims yet</td></tr>{{end}}
+        </tbody>
+      </table>
+      <ul class="pager">
+        {{if gt .Page 0}}<li class="previous"><a href="?page={{ dec .Page }}">Newer</a></li>{{end}}
+        {{if lt (inc .Page) .Pages}}<li class="next"><a href="?page={{ inc .Page }}">Older</a></li>{{end}}
+      </ul>
+
+      <h2>Biggest recipients</h2>
+      <table class="table table-condensed">
+        <thead><tr><th>Recipient</th><th>Total</th><th>Claims</th></tr></thead>
+        <tbody>
+          {{range .Top}}<tr><td><code>{{ .Address }}</code></td><td>{{ .Total }} {{ $.Unit }}</td><td>{{ .Claims }}</td></tr>{{end}}
+        </tbody>
+      </table>
+
+      <h2>Dispensed per day</h2>
+      <table class="table table-condensed">
+        <thead><tr><th>Date (UTC)</th><th>Amount</th></tr></thead>
+        <tbody>
+          {{range .Daily}}<tr><td>{{ .Date }}</td><td>{{ .Amount }} {{ $.Unit }}</td></tr>{{end}}
+        </tbody>
+      </table>
+    </div>
+    <script>
+      // Show the claim times in the visitor's time zone, keeping UTC without scripts
+      document.querySelectorAll("time[datetime]").forEach(function(el) {
+        el.title = el.textContent;
+        el.textContent = new Date(el.dateTime).toLocaleString(undefined, {timeZoneName: "short"});
+      });
+    </script>
+  </body>
+</html>
+`))
+
+// onActivityPage serves the activity report as a web page.
+func onActivityPage(w http.ResponseWriter, r *http.Request) {
+	report, err := collectActivity(parseActivityQuery(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := activityTemplate.Execute(w, report); err != nil {
+		log.Error("Failed to render activity page err: ", err)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestActivityRetention(t *testing.T) {
+	var err error
+	if store, err = openStore(filepath.Join(t.TempDir(), "state.json")); err != nil {
+		t.Fatal(err)
+	}
+	defer func(retain int, anonymize bool) {
+		*activityRetainFlag, *activityAnonymizeFlag = retain, anonymize
+		store = newMemoryStore()
+	}(*activityRetainFlag, *activityAnonymizeFlag)
+	*activityRetainFlag, *activityAnonymizeFlag = 3, false
+
+	// A history recorded before summaries were kept is numbered on startup
+	for i := 0; i < 2; i++ {
+		putJSON(store, claimsBucket, fmt.Sprintf("%020d-0x%d", time.Now().UnixNano(), i), &claimRecord{Address: "0xa", Amount: "5"})
+	}
+	putJSON(store, recipientsBucket, "0xa", &recipientRecord{Address: "0xa", Total: "10", Claims: 2})
+	store.Put(legacyDailyBucket, "2024-05-01", []byte("10"))
+	startActivity()
+
+	for i, addr := range []string{"0xb", "0xa", "0xb"} {
+		recordClaim(&Claim{Address: addr, Amount: big.NewInt(int64(10 * (i + 1))), Tx: sentTx})
+	}
+	pruneActivity()
+
+	report, err := collectActivity(0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Pages != 2 || len(report.Recent) != 2 || report.Recent[0].Amount != fromWei(big.NewInt(30)) {
+		t.Errorf("first page %+v, want the 2 newest of 3 retained claims", report.Recent)
+	}
+	if report.Total != fromWei(big.NewInt(70)) {
+		t.Errorf("total %s, want the pruned claims included", report.Total)
+	}
+	if len(report.Top) != 2 || report.Top[0].Address != "0xb" || report.Top[1].Address != "0xa" || report.Top[1].Claims != 3 {
+		t.Errorf("biggest recipients %+v, want 0xb and 0xa", report.Top)
+	}
+	if len(report.Daily) != 2 {
+		t.Errorf("daily amounts %+v, want the migrated and today's", report.Daily)
+	}
+	if report, _ := collectActivity(1, 2); len(report.Recent) != 1 {
+		t.Errorf("second page %+v, want the oldest retained claim", report.Recent)
+	}
+	// The pruned claims are gone from the index of their recipient
+	report, err = collectAddressActivity("0xa", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Recent) != 1 || report.Recent[0].Amount != fromWei(big.NewInt(20)) || report.Total != fromWei(big.NewInt(30)) {
+		t.Errorf("claims of 0xa %+v total %s, want the retained one of 30 wei overall", report.Recent, report.Total)
+	}
+	var keys []string
+	store.Iterate(claimsBucket, func(key string, _ []byte) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if len(keys) != 3 || keys[0] != claimKey(3) {
+		t.Errorf("claim records %v, want the 3 newest", keys)
+	}
+}
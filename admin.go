@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/subtle"
+	"flag"
+	"net/http"
+	"strings"
+
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	adminAddrFlag  = flag.String("admin.addr", "", "Listener address of the admin API, e.g. 127.0.0.1:8081 (empty = disabled)")
+	adminTokenFlag = flag.String("admin.token", "", "Bearer token required to access the admin API")
+)
+
+// startAdmin starts serving the admin API on its own listener, if enabled.
+func startAdmin(s *Server) {
+	if _, ok := activatedListeners()["admin"]; !ok && *adminAddrFlag == "" {
+		return
+	}
+	if *adminTokenFlag == "" {
+		log.Fatal("Admin API enabled without an access token")
+	}
+	mux := http.NewServeMux()
+	if !*readOnlyFlag {
+		// Read-only mirrors have neither a chain connection nor a writable store
+		mux.HandleFunc("/admin/vouchers", adminAuth(onAdminVouchers))
+		mux.HandleFunc("/admin/shadowbans", adminAuth(onAdminShadowbans))
+		mux.HandleFunc("/admin/nonces", adminAuth(s.onAdminNonces))
+		mux.HandleFunc("/admin/drips/keys", adminAuth(onAdminDripKeys))
+		mux.HandleFunc("/admin/appeals", adminAuth(onAdminAppeals))
+		mux.HandleFunc("/admin/terms", adminAuth(s.onAdminTerms))
+	}
+	mux.HandleFunc("/admin/claims", adminAuth(onAdminClaims))
+	mux.HandleFunc("/admin/providers", adminAuth(onAdminProviders))
+	mux.HandleFunc("/admin/config", adminAuth(s.onAdminConfig))
+	mux.HandleFunc("/metrics", adminAuth(onMetrics))
+	registerDebug(s, mux)
+
+	listener, err := listen("admin", *adminAddrFlag)
+	if err != nil {
+		log.Fatal("Failed to listen for admin connections: ", err)
+	}
+	log.Infof("admin API booting with %s \n", listener.Addr())
+	go func() {
+		if err := serve("admin", listener, recoverPanics(mux), serverTLS(*adminTLSFlag || *adminClientCAFlag != "", *adminClientCAFlag)); err != http.ErrServerClosed {
+			log.Fatal("Admin API failed: ", err)
+		}
+	}()
+}
+
+// adminAuth wraps an admin handler, rejecting any request that doesn't carry the
+// configured bearer token.
+func adminAuth(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(*adminTokenFlag)) != 1 {
+			log.Info("Unauthorized admin request: ", r.Method, " ", r.URL.Path, " client: ", clientIdentity(r))
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodGet {
+			log.Info("Admin request: ", r.Method, " ", r.URL.Path, " client: ", clientIdentity(r))
+		}
+		handler(w, r)
+	}
+}
+
+// writeJSON replies to an HTTP request with the JSON encoding of value.
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	blob, err := marshalAPI(value)
+	if err != nil {
+		log.Error("Failed to encode response err: ", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(blob, '\n'))
+}
+
+// apiError is the body of failed HTTP API requests.
+type apiError struct {
+	Error  string `json:"error"`
+	Reason string `json:"reason,omitempty"` // Machine readable reason of failed funding transactions
+}
+
+// writeJSONError replies to an HTTP request with a JSON encoded error.
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, &apiError{Error: err.Error()})
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	alertWebhookFlag  = flag.String("alert.webhook", "", "Webhook to POST operator alerts to as {\"text\": ...}, e.g. Slack or Mattermost (empty = log only)")
+	alertIntervalFlag = flag.Duration("alert.interval", time.Hour, "Minimum time between two alerts of the same kind")
+)
+
+// alertsRaised counts the operator alerts raised by kind.
+var alertsRaised = newCounterVec("faucet_alerts_total", "Operator alerts raised.", "kind")
+
+// alerts tracks when each kind of alert was last sent, to avoid flooding the
+// operator with repeats of the same problem.
+var alerts = struct {
+	lock sync.Mutex
+	last map[string]time.Time
+}{
+	last: make(map[string]time.Time),
+}
+
+// alert notifies the operator of a problem needing attention. Alerts are always
+// logged, but only forwarded to the webhook once per interval and kind.
+func alert(kind string, format string, args ...interface{}) {
+	text := fmt.Sprintf("%s faucet: %s", *apiName, fmt.Sprintf(format, args...))
+	log.Error("ALERT ", kind, ": ", text)
+	alertsRaised.With(kind).Inc()
+
+	alerts.lock.Lock()
+	if last, ok := alerts.last[kind]; ok && time.Since(last) < *alertIntervalFlag {
+		alerts.lock.Unlock()
+		return
+	}
+	alerts.last[kind] = time.Now()
+	alerts.lock.Unlock()
+
+	if *alertWebhookFlag == "" {
+		return
+	}
+	go func() {
+		if err := postWebhook(*alertWebhookFlag, map[string]string{"text": text}); err != nil {
+			log.Error("Failed to deliver alert err: ", err)
+		}
+	}()
+}
+
+// postWebhook POSTs a JSON document to a webhook.
+func postWebhook(url string, value interface{}) error {
+	blob, err := marshalAPI(value)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(blob))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("webhook rejected request: %s", res.Status)
+	}
+	return nil
+}
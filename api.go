@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+// claimResponse is the outcome of a funding request made over the REST API.
+type claimResponse struct {
+	Message string `json:"message"`
+	ID      string `json:"id,omitempty"`     // Funding job ID
+	Amount  string `json:"amount,omitempty"` // Payout in token units
+	Tx      string `json:"tx,omitempty"`     // Funding transaction hash
+	NFT     string `json:"nft,omitempty"`    // ID of the minted NFT
+
+	Receipt *claimReceipt `json:"receipt,omitempty"` // Faucet signed proof of the claim
+}
+
+// onClaim serves funding requests made over the REST API (POST /api/claim) by
+// automated clients, taking the same JSON request as the websocket API.
+func (s *Server) onClaim(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSONError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	lang := negotiateLanguage(r)
+	if err := checkCSRF(r, false); err != nil {
+		writeJSONError(w, http.StatusForbidden, localizeError(lang, err))
+		return
+	}
+	msg, err := decodeFundRequest(r.Body)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, localizeError(lang, err))
+		return
+	}
+	log.Info("Faucet funds requested via API: ", "url: ", msg.URL, " tier: ", msg.Tier)
+
+	claim := s.newClaim(r.Context(), r, msg, lang)
+	if err := s.handle(claim); err != nil {
+		for _, sibling := range claim.Siblings {
+			w.Header().Add("Link", "<"+sibling+`>; rel="alternate"`)
+		}
+		var throttled *throttledError
+		if errors.As(err, &throttled) {
+			setRateLimit(w, throttled.limit, 0, throttled.retry)
+			w.Header().Set("Retry-After", strconv.Itoa(retrySeconds(throttled.retry)))
+			writeJSONError(w, http.StatusTooManyRequests, localizeError(lang, err))
+			return
+		}
+		var busy *busyError
+		if errors.As(err, &busy) {
+			w.Header().Set("Retry-After", strconv.Itoa(retrySeconds(busy.retry)))
+			writeJSONError(w, http.StatusServiceUnavailable, localizeError(lang, err))
+			return
+		}
+		var failed *txFailedError
+		if errors.As(err, &failed) {
+			writeJSON(w, http.StatusBadGateway, &apiError{Error: localizeError(lang, err).Error(), Reason: failed.reason})
+			return
+		}
+		var internal *internalError
+		if errors.As(err, &internal) {
+			writeJSONError(w, http.StatusInternalServerError, localizeError(lang, err))
+			return
+		}
+		var uerr *userError
+		if errors.As(err, &uerr) {
+			writeJSONError(w, http.StatusBadRequest, localizeError(lang, err))
+			return
+		}
+		log.Error("Failed to fund API request err: ", err)
+		writeJSONError(w, http.StatusInternalServerError, errors.New("funding failed"))
+		return
+	}
+	if !claim.SkipCooldown {
+		setRateLimit(w, 1, 0, claim.Cooldown)
+	}
+	res := &claimResponse{Message: successMessage(claim), ID: claim.ID, Amount: fromWei(claim.Amount)}
+	if claim.Tx != nil {
+		res.Tx = claim.Tx.ID()
+	}
+	if claim.TokenID != nil {
+		res.NFT = claim.TokenID.String()
+	}
+	res.Receipt = signClaimReceipt(claim)
+	writeJSON(w, http.StatusOK, res)
+}
+
+// setRateLimit sets the RateLimit header fields (IETF httpapi-ratelimit-headers)
+// describing the allowance of the requester.
+func setRateLimit(w http.ResponseWriter, limit int, remaining int, reset time.Duration) {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(retrySeconds(reset)))
+}
+
+// limitBody caps the size of HTTP request bodies to keep malicious clients from
+// exhausting the memory of the faucet.
+func limitBody(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > *maxBodyFlag {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, errors.New("request body too large"))
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, *maxBodyFlag)
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// forwardedFor replaces the remote address of requests relayed by a trusted
+// local proxy with the client address it forwarded, if enabled.
+func forwardedFor(handler http.Handler) http.Handler {
+	if !*apiProxyFlag {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ip := net.ParseIP(remoteHost(r.RemoteAddr)); ip != nil && ip.IsLoopback() {
+			if hops := strings.Split(r.Header.Get("X-Forwarded-For"), ","); hops[0] != "" {
+				// The last hop was appended by the trusted proxy itself
+				r.RemoteAddr = strings.TrimSpace(hops[len(hops)-1])
+			}
+		} else if *geoHeaderFlag != "" {
+			r.Header.Del(*geoHeaderFlag) // Forged by the requester
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// retrySeconds rounds a wait up to whole seconds, as used by the HTTP headers.
+func retrySeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
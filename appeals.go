@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	appealsFlag       = flag.Bool("appeals.enabled", false, "Let users blocked by the risk engine appeal via /api/appeal, for operators to review on the admin API")
+	appealExemptFlag  = flag.Duration("appeals.exempt", 30*24*time.Hour, "Time an address with an approved appeal is exempt from the risk score threshold")
+	appealMessageFlag = flag.Int("appeals.maxlength", 1000, "Longest explanation in characters an appeal may carry")
+)
+
+const (
+	// appealsBucket is the store bucket holding the appeals by ID.
+	appealsBucket = "appeals"
+
+	// riskExemptBucket is the store bucket holding the addresses exempt from the
+	// risk score threshold, by address.
+	riskExemptBucket = "risk-exempt"
+)
+
+// appeal is a request of a blocked user to be let through again.
+type appeal struct {
+	ID      string    `json:"id"`
+	Address string    `json:"address"`
+	IP      string    `json:"ip"`
+	Message string    `json:"message"` // Explanation of the user
+	Contact string    `json:"contact"` // Way to reach the user, e.g. an email address
+	Status  string    `json:"status"`  // "pending", "approved" or "rejected"
+	Created time.Time `json:"created"`
+
+	Decided   *time.Time `json:"decided,omitempty"`
+	DecidedBy string     `json:"decidedBy,omitempty"` // Admin client that decided
+	Note      string     `json:"note,omitempty"`      // Reasoning of the operator
+}
+
+func init() {
+	registerGauge("faucet_appeals_pending", "Appeals waiting for a decision of an operator.", func() float64 {
+		appeals, err := listAppeals("pending")
+		if err != nil {
+			return 0
+		}
+		return float64(len(appeals))
+	})
+}
+
+// listAppeals returns the appeals of the given status (all if empty), oldest
+// first.
+func listAppeals(status string) ([]*appeal, error) {
+	appeals := []*appeal{}
+	err := store.Iterate(appealsBucket, func(id string, blob []byte) bool {
+		a := new(appeal)
+		if err := json.Unmarshal(blob, a); err != nil {
+			log.Error("Failed to decode appeal err: ", err)
+			return true
+		}
+		if status == "" || a.Status == status {
+			appeals = append(appeals, a)
+		}
+		return true
+	})
+	sort.Slice(appeals, func(i, j int) bool { return appeals[i].Created.Before(appeals[j].Created) })
+	return appeals, err
+}
+
+// riskExempt reports whether the address of a claim had an appeal approved
+// recently enough to bypass the risk score threshold.
+func riskExempt(c *Claim) bool {
+	var until time.Time
+	if err := getJSON(store, riskExemptBucket, c.Address, &until); err != nil {
+		return false
+	}
+	return time.Now().Before(until)
+}
+
+// onAppeal accepts the appeal of a blocked user (POST /api/appeal with
+// {"address", "message", "contact"}). Every address and IP may have a single
+// appeal pending at a time.
+func onAppeal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSONError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	lang := negotiateLanguage(r)
+
+	var req struct {
+		Address string `json:"address"`
+		Message string `json:"message"`
+		Contact string `json:"contact"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, localizeError(lang, newUserError("Invalid request, malformed JSON")))
+		return
+	}
+	req.Message, req.Contact = strings.TrimSpace(req.Message), strings.TrimSpace(req.Contact)
+	switch {
+	case !common.IsHexAddress(req.Address):
+		writeJSONError(w, http.StatusBadRequest, localizeError(lang, newUserError("Invalid address to fund")))
+		return
+	case req.Message == "" || utf8.RuneCountInString(req.Message) > *appealMessageFlag:
+		writeJSONError(w, http.StatusBadRequest, localizeError(lang, newUserError("Please explain your appeal in at most %d characters", *appealMessageFlag)))
+		return
+	case req.Contact == "" || utf8.RuneCountInString(req.Contact) > 200:
+		writeJSONError(w, http.StatusBadRequest, localizeError(lang, newUserError("Please leave a way to contact you")))
+		return
+	}
+	address, ip := common.HexToAddress(req.Address).Hex(), remoteHost(r.RemoteAddr)
+
+	pending, err := listAppeals("pending")
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	for _, a := range pending {
+		if a.Address == address || a.IP == ip {
+			writeJSONError(w, http.StatusConflict, localizeError(lang, newUserError("An appeal of yours is already awaiting review")))
+			return
+		}
+	}
+	a := &appeal{
+		ID:      newJobID(),
+		Address: address,
+		IP:      ip,
+		Message: req.Message,
+		Contact: req.Contact,
+		Status:  "pending",
+		Created: time.Now(),
+	}
+	if err := putJSON(store, appealsBucket, a.ID, a); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	log.Info("Appeal submitted: ", a.ID, " address: ", address, " ip: ", ip)
+	writeJSON(w, http.StatusOK, map[string]string{"id": a.ID, "message": translate(lang, "Appeal submitted, we'll get back to you")})
+}
+
+// onAdminAppeals lists (GET, ?status= to filter) and decides (POST {"id",
+// "decision": "approve" or "reject", "note"}) appeals. Approving an appeal
+// lifts the shadow-bans of its address and IP and exempts the address from the
+// risk score threshold for a while.
+func onAdminAppeals(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		appeals, err := listAppeals(r.URL.Query().Get("status"))
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, appeals)
+
+	case http.MethodPost:
+		var req struct {
+			ID       string `json:"id"`
+			Decision string `json:"decision"`
+			Note     string `json:"note"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		status := map[string]string{"approve": "approved", "reject": "rejected"}[req.Decision]
+		if status == "" {
+			writeJSONError(w, http.StatusBadRequest, errors.New("decision must be approve or reject"))
+			return
+		}
+		a := new(appeal)
+		if err := getJSON(store, appealsBucket, req.ID, a); err != nil {
+			if err == errNotFound {
+				writeJSONError(w, http.StatusNotFound, errors.New("unknown appeal"))
+				return
+			}
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if a.Status != "pending" {
+			writeJSONError(w, http.StatusConflict, errors.New("appeal already "+a.Status))
+			return
+		}
+		if status == "approved" {
+			for _, target := range []string{a.Address, a.IP} {
+				if key, ok := shadowbanKey(target); ok {
+					if err := store.Delete(shadowbansBucket, key); err != nil {
+						writeJSONError(w, http.StatusInternalServerError, err)
+						return
+					}
+				}
+			}
+			if err := putJSON(store, riskExemptBucket, a.Address, time.Now().Add(*appealExemptFlag)); err != nil {
+				writeJSONError(w, http.StatusInternalServerError, err)
+				return
+			}
+		}
+		now := time.Now()
+		a.Status, a.Decided, a.DecidedBy, a.Note = status, &now, clientIdentity(r), req.Note
+		if err := putJSON(store, appealsBucket, a.ID, a); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		log.Info("Appeal ", a.ID, " of ", a.Address, " ", status, " by ", a.DecidedBy, " note: ", req.Note)
+		writeJSON(w, http.StatusOK, a)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	autoFundFlag          = flag.Bool("devnet.autofund", false, "Automatically top up new addresses seen on chain, for local devnets and e2e environments")
+	autoFundFloorFlag     = flag.Float64("devnet.floor", 10, "Balance in units to top up automatically funded addresses to")
+	autoFundAllowlistFlag = flag.String("devnet.allowlist", "", "File with addresses (one per line) to fund automatically besides those seen on chain")
+	autoFundIntervalFlag  = flag.Duration("devnet.interval", 2*time.Second, "Time between two scans for addresses to fund automatically")
+)
+
+// autoFunding sends automatic top ups through the same queue as the claims, so
+// they don't race the regular payouts for nonces.
+var autoFunding = NewPipeline(
+	Stage{"enqueue", enqueueStage},
+	Stage{"send", sendStage},
+	Stage{"record", recordStage},
+)
+
+// startAutoFund starts watching the chain for addresses to fund, if enabled.
+func startAutoFund(s *Server) {
+	if !*autoFundFlag {
+		return
+	}
+	log.Info("Automatically funding new addresses up to ", *autoFundFloorFlag, " ", *UnitFlag)
+	go s.loopAutoFund()
+}
+
+// BlockScanner is implemented by chain backends able to list the accounts active
+// in a block, so that those new to the chain can be funded automatically.
+type BlockScanner interface {
+	// BlockAccounts retrieves the accounts sending or receiving transactions
+	// in a block.
+	BlockAccounts(ctx context.Context, number uint64) ([]string, error)
+}
+
+// BlockAccounts retrieves the senders and recipients of the transactions in a
+// block as hex addresses.
+func (b *evmBackend) BlockAccounts(ctx context.Context, number uint64) ([]string, error) {
+	rctx, cancel := rpcContext(ctx)
+	block, err := b.client.BlockByNumber(rctx, new(big.Int).SetUint64(number))
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	signer := types.LatestSignerForChainID(b.chainID)
+
+	var accounts []string
+	for _, tx := range block.Transactions() {
+		if from, err := types.Sender(signer, tx); err == nil {
+			accounts = append(accounts, from.Hex())
+		}
+		if to := tx.To(); to != nil {
+			accounts = append(accounts, to.Hex())
+		}
+	}
+	return accounts, nil
+}
+
+// loopAutoFund periodically scans the new blocks and the allowlist for addresses
+// not seen before, topping up any of them below the floor balance. Blocks are
+// only scanned if the chain backend supports it.
+func (s *Server) loopAutoFund() {
+	var (
+		handle     = autoFunding.Handler()
+		seen       = map[string]bool{s.address.Hex(): true}
+		scanner, _ = unwrapChain(s.chain).(BlockScanner)
+		next       uint64 // Next block to scan, starting at the head
+	)
+	ticker := time.NewTicker(*autoFundIntervalFlag)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		if s.isPassive() {
+			continue // Leave funding to the primary
+		}
+		var candidates []string
+		for _, addr := range s.readAllowlist(*autoFundAllowlistFlag) {
+			if !seen[addr] {
+				candidates = append(candidates, addr)
+			}
+		}
+		if scanner != nil {
+			head, err := s.chain.Head(context.Background())
+			if err != nil {
+				log.Error("Failed to retrieve chain head err: ", err)
+				continue
+			}
+			if next == 0 {
+				next = head
+			}
+			for ; next <= head; next++ {
+				accounts, err := scanner.BlockAccounts(context.Background(), next)
+				if err != nil {
+					log.Error("Failed to retrieve block err: ", err)
+					break
+				}
+				for _, addr := range accounts {
+					if !seen[addr] {
+						candidates = append(candidates, addr)
+					}
+				}
+			}
+		}
+		for _, addr := range candidates {
+			if seen[addr] {
+				continue // Listed multiple times within the same scan
+			}
+			seen[addr] = true
+			s.autoFund(handle, addr)
+		}
+	}
+}
+
+// autoFund tops up an address to the floor balance, unless it holds more.
+func (s *Server) autoFund(handle Handler, addr string) {
+	balance, err := s.chain.BalanceOf(context.Background(), addr)
+	if err != nil {
+		log.Error("Failed to retrieve balance of ", addr, " err: ", err)
+		return
+	}
+	amount := new(big.Int).Sub(toWei(*autoFundFloorFlag), balance)
+	if amount.Sign() <= 0 {
+		return
+	}
+	c := &Claim{ctx: context.Background(), server: s, Address: addr, Amount: amount, IP: "autofund", Lang: defaultLanguage}
+	if err := handle(c); err != nil {
+		log.Error("Failed to auto-fund ", addr, " err: ", err)
+		return
+	}
+	log.Info("Auto-funded ", addr, " with ", fromWei(amount), " ", *UnitFlag)
+}
+
+// readAllowlist loads the addresses listed in a file, skipping blank lines and
+// # comments. A missing or unreadable file yields no addresses.
+func (s *Server) readAllowlist(path string) []string {
+	if path == "" {
+		return nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		log.Error("Failed to open auto-fund allowlist err: ", err)
+		return nil
+	}
+	defer file.Close()
+
+	var addrs []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		addr, err := s.chain.ParseAddress(line)
+		if err != nil {
+			log.Error("Invalid address in auto-fund allowlist: ", line)
+			continue
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
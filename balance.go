@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var expectedClaimsFlag = flag.Int("payout.expected", 0, "Expected claims per day to spread the remaining balance over, capping payouts at balance/expected (0 = disabled)")
+
+// balanceCache holds the recently retrieved balance of the faucet account, so
+// not every claim needs a round trip to the node.
+var balanceCache = struct {
+	lock    sync.Mutex
+	balance *big.Int
+	updated time.Time
+}{}
+
+// faucetBalance returns the balance of the faucet account, cached for a while,
+// or as last published by the sending instance on read-only mirrors.
+func (s *Server) faucetBalance(ctx context.Context) (*big.Int, error) {
+	if *readOnlyFlag {
+		return mirroredBalance()
+	}
+	balanceCache.lock.Lock()
+	defer balanceCache.lock.Unlock()
+
+	if balanceCache.balance != nil && time.Since(balanceCache.updated) < 30*time.Second {
+		return balanceCache.balance, nil
+	}
+	balance, err := s.chain.Balance(ctx)
+	if err != nil {
+		return nil, err
+	}
+	balanceCache.balance, balanceCache.updated = balance, time.Now()
+	return balance, nil
+}
+
+// payoutCap returns the current limit on payouts, or nil if payouts are not
+// scaled with the balance.
+func (s *Server) payoutCap(ctx context.Context) (*big.Int, error) {
+	if *expectedClaimsFlag <= 0 {
+		return nil, nil
+	}
+	balance, err := s.faucetBalance(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).Div(balance, big.NewInt(int64(*expectedClaimsFlag))), nil
+}
+
+// balanceStage scales the payout down as the faucet balance shrinks, so the
+// faucet serves more users for less instead of running dry abruptly.
+func balanceStage(next Handler) Handler {
+	return func(c *Claim) error {
+		limit, err := c.server.payoutCap(c.ctx)
+		if err != nil {
+			log.Error("Failed to retrieve faucet balance err: ", err)
+			return next(c) // Pay out the base amount rather than failing the claim
+		}
+		if limit == nil {
+			return next(c)
+		}
+		if limit.Sign() == 0 {
+			alert("balance", "faucet account %s is out of funds", c.server.address.Hex())
+			return newUserError("Faucet is out of funds")
+		}
+		if c.Amount.Cmp(limit) > 0 {
+			c.Amount = limit
+			c.Notes = append(c.Notes, translate(c.Lang, "Payout reduced to %s %s while the faucet is running low", fromWei(limit), *UnitFlag))
+		}
+		return next(c)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var basePathFlag = flag.String("base-path", "", "Path all routes of the faucet are served under, for reverse proxies mounting it at a subpath without stripping it, e.g. /faucet")
+
+// validateBasePath checks the base path is usable as a route prefix.
+func validateBasePath(path string) error {
+	if path != "" && (!strings.HasPrefix(path, "/") || strings.HasSuffix(path, "/")) {
+		return fmt.Errorf("must start but not end with a slash: %q", path)
+	}
+	return nil
+}
+
+// publicPrefix is the path the faucet is reachable under by clients, made up
+// of the prefix stripped by the reverse proxy and the base path.
+func publicPrefix() string {
+	return *apiPrefixFlag + *basePathFlag
+}
+
+// publicURL returns the absolute URL clients reach the given faucet route at.
+func publicURL(r *http.Request, path string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if *apiProxyFlag && r.Header.Get("X-Forwarded-Proto") != "" {
+		scheme = r.Header.Get("X-Forwarded-Proto")
+	}
+	return scheme + "://" + r.Host + publicPrefix() + path
+}
+
+// withBasePath serves the handler under the base path, redirecting the bare
+// base path to the website.
+func withBasePath(handler http.Handler) http.Handler {
+	if *basePathFlag == "" {
+		return handler
+	}
+	stripped := http.StripPrefix(*basePathFlag, handler)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == *basePathFlag {
+			http.Redirect(w, r, publicPrefix()+"/", http.StatusMovedPermanently)
+			return
+		}
+		if !strings.HasPrefix(r.URL.Path, *basePathFlag+"/") {
+			http.NotFound(w, r)
+			return
+		}
+		stripped.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	batchContractFlag = flag.String("batch.contract", "", "Disperser contract to pay out queued requests in batches through (empty = disabled)")
+	batchSizeFlag     = flag.Int("batch.size", 50, "Maximum number of recipients per batched payout")
+	batchMinFlag      = flag.Int("batch.min", 3, "Minimum number of queued requests worth a batched payout")
+)
+
+// disperserABI is the interface of the disperser contract paying out batches.
+var disperserABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(`[{"type":"function","name":"disperse","stateMutability":"payable","inputs":[{"name":"recipients","type":"address[]"},{"name":"values","type":"uint256[]"}],"outputs":[]}]`))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// collectBatch gathers the claims already waiting in the queue behind the given
+// one, up to the configured batch size, if batched payouts are enabled.
+func collectBatch(queue *jobQueue, job *fundJob) []*fundJob {
+	jobs := []*fundJob{job}
+	if *batchContractFlag == "" {
+		return jobs
+	}
+	for len(jobs) < *batchSizeFlag {
+		next := queue.tryPop()
+		if next == nil {
+			break
+		}
+		if !next.take() {
+			queueStats.serve() // Abandoned by the requester, skip it
+			continue
+		}
+		jobs = append(jobs, next)
+	}
+	return jobs
+}
+
+// runBatch runs the claims through the rest of the pipeline, paying them out
+// together in a single transaction once they all reached the send stage.
+func (s *Server) runBatch(jobs []*fundJob) {
+	start := time.Now()
+	batch := newPayoutBatch(s, len(jobs))
+	for _, job := range jobs {
+		queueStats.serve()
+		job.claim.batch = batch
+
+		go func(job *fundJob) {
+			defer batch.leave(job.claim)
+			defer func() {
+				if v := recover(); v != nil {
+					job.done <- recoverPanic("sender", job.claim, v)
+				}
+			}()
+			job.done <- job.next(job.claim)
+		}(job)
+	}
+	<-batch.done
+	queueStats.sent(time.Since(start) / time.Duration(len(jobs)))
+}
+
+// payoutBatch collects the claims to pay out in a single disperser transaction.
+// The transaction is sent once every member either reached the send stage or
+// dropped out of the pipeline before it.
+type payoutBatch struct {
+	server  *Server // Faucet paying out the batch
+	lock    sync.Mutex
+	size    int             // Number of claims in the batch
+	settled int             // Number of claims arrived or dropped out
+	seen    map[*Claim]bool // Claims arrived or dropped out
+	members []*Claim        // Claims arrived at the send stage
+
+	done chan struct{} // Closed once the transaction is out (or failed)
+	tx   ChainTx
+	err  error
+}
+
+func newPayoutBatch(s *Server, size int) *payoutBatch {
+	return &payoutBatch{server: s, size: size, seen: make(map[*Claim]bool), done: make(chan struct{})}
+}
+
+// join adds a claim arriving at the send stage to the batch, and waits for the
+// batch transaction to be broadcast.
+func (b *payoutBatch) join(c *Claim) (ChainTx, error) {
+	b.lock.Lock()
+	b.members = append(b.members, c)
+	full := b.settle(c)
+	b.lock.Unlock()
+
+	if full {
+		b.send()
+	}
+	<-b.done
+	return b.tx, b.err
+}
+
+// leave drops a claim from the batch if it didn't make it to the send stage.
+func (b *payoutBatch) leave(c *Claim) {
+	b.lock.Lock()
+	full := b.settle(c)
+	b.lock.Unlock()
+
+	if full {
+		b.send()
+	}
+}
+
+// settle marks a claim as arrived or dropped out, reporting whether it was the
+// last one to do so. The caller must hold the lock.
+func (b *payoutBatch) settle(c *Claim) bool {
+	if b.seen[c] {
+		return false
+	}
+	b.seen[c] = true
+	b.settled++
+	return b.settled == b.size
+}
+
+// send broadcasts the payout of all arrived members. Single members are paid
+// out with a plain transfer instead of the disperser.
+func (b *payoutBatch) send() {
+	defer close(b.done)
+
+	// Members may have left already, the batch must go out regardless
+	ctx := context.Background()
+	switch len(b.members) {
+	case 0:
+		return
+	case 1:
+		b.tx, b.err = b.server.SendTx(ctx, b.members[0].Amount, b.members[0].Address)
+		return
+	}
+	var (
+		recipients = make([]common.Address, len(b.members))
+		values     = make([]*big.Int, len(b.members))
+		total      = new(big.Int)
+	)
+	for i, c := range b.members {
+		recipients[i], values[i] = common.HexToAddress(c.Address), c.Amount
+		total.Add(total, c.Amount)
+	}
+	data, err := disperserABI.Pack("disperse", recipients, values)
+	if err != nil {
+		b.err = err
+		return
+	}
+	log.Info("Paying out batch of ", len(b.members), " requests")
+	b.tx, b.err = b.server.sendTx(ctx, *batchContractFlag, total, data)
+}
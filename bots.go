@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	honeypotFlag   = flag.Bool("bots.honeypot", false, "Add a hidden field to the claim form, silently ignoring claims that fill it in")
+	botMinTimeFlag = flag.Duration("bots.mintime", 0, "Minimum time between loading the website and claiming, silently ignoring faster claims (0 = disabled)")
+)
+
+// formNonceCookie is the cookie carrying the nonce of the website a claim is
+// submitted from, recording when the page was loaded.
+const formNonceCookie = "faucet-form"
+
+// formNonceTTL is the time after which a form nonce expires and the user needs
+// to reload the website.
+const formNonceTTL = 24 * time.Hour
+
+// botsTrapped counts the claims silently ignored as automated, by reason.
+var botsTrapped = newCounterVec("faucet_bots_trapped_total", "Website claims silently ignored as automated.", "reason")
+
+// setFormNonce hands a nonce bound to the requester and the time of loading the
+// website to the browser in a cookie.
+func setFormNonce(w http.ResponseWriter, r *http.Request) {
+	issued := time.Now().Unix()
+	http.SetCookie(w, &http.Cookie{
+		Name:     formNonceCookie,
+		Value:    fmt.Sprintf("%d.%s", issued, tokenMAC("form:"+remoteHost(r.RemoteAddr), issued)),
+		Path:     publicPrefix() + "/",
+		MaxAge:   int(formNonceTTL.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// formNonce returns the form nonce carried by a request in its cookies.
+func formNonce(r *http.Request) string {
+	if cookie, err := r.Cookie(formNonceCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// formAge returns the time since the website carrying a form nonce was loaded by
+// the given remote address, or false if the nonce wasn't issued to it.
+func formAge(nonce string, addr string) (time.Duration, bool) {
+	parts := strings.SplitN(nonce, ".", 2)
+	if len(parts) != 2 {
+		return 0, false
+	}
+	issued, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || parts[1] != tokenMAC("form:"+remoteHost(addr), issued) {
+		return 0, false
+	}
+	return time.Since(time.Unix(issued, 0)), true
+}
+
+// botStage shadow-bans claims submitted through the website by obvious bots:
+// claims filling in the honeypot field, not coming from a loaded website or
+// submitted too fast. They are answered as if funded, without paying out, so
+// bots don't learn to evade the checks.
+func botStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if !c.website {
+			return next(c)
+		}
+		trap := ""
+		if *honeypotFlag && c.Honeypot != "" {
+			trap = "honeypot"
+		}
+		if trap == "" && *botMinTimeFlag > 0 {
+			age, ok := formAge(c.FormNonce, c.IP)
+			switch {
+			case !ok:
+				trap = "nonce"
+			case age > formNonceTTL:
+				return newUserError("Session expired, please reload the page")
+			case age < *botMinTimeFlag:
+				trap = "too-fast"
+			}
+		}
+		if trap == "" {
+			return next(c)
+		}
+		log.Info("Shadow-banning automated claim: ", c.Address, " ip: ", remoteHost(c.IP), " reason: ", trap)
+		botsTrapped.With(trap).Inc()
+		return decoyPayout(c)
+	}
+}
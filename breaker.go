@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"math/big"
+	"sync"
+	"time"
+
+	ethrpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	breakerFailuresFlag = flag.Int("rpc.breaker.failures", 5, "Consecutive failed RPC calls after which claims are turned away until the node recovers (0 = disabled)")
+	breakerCooloffFlag  = flag.Duration("rpc.breaker.cooloff", 30*time.Second, "Time to turn claims away for before probing the node again")
+)
+
+// breaker tracks the health of the chain backend. After repeated failures it
+// opens, turning claims away right away instead of having each wait for the
+// node to time out, and probes the node every cool-off until it recovers.
+var breaker struct {
+	lock     sync.Mutex
+	failures int       // Consecutive failed calls
+	open     bool      // Whether claims are turned away
+	retry    time.Time // Time of the next probe while open
+}
+
+func init() {
+	registerGauge("faucet_rpc_breaker_open", "Whether claims are turned away as the RPC node is failing (1) or not (0).", func() float64 {
+		breaker.lock.Lock()
+		defer breaker.lock.Unlock()
+		if breaker.open {
+			return 1
+		}
+		return 0
+	})
+}
+
+// backendFailure reports whether an error means the node is unavailable, as
+// opposed to the node rejecting a call (e.g. a revert or a nonce too low) or
+// the client going away.
+func backendFailure(err error) bool {
+	var (
+		uerr    *userError
+		failed  *txFailedError
+		rpcErr  ethrpc.Error
+		httpErr ethrpc.HTTPError
+	)
+	switch {
+	case errors.Is(err, context.Canceled), errors.As(err, &uerr), errors.As(err, &failed):
+		return false
+	case errors.As(err, &httpErr):
+		return httpErr.StatusCode >= 500 || httpErr.StatusCode == 429
+	case errors.As(err, &rpcErr):
+		return false
+	}
+	return true
+}
+
+// recordBackend folds the outcome of a backend call into the breaker, opening
+// it once the configured number of consecutive calls failed.
+func recordBackend(chain ChainBackend, err error) {
+	if *breakerFailuresFlag <= 0 {
+		return
+	}
+	breaker.lock.Lock()
+	defer breaker.lock.Unlock()
+
+	if err == nil {
+		breaker.failures = 0
+		return
+	}
+	if !backendFailure(err) {
+		return
+	}
+	breaker.failures++
+	if breaker.open || breaker.failures < *breakerFailuresFlag {
+		return
+	}
+	breaker.open, breaker.retry = true, time.Now().Add(*breakerCooloffFlag)
+	alert("rpc", "RPC node failing (%v), turning claims away for %s", err, *breakerCooloffFlag)
+	go probeBackend(chain)
+}
+
+// probeBackend checks the node every cool-off while the breaker is open,
+// closing it once the node answers again.
+func probeBackend(chain ChainBackend) {
+	for {
+		time.Sleep(*breakerCooloffFlag)
+
+		ctx, cancel := rpcContext(context.Background())
+		_, err := chain.Head(ctx)
+		cancel()
+
+		breaker.lock.Lock()
+		if err == nil {
+			breaker.open, breaker.failures = false, 0
+			breaker.lock.Unlock()
+			log.Info("RPC node recovered, accepting claims again")
+			return
+		}
+		breaker.retry = time.Now().Add(*breakerCooloffFlag)
+		breaker.lock.Unlock()
+		log.Error("RPC node still failing err: ", err)
+	}
+}
+
+// breakerStage turns claims away while the breaker is open.
+func breakerStage(next Handler) Handler {
+	return func(c *Claim) error {
+		breaker.lock.Lock()
+		open, retry := breaker.open, time.Until(breaker.retry).Round(time.Second)
+		breaker.lock.Unlock()
+
+		if open {
+			if retry < time.Second {
+				retry = time.Second
+			}
+			return &busyError{error: newUserError("Faucet backend unavailable, please retry in %s", retry), retry: retry}
+		}
+		return next(c)
+	}
+}
+
+// breakerBackend reports the outcome of the calls to a chain backend to the
+// circuit breaker.
+type breakerBackend struct {
+	ChainBackend
+}
+
+// unwrapChain returns the chain backend the faucet was started with.
+func unwrapChain(chain ChainBackend) ChainBackend {
+	if wrapped, ok := chain.(*breakerBackend); ok {
+		return wrapped.ChainBackend
+	}
+	return chain
+}
+
+func (b *breakerBackend) BuildTx(ctx context.Context, to string, amount *big.Int, data []byte) (ChainTx, error) {
+	tx, err := b.ChainBackend.BuildTx(ctx, to, amount, data)
+	recordBackend(b.ChainBackend, err)
+	return tx, err
+}
+
+func (b *breakerBackend) Broadcast(ctx context.Context, tx ChainTx) error {
+	err := b.ChainBackend.Broadcast(ctx, tx)
+	recordBackend(b.ChainBackend, err)
+	return err
+}
+
+func (b *breakerBackend) Balance(ctx context.Context) (*big.Int, error) {
+	balance, err := b.ChainBackend.Balance(ctx)
+	recordBackend(b.ChainBackend, err)
+	return balance, err
+}
+
+func (b *breakerBackend) Head(ctx context.Context) (uint64, error) {
+	head, err := b.ChainBackend.Head(ctx)
+	recordBackend(b.ChainBackend, err)
+	return head, err
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sunvim/utils/log"
+)
+
+var wsQueueFlag = flag.Int("ws.sendqueue", 64, "Messages buffered per websocket client before it's dropped as too slow to keep up")
+
+// errSlowClient is returned when sending to a websocket client whose send queue
+// is full. The client is dropped.
+var errSlowClient = errors.New("websocket client too slow")
+
+// wsDropped counts the websocket clients dropped for being too slow.
+var wsDropped = newCounter("faucet_ws_dropped_total", "Websocket clients dropped for falling too far behind on messages.")
+
+// wsMessage is an encoded message waiting to be written to a websocket client.
+type wsMessage struct {
+	kind    int // websocket.TextMessage or websocket.BinaryMessage
+	data    []byte
+	timeout time.Duration // Write deadline of the message
+}
+
+// newWSConn wraps a freshly upgraded websocket connection and starts its writer.
+func newWSConn(conn *websocket.Conn, cbor bool) *wsConn {
+	c := &wsConn{
+		conn:    conn,
+		cbor:    cbor,
+		queue:   make(chan *wsMessage, *wsQueueFlag),
+		closing: make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+	go c.loopWrites()
+	return c
+}
+
+// encode encodes a message in the encoding negotiated for the connection.
+func (c *wsConn) encode(value interface{}, timeout time.Duration) (*wsMessage, error) {
+	if timeout == 0 {
+		timeout = 60 * time.Second
+	}
+	if c.cbor {
+		blob, err := cborMarshal(value)
+		if err != nil {
+			return nil, err
+		}
+		return &wsMessage{kind: websocket.BinaryMessage, data: blob, timeout: timeout}, nil
+	}
+	blob, err := marshalAPI(value)
+	if err != nil {
+		return nil, err
+	}
+	return &wsMessage{kind: websocket.TextMessage, data: blob, timeout: timeout}, nil
+}
+
+// enqueue queues a message for the writer without ever blocking. A client that
+// fell too far behind is dropped instead of holding up the sender.
+func (c *wsConn) enqueue(msg *wsMessage) error {
+	select {
+	case <-c.closing:
+		return websocket.ErrCloseSent
+	default:
+	}
+	select {
+	case c.queue <- msg:
+		return nil
+	default:
+		log.Info("Dropping websocket client too slow to keep up: ", c.conn.RemoteAddr())
+		wsDropped.Inc()
+		c.conn.Close()
+		return errSlowClient
+	}
+}
+
+// loopWrites writes the queued messages to the client one at a time. Once the
+// connection is closing, the remaining queued messages are flushed.
+func (c *wsConn) loopWrites() {
+	defer close(c.closed)
+
+	write := func(msg *wsMessage) error {
+		c.conn.SetWriteDeadline(time.Now().Add(msg.timeout))
+		return c.conn.WriteMessage(msg.kind, msg.data)
+	}
+	for {
+		select {
+		case msg := <-c.queue:
+			if err := write(msg); err != nil {
+				c.conn.Close() // Unblocks the reader, tearing the connection down
+				return
+			}
+		case <-c.closing:
+			for {
+				select {
+				case msg := <-c.queue:
+					if write(msg) != nil {
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// close flushes the queued messages and closes the connection.
+func (c *wsConn) close() {
+	close(c.closing)
+	<-c.closed
+	c.conn.Close()
+}
+
+// broadcaster tracks the connected websocket clients and fans feed messages out
+// to them through their send queues, so a stalled client can't hold up the
+// funding path or the other clients.
+type broadcaster struct {
+	lock  sync.RWMutex
+	conns map[*wsConn]struct{}
+}
+
+// hub is the broadcaster of the websocket API.
+var hub = &broadcaster{conns: make(map[*wsConn]struct{})}
+
+// add starts delivering feed messages to a client.
+func (b *broadcaster) add(conn *wsConn) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.conns[conn] = struct{}{}
+}
+
+// remove stops delivering feed messages to a client.
+func (b *broadcaster) remove(conn *wsConn) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	delete(b.conns, conn)
+}
+
+// len returns the number of connected clients.
+func (b *broadcaster) len() int {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	return len(b.conns)
+}
+
+// broadcast queues a message to every connected client, encoding it once per
+// encoding in use.
+func (b *broadcaster) broadcast(value interface{}) {
+	b.lock.RLock()
+	conns := make([]*wsConn, 0, len(b.conns))
+	for conn := range b.conns {
+		conns = append(conns, conn)
+	}
+	b.lock.RUnlock()
+
+	encoded := make(map[bool]*wsMessage)
+	for _, conn := range conns {
+		msg, ok := encoded[conn.cbor]
+		if !ok {
+			var err error
+			if msg, err = conn.encode(value, time.Second); err != nil {
+				log.Error("Failed to encode broadcast err: ", err)
+				return
+			}
+			encoded[conn.cbor] = msg
+		}
+		conn.enqueue(msg)
+	}
+}
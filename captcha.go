@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	captchaVerifyFlag = flag.String("captcha.verify", "https://www.google.com/recaptcha/api/siteverify", "Captcha verification endpoint (e.g. Cloudflare Turnstile's siteverify)")
+	captchaV3Flag     = flag.Bool("captcha.v3", false, "Use score based reCAPTCHA v3 instead of the invisible v2 challenge")
+	captchaCurveFlag  = flag.String("captcha.curve", "", `Payout and cooldown multipliers by minimum captcha score, e.g. "0.7:1:1,0.5:0.5:2,0.3:0.25:4" (empty = pass/fail)`)
+)
+
+// scoreStep scales the payout and cooldown of claims whose captcha score is at
+// least the given minimum.
+type scoreStep struct {
+	min      float64 // Minimum captcha score for the step to apply
+	payout   float64 // Multiplier applied to the payout
+	cooldown float64 // Multiplier applied to the cooldown
+}
+
+// scoreCurve maps captcha scores to payout and cooldown multipliers, ordered by
+// descending minimum score. Claims scoring below the last step are rejected. An
+// empty curve accepts any successfully verified captcha as is.
+type scoreCurve []scoreStep
+
+// captchaCurve is the captcha score curve configured for the faucet.
+var captchaCurve scoreCurve
+
+// parseScoreCurve parses a comma separated list of min:payout:cooldown steps.
+func parseScoreCurve(spec string) (scoreCurve, error) {
+	var curve scoreCurve
+	for _, item := range strings.Split(spec, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		parts := strings.Split(item, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid score step %q, want min:payout:cooldown", item)
+		}
+		var values [3]float64
+		for i, part := range parts {
+			value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil || value < 0 {
+				return nil, fmt.Errorf("invalid score step %q", item)
+			}
+			values[i] = value
+		}
+		if values[0] > 1 || values[1] == 0 || values[2] == 0 {
+			return nil, fmt.Errorf("invalid score step %q", item)
+		}
+		curve = append(curve, scoreStep{min: values[0], payout: values[1], cooldown: values[2]})
+	}
+	sort.Slice(curve, func(i, j int) bool { return curve[i].min > curve[j].min })
+	return curve, nil
+}
+
+// apply scales the payout and cooldown of a claim according to its captcha
+// score, returning false if the score is too low to be funded at all.
+func (sc scoreCurve) apply(c *Claim, score float64) bool {
+	if len(sc) == 0 {
+		return true
+	}
+	for _, step := range sc {
+		if score < step.min {
+			continue
+		}
+		payout, _ := new(big.Float).Mul(new(big.Float).SetInt(c.Amount), big.NewFloat(step.payout)).Int(nil)
+		c.Amount = payout
+		c.Cooldown = time.Duration(float64(c.Cooldown) * step.cooldown)
+		return true
+	}
+	return false
+}
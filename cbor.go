@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"time"
+)
+
+var apiCBORFlag = flag.Bool("api.cbor", true, "Offer the faucet.cbor websocket subprotocol, exchanging CBOR encoded messages instead of JSON")
+
+// cborSubprotocol is the websocket subprotocol negotiating CBOR (RFC 8949)
+// framing of the websocket messages, which otherwise carry the same fields as
+// their JSON counterparts.
+const cborSubprotocol = "faucet.cbor"
+
+// maxCBORDepth bounds the nesting of decoded CBOR items.
+const maxCBORDepth = 16
+
+// errMalformedCBOR is returned for CBOR input that can't be decoded.
+var errMalformedCBOR = errors.New("malformed CBOR")
+
+// cborMarshaler is implemented by the websocket messages, which encode
+// themselves into CBOR with the same fields as into JSON. Encoding them directly
+// spares busy deployments a detour through JSON for every message.
+type cborMarshaler interface {
+	marshalCBOR(buf *bytes.Buffer)
+}
+
+// cborMarshal encodes a websocket message into CBOR.
+func cborMarshal(value interface{}) ([]byte, error) {
+	msg, ok := value.(cborMarshaler)
+	if !ok {
+		return nil, fmt.Errorf("unsupported CBOR message %T", value)
+	}
+	buf := new(bytes.Buffer)
+	msg.marshalCBOR(buf)
+	return buf.Bytes(), nil
+}
+
+func (m *wsErrorMessage) marshalCBOR(buf *bytes.Buffer) {
+	writeCBORHead(buf, 5, 1)
+	writeCBORString(buf, "error")
+	writeCBORString(buf, m.Error)
+}
+
+func (m *wsSuccessMessage) marshalCBOR(buf *bytes.Buffer) {
+	if m.Receipt == nil {
+		writeCBORHead(buf, 5, 1)
+	} else {
+		writeCBORHead(buf, 5, 2)
+	}
+	writeCBORString(buf, "success")
+	writeCBORString(buf, m.Success)
+	if m.Receipt != nil {
+		writeCBORString(buf, "receipt")
+		writeCBORHead(buf, 5, 3)
+		writeCBORString(buf, "message")
+		writeCBORString(buf, m.Receipt.Message)
+		writeCBORString(buf, "signature")
+		writeCBORString(buf, m.Receipt.Signature)
+		writeCBORString(buf, "signer")
+		writeCBORString(buf, m.Receipt.Signer)
+	}
+}
+
+func (m *wsQueueMessage) marshalCBOR(buf *bytes.Buffer) {
+	writeCBORHead(buf, 5, 3)
+	writeCBORString(buf, "queue")
+	writeCBORInt(buf, int64(m.Queue))
+	writeCBORString(buf, "eta")
+	writeCBORInt(buf, int64(m.ETA))
+	writeCBORString(buf, "status")
+	writeCBORString(buf, m.Status)
+}
+
+func (m *wsStatusMessage) marshalCBOR(buf *bytes.Buffer) {
+	writeCBORHead(buf, 5, 1)
+	writeCBORString(buf, "status")
+	writeCBORString(buf, m.Status)
+}
+
+func (m *wsSessionMessage) marshalCBOR(buf *bytes.Buffer) {
+	writeCBORHead(buf, 5, 1)
+	writeCBORString(buf, "session")
+	writeCBORString(buf, m.Session)
+}
+
+func (m *wsFundedMessage) marshalCBOR(buf *bytes.Buffer) {
+	writeCBORHead(buf, 5, 1)
+	writeCBORString(buf, "funded")
+	if m.Funded == nil {
+		buf.WriteByte(0xf6)
+		return
+	}
+	c := m.Funded
+	if c.NFT == "" {
+		writeCBORHead(buf, 5, 4)
+	} else {
+		writeCBORHead(buf, 5, 5)
+	}
+	writeCBORString(buf, "time")
+	writeCBORString(buf, c.Time.UTC().Format(time.RFC3339Nano)) // As normalized by marshalAPI
+	writeCBORString(buf, "address")
+	writeCBORString(buf, c.Address)
+	writeCBORString(buf, "amount")
+	writeCBORString(buf, c.Amount)
+	writeCBORString(buf, "tx")
+	writeCBORString(buf, c.Tx)
+	if c.NFT != "" {
+		writeCBORString(buf, "nft")
+		writeCBORString(buf, c.NFT)
+	}
+}
+
+// writeCBORString writes a CBOR text string.
+func writeCBORString(buf *bytes.Buffer, s string) {
+	writeCBORHead(buf, 3, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+// writeCBORInt writes a CBOR integer.
+func writeCBORInt(buf *bytes.Buffer, n int64) {
+	if n < 0 {
+		writeCBORHead(buf, 1, uint64(-1-n))
+	} else {
+		writeCBORHead(buf, 0, uint64(n))
+	}
+}
+
+// writeCBORHead writes the initial bytes of a CBOR item of the given major type
+// and argument in their shortest form.
+func writeCBORHead(buf *bytes.Buffer, major byte, arg uint64) {
+	major <<= 5
+	switch {
+	case arg < 24:
+		buf.WriteByte(major | byte(arg))
+	case arg <= math.MaxUint8:
+		buf.Write([]byte{major | 24, byte(arg)})
+	case arg <= math.MaxUint16:
+		buf.WriteByte(major | 25)
+		binary.Write(buf, binary.BigEndian, uint16(arg))
+	case arg <= math.MaxUint32:
+		buf.WriteByte(major | 26)
+		binary.Write(buf, binary.BigEndian, uint32(arg))
+	default:
+		buf.WriteByte(major | 27)
+		binary.Write(buf, binary.BigEndian, arg)
+	}
+}
+
+// cborToJSON transcodes a single definite-length CBOR item into JSON, so it can
+// be decoded as strictly as the JSON messages. Byte strings and undefined have
+// no JSON counterpart and are rejected; tags are ignored.
+func cborToJSON(data []byte) ([]byte, error) {
+	d := &cborDecoder{data: data}
+	value, err := d.decode(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, errMalformedCBOR
+	}
+	return json.Marshal(value)
+}
+
+// cborDecoder decodes CBOR items into the generic values of encoding/json.
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+// head reads the initial bytes of a CBOR item.
+func (d *cborDecoder) head() (major byte, info byte, arg uint64, err error) {
+	if d.pos >= len(d.data) {
+		return 0, 0, 0, errMalformedCBOR
+	}
+	major, info = d.data[d.pos]>>5, d.data[d.pos]&0x1f
+	d.pos++
+
+	size := 0
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, 0, 0, errMalformedCBOR // Reserved or indefinite length
+	}
+	if len(d.data)-d.pos < size {
+		return 0, 0, 0, errMalformedCBOR
+	}
+	for _, b := range d.data[d.pos : d.pos+size] {
+		arg = arg<<8 | uint64(b)
+	}
+	d.pos += size
+	return major, info, arg, nil
+}
+
+// decode decodes the next CBOR item.
+func (d *cborDecoder) decode(depth int) (interface{}, error) {
+	if depth > maxCBORDepth {
+		return nil, errMalformedCBOR
+	}
+	major, info, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case 0:
+		return arg, nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, errMalformedCBOR
+		}
+		return -1 - int64(arg), nil
+	case 3:
+		if arg > uint64(len(d.data)-d.pos) {
+			return nil, errMalformedCBOR
+		}
+		s := string(d.data[d.pos : d.pos+int(arg)])
+		d.pos += int(arg)
+		return s, nil
+	case 4:
+		if arg > uint64(len(d.data)-d.pos) { // Every item takes a byte at least
+			return nil, errMalformedCBOR
+		}
+		items := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			item, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case 5:
+		if arg > uint64(len(d.data)-d.pos)/2 {
+			return nil, errMalformedCBOR
+		}
+		fields := make(map[string]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			key, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, errMalformedCBOR
+			}
+			if fields[name], err = d.decode(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return fields, nil
+	case 6:
+		return d.decode(depth + 1)
+	case 7:
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22:
+			return nil, nil
+		case 25:
+			return float16(uint16(arg)), nil
+		case 26:
+			return float64(math.Float32frombits(uint32(arg))), nil
+		case 27:
+			return math.Float64frombits(arg), nil
+		}
+	}
+	return nil, errMalformedCBOR
+}
+
+// float16 converts an IEEE 754 half precision float.
+func float16(bits uint16) float64 {
+	exp, frac := int(bits>>10&0x1f), float64(bits&0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(frac, -24)
+	case 0x1f:
+		if frac == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(frac+1024, exp-25)
+	}
+	if bits&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
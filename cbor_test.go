@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCBORMessages checks the websocket messages encode into CBOR with the same
+// fields and values as into JSON.
+func TestCBORMessages(t *testing.T) {
+	when := time.Date(2024, 5, 1, 14, 0, 0, 123, time.FixedZone("CEST", 2*3600))
+	for _, msg := range []interface{}{
+		&wsErrorMessage{Error: "Invalid request"},
+		&wsSuccessMessage{Success: "Funding request accepted"},
+		&wsSuccessMessage{Success: "Funded", Receipt: &claimReceipt{Message: "m", Signature: "0x01", Signer: "0x02"}},
+		&wsQueueMessage{Queue: 300, ETA: 70000, Status: "Waiting"},
+		&wsQueueMessage{Queue: -1},
+		&wsStatusMessage{Status: "Sending 🚰"},
+		&wsSessionMessage{Session: "abc"},
+		&wsFundedMessage{},
+		&wsFundedMessage{Funded: &activityClaim{Time: when, Address: "0xab", Amount: "0.5", Tx: "0xcd"}},
+		&wsFundedMessage{Funded: &activityClaim{Time: when, Address: "0xab", Amount: "0.5", Tx: "0xcd", NFT: "7"}},
+	} {
+		blob, err := cborMarshal(msg)
+		if err != nil {
+			t.Fatalf("failed to encode %+v: %v", msg, err)
+		}
+		transcoded, err := cborToJSON(blob)
+		if err != nil {
+			t.Fatalf("failed to decode %+v from %x: %v", msg, blob, err)
+		}
+		want, _ := marshalAPI(msg)
+
+		var have, expect interface{}
+		json.Unmarshal(transcoded, &have)
+		json.Unmarshal(want, &expect)
+		if !reflect.DeepEqual(have, expect) {
+			t.Errorf("CBOR of %T is %s, JSON %s", msg, transcoded, want)
+		}
+	}
+	if _, err := cborMarshal(map[string]string{}); err == nil {
+		t.Error("encoded a value that is no websocket message")
+	}
+}
+
+func TestCBORToJSON(t *testing.T) {
+	nested := func(depth int) string {
+		return strings.Repeat("81", depth) + "00" // Arrays of a single item each
+	}
+	tests := []struct {
+		name string
+		cbor string // Hex encoded input
+		json string // Expected output, empty if malformed
+	}{
+		{"small uint", "17", `23`},
+		{"uint8", "1818", `24`},
+		{"uint16", "190100", `256`},
+		{"uint32", "1a00010000", `65536`},
+		{"max uint64", "1bffffffffffffffff", `18446744073709551615`},
+		{"negative", "20", `-1`},
+		{"min int64", "3b7fffffffffffffff", `-9223372036854775808`},
+		{"below int64", "3b8000000000000000", ``},
+		{"text", "6449455446", `"IETF"`},
+		{"empty text", "60", `""`},
+		{"utf-8 text", "62c3bc", `"ü"`},
+		{"truncated text", "64494554", ``},
+		{"overlong text", "7bffffffffffffffff00", ``},
+		{"byte string", "4401020304", ``},
+		{"array", "83010203", `[1,2,3]`},
+		{"empty array", "80", `[]`},
+		{"overlong array", "9bffffffffffffffff", ``},
+		{"map", "a2616101616283020304", `{"a":1,"b":[2,3,4]}`},
+		{"nested map", "a16175a161626179", `{"u":{"b":"y"}}`},
+		{"empty map", "a0", `{}`},
+		{"integer map key", "a10102", ``},
+		{"map missing value", "a16161", ``},
+		{"overlong map", "bbffffffffffffffff", ``},
+		{"tag ignored", "c074323031332d30332d32315432303a30343a30305a", `"2013-03-21T20:04:00Z"`},
+		{"simple values", "83f4f5f6", `[false,true,null]`},
+		{"undefined", "f7", ``},
+		{"half float", "f93e00", `1.5`},
+		{"single float", "fa47c35000", `100000`},
+		{"double float", "fb3ff199999999999a", `1.1`},
+		{"indefinite array", "9f01ff", ``},
+		{"reserved info", "1c", ``},
+		{"trailing bytes", "0000", ``},
+		{"empty", "", ``},
+		{"max nesting", nested(maxCBORDepth), `[` + strings.Repeat("[", maxCBORDepth-1) + `0` + strings.Repeat("]", maxCBORDepth)},
+		{"too deep", nested(maxCBORDepth + 1), ``},
+		{"too deep tags", strings.Repeat("c0", maxCBORDepth+1) + "00", ``},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := hex.DecodeString(tt.cbor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			blob, err := cborToJSON(data)
+			switch {
+			case tt.json == "" && err == nil:
+				t.Fatalf("malformed input decoded to %s", blob)
+			case tt.json != "" && err != nil:
+				t.Fatalf("failed to decode: %v", err)
+			case tt.json != "" && !bytes.Equal(blob, []byte(tt.json)):
+				t.Fatalf("decoded to %s, want %s", blob, tt.json)
+			}
+		})
+	}
+}
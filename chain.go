@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"math/big"
+)
+
+// ChainBackend abstracts the chain the faucet pays out on, so that networks
+// other than EVM ones can be funded by plugging in a different implementation.
+type ChainBackend interface {
+	// ParseAddress checks an account given by a user in the native address
+	// format of the chain, returning it in canonical form.
+	ParseAddress(addr string) (string, error)
+
+	// BuildTx creates an unsigned transfer of amount to the recipient, given in
+	// the native address format of the chain, optionally carrying call data.
+	BuildTx(ctx context.Context, to string, amount *big.Int, data []byte) (ChainTx, error)
+
+	// Sign signs a transaction built by the backend with the faucet key.
+	Sign(tx ChainTx) (ChainTx, error)
+
+	// Broadcast submits a signed transaction to the network.
+	Broadcast(ctx context.Context, tx ChainTx) error
+
+	// Confirm waits for a broadcast transaction to be included in a block or
+	// the context to be cancelled.
+	Confirm(ctx context.Context, tx ChainTx) (*ChainReceipt, error)
+
+	// Balance retrieves the balance of the faucet account.
+	Balance(ctx context.Context) (*big.Int, error)
+
+	// BalanceOf retrieves the balance of an account.
+	BalanceOf(ctx context.Context, addr string) (*big.Int, error)
+
+	// TxCount retrieves the number of transactions sent by an account as of
+	// the given block, or the latest one if nil.
+	TxCount(ctx context.Context, addr string, block *uint64) (uint64, error)
+
+	// Head retrieves the number of the latest block.
+	Head(ctx context.Context) (uint64, error)
+
+	// DecodeTx restores a signed transaction encoded by its MarshalBinary.
+	DecodeTx(raw []byte) (ChainTx, error)
+}
+
+// ChainTx is a transaction created by a chain backend. Its content is only
+// interpreted by the backend that built it.
+type ChainTx interface {
+	// ID returns the identifier of the transaction as shown to users.
+	ID() string
+
+	// MarshalBinary encodes a signed transaction for the journal.
+	MarshalBinary() ([]byte, error)
+}
+
+// ChainReceipt is the outcome of an included transaction.
+type ChainReceipt struct {
+	Block   uint64 // Number of the block the transaction was included in
+	Success bool   // Whether the transaction executed successfully
+	Reason  string // Why the transaction failed, if known
+
+	Logs []ChainLog // Events emitted by the transaction, on chains with contracts
+}
+
+// ChainLog is an event emitted by a contract.
+type ChainLog struct {
+	Contract string   // Address of the emitting contract
+	Topics   [][]byte // Indexed parameters, led by the event signature
+	Data     []byte   // Non-indexed parameters
+}
+
+// sendTx builds, signs and broadcasts a transaction from the faucet account,
+// journaling it until it's known to be mined.
+func (s *Server) sendTx(ctx context.Context, to string, amount *big.Int, data []byte) (ChainTx, error) {
+	tx, err := s.chain.BuildTx(ctx, to, amount, data)
+	if err != nil {
+		return nil, err
+	}
+	if tx, err = s.chain.Sign(tx); err != nil {
+		return nil, err
+	}
+	if err := journalTx(tx, to, amount); err != nil {
+		return nil, err
+	}
+	if err := s.chain.Broadcast(ctx, tx); err != nil {
+		unjournalTx(tx)
+		return nil, err
+	}
+	return tx, nil
+}
+
+// SendTx pays out the given amount to the recipient.
+func (s *Server) SendTx(ctx context.Context, amount *big.Int, to string) (ChainTx, error) {
+	return s.sendTx(ctx, to, amount, nil)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var chainCheckFlag = flag.Uint64("rpc.chaincheck", 100, "Verify the chain ID reported by the node before signing every Nth transaction, refusing to sign while it differs from --chain_id (0 = disabled)")
+
+// chainGuard tracks the periodic chain ID checks of a backend.
+type chainGuard struct {
+	lock     sync.Mutex
+	signed   uint64 // Transactions signed since startup
+	recheck  bool   // Whether the next transaction must be checked regardless
+	mismatch bool   // Whether the last check found the node on another chain
+}
+
+// verifyChain checks that the node still serves the chain transactions are
+// signed for, on the first and every --rpc.chaincheck transaction thereafter.
+// Nodes behind the same URL may be switched to another network, on which the
+// payouts would either be rejected or, worse, replayable. Once a check fails,
+// every transaction is checked until the node is back on the expected chain.
+func (b *evmBackend) verifyChain() error {
+	if *chainCheckFlag == 0 {
+		return nil
+	}
+	b.guard.lock.Lock()
+	defer b.guard.lock.Unlock()
+
+	due := b.guard.signed%*chainCheckFlag == 0 || b.guard.recheck
+	b.guard.signed++
+	if !due {
+		return nil
+	}
+	ctx, cancel := rpcContext(context.Background())
+	defer cancel()
+
+	id, err := b.client.ChainID(ctx)
+	if err != nil {
+		b.guard.recheck = true
+		return fmt.Errorf("failed to verify chain ID: %v", err)
+	}
+	if id.Cmp(b.chainID) != 0 {
+		b.guard.recheck, b.guard.mismatch = true, true
+		alert("chain", "node reports chain %v but transactions are signed for chain %v, refusing to sign", id, b.chainID)
+		return &busyError{error: newUserError("Faucet backend unavailable, please retry in %s", prettyDuration(time.Minute)), retry: time.Minute}
+	}
+	if b.guard.mismatch {
+		log.Info("Node is back on chain ", id, ", resuming payouts")
+	}
+	b.guard.recheck, b.guard.mismatch = false, false
+	return nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+var checkBalanceFlag = flag.Float64("check.balance", 0, "Minimum faucet balance in units for `faucet check` to pass (0 = the highest tier payout)")
+
+// selfCheck is a single step of the startup self-check, returning a short
+// description of the success or an error suggesting how to fix the problem.
+type selfCheck struct {
+	name string
+	run  func(ctx context.Context) (string, error)
+}
+
+// runCheck validates the configuration and the services the faucet depends on,
+// reporting every step on stdout. It returns the process exit code: nonzero if
+// any check failed, for use in deploy pipelines.
+func runCheck() int {
+	var (
+		client  *ethclient.Client
+		balance *big.Int
+		signer  common.Address // Faucet account, once the key was loaded
+	)
+	checks := []selfCheck{
+		{"config", func(ctx context.Context) (string, error) {
+			var err error
+			if payoutTiers, err = parseTiers(*tierListFlag); err != nil {
+				return "", fmt.Errorf("%v, fix --faucet.tierlist or --faucet.amount, --faucet.start and --faucet.tiers", err)
+			}
+			if err := validateL2(); err != nil {
+				return "", fmt.Errorf("%v, fix --chain.l2", err)
+			}
+			if _, err := parseSchedule(*hoursFlag, *hoursTZFlag); err != nil {
+				return "", fmt.Errorf("%v, fix --faucet.hours or --faucet.hours.tz", err)
+			}
+			if _, err := parseScoreCurve(*captchaCurveFlag); err != nil {
+				return "", fmt.Errorf("%v, fix --captcha.curve", err)
+			}
+			return "flags valid", nil
+		}},
+		{"rpc", func(ctx context.Context) (string, error) {
+			var err error
+			if client, err = ethclient.DialContext(ctx, *rpc); err != nil {
+				return "", fmt.Errorf("%v, check --rpc", err)
+			}
+			head, err := client.BlockNumber(ctx)
+			if err != nil {
+				client = nil
+				return "", fmt.Errorf("node at %s not responding (%v), check --rpc", redactFlag("rpc", *rpc), err)
+			}
+			return fmt.Sprintf("connected to %s at block %d", redactFlag("rpc", *rpc), head), nil
+		}},
+		{"chain", func(ctx context.Context) (string, error) {
+			if client == nil {
+				return "", fmt.Errorf("skipped, no RPC connection")
+			}
+			id, err := client.ChainID(ctx)
+			if err != nil {
+				return "", fmt.Errorf("failed to retrieve chain ID: %v", err)
+			}
+			if id.Cmp(big.NewInt(*chainID)) != 0 {
+				return "", fmt.Errorf("node is on chain %v but --chain_id is %d", id, *chainID)
+			}
+			return fmt.Sprintf("chain ID %v", id), nil
+		}},
+		{"signer", func(ctx context.Context) (string, error) {
+			key, err := crypto.HexToECDSA(*priKey)
+			if err != nil {
+				return "", fmt.Errorf("invalid key (%v), check --pri_key", err)
+			}
+			signer = crypto.PubkeyToAddress(key.PublicKey)
+			return fmt.Sprintf("signing as %s", signer.Hex()), nil
+		}},
+		{"balance", func(ctx context.Context) (string, error) {
+			if client == nil || signer == (common.Address{}) {
+				return "", fmt.Errorf("skipped, no RPC connection or signer")
+			}
+			var err error
+			if balance, err = client.BalanceAt(ctx, signer, nil); err != nil {
+				return "", fmt.Errorf("failed to retrieve balance: %v", err)
+			}
+			minimum := toWei(*checkBalanceFlag)
+			if minimum.Sign() <= 0 {
+				if len(payoutTiers) == 0 {
+					return "", fmt.Errorf("skipped, no valid funding tiers")
+				}
+				minimum = payoutTiers[len(payoutTiers)-1].Amount
+			}
+			if balance.Cmp(minimum) < 0 {
+				return "", fmt.Errorf("balance of %s %s below the minimum of %s, fund %s", fromWei(balance), *UnitFlag, fromWei(minimum), signer.Hex())
+			}
+			return fmt.Sprintf("%s %s", fromWei(balance), *UnitFlag), nil
+		}},
+		{"store", func(ctx context.Context) (string, error) {
+			s, err := openStore(*storePathFlag)
+			if err != nil {
+				return "", fmt.Errorf("%v, check --store.path and that no other faucet holds it", err)
+			}
+			defer s.Close()
+
+			if err := s.Put("check", "probe", []byte("ok")); err != nil {
+				return "", fmt.Errorf("store not writable: %v", err)
+			}
+			if _, err := s.Get("check", "probe"); err != nil {
+				return "", fmt.Errorf("store not readable: %v", err)
+			}
+			if err := s.Delete("check", "probe"); err != nil {
+				return "", fmt.Errorf("store not writable: %v", err)
+			}
+			if *storePathFlag == "" {
+				return "in memory, state is lost on restart", nil
+			}
+			return *storePathFlag, nil
+		}},
+		{"captcha", checkCaptcha},
+	}
+	failed := 0
+	for _, check := range checks {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		detail, err := check.run(ctx)
+		cancel()
+
+		if err != nil {
+			failed++
+			fmt.Printf("FAIL  %-8s %v\n", check.name, err)
+			continue
+		}
+		fmt.Printf("ok    %-8s %s\n", check.name, detail)
+	}
+	if failed > 0 {
+		fmt.Printf("%d of %d checks failed\n", failed, len(checks))
+		return 1
+	}
+	return 0
+}
+
+// checkCaptcha verifies the captcha secret by submitting a dummy response: the
+// verifier rejects the response either way, but names a bad secret explicitly.
+func checkCaptcha(ctx context.Context) (string, error) {
+	if *captchaToken == "" || *captchaSecret == "" {
+		return "disabled", nil
+	}
+	form := url.Values{}
+	form.Add("secret", *captchaSecret)
+	form.Add("response", "faucet-check")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, *captchaVerifyFlag, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("%v, check --captcha.verify", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("verifier unreachable (%v), check --captcha.verify", err)
+	}
+	defer res.Body.Close()
+
+	var result struct {
+		Errors []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("unexpected verifier response (%v), check --captcha.verify", err)
+	}
+	for _, code := range result.Errors {
+		if code == "invalid-input-secret" || code == "missing-input-secret" {
+			return "", fmt.Errorf("secret rejected by the verifier, check --captcha.secret")
+		}
+	}
+	return "secret accepted", nil
+}
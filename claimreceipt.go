@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sunvim/utils/log"
+)
+
+var claimReceiptsFlag = flag.Bool("claim.receipts", false, "Hand out a receipt signed by the faucet key (personal_sign) with every successful claim")
+
+// claimReceipt is the proof of a successful claim, signed by the faucet so
+// third parties can verify it against the faucet address alone.
+type claimReceipt struct {
+	Message   string `json:"message"`   // Signed claim details
+	Signature string `json:"signature"` // EIP-191 signature of the message
+	Signer    string `json:"signer"`    // Address of the faucet
+}
+
+// claimReceiptMessage is the message the faucet signs for a successful claim.
+func claimReceiptMessage(c *Claim, ts int64) string {
+	return fmt.Sprintf("%s faucet receipt\nChain: %d\nAddress: %s\nAmount: %s\nTimestamp: %d\nTx: %s",
+		*apiName, *chainID, c.Address, c.Amount, ts, c.Tx.ID())
+}
+
+// signClaimReceipt signs the receipt of a successful claim, if enabled. Claims
+// without a transaction of their own get none, and neither do decoy payouts, as
+// the faucet never vouches for a transaction it didn't send.
+func signClaimReceipt(c *Claim) *claimReceipt {
+	if !*claimReceiptsFlag || c.Tx == nil || c.decoy || c.Amount == nil {
+		return nil
+	}
+	msg := claimReceiptMessage(c, time.Now().Unix())
+	sig, err := crypto.Sign(accounts.TextHash([]byte(msg)), c.server.key)
+	if err != nil {
+		log.Error("Failed to sign claim receipt err: ", err)
+		return nil
+	}
+	sig[crypto.RecoveryIDOffset] += 27 // Wallets and libraries expect legacy 27/28 recovery ids
+	return &claimReceipt{Message: msg, Signature: hexutil.Encode(sig), Signer: c.server.address.Hex()}
+}
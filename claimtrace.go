@@ -0,0 +1,368 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sunvim/utils/log"
+)
+
+const (
+	maxClaimTraces = 100       // Captured traces kept, oldest dropped first
+	maxTraceEvents = 1000      // Events recorded per trace, later ones dropped
+	traceTTL       = time.Hour // Default time a trace selector stays armed
+)
+
+// traceSelector picks the claims to capture the lifecycle of.
+type traceSelector struct {
+	Address string    `json:"address,omitempty"` // Funded address to match, any if empty
+	IP      string    `json:"ip,omitempty"`      // Requester IP to match, any if empty
+	Count   int       `json:"count"`             // Claims left to capture
+	Expires time.Time `json:"expires"`
+}
+
+// matches reports whether a claim is selected for capturing.
+func (s *traceSelector) matches(c *Claim) bool {
+	if s.Address != "" && !strings.EqualFold(s.Address, c.Address) {
+		return false
+	}
+	return s.IP == "" || s.IP == remoteHost(c.IP)
+}
+
+// claimTrace is the captured lifecycle of a claim: the request, every stage it
+// passed through, the RPC calls made on its behalf, the messages sent back to
+// the requester and the outcome.
+type claimTrace struct {
+	ID      string     `json:"id"`
+	Started time.Time  `json:"started"`
+	Elapsed float64    `json:"elapsed"` // Seconds until the claim was done, 0 if in progress
+	Request traceClaim `json:"request"`
+
+	Events []*traceEvent `json:"events"`
+
+	Outcome string `json:"outcome,omitempty"` // "funded", "dropped" (silently), "rejected" or "failed" once done
+	Error   string `json:"error,omitempty"`   // Error the claim failed with, if any
+	Amount  string `json:"amount,omitempty"`  // Payout in token units
+	Tx      string `json:"tx,omitempty"`
+
+	lock sync.Mutex
+}
+
+// traceClaim is the claim as submitted, with secrets left out.
+type traceClaim struct {
+	Address   string            `json:"address"`
+	Tier      uint              `json:"tier"`
+	IP        string            `json:"ip"`
+	Country   string            `json:"country,omitempty"`
+	Lang      string            `json:"lang"`
+	Website   bool              `json:"website"` // Submitted through the website form
+	Trusted   bool              `json:"trusted,omitempty"`
+	Captcha   bool              `json:"captcha"` // Whether a captcha response was supplied
+	Voucher   bool              `json:"voucher"`
+	Referral  string            `json:"referral,omitempty"`
+	Signature bool              `json:"signature"`
+	Token     bool              `json:"token"`
+	JWT       bool              `json:"jwt"`
+	Terms     bool              `json:"terms"`
+	Fields    map[string]string `json:"fields,omitempty"`
+}
+
+// traceEvent is a single step in the lifecycle of a traced claim.
+type traceEvent struct {
+	At       float64 `json:"at"`                 // Seconds since the claim started
+	Kind     string  `json:"kind"`               // "enter", "leave", "rpc", "status" or "queue"
+	Stage    string  `json:"stage,omitempty"`    // Pipeline stage entered or left
+	Method   string  `json:"method,omitempty"`   // RPC method(s) called
+	Provider string  `json:"provider,omitempty"` // RPC endpoint called
+	Duration float64 `json:"duration,omitempty"` // Seconds the stage or call took
+	Error    string  `json:"error,omitempty"`
+	Message  string  `json:"message,omitempty"` // Progress reported to the requester
+}
+
+// add appends an event to the trace, timestamping it.
+func (t *claimTrace) add(event *traceEvent) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if len(t.Events) >= maxTraceEvents {
+		return
+	}
+	event.At = time.Since(t.Started).Seconds()
+	t.Events = append(t.Events, event)
+}
+
+// claimTraces holds the armed selectors and the captured traces, newest last.
+var claimTraces = struct {
+	lock      sync.Mutex
+	selectors []*traceSelector
+	traces    []*claimTrace
+}{}
+
+// claimTraceKey is the context key of the trace of the claim an RPC call is
+// made on behalf of.
+type claimTraceKey struct{}
+
+// claimTraceFrom returns the trace of the claim a context belongs to, if any.
+func claimTraceFrom(ctx context.Context) *claimTrace {
+	trace, _ := ctx.Value(claimTraceKey{}).(*claimTrace)
+	return trace
+}
+
+// selectTrace starts capturing a claim if an armed selector matches it.
+func selectTrace(c *Claim) *claimTrace {
+	claimTraces.lock.Lock()
+	defer claimTraces.lock.Unlock()
+
+	if len(claimTraces.selectors) == 0 {
+		return nil
+	}
+	var selected bool
+	selectors := claimTraces.selectors[:0]
+	for _, selector := range claimTraces.selectors {
+		if time.Now().After(selector.Expires) {
+			continue
+		}
+		if !selected && selector.matches(c) {
+			selected = true
+			selector.Count--
+		}
+		if selector.Count > 0 {
+			selectors = append(selectors, selector)
+		}
+	}
+	claimTraces.selectors = selectors
+	if !selected {
+		return nil
+	}
+	trace := &claimTrace{
+		ID:      newJobID(),
+		Started: time.Now(),
+		Request: traceClaim{
+			Address:   c.Address,
+			Tier:      c.Tier,
+			IP:        remoteHost(c.IP),
+			Country:   c.Country,
+			Lang:      c.Lang,
+			Website:   c.website,
+			Trusted:   c.Trusted,
+			Captcha:   c.Captcha != "",
+			Voucher:   c.Voucher != "",
+			Referral:  c.Referral,
+			Signature: c.Signature != "",
+			Token:     c.Token != "",
+			JWT:       c.JWT != "",
+			Terms:     c.Terms,
+			Fields:    c.Fields,
+		},
+	}
+	claimTraces.traces = append(claimTraces.traces, trace)
+	if len(claimTraces.traces) > maxClaimTraces {
+		claimTraces.traces = claimTraces.traces[len(claimTraces.traces)-maxClaimTraces:]
+	}
+	return trace
+}
+
+// traceClaims captures the lifecycle of the claims selected for tracing: the
+// progress reported to the requester, the RPC calls made on its behalf (via
+// the context) and the outcome.
+func traceClaims(handler Handler) Handler {
+	return func(c *Claim) error {
+		trace := selectTrace(c)
+		if trace == nil {
+			return handler(c)
+		}
+		log.Info("Tracing claim ", trace.ID, " of ", c.Address)
+		c.trace = trace
+		c.ctx = context.WithValue(c.ctx, claimTraceKey{}, trace)
+		if status := c.status; status != nil {
+			c.status = func(msg string) {
+				trace.add(&traceEvent{Kind: "status", Message: msg})
+				status(msg)
+			}
+		}
+		if notify := c.notify; notify != nil {
+			c.notify = func(position int, eta time.Duration) {
+				trace.add(&traceEvent{Kind: "queue", Message: fmt.Sprintf("position %d, eta %s", position, eta.Round(time.Second))})
+				notify(position, eta)
+			}
+		}
+		err := handler(c)
+
+		var uerr *userError
+		trace.lock.Lock()
+		trace.Elapsed = time.Since(trace.Started).Seconds()
+		switch {
+		case err == nil && c.Tx == nil:
+			trace.Outcome = "dropped"
+		case err == nil:
+			trace.Outcome = "funded"
+		case errors.As(err, &uerr):
+			trace.Outcome = "rejected"
+		default:
+			trace.Outcome = "failed"
+		}
+		if err != nil {
+			trace.Error = err.Error()
+		}
+		if c.Amount != nil {
+			trace.Amount = fromWei(c.Amount)
+		}
+		if c.Tx != nil {
+			trace.Tx = c.Tx.ID()
+		}
+		trace.lock.Unlock()
+		return err
+	}
+}
+
+// traceStage records entering and leaving a pipeline stage of traced claims.
+// The stage that turned a claim away is the last one entered.
+func traceStage(name string, handler Handler) Handler {
+	return func(c *Claim) error {
+		if c.trace == nil {
+			return handler(c)
+		}
+		c.trace.add(&traceEvent{Kind: "enter", Stage: name})
+		start := time.Now()
+		err := handler(c)
+
+		event := &traceEvent{Kind: "leave", Stage: name, Duration: time.Since(start).Seconds()}
+		if err != nil {
+			event.Error = err.Error()
+		}
+		c.trace.add(event)
+		return err
+	}
+}
+
+// traceRPC records an RPC request made on behalf of a traced claim.
+func traceRPC(ctx context.Context, body []byte, provider string, duration time.Duration, err error) {
+	trace := claimTraceFrom(ctx)
+	if trace == nil {
+		return
+	}
+	var (
+		call  struct{ Method string }
+		batch []struct{ Method string }
+		names []string
+	)
+	if json.Unmarshal(body, &call) == nil {
+		names = append(names, call.Method)
+	} else if json.Unmarshal(body, &batch) == nil {
+		for _, call := range batch {
+			names = append(names, call.Method)
+		}
+	}
+	event := &traceEvent{Kind: "rpc", Method: strings.Join(names, ","), Provider: provider, Duration: duration.Seconds()}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	trace.add(event)
+}
+
+// onAdminTraces manages the capturing of claim traces. GET lists the captured
+// traces, or downloads the one given by ?id= as a JSON bundle; POST arms a
+// selector of the claims to capture; DELETE disarms all selectors and drops
+// the captured traces.
+func onAdminTraces(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		claimTraces.lock.Lock()
+		traces := append([]*claimTrace{}, claimTraces.traces...)
+		claimTraces.lock.Unlock()
+
+		if id := r.URL.Query().Get("id"); id != "" {
+			for _, trace := range traces {
+				if trace.ID == id {
+					trace.lock.Lock()
+					blob, err := marshalAPI(trace)
+					trace.lock.Unlock()
+					if err != nil {
+						writeJSONError(w, http.StatusInternalServerError, err)
+						return
+					}
+					w.Header().Set("Content-Type", "application/json")
+					w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"claim-trace-%s.json\"", id))
+					w.Write(blob)
+					return
+				}
+			}
+			writeJSONError(w, http.StatusNotFound, errors.New("unknown trace"))
+			return
+		}
+		type summary struct {
+			ID      string    `json:"id"`
+			Started time.Time `json:"started"`
+			Address string    `json:"address"`
+			Outcome string    `json:"outcome,omitempty"`
+			Error   string    `json:"error,omitempty"`
+			Events  int       `json:"events"`
+		}
+		summaries := []*summary{}
+		for i := len(traces) - 1; i >= 0; i-- {
+			trace := traces[i]
+			trace.lock.Lock()
+			summaries = append(summaries, &summary{trace.ID, trace.Started, trace.Request.Address, trace.Outcome, trace.Error, len(trace.Events)})
+			trace.lock.Unlock()
+		}
+		claimTraces.lock.Lock()
+		selectors := []traceSelector{}
+		for _, selector := range claimTraces.selectors {
+			selectors = append(selectors, *selector)
+		}
+		claimTraces.lock.Unlock()
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{"selectors": selectors, "traces": summaries})
+
+	case http.MethodPost:
+		var req struct {
+			Address string `json:"address"`
+			IP      string `json:"ip"`
+			Count   int    `json:"count"`
+			TTL     string `json:"ttl"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		if req.Address != "" && !common.IsHexAddress(req.Address) {
+			writeJSONError(w, http.StatusBadRequest, errors.New("invalid address"))
+			return
+		}
+		if req.Count <= 0 {
+			req.Count = 1
+		}
+		ttl := traceTTL
+		if req.TTL != "" {
+			var err error
+			if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl <= 0 {
+				writeJSONError(w, http.StatusBadRequest, errors.New("invalid ttl"))
+				return
+			}
+		}
+		selector := &traceSelector{Address: req.Address, IP: req.IP, Count: req.Count, Expires: time.Now().Add(ttl)}
+
+		log.Info("Armed claim tracing for ", selector.Count, " claims of address: ", selector.Address, " ip: ", selector.IP)
+		writeJSON(w, http.StatusCreated, selector)
+
+		claimTraces.lock.Lock()
+		claimTraces.selectors = append(claimTraces.selectors, selector)
+		claimTraces.lock.Unlock()
+
+	case http.MethodDelete:
+		claimTraces.lock.Lock()
+		claimTraces.selectors, claimTraces.traces = nil, nil
+		claimTraces.lock.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,191 @@
+// Package client implements the REST and websocket protocols of the faucet, so
+// Go tooling can request funds, follow claims and watch the payouts without
+// re-implementing the wire format:
+//
+//	c := client.New("https://faucet.example.org")
+//	res, err := c.Claim(ctx, &client.Request{Address: "0x...", Tier: 0})
+//	var ferr *client.Error
+//	if errors.As(err, &ferr) && ferr.Kind == client.Throttled {
+//		log.Printf("Try again in %s", ferr.RetryAfter)
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Client talks to a single faucet.
+type Client struct {
+	BaseURL  string // URL the faucet website is served at, including any base path
+	Language string // Language of the messages of the faucet, e.g. "es" (empty = English)
+
+	HTTPClient *http.Client      // Client of the REST API, http.DefaultClient if nil
+	Dialer     *websocket.Dialer // Dialer of the websocket API, websocket.DefaultDialer if nil
+
+	Retries    int           // Times temporary failures are retried
+	MaxBackoff time.Duration // Longest wait between two attempts
+}
+
+// New creates a client of the faucet served at the given URL, retrying
+// temporary failures up to 3 times.
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		Retries:    3,
+		MaxBackoff: 30 * time.Second,
+	}
+}
+
+// Info retrieves the configuration of the faucet.
+func (c *Client) Info(ctx context.Context) (*Info, error) {
+	info := new(Info)
+	if err := c.get(ctx, "/api/info", info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Stats retrieves the reliability statistics of the faucet.
+func (c *Client) Stats(ctx context.Context) (*Stats, error) {
+	stats := new(Stats)
+	if err := c.get(ctx, "/api/stats", stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Challenge retrieves the challenge the funded address has to sign to prove
+// ownership, if the faucet requires signatures.
+func (c *Client) Challenge(ctx context.Context, address string) (string, error) {
+	var res struct {
+		Challenge string `json:"challenge"`
+	}
+	if err := c.get(ctx, "/api/challenge?address="+url.QueryEscape(address), &res); err != nil {
+		return "", err
+	}
+	return res.Challenge, nil
+}
+
+// Token retrieves a fresh claim token, if the faucet requires them.
+func (c *Client) Token(ctx context.Context) (string, error) {
+	var res struct {
+		Token string `json:"token"`
+	}
+	if err := c.get(ctx, "/api/token", &res); err != nil {
+		return "", err
+	}
+	return res.Token, nil
+}
+
+// Terms retrieves the terms of service claims have to accept, along with their
+// version, if the faucet has any.
+func (c *Client) Terms(ctx context.Context) (*Terms, error) {
+	terms := new(Terms)
+	if err := c.get(ctx, "/api/terms", terms); err != nil {
+		return nil, err
+	}
+	return terms, nil
+}
+
+// Claim requests funds over the REST API, waiting for the outcome. Temporary
+// failures are retried with backoff; claims turned away are returned as *Error.
+func (c *Client) Claim(ctx context.Context, req *Request) (*Result, error) {
+	blob, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	res := new(Result)
+	err = c.retry(ctx, func() error {
+		return c.do(ctx, http.MethodPost, "/api/claim", bytes.NewReader(blob), res)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// get retrieves a JSON document from the REST API, retrying temporary failures.
+func (c *Client) get(ctx context.Context, path string, value interface{}) error {
+	return c.retry(ctx, func() error {
+		return c.do(ctx, http.MethodGet, path, nil, value)
+	})
+}
+
+// do makes a single request to the REST API, decoding the response into value.
+func (c *Client) do(ctx context.Context, method string, path string, body io.Reader, value interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Language != "" {
+		req.Header.Set("Accept-Language", c.Language)
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		var failure struct {
+			Error  string `json:"error"`
+			Reason string `json:"reason"`
+		}
+		json.NewDecoder(res.Body).Decode(&failure)
+		return newHTTPError(res, failure.Error, failure.Reason)
+	}
+	if err := json.NewDecoder(res.Body).Decode(value); err != nil {
+		return fmt.Errorf("malformed response: %w", err)
+	}
+	return nil
+}
+
+// retry calls fn until it succeeds, fails permanently or the retries are used
+// up. Errors of the faucet are retried if temporary, after the wait suggested
+// by the faucet if any; transport errors always are.
+func (c *Client) retry(ctx context.Context, fn func() error) error {
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= c.Retries || ctx.Err() != nil {
+			return err
+		}
+		wait := backoff
+		var ferr *Error
+		if errors.As(err, &ferr) {
+			if !ferr.Temporary() {
+				return err
+			}
+			if ferr.RetryAfter > 0 {
+				wait = ferr.RetryAfter
+			}
+		}
+		if c.MaxBackoff > 0 && wait > c.MaxBackoff {
+			wait = c.MaxBackoff
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return err
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kind classifies the errors returned by the faucet.
+type Kind int
+
+const (
+	Rejected  Kind = iota // The claim was turned away, e.g. invalid or failing a check
+	Throttled             // The requester is in its cooldown, see Error.RetryAfter
+	Busy                  // The funding queue is full, see Error.RetryAfter
+	TxFailed              // The funding transaction failed on chain, see Error.Reason
+	Internal              // The faucet failed to serve the claim
+)
+
+// Error is an error reported by the faucet.
+type Error struct {
+	Kind    Kind
+	Status  int    // HTTP status of the REST API, 0 over the websocket API
+	Message string // Error message, in the requested language
+	Reason  string // Machine readable failure reason of failed transactions
+
+	RetryAfter time.Duration // Suggested wait before trying again, if any
+	Siblings   []string      // Sibling faucets suggested instead, if any
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Temporary reports whether the claim may succeed if retried as is.
+func (e *Error) Temporary() bool {
+	return e.Kind == Busy || e.Kind == Internal || (e.Kind == TxFailed && e.Reason == "out-of-gas")
+}
+
+// newHTTPError creates the error of a failed REST API response.
+func newHTTPError(res *http.Response, message string, reason string) *Error {
+	err := &Error{Status: res.StatusCode, Message: message, Reason: reason}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests:
+		err.Kind = Throttled
+	case http.StatusServiceUnavailable:
+		err.Kind = Busy
+	case http.StatusBadGateway:
+		err.Kind = TxFailed
+	default:
+		if res.StatusCode >= 500 {
+			err.Kind = Internal
+		}
+	}
+	if seconds, perr := strconv.Atoi(res.Header.Get("Retry-After")); perr == nil {
+		err.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	for _, link := range res.Header.Values("Link") {
+		if strings.Contains(link, `rel="alternate"`) {
+			if start, end := strings.Index(link, "<"), strings.Index(link, ">"); start >= 0 && end > start {
+				err.Siblings = append(err.Siblings, link[start+1:end])
+			}
+		}
+	}
+	if message == "" {
+		err.Message = res.Status
+	}
+	return err
+}
@@ -0,0 +1,178 @@
+package client
+
+import "time"
+
+// Request is a funding request, as submitted over both the REST and the
+// websocket API. Only the address is mandatory; which of the other fields are
+// needed depends on the verification methods the faucet advertises in its
+// Info.
+type Request struct {
+	Address   string `json:"url"`  // Account to fund
+	Tier      uint   `json:"tier"` // Funding tier, index into Info.Tiers
+	Captcha   string `json:"captcha,omitempty"`
+	Voucher   string `json:"voucher,omitempty"`
+	Referral  string `json:"referral,omitempty"`
+	Challenge string `json:"challenge,omitempty"`   // Ownership challenge, see Client.Challenge
+	Signature string `json:"signature,omitempty"`   // Signature of the challenge by the funded address
+	Token     string `json:"token,omitempty"`       // Claim token, see Client.Token
+	JWT       string `json:"jwt,omitempty"`         // Identity token issued by the operator
+	Terms     bool   `json:"acceptTerms,omitempty"` // Acceptance of the terms of service, see Info.Terms
+
+	Fields map[string]string `json:"fields,omitempty"` // Extra form fields by name, see Info.Fields
+
+	WorldID *WorldIDProof `json:"worldid,omitempty"`
+}
+
+// WorldIDProof is a World ID proof of unique personhood as returned by IDKit.
+type WorldIDProof struct {
+	MerkleRoot        string `json:"merkle_root"`
+	NullifierHash     string `json:"nullifier_hash"`
+	Proof             string `json:"proof"`
+	VerificationLevel string `json:"verification_level"`
+}
+
+// Result is the outcome of a successful claim. The websocket API only reports
+// the message and receipt.
+type Result struct {
+	Message string `json:"message"`
+	ID      string `json:"id,omitempty"`     // Funding job ID
+	Amount  string `json:"amount,omitempty"` // Payout in token units
+	Tx      string `json:"tx,omitempty"`     // Funding transaction hash
+	NFT     string `json:"nft,omitempty"`    // ID of the minted NFT
+
+	Receipt *Receipt `json:"receipt,omitempty"` // Faucet signed proof of the claim
+}
+
+// Receipt is a claim receipt signed by the faucet key (EIP-191).
+type Receipt struct {
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+	Signer    string `json:"signer"`
+}
+
+// Update is a progress report of a claim submitted over the websocket API.
+type Update struct {
+	Queue   int           // Position in the funding queue, 0 if not queued
+	ETA     time.Duration // Estimated wait until the claim is served
+	Status  string        // Human readable progress, in the requested language
+	Session string        // Token to resume the claim with after a disconnect
+}
+
+// Funded is a claim paid out by the faucet, as pushed to websocket clients and
+// listed by the activity report.
+type Funded struct {
+	Time    time.Time `json:"time"`
+	Address string    `json:"address"`
+	Amount  string    `json:"amount"` // Payout in token units
+	Tx      string    `json:"tx"`
+	NFT     string    `json:"nft,omitempty"` // ID of the minted NFT, if any
+}
+
+// Info describes the faucet configuration.
+type Info struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Commit  string `json:"commit,omitempty"`
+
+	Chain    Chain  `json:"chain"`
+	Unit     string `json:"unit"`     // Native currency paid out
+	Decimals int    `json:"decimals"` // Decimals of the native currency
+	Token    *Asset `json:"token,omitempty"`
+
+	Tiers        []Tier       `json:"tiers"`
+	Verification Verification `json:"verification"`
+	Hours        string       `json:"hours,omitempty"`
+	Fields       []FormField  `json:"fields,omitempty"` // Extra form fields requests carry
+	Links        Links        `json:"links"`
+
+	ReceiptSigner string `json:"receiptSigner,omitempty"` // Address signing claim receipts
+	Terms         string `json:"terms,omitempty"`         // Version of the terms of service claims must accept
+}
+
+// Chain describes the funded network in the format of EIP-3085.
+type Chain struct {
+	ChainID        string `json:"chainId"` // Hex encoded
+	ChainName      string `json:"chainName"`
+	NativeCurrency struct {
+		Name     string `json:"name"`
+		Symbol   string `json:"symbol"`
+		Decimals int    `json:"decimals"`
+	} `json:"nativeCurrency"`
+	RPCURLs           []string `json:"rpcUrls"`
+	BlockExplorerURLs []string `json:"blockExplorerUrls,omitempty"`
+}
+
+// Asset describes the ERC-20 token paid out in the format of EIP-747.
+type Asset struct {
+	Type    string `json:"type"`
+	Options struct {
+		Address  string `json:"address"`
+		Symbol   string `json:"symbol"`
+		Decimals int    `json:"decimals"`
+		Image    string `json:"image,omitempty"`
+	} `json:"options"`
+}
+
+// Tier is a single funding tier.
+type Tier struct {
+	Amount   string  `json:"amount"`          // Payout in token units
+	Cooldown int64   `json:"cooldown"`        // Wait until the next request in seconds
+	Score    float64 `json:"score,omitempty"` // Minimum passport score unlocking the tier
+}
+
+// FormField is an extra field of the claim form defined by the operator.
+type FormField struct {
+	Name        string `json:"name"`
+	Label       string `json:"label"`
+	Placeholder string `json:"placeholder,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Pattern     string `json:"pattern,omitempty"` // Regular expression values must match in full
+	MaxLength   int    `json:"maxLength"`         // Maximum length of values in characters
+}
+
+// Verification lists the verification methods requests are subject to.
+type Verification struct {
+	Captcha       string `json:"captcha,omitempty"`    // "recaptcha" or "recaptcha-v3"
+	CaptchaKey    string `json:"captchaKey,omitempty"` // Site key of the captcha
+	Signature     bool   `json:"signature"`            // Ownership proof via Client.Challenge
+	Token         bool   `json:"token"`                // Claim token via Client.Token
+	JWT           bool   `json:"jwt"`                  // Identity token of the operator
+	Vouchers      bool   `json:"vouchers"`
+	Referrals     bool   `json:"referrals"`
+	Passport      bool   `json:"passport"`
+	WorldID       string `json:"worldId,omitempty"`
+	WorldIDAction string `json:"worldIdAction,omitempty"`
+}
+
+// Links are the absolute URLs of the faucet endpoints.
+type Links struct {
+	Website   string `json:"website"`
+	Websocket string `json:"websocket"`
+	Claim     string `json:"claim"`
+	Activity  string `json:"activity,omitempty"`
+	Terms     string `json:"terms,omitempty"`
+}
+
+// Terms are the terms of service of the faucet.
+type Terms struct {
+	Version string `json:"version"`
+	Text    string `json:"text"`
+}
+
+// Stats are the reliability statistics of the faucet over its SLO window.
+type Stats struct {
+	Window        string  `json:"window"`
+	Claims        int     `json:"claims"`
+	Failed        int     `json:"failed"`
+	SuccessRatio  float64 `json:"successRatio"`
+	SuccessTarget float64 `json:"successTarget"`
+	ErrorBudget   float64 `json:"errorBudgetRemaining"`
+
+	Latency struct {
+		Confirmed int     `json:"confirmed"`
+		P50       float64 `json:"p50"` // Seconds
+		P95       float64 `json:"p95"` // Seconds
+		Target    float64 `json:"target"`
+		Met       bool    `json:"met"`
+	} `json:"latency"`
+}
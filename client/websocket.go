@@ -0,0 +1,175 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// message is any message sent by the faucet over the websocket API.
+type message struct {
+	Error   *string  `json:"error"`
+	Success *string  `json:"success"`
+	Receipt *Receipt `json:"receipt"`
+	Queue   *int     `json:"queue"`
+	ETA     int      `json:"eta"` // Seconds
+	Status  *string  `json:"status"`
+	Session *string  `json:"session"`
+	Funded  *Funded  `json:"funded"`
+}
+
+// dial opens a websocket connection to the faucet. The connection is closed once
+// the context is done.
+func (c *Client) dial(ctx context.Context) (*websocket.Conn, error) {
+	endpoint := "ws" + strings.TrimPrefix(c.BaseURL, "http") + "/api"
+
+	header := http.Header{}
+	if c.Language != "" {
+		header.Set("Accept-Language", c.Language)
+	}
+	dialer := c.Dialer
+	if dialer == nil {
+		dialer = websocket.DefaultDialer
+	}
+	conn, _, err := dialer.DialContext(ctx, endpoint, header)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	return conn, nil
+}
+
+// ClaimStream requests funds over the websocket API, reporting the progress of
+// the claim (queue position, status, session) to updates, which may be nil. If
+// the connection drops while the claim is in progress, the client reconnects
+// and resumes the claim's session, up to the configured number of retries.
+func (c *Client) ClaimStream(ctx context.Context, req *Request, updates func(Update)) (*Result, error) {
+	var (
+		session string
+		backoff = time.Second
+	)
+	for attempt := 0; ; attempt++ {
+		res, err := c.streamOnce(ctx, req, session, func(u Update) {
+			if u.Session != "" {
+				session = u.Session
+			}
+			if updates != nil {
+				updates(u)
+			}
+		})
+		var ferr *Error
+		if err == nil || errors.As(err, &ferr) || attempt >= c.Retries || ctx.Err() != nil {
+			return res, err
+		}
+		if session == "" {
+			return nil, err // The claim never started, or can't be resumed
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, err
+		}
+		if backoff *= 2; c.MaxBackoff > 0 && backoff > c.MaxBackoff {
+			backoff = c.MaxBackoff
+		}
+	}
+}
+
+// streamOnce submits a claim, or resumes the given session, on a single
+// connection and waits for its outcome.
+func (c *Client) streamOnce(ctx context.Context, req *Request, session string, updates func(Update)) (*Result, error) {
+	connCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	conn, err := c.dial(connCtx)
+	if err != nil {
+		return nil, err
+	}
+	if session != "" {
+		err = conn.WriteJSON(map[string]string{"resume": session})
+	} else {
+		err = conn.WriteJSON(req)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var msg message
+		if err := conn.ReadJSON(&msg); err != nil {
+			return nil, err
+		}
+		switch {
+		case msg.Error != nil:
+			return nil, &Error{Kind: Rejected, Message: *msg.Error}
+		case msg.Success != nil:
+			return &Result{Message: *msg.Success, Receipt: msg.Receipt}, nil
+		case msg.Session != nil:
+			updates(Update{Session: *msg.Session})
+		case msg.Queue != nil || msg.Status != nil:
+			u := Update{ETA: time.Duration(msg.ETA) * time.Second}
+			if msg.Queue != nil {
+				u.Queue = *msg.Queue
+			}
+			if msg.Status != nil {
+				u.Status = *msg.Status
+			}
+			updates(u)
+		}
+	}
+}
+
+// Subscribe calls fn for every claim paid out by the faucet until the context
+// is done or the connection fails, reconnecting up to the configured number of
+// retries in a row. Payouts made while disconnected are missed.
+func (c *Client) Subscribe(ctx context.Context, fn func(*Funded)) error {
+	var (
+		failures int
+		backoff  = time.Second
+	)
+	for {
+		err := c.subscribeOnce(ctx, fn, func() { failures, backoff = 0, time.Second })
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if failures++; failures > c.Retries {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if backoff *= 2; c.MaxBackoff > 0 && backoff > c.MaxBackoff {
+			backoff = c.MaxBackoff
+		}
+	}
+}
+
+// subscribeOnce relays the payouts pushed over a single connection until it
+// fails, calling connected once it's established.
+func (c *Client) subscribeOnce(ctx context.Context, fn func(*Funded), connected func()) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	connected()
+	for {
+		var msg message
+		if err := conn.ReadJSON(&msg); err != nil {
+			return err
+		}
+		if msg.Funded != nil {
+			fn(msg.Funded)
+		}
+	}
+}
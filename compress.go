@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var compressFlag = flag.Bool("api.compress", true, "Compress the website and API responses with gzip for clients accepting it")
+
+// gzipWriters recycles the compressors of responses, which are costly to set up.
+var gzipWriters = sync.Pool{
+	New: func() interface{} { return gzip.NewWriter(io.Discard) },
+}
+
+// acceptsGzip reports whether the client accepts gzip encoded responses.
+func acceptsGzip(r *http.Request) bool {
+	for _, coding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		params := strings.Split(coding, ";")
+		if strings.TrimSpace(params[0]) != "gzip" {
+			continue
+		}
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); strings.HasPrefix(param, "q=") && err == nil && q == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// compress gzips responses for the clients accepting it, leaving websocket
+// upgrades and responses encoded by the handler itself untouched.
+func compress(handler http.Handler) http.Handler {
+	if !*compressFlag {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if r.Header.Get("Upgrade") != "" || !acceptsGzip(r) {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.close()
+		handler.ServeHTTP(gw, r)
+	})
+}
+
+// gzipResponseWriter compresses the response body, deciding on the first write
+// whether the response is suitable for compression.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz      *gzip.Writer
+	decided bool
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if !w.decided {
+		w.decided = true
+		header := w.Header()
+		if header.Get("Content-Encoding") == "" && status != http.StatusNoContent && status != http.StatusNotModified && status >= http.StatusOK {
+			header.Set("Content-Encoding", "gzip")
+			header.Del("Content-Length")
+			w.gz = gzipWriters.Get().(*gzip.Writer)
+			w.gz.Reset(w.ResponseWriter)
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *gzipResponseWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(data))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.gz != nil {
+		return w.gz.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *gzipResponseWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *gzipResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("connection cannot be hijacked")
+	}
+	return hijacker.Hijack()
+}
+
+// close flushes the compressed body and recycles the compressor.
+func (w *gzipResponseWriter) close() {
+	if w.gz == nil {
+		return
+	}
+	w.gz.Close()
+	w.gz.Reset(io.Discard)
+	gzipWriters.Put(w.gz)
+}
+
+// gzipPage compresses a rendered page once, to be served to every visitor.
+func gzipPage(page []byte) []byte {
+	buf := new(bytes.Buffer)
+	gz, _ := gzip.NewWriterLevel(buf, gzip.BestCompression)
+	gz.Write(page)
+	gz.Close()
+	return buf.Bytes()
+}
+
+// pageETag derives the strong entity tag of a rendered page.
+func pageETag(page []byte) string {
+	hash := sha256.Sum256(page)
+	return `"` + hex.EncodeToString(hash[:16]) + `"`
+}
+
+// notModified reports whether the client's cached copy of a page with the
+// given entity tag is still current.
+func notModified(r *http.Request, etag string) bool {
+	for _, tag := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		if tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/"); tag == etag || tag == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// secretFlags are the flags whose values never leave the process.
+var secretFlags = map[string]bool{
+	"pri_key":         true,
+	"admin.token":     true,
+	"captcha.secret":  true,
+	"passport.key":    true,
+	"log.access.salt": true,
+	"alert.webhook":   true, // Chat webhooks embed their credentials
+	"form.webhook":    true,
+	"rpc.relays":      true, // Private transaction APIs embed their keys
+	"rpc.fallbacks":   true,
+}
+
+// redactFlag returns the printable value of a flag, with secrets masked and
+// credentials stripped from URLs. Besides in the userinfo and query string, RPC
+// and webhook providers commonly embed API keys in the path (e.g. Infura's
+// /v3/<key>), so paths are stripped as well, leaving only scheme and host.
+func redactFlag(name, value string) string {
+	if value == "" {
+		return value
+	}
+	if secretFlags[name] {
+		return "<redacted>"
+	}
+	if u, err := url.Parse(value); err == nil && u.Scheme != "" && u.Host != "" {
+		if u.User != nil {
+			u.User = url.User("redacted")
+		}
+		if u.RawQuery != "" {
+			u.RawQuery = "redacted"
+		}
+		if strings.Trim(u.Path, "/") != "" {
+			u.Path, u.RawPath = "/redacted", ""
+		}
+		u.Fragment = ""
+		return u.String()
+	}
+	return value
+}
+
+// dumpConfig renders the resolved configuration as YAML, one key per flag in
+// alphabetical order, marking the values left at their defaults and the secrets
+// loaded from the environment.
+func dumpConfig() string {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	var flags []*flag.Flag
+	flag.VisitAll(func(f *flag.Flag) { flags = append(flags, f) })
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+
+	var b strings.Builder
+	for _, f := range flags {
+		fmt.Fprintf(&b, "%s: %s", f.Name, strconv.Quote(redactFlag(f.Name, f.Value.String())))
+		switch {
+		case secretSources[f.Name] != "":
+			b.WriteString(" # $" + secretSources[f.Name])
+		case !set[f.Name]:
+			b.WriteString(" # default")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// configSnapshot is the configuration and runtime state the faucet started
+// with, taken once the setup completed, to tell the values changed since.
+var configSnapshot struct {
+	taken time.Time
+	flags map[string]string
+	state map[string]string
+}
+
+// snapshotConfig records the configuration and runtime state the faucet started
+// with.
+func (s *Server) snapshotConfig() {
+	configSnapshot.taken = time.Now()
+	configSnapshot.flags = make(map[string]string)
+	flag.VisitAll(func(f *flag.Flag) { configSnapshot.flags[f.Name] = f.Value.String() })
+	configSnapshot.state = s.runtimeState()
+}
+
+// runtimeState returns the settings the faucet adjusts by itself while running,
+// by name: the velocity multipliers in effect, whether the RPC breaker is open,
+// whether the instance is a passive standby and the terms of service version.
+func (s *Server) runtimeState() map[string]string {
+	payout, cooldown := 1.0, 1.0
+	if velocityTightened() {
+		payout, cooldown = *velocityPayoutFlag, *velocityCooldownFlag
+	}
+	breaker.lock.Lock()
+	open := breaker.open
+	breaker.lock.Unlock()
+
+	return map[string]string{
+		"velocity.payout":   strconv.FormatFloat(payout, 'g', -1, 64),
+		"velocity.cooldown": strconv.FormatFloat(cooldown, 'g', -1, 64),
+		"breaker.open":      strconv.FormatBool(open),
+		"standby.passive":   strconv.FormatBool(s.isPassive()),
+		"tos.version":       terms.version,
+	}
+}
+
+// configEntry is a single flag or runtime setting of the effective configuration.
+type configEntry struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	Source  string `json:"source"`            // Command line, environment variable, default or runtime
+	Changed bool   `json:"changed,omitempty"` // Whether the value differs from that at startup
+	Startup string `json:"startup,omitempty"` // Value at startup, if changed since
+}
+
+// configReport is the effective configuration of an instance.
+type configReport struct {
+	Instance string         `json:"instance"`
+	Started  time.Time      `json:"started"`
+	Changed  int            `json:"changed"` // Number of entries changed since startup
+	Flags    []*configEntry `json:"flags"`
+	State    []*configEntry `json:"state"`
+}
+
+// onAdminConfig reports the effective configuration of this instance, redacted
+// as in the configuration dump, along with the runtime state, marking what
+// changed since startup (GET /admin/config, ?changed=1 to list only those).
+func (s *Server) onAdminConfig(w http.ResponseWriter, r *http.Request) {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	report := &configReport{Started: configSnapshot.taken, Flags: []*configEntry{}, State: []*configEntry{}}
+	report.Instance, _ = os.Hostname()
+	onlyChanged := r.URL.Query().Get("changed") != ""
+
+	// diff marks an entry changed if it differs from the snapshot, and adds it
+	// to the report unless filtered out
+	diff := func(entries *[]*configEntry, entry *configEntry, startup string, known bool) {
+		if known && startup != entry.Value {
+			entry.Changed, entry.Startup = true, startup
+			report.Changed++
+		}
+		if onlyChanged && !entry.Changed {
+			return
+		}
+		*entries = append(*entries, entry)
+	}
+	var flags []*flag.Flag
+	flag.VisitAll(func(f *flag.Flag) { flags = append(flags, f) })
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+
+	for _, f := range flags {
+		entry := &configEntry{Name: f.Name, Value: redactFlag(f.Name, f.Value.String()), Source: "default"}
+		switch {
+		case secretSources[f.Name] != "":
+			entry.Source = "$" + secretSources[f.Name]
+		case set[f.Name]:
+			entry.Source = "flag"
+		}
+		startup, known := configSnapshot.flags[f.Name]
+		diff(&report.Flags, entry, redactFlag(f.Name, startup), known)
+	}
+	state := s.runtimeState()
+	names := make([]string, 0, len(state))
+	for name := range state {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		startup, known := configSnapshot.state[name]
+		diff(&report.State, &configEntry{Name: name, Value: state[name], Source: "runtime"}, startup, known)
+	}
+	writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var confirmationsFlag = flag.String("rpc.confirmations", "1", "Confirmations a funding transaction needs to count as successful, either a number or chainid:depth pairs, e.g. 11155111:3,17000:5 (unlisted chains need 1)")
+
+// confirmations is the depth funding transactions need to reach before being
+// reported successful on the funded chain. It's parsed on startup.
+var confirmations uint64 = 1
+
+// parseConfirmations picks the confirmation depth of the funded chain from the
+// configured depths.
+func parseConfirmations(spec string, chain int64) (uint64, error) {
+	spec = strings.TrimSpace(spec)
+	if !strings.Contains(spec, ":") {
+		depth, err := strconv.ParseUint(spec, 10, 64)
+		if err != nil || depth == 0 {
+			return 0, fmt.Errorf("invalid depth %q", spec)
+		}
+		return depth, nil
+	}
+	depth := uint64(1)
+	for _, item := range strings.Split(spec, ",") {
+		parts := strings.Split(strings.TrimSpace(item), ":")
+		if len(parts) != 2 {
+			return 0, fmt.Errorf("invalid depth %q, want chainid:depth", item)
+		}
+		id, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid chain id %q", parts[0])
+		}
+		n, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil || n == 0 {
+			return 0, fmt.Errorf("invalid depth %q", parts[1])
+		}
+		if id == chain {
+			depth = n
+		}
+	}
+	return depth, nil
+}
+
+// awaitConfirmations waits for an included funding transaction to be buried
+// under the configured number of blocks. If a reorg drops it meanwhile, it is
+// broadcast again and the requester told so.
+func awaitConfirmations(ctx context.Context, c *Claim, receipt *ChainReceipt) (*ChainReceipt, error) {
+	if confirmations <= 1 {
+		return receipt, nil
+	}
+	if c.status != nil {
+		c.status(translate(c.Lang, "Included in block %d, waiting for %d confirmations", receipt.Block, confirmations))
+	}
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		head, err := c.server.chain.Head(ctx)
+		if err != nil {
+			log.Error("Failed to retrieve chain head err: ", err)
+		}
+		// Make sure the transaction is still where it was, or anywhere at all
+		rctx, cancel := rpcContext(ctx)
+		current, err := c.server.chain.Confirm(rctx, c.Tx)
+		cancel()
+		switch {
+		case err != nil && ctx.Err() != nil:
+			return nil, ctx.Err()
+
+		case err != nil:
+			log.Info("Funding transaction ", c.Tx.ID(), " dropped by a reorg, rebroadcasting")
+			if c.status != nil {
+				c.status(translate(c.Lang, "Funding transaction %s dropped by a reorg, rebroadcasting", c.Tx.ID()))
+			}
+			if err := c.server.chain.Broadcast(ctx, c.Tx); err != nil && !strings.Contains(err.Error(), "known") {
+				return nil, err
+			}
+			if current, err = c.server.chain.Confirm(ctx, c.Tx); err != nil {
+				return nil, err
+			}
+			receipt = current
+
+		case current.Block != receipt.Block:
+			log.Info("Funding transaction ", c.Tx.ID(), " reorged from block ", receipt.Block, " to ", current.Block)
+			receipt = current
+
+		case head+1 >= receipt.Block+confirmations:
+			return receipt, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	contractPayoutFlag = flag.Float64("contract.payout", 1, "Factor to scale the payouts to smart contract wallets by (0 = turn them away)")
+	contractGasFlag    = flag.Uint64("contract.gas", 200000, "Highest gas limit spent on a transfer to a smart contract wallet")
+)
+
+// contractClaims counts the claims funding smart contract wallets.
+var contractClaims = newCounter("faucet_contract_claims_total", "Claims funding addresses holding code, i.e. smart contract wallets.")
+
+// codeCacheTTL is how long the code lookup of an address is reused for. Code
+// rarely comes and goes, but accounts may delegate to or revoke code at will.
+const codeCacheTTL = 10 * time.Minute
+
+// codeCache holds recent code lookups by address, so the sender building the
+// transfer reuses the lookup made by the contract stage ahead of the queue.
+var codeCache = struct {
+	lock    sync.Mutex
+	entries map[common.Address]codeLookup
+}{
+	entries: make(map[common.Address]codeLookup),
+}
+
+// ContractChain is implemented by chain backends of networks with smart contract
+// wallets, so that claims funding them can be told apart.
+type ContractChain interface {
+	// IsContract reports whether an account holds code, i.e. is a smart
+	// contract wallet rather than a plain key pair.
+	IsContract(ctx context.Context, addr string) (bool, error)
+}
+
+// codeLookup is whether an address held code when looked up.
+type codeLookup struct {
+	contract bool
+	checked  time.Time
+}
+
+// IsContract reports whether a hex address holds code, i.e. is a smart contract
+// wallet (or an account delegating to one) rather than a plain key pair.
+// Lookups are cached for a while.
+func (b *evmBackend) IsContract(ctx context.Context, address string) (bool, error) {
+	addr := common.HexToAddress(address)
+
+	codeCache.lock.Lock()
+	lookup, ok := codeCache.entries[addr]
+	codeCache.lock.Unlock()
+
+	if ok && time.Since(lookup.checked) < codeCacheTTL {
+		return lookup.contract, nil
+	}
+	rctx, cancel := rpcContext(ctx)
+	defer cancel()
+
+	code, err := b.client.CodeAt(rctx, addr, nil)
+	if err != nil {
+		return false, err
+	}
+	codeCache.lock.Lock()
+	defer codeCache.lock.Unlock()
+
+	if len(codeCache.entries) >= 10000 {
+		for cached, lookup := range codeCache.entries {
+			if time.Since(lookup.checked) >= codeCacheTTL {
+				delete(codeCache.entries, cached)
+			}
+		}
+	}
+	codeCache.entries[addr] = codeLookup{contract: len(code) > 0, checked: time.Now()}
+	return len(code) > 0, nil
+}
+
+// contractStage looks up whether claims fund smart contract wallets ahead of
+// the queue, counting them and applying their payout policy. The gas needed to
+// fund them is taken care of when the transfer is built, reusing the lookup.
+func contractStage(next Handler) Handler {
+	return func(c *Claim) error {
+		chain, ok := unwrapChain(c.server.chain).(ContractChain)
+		if !ok {
+			return next(c)
+		}
+		contract, err := chain.IsContract(c.ctx, c.Address)
+		if err != nil {
+			log.Error("Failed to check for contract wallet err: ", err)
+			return next(c)
+		}
+		if !contract {
+			return next(c)
+		}
+		contractClaims.Inc()
+		if *contractPayoutFlag == 1 {
+			return next(c)
+		}
+		if *contractPayoutFlag <= 0 {
+			return newUserError("Smart contract wallets are not supported, please use a regular account")
+		}
+		c.Amount, _ = new(big.Float).Mul(new(big.Float).SetInt(c.Amount), big.NewFloat(*contractPayoutFlag)).Int(nil)
+		return next(c)
+	}
+}
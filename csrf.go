@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"net/http"
+	"net/url"
+
+	"github.com/sunvim/utils/log"
+)
+
+var csrfFlag = flag.Bool("api.csrf", true, "Require claims posted by browsers to come from the faucet's own pages, so third-party pages can't submit claims on behalf of visitors")
+
+// sessionCookie is the same-site cookie identifying the browser session the
+// CSRF tokens are bound to.
+const sessionCookie = "faucet-session"
+
+// csrfHeader is the header scripts pass the CSRF token of their session in.
+// Form posts pass it in the csrf field.
+const csrfHeader = "X-CSRF-Token"
+
+// csrfToken derives the CSRF token of a browser session.
+func csrfToken(session string) string {
+	return tokenMAC("csrf:"+session, 0)
+}
+
+// setSession hands a browser a session cookie, unless it already has one, and
+// returns the session.
+func setSession(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(sessionCookie); err == nil && len(cookie.Value) == 32 {
+		return cookie.Value
+	}
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		panic(err)
+	}
+	session := hex.EncodeToString(raw)
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    session,
+		Path:     publicPrefix() + "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return session
+}
+
+// fromBrowser reports whether a request was made by a browser on behalf of a
+// page, which automated clients don't claim to be.
+func fromBrowser(r *http.Request) bool {
+	return r.Header.Get("Origin") != "" || r.Header.Get("Sec-Fetch-Site") != "" || r.Header.Get("Cookie") != ""
+}
+
+// sameOrigin reports whether a browser request was made by a page of the
+// faucet itself.
+func sameOrigin(r *http.Request) bool {
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" {
+		return site == "same-origin"
+	}
+	origin, err := url.Parse(r.Header.Get("Origin"))
+	return err == nil && origin.Host != "" && origin.Host == r.Host
+}
+
+// checkCSRF rejects claims posted by browsers unless they carry the CSRF token
+// of their session. Forms of the pre-rendered website can't carry one, so they
+// are accepted from the faucet's own pages along with the session cookie, which
+// browsers never send along with requests of other sites.
+func checkCSRF(r *http.Request, form bool) error {
+	if !*csrfFlag || !fromBrowser(r) {
+		return nil
+	}
+	cookie, err := r.Cookie(sessionCookie)
+	if err == nil {
+		token := r.Header.Get(csrfHeader)
+		if token == "" && form {
+			token = r.PostFormValue("csrf")
+		}
+		if hmac.Equal([]byte(token), []byte(csrfToken(cookie.Value))) || (form && sameOrigin(r)) {
+			return nil
+		}
+	}
+	log.Info("Rejecting cross-site claim: ", r.Header.Get("Origin"), " ip: ", remoteHost(r.RemoteAddr))
+	return newUserError("Session expired, please reload the page")
+}
+
+// onCSRFToken issues the CSRF token of the requester's browser session, starting
+// one if needed, for scripts of the faucet's pages posting claims.
+func onCSRFToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]string{"token": csrfToken(setSession(w, r))})
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+var debugFlag = flag.Bool("admin.debug", false, "Expose pprof and internal state dumps on the admin API")
+
+// debugState is a snapshot of the faucet internals for debugging incidents.
+type debugState struct {
+	Goroutines   int    `json:"goroutines"`
+	Connections  int    `json:"connections"`  // Open websocket connections
+	Cooldowns    int    `json:"cooldowns"`    // Tracked cooldown entries
+	Inflight     int    `json:"inflight"`     // Entries of the in-flight limiter
+	QueueDepth   int    `json:"queueDepth"`   // Claims waiting for the sender
+	QueueSize    int    `json:"queueSize"`    // Capacity of the funding queue
+	Enqueued     uint64 `json:"enqueued"`     // Claims ever added to the queue
+	Served       uint64 `json:"served"`       // Claims ever picked up by the sender
+	Account      string `json:"account"`      // Faucet account funds are sent from
+	Nonce        uint64 `json:"nonce"`        // Next nonce of the account as of the latest block
+	PendingNonce uint64 `json:"pendingNonce"` // Next nonce of the account including pending txs
+	NonceError   string `json:"nonceError,omitempty"`
+}
+
+// registerDebug adds the debug endpoints to the admin API mux, if enabled.
+func registerDebug(s *Server, mux *http.ServeMux) {
+	if !*debugFlag {
+		return
+	}
+	mux.HandleFunc("/debug/pprof/", adminAuth(pprof.Index))
+	mux.HandleFunc("/debug/pprof/cmdline", adminAuth(pprof.Cmdline))
+	mux.HandleFunc("/debug/pprof/profile", adminAuth(pprof.Profile))
+	mux.HandleFunc("/debug/pprof/symbol", adminAuth(pprof.Symbol))
+	mux.HandleFunc("/debug/pprof/trace", adminAuth(pprof.Trace))
+	mux.HandleFunc("/debug/goroutines", adminAuth(onDebugGoroutines))
+	mux.HandleFunc("/debug/traces", adminAuth(onAdminTraces))
+	if !*readOnlyFlag {
+		mux.HandleFunc("/debug/state", adminAuth(s.onDebugState))
+	}
+}
+
+// onDebugGoroutines dumps the stacks of all running goroutines.
+func onDebugGoroutines(w http.ResponseWriter, r *http.Request) {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(buf)
+}
+
+// onDebugState dumps a snapshot of the faucet internals.
+func (s *Server) onDebugState(w http.ResponseWriter, r *http.Request) {
+	state := &debugState{
+		Goroutines: runtime.NumGoroutine(),
+		QueueDepth: s.queue.len(),
+		QueueSize:  s.queue.cap(),
+		Enqueued:   atomic.LoadUint64(&queueStats.enqueued),
+		Served:     atomic.LoadUint64(&queueStats.served),
+		Account:    s.address.Hex(),
+	}
+	state.Connections = hub.len()
+
+	s.lock.RLock()
+	state.Cooldowns = len(s.timeouts)
+	s.lock.RUnlock()
+
+	inflight.lock.Lock()
+	state.Inflight = len(inflight.jobs)
+	inflight.lock.Unlock()
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if backend, ok := unwrapChain(s.chain).(*evmBackend); !ok {
+		state.NonceError = "nonces are only tracked on the EVM backend"
+	} else if nonce, pending, err := backend.accountNonces(ctx); err != nil {
+		state.NonceError = err.Error()
+	} else {
+		state.Nonce, state.PendingNonce = nonce, pending
+	}
+	writeJSON(w, http.StatusOK, state)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	decayWindowFlag = flag.Duration("decay.window", 0, "Window within which repeat claims of an address get reduced payouts (0 = disabled)")
+	decayFactorFlag = flag.Float64("decay.factor", 0.5, "Payout multiplier applied for every earlier claim within the decay window")
+)
+
+// claimCountsBucket is the store bucket tracking recent claims by address.
+const claimCountsBucket = "claim-counts"
+
+// claimHistory is the list of recent claims of an address, used to decay the
+// payout of repeat claimants.
+type claimHistory struct {
+	Times []time.Time `json:"times"`
+}
+
+// prune drops the claims that fell out of the decay window.
+func (h *claimHistory) prune(now time.Time) {
+	kept := h.Times[:0]
+	for _, t := range h.Times {
+		if now.Sub(t) < *decayWindowFlag {
+			kept = append(kept, t)
+		}
+	}
+	h.Times = kept
+}
+
+// decayStage reduces the payout geometrically with the number of claims of the
+// same address within the decay window: the first claim gets the full amount,
+// the next one factor times that, and so on.
+func decayStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if *decayWindowFlag <= 0 {
+			return next(c)
+		}
+		id := c.Address
+
+		var history claimHistory
+		if err := getJSON(store, claimCountsBucket, id, &history); err != nil && err != errNotFound {
+			log.Error("Failed to load claim history err: ", err)
+		}
+		history.prune(time.Now())
+		if n := len(history.Times); n > 0 {
+			scale := big.NewFloat(math.Pow(*decayFactorFlag, float64(n)))
+			c.Amount, _ = new(big.Float).Mul(new(big.Float).SetInt(c.Amount), scale).Int(nil)
+		}
+		// Claims taken by the sender are waited for even if the requester left,
+		// so the transaction is settled by the time the claim returns here
+		err := next(c)
+		if !refundable(c, err) && !c.decoy {
+			recordHistory(id)
+		}
+		return err
+	}
+}
+
+// recordHistory adds a funded claim to the history of an address.
+func recordHistory(address string) {
+	err := store.Update(claimCountsBucket, address, func(blob []byte) ([]byte, error) {
+		var history claimHistory
+		if blob != nil {
+			if err := json.Unmarshal(blob, &history); err != nil {
+				return nil, err
+			}
+		}
+		now := time.Now()
+		history.prune(now)
+		history.Times = append(history.Times, now)
+		return json.Marshal(&history)
+	})
+	if err != nil {
+		log.Error("Failed to record claim history err: ", err)
+	}
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	dripsFlag             = flag.Bool("drips.enabled", false, "Let registered developers subscribe addresses to recurring payouts via /api/drips")
+	dripIntervalFlag      = flag.Duration("drips.interval", 24*time.Hour, "Shortest time allowed between two payouts of a drip")
+	dripTotalFlag         = flag.Float64("drips.total", 10, "Largest total in units a single drip may pay out before it ends")
+	dripSubscriptionsFlag = flag.Int("drips.subscriptions", 3, "Number of drips a developer may have running at once")
+)
+
+const (
+	// dripsBucket is the store bucket holding the running drips by ID.
+	dripsBucket = "drips"
+
+	// dripKeysBucket is the store bucket holding the owners of the issued drip
+	// API keys by key hash.
+	dripKeysBucket = "drip-keys"
+)
+
+// errDripUnauthorized is returned if a drip request carries neither a known API
+// key nor a valid JWT.
+var errDripUnauthorized = errors.New("invalid API key or token")
+
+// drip is a subscription of an address to a recurring payout until a total is
+// paid out.
+type drip struct {
+	ID       string    `json:"id"`
+	Owner    string    `json:"owner"` // Developer that subscribed
+	Address  string    `json:"address"`
+	Amount   string    `json:"amount"`   // Payout in wei, decimal
+	Interval string    `json:"interval"` // Time between payouts, e.g. 24h
+	Total    string    `json:"total"`    // Cap of all payouts in wei, decimal
+	Paid     string    `json:"paid"`     // Paid out so far in wei, decimal
+	Next     time.Time `json:"next"`     // Time the next payout is due
+	Created  time.Time `json:"created"`
+	LastTx   string    `json:"lastTx,omitempty"`
+}
+
+// dripKey is the record of an issued drip API key. The key itself is only
+// handed out once, the store keeps its hash.
+type dripKey struct {
+	Owner   string    `json:"owner"`
+	Created time.Time `json:"created"`
+}
+
+// dripping sends the drip payouts through the same queue as the claims, so they
+// don't race the regular payouts for nonces.
+var dripping = NewPipeline(
+	Stage{"enqueue", enqueueStage},
+	Stage{"send", sendStage},
+	Stage{"record", recordStage},
+)
+
+// hashDripKey derives the store key of a drip API key.
+func hashDripKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// newDripKey generates a random drip API key.
+func newDripKey() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// startDrips starts paying out the due drips, if enabled.
+func startDrips(s *Server) {
+	if !*dripsFlag {
+		return
+	}
+	if *dripIntervalFlag < time.Minute {
+		log.Fatal("Invalid drip interval: must be at least a minute")
+	}
+	registerGauge("faucet_drips_active", "Number of drips running.", func() float64 {
+		var n int
+		store.Iterate(dripsBucket, func(string, []byte) bool {
+			n++
+			return true
+		})
+		return float64(n)
+	})
+	log.Info("Paying out drips at most every ", common.PrettyDuration(*dripIntervalFlag))
+	go s.loopDrips()
+}
+
+// loopDrips periodically pays out the drips that are due.
+func (s *Server) loopDrips() {
+	handle := dripping.Handler()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		if s.isPassive() {
+			continue // Leave the drips to the primary
+		}
+		var due []*drip
+		store.Iterate(dripsBucket, func(key string, blob []byte) bool {
+			d := new(drip)
+			if err := json.Unmarshal(blob, d); err != nil {
+				log.Error("Failed to decode drip err: ", err)
+				return true
+			}
+			if !time.Now().Before(d.Next) {
+				due = append(due, d)
+			}
+			return true
+		})
+		for _, d := range due {
+			s.payDrip(handle, d)
+		}
+	}
+}
+
+// payDrip pays out a single due drip, ending it once its total is reached.
+func (s *Server) payDrip(handle Handler, d *drip) {
+	amount, _ := new(big.Int).SetString(d.Amount, 10)
+	total, _ := new(big.Int).SetString(d.Total, 10)
+	paid, _ := new(big.Int).SetString(d.Paid, 10)
+	interval, err := time.ParseDuration(d.Interval)
+	if amount == nil || total == nil || paid == nil || err != nil {
+		log.Error("Dropping corrupt drip ", d.ID)
+		store.Delete(dripsBucket, d.ID)
+		return
+	}
+	if left := new(big.Int).Sub(total, paid); left.Cmp(amount) < 0 {
+		amount = left
+	}
+	c := &Claim{ctx: context.Background(), server: s, Address: d.Address, Amount: amount, IP: "drip", Lang: defaultLanguage, SkipCooldown: true}
+	if err := handle(c); err != nil {
+		log.Error("Failed to pay out drip ", d.ID, " err: ", err)
+		return // Retried on the next tick
+	}
+	paid.Add(paid, amount)
+
+	// Keep to the schedule rather than drifting by the time payouts take, but
+	// don't catch up on payouts missed while the faucet was down
+	next := d.Next.Add(interval)
+	if next.Before(time.Now()) {
+		next = time.Now().Add(interval)
+	}
+	err = store.Update(dripsBucket, d.ID, func(blob []byte) ([]byte, error) {
+		if blob == nil || paid.Cmp(total) >= 0 {
+			return nil, nil // Cancelled meanwhile or done
+		}
+		d.Paid, d.Next, d.LastTx = paid.String(), next, c.Tx.ID()
+		return json.Marshal(d)
+	})
+	if err != nil {
+		log.Error("Failed to update drip err: ", err)
+	}
+	log.Info("Paid out drip ", d.ID, " to ", d.Address, ", ", fromWei(paid), " of ", fromWei(total), " ", *UnitFlag)
+}
+
+// dripOwner authenticates a drip request by its bearer token, either a drip API
+// key issued through the admin API or a JWT of the identity provider.
+func dripOwner(r *http.Request) (string, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return "", errDripUnauthorized
+	}
+	var key dripKey
+	switch err := getJSON(store, dripKeysBucket, hashDripKey(token), &key); err {
+	case nil:
+		return key.Owner, nil
+	case errNotFound:
+	default:
+		return "", err
+	}
+	if *jwksFlag != "" {
+		if claims, err := verifyJWT(r.Context(), token); err == nil {
+			return "jwt:" + claims.Subject, nil
+		}
+	}
+	return "", errDripUnauthorized
+}
+
+// ownedDrips returns the drips subscribed by the given developer.
+func ownedDrips(owner string) ([]*drip, error) {
+	drips := []*drip{}
+	err := store.Iterate(dripsBucket, func(key string, blob []byte) bool {
+		d := new(drip)
+		if err := json.Unmarshal(blob, d); err != nil {
+			log.Error("Failed to decode drip err: ", err)
+			return true
+		}
+		if d.Owner == owner {
+			drips = append(drips, d)
+		}
+		return true
+	})
+	return drips, err
+}
+
+// onDrips lets registered developers list their drips on GET, subscribe an
+// address on POST and cancel a drip by the id query parameter on DELETE.
+func (s *Server) onDrips(w http.ResponseWriter, r *http.Request) {
+	owner, err := dripOwner(r)
+	if err == errDripUnauthorized {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSONError(w, http.StatusUnauthorized, err)
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	drips, err := ownedDrips(owner)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, drips)
+
+	case http.MethodPost:
+		var req struct {
+			Address  string  `json:"address"`
+			Amount   float64 `json:"amount"`   // Payout in token units
+			Interval string  `json:"interval"` // Time between payouts, e.g. 24h
+			Total    float64 `json:"total"`    // Cap of all payouts in token units
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		address, err := s.chain.ParseAddress(req.Address)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, errors.New("invalid address"))
+			return
+		}
+		interval, err := time.ParseDuration(req.Interval)
+		if err != nil || interval < *dripIntervalFlag {
+			writeJSONError(w, http.StatusBadRequest, fmt.Errorf("interval must be at least %s", common.PrettyDuration(*dripIntervalFlag)))
+			return
+		}
+		amount, total := toWei(req.Amount), toWei(req.Total)
+		if amount.Sign() <= 0 || amount.Cmp(payoutTiers[len(payoutTiers)-1].Amount) > 0 {
+			writeJSONError(w, http.StatusBadRequest, fmt.Errorf("amount must be positive and at most %s", fromWei(payoutTiers[len(payoutTiers)-1].Amount)))
+			return
+		}
+		if total.Cmp(amount) < 0 || total.Cmp(toWei(*dripTotalFlag)) > 0 {
+			writeJSONError(w, http.StatusBadRequest, fmt.Errorf("total must be between the amount and %v", *dripTotalFlag))
+			return
+		}
+		if len(drips) >= *dripSubscriptionsFlag {
+			writeJSONError(w, http.StatusConflict, fmt.Errorf("limit of %d drips reached", *dripSubscriptionsFlag))
+			return
+		}
+		id := newJobID()
+		d := &drip{
+			ID:       id,
+			Owner:    owner,
+			Address:  address,
+			Amount:   amount.String(),
+			Interval: interval.String(),
+			Total:    total.String(),
+			Paid:     "0",
+			Next:     time.Now(),
+			Created:  time.Now(),
+		}
+		if err := putJSON(store, dripsBucket, id, d); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		log.Info("Drip subscribed: ", id, " owner: ", owner, " address: ", d.Address)
+		writeJSON(w, http.StatusOK, d)
+
+	case http.MethodDelete:
+		id := r.URL.Query().Get("id")
+		for _, d := range drips {
+			if d.ID == id {
+				if err := store.Delete(dripsBucket, id); err != nil {
+					writeJSONError(w, http.StatusInternalServerError, err)
+					return
+				}
+				log.Info("Drip cancelled: ", id, " owner: ", owner)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		writeJSONError(w, http.StatusNotFound, errors.New("unknown drip"))
+
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		writeJSONError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+// onAdminDripKeys lists the owners of the drip API keys on GET, issues a key to
+// an owner on POST and revokes all keys of the owner query parameter on DELETE.
+func onAdminDripKeys(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		keys := []*dripKey{}
+		err := store.Iterate(dripKeysBucket, func(hash string, blob []byte) bool {
+			k := new(dripKey)
+			if err := json.Unmarshal(blob, k); err != nil {
+				log.Error("Failed to decode drip key err: ", err)
+				return true
+			}
+			keys = append(keys, k)
+			return true
+		})
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, keys)
+
+	case http.MethodPost:
+		var req struct {
+			Owner string `json:"owner"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		if req.Owner == "" {
+			writeJSONError(w, http.StatusBadRequest, errors.New("owner missing"))
+			return
+		}
+		key, err := newDripKey()
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if err := putJSON(store, dripKeysBucket, hashDripKey(key), &dripKey{Owner: req.Owner, Created: time.Now()}); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		log.Info("Issued drip API key to ", req.Owner)
+		writeJSON(w, http.StatusOK, map[string]string{"owner": req.Owner, "key": key})
+
+	case http.MethodDelete:
+		owner := r.URL.Query().Get("owner")
+
+		var revoked []string
+		store.Iterate(dripKeysBucket, func(hash string, blob []byte) bool {
+			var k dripKey
+			if json.Unmarshal(blob, &k) == nil && k.Owner == owner {
+				revoked = append(revoked, hash)
+			}
+			return true
+		})
+		for _, hash := range revoked {
+			if err := store.Delete(dripKeysBucket, hash); err != nil {
+				writeJSONError(w, http.StatusInternalServerError, err)
+				return
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]int{"revoked": len(revoked)})
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
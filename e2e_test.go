@@ -0,0 +1,278 @@
+//go:build e2e
+// +build e2e
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// The end-to-end tests boot the faucet against go-ethereum's simulated backend
+// by default, or against the node at $FAUCET_E2E_RPC (e.g. a local anvil) when
+// set, and claim through the public API handler. Run them via `make e2e`.
+
+// anvilKey is the first of the well known development accounts of anvil and
+// hardhat, funded on every fresh node.
+const anvilKey = "ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+
+// The faucet under test, set up once for all tests, along with a client of the
+// node it pays out on.
+var (
+	faucet  *Server
+	backend *evmBackend
+	client  *ethclient.Client
+)
+
+func TestMain(m *testing.M) {
+	settings := map[string]string{
+		"chain.backend": "sim",
+		"pri_key":       "b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291",
+		"sim.blocktime": "100ms",
+
+		// Have claims wait for their receipt, covering the whole claim flow
+		"rpc.receipt.timeout": "30s",
+	}
+	if node := os.Getenv("FAUCET_E2E_RPC"); node != "" {
+		settings["chain.backend"] = "evm"
+		settings["rpc"] = node
+		settings["pri_key"] = envOr("FAUCET_E2E_KEY", anvilKey)
+		settings["chain_id"] = envOr("FAUCET_E2E_CHAIN_ID", "31337")
+	}
+	for name, value := range settings {
+		if err := flag.Set(name, value); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to set --%s: %v\n", name, err)
+			os.Exit(1)
+		}
+	}
+	faucet = setupFaucet()
+	backend = unwrapChain(faucet.chain).(*evmBackend)
+	client = ethclient.NewClient(backend.rpc)
+	os.Exit(m.Run())
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// claimE2E posts a claim for addr to the API handler as if from ip. It is safe
+// to call from other goroutines than the one of the test.
+func claimE2E(handler http.Handler, ip string, addr common.Address) (int, *claimResponse, error) {
+	body, _ := json.Marshal(&fundRequest{URL: addr.Hex()})
+	req := httptest.NewRequest(http.MethodPost, "/api/claim", bytes.NewReader(body))
+	req.RemoteAddr = ip + ":4242"
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	res := new(claimResponse)
+	if err := json.Unmarshal(rec.Body.Bytes(), res); err != nil {
+		return rec.Code, nil, fmt.Errorf("undecodable response %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, res, nil
+}
+
+// freshAddress returns a new random account, unfunded on any chain.
+func freshAddress(t *testing.T) common.Address {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return crypto.PubkeyToAddress(key.PublicKey)
+}
+
+// waitReceipt waits for a transaction to be mined.
+func waitReceipt(t *testing.T, hash common.Hash) *types.Receipt {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	for {
+		if receipt, err := client.TransactionReceipt(ctx, hash); err == nil {
+			return receipt
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("transaction %s not mined", hash.Hex())
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func TestE2EClaimConfirm(t *testing.T) {
+	handler := newAPIHandler(faucet)
+	addr := freshAddress(t)
+
+	code, res, err := claimE2E(handler, "10.0.0.1", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != http.StatusOK {
+		t.Fatalf("claim failed with %d: %+v", code, res)
+	}
+	if res.Tx == "" {
+		t.Fatalf("claim returned no transaction: %+v", res)
+	}
+	if receipt := waitReceipt(t, common.HexToHash(res.Tx)); receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("payout reverted: %+v", receipt)
+	}
+	balance, err := client.BalanceAt(context.Background(), addr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := payoutTiers[0].Amount; balance.Cmp(want) != 0 {
+		t.Errorf("recipient balance %v, want %v", balance, want)
+	}
+	if _, err := store.Get(journalBucket, res.Tx); err == nil {
+		t.Errorf("confirmed payout %s still journaled", res.Tx)
+	}
+	// The cooldown of the requester kicks in right away
+	if code, res, err := claimE2E(handler, "10.0.0.1", addr); err != nil {
+		t.Error(err)
+	} else if code != http.StatusTooManyRequests {
+		t.Errorf("repeated claim answered with %d: %+v", code, res)
+	}
+}
+
+func TestE2ENonceRace(t *testing.T) {
+	const claims = 16
+
+	handler := newAPIHandler(faucet)
+	start, err := client.PendingNonceAt(context.Background(), backend.from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Generate the recipients up front, as failing the test only works from the
+	// goroutine of the test
+	addrs := make([]common.Address, claims)
+	for i := range addrs {
+		addrs[i] = freshAddress(t)
+	}
+	var (
+		wg     sync.WaitGroup
+		lock   sync.Mutex
+		hashes []common.Hash
+	)
+	for i := 0; i < claims; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			code, res, err := claimE2E(handler, fmt.Sprintf("10.1.%d.1", i), addrs[i])
+			if err != nil {
+				t.Errorf("concurrent claim %d failed: %v", i, err)
+				return
+			}
+			if code != http.StatusOK || res.Tx == "" {
+				t.Errorf("concurrent claim %d failed with %d: %+v", i, code, res)
+				return
+			}
+			lock.Lock()
+			hashes = append(hashes, common.HexToHash(res.Tx))
+			lock.Unlock()
+		}(i)
+	}
+	wg.Wait()
+
+	nonces := make(map[uint64]common.Hash)
+	for _, hash := range hashes {
+		waitReceipt(t, hash)
+		tx, _, err := client.TransactionByHash(context.Background(), hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if other, ok := nonces[tx.Nonce()]; ok {
+			t.Errorf("transactions %s and %s share nonce %d", other.Hex(), hash.Hex(), tx.Nonce())
+		}
+		nonces[tx.Nonce()] = hash
+	}
+	for nonce := start; nonce < start+uint64(len(hashes)); nonce++ {
+		if _, ok := nonces[nonce]; !ok {
+			t.Errorf("nonce %d skipped", nonce)
+		}
+	}
+}
+
+// TestE2EFeeBump leaves an underpriced payout stuck in the pool of the node and
+// checks the nonce repair resends it to the same recipient at a bumped fee. The
+// simulated backend mines every transaction right away and rejects replacements,
+// so this needs a real node.
+func TestE2EFeeBump(t *testing.T) {
+	if os.Getenv("FAUCET_E2E_RPC") == "" {
+		t.Skip("needs a node with manual mining, set FAUCET_E2E_RPC")
+	}
+	ctx := context.Background()
+	if err := backend.rpc.CallContext(ctx, nil, "evm_setAutomine", false); err != nil {
+		t.Skip("node doesn't support switching off automining: ", err)
+	}
+	defer backend.rpc.CallContext(ctx, nil, "evm_setAutomine", true)
+
+	nonce, err := client.PendingNonceAt(ctx, backend.from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	suggested, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Price the payout just below the market, so the node pool holds it but
+	// the repair considers it underpriced
+	price := new(big.Int).Sub(suggested, big.NewInt(1))
+	addr, amount := freshAddress(t), big.NewInt(1_000_000)
+	tx, err := backend.Sign(&evmTx{Transaction: types.NewTransaction(nonce, addr, amount, 21000, price, nil), gasPrice: price})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := journalTx(tx, addr.Hex(), amount); err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Broadcast(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	// Age the journal entry past the stuck threshold
+	defer flag.Set("nonce.stuck", nonceStuckFlag.String())
+	flag.Set("nonce.stuck", "0s")
+
+	claim := &Claim{ctx: ctx, server: faucet, Values: make(map[string]interface{})}
+	if err := nonceRepair.Handler()(claim); err != nil {
+		t.Fatal(err)
+	}
+	repairs, _ := claim.Values["repairs"].([]string)
+	if len(repairs) != 1 {
+		t.Fatalf("repaired %v, want the stuck payout only", repairs)
+	}
+	if err := backend.rpc.CallContext(ctx, nil, "evm_mine"); err != nil {
+		t.Fatal(err)
+	}
+	replacement, _, err := client.TransactionByHash(ctx, common.HexToHash(repairs[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replacement.Nonce() != nonce || replacement.GasPrice().Cmp(price) <= 0 {
+		t.Errorf("replacement nonce %d price %v, want nonce %d above %v", replacement.Nonce(), replacement.GasPrice(), nonce, price)
+	}
+	waitReceipt(t, replacement.Hash())
+	balance, err := client.BalanceAt(ctx, addr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Cmp(amount) != 0 {
+		t.Errorf("recipient balance %v after repair, want %v", balance, amount)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	eligibilityURLFlag      = flag.String("eligibility.url", "", "External eligibility service to consult for every claim (empty = disabled)")
+	eligibilityTimeoutFlag  = flag.Duration("eligibility.timeout", 5*time.Second, "Deadline for the eligibility service to respond")
+	eligibilityFailOpenFlag = flag.Bool("eligibility.failopen", false, "Allow claims through if the eligibility service is unreachable")
+)
+
+// eligibilityRequest is the claim summary POSTed to the eligibility service.
+type eligibilityRequest struct {
+	Address string  `json:"address"`
+	Tier    uint    `json:"tier"`
+	Amount  string  `json:"amount"`
+	IP      string  `json:"ip"`
+	Risk    float64 `json:"risk"`
+}
+
+// eligibilityResponse is the verdict of the eligibility service. The decision
+// is one of "allow", "deny" or "modify", the latter replacing the payout with
+// the returned amount (in wei, decimal).
+type eligibilityResponse struct {
+	Decision string `json:"decision"`
+	Amount   string `json:"amount,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// eligibilityStage consults the operator supplied eligibility service, if one
+// is configured, and applies its verdict to the claim.
+func eligibilityStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if *eligibilityURLFlag == "" {
+			return next(c)
+		}
+		verdict, err := queryEligibility(c)
+		if err != nil {
+			log.Error("Failed to query eligibility service err: ", err)
+			if *eligibilityFailOpenFlag {
+				return next(c)
+			}
+			return newUserError("Eligibility check unavailable, try again later")
+		}
+		switch verdict.Decision {
+		case "allow":
+		case "deny":
+			if verdict.Reason != "" {
+				return errors.New(verdict.Reason)
+			}
+			return newUserError("Request denied")
+		case "modify":
+			amount, ok := new(big.Int).SetString(verdict.Amount, 10)
+			if !ok || amount.Sign() <= 0 {
+				log.Error("Invalid amount from eligibility service: ", verdict.Amount)
+				return newUserError("Eligibility check unavailable, try again later")
+			}
+			c.Amount = amount
+		default:
+			log.Error("Unknown decision from eligibility service: ", verdict.Decision)
+			return newUserError("Eligibility check unavailable, try again later")
+		}
+		return next(c)
+	}
+}
+
+// queryEligibility POSTs the claim details to the eligibility service and
+// returns its verdict.
+func queryEligibility(c *Claim) (*eligibilityResponse, error) {
+	blob, err := json.Marshal(&eligibilityRequest{
+		Address: c.Address,
+		Tier:    c.Tier,
+		Amount:  c.Amount.String(),
+		IP:      remoteHost(c.IP),
+		Risk:    c.Risk,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(c.ctx, *eligibilityTimeoutFlag)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, *eligibilityURLFlag, bytes.NewReader(blob))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", res.Status)
+	}
+	var verdict eligibilityResponse
+	if err := json.NewDecoder(res.Body).Decode(&verdict); err != nil {
+		return nil, err
+	}
+	return &verdict, nil
+}
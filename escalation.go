@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	escalationFactorFlag  = flag.Float64("cooldown.escalation", 0, "Factor to multiply the next cooldown of repeat offenders by, per --cooldown.strikes early requests (0 = disabled)")
+	escalationStrikesFlag = flag.Int("cooldown.strikes", 3, "Requests during a cooldown tolerated before escalating the next one")
+	escalationMaxFlag     = flag.Duration("cooldown.max", 7*24*time.Hour, "Ceiling of escalated cooldowns")
+)
+
+// strikesBucket is the store bucket counting the requests rejected during a
+// cooldown, keyed by "addr:<address>" and "ip:<ip>".
+const strikesBucket = "strikes"
+
+// strikeKeys returns the keys a claim's strikes are counted under.
+func strikeKeys(c *Claim) []string {
+	return []string{"addr:" + c.Address, "ip:" + remoteHost(c.IP)}
+}
+
+// recordStrike counts a request of the claimant rejected during its cooldown.
+func recordStrike(c *Claim) {
+	if *escalationFactorFlag <= 1 {
+		return
+	}
+	for _, key := range strikeKeys(c) {
+		err := store.Update(strikesBucket, key, func(blob []byte) ([]byte, error) {
+			strikes, _ := strconv.Atoi(string(blob))
+			return []byte(strconv.Itoa(strikes + 1)), nil
+		})
+		if err != nil {
+			log.Error("Failed to record cooldown strike err: ", err)
+		}
+	}
+}
+
+// escalateCooldown multiplies the cooldown of a claim once for every block of
+// strikes its address or IP collected during the previous cooldown, capped at
+// the configured ceiling. It reports whether there were strikes to clear once
+// the claim is paid out.
+func escalateCooldown(c *Claim) bool {
+	if *escalationFactorFlag <= 1 || *escalationStrikesFlag <= 0 {
+		return false
+	}
+	strikes, struck := 0, false
+	for _, key := range strikeKeys(c) {
+		if blob, err := store.Get(strikesBucket, key); err == nil {
+			if n, _ := strconv.Atoi(string(blob)); n > strikes {
+				strikes = n
+			}
+			struck = true
+		}
+	}
+	level := strikes / *escalationStrikesFlag
+	if level == 0 {
+		return struck
+	}
+	cooldown := time.Duration(float64(c.Cooldown) * math.Pow(*escalationFactorFlag, float64(level)))
+	if cooldown > *escalationMaxFlag || cooldown < 0 {
+		cooldown = *escalationMaxFlag
+	}
+	if cooldown <= c.Cooldown {
+		return struck
+	}
+	log.Info("Escalating cooldown of repeat offender: ", c.Address, " strikes: ", strikes, " cooldown: ", cooldown)
+	c.Cooldown = cooldown
+	c.Notes = append(c.Notes, translate(c.Lang, "Cooldown extended to %s for repeated early requests", prettyDuration(cooldown)))
+	return struck
+}
+
+// clearStrikes forgets the strikes of a claimant once its claim was paid out,
+// starting the count anew for the next cooldown.
+func clearStrikes(c *Claim) {
+	for _, key := range strikeKeys(c) {
+		if err := store.Delete(strikesBucket, key); err != nil && err != errNotFound {
+			log.Error("Failed to clear cooldown strikes err: ", err)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	ethrpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/sunvim/utils/log"
+)
+
+var simulateFlag = flag.Bool("rpc.simulate", true, "Simulate plain transfers against the pending state before broadcasting them, rejecting those that would revert (costs an eth_call per payout in the sender; estimated transactions are always checked)")
+
+// evmClient is the part of the node API used by the EVM backend, satisfied by
+// both ethclient and the simulated backend of go-ethereum.
+type evmClient interface {
+	EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error)
+	PendingCallContract(ctx context.Context, call ethereum.CallMsg) ([]byte, error)
+	CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+	TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error)
+	BalanceAt(ctx context.Context, account common.Address, number *big.Int) (*big.Int, error)
+	NonceAt(ctx context.Context, account common.Address, number *big.Int) (uint64, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+	CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error)
+	FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error)
+	ChainID(ctx context.Context) (*big.Int, error)
+}
+
+// evmBackend is the chain backend paying out on Ethereum compatible networks.
+type evmBackend struct {
+	client  evmClient
+	rpc     *ethrpc.Client // Raw RPC client for calls not wrapped by the client, if any
+	key     *ecdsa.PrivateKey
+	from    common.Address
+	chainID *big.Int
+
+	prefetch *evmPrefetch   // Cache of the nonce and gas price, if enabled
+	receipts *receiptPoller // Batched receipt polling, if enabled
+	guard    chainGuard     // Periodic chain ID checks before signing
+}
+
+// evmTx is a transaction built by the EVM backend, along with the fee details
+// needed to account for its gas spend.
+type evmTx struct {
+	*types.Transaction
+	gasPrice  *big.Int // Maximum price paid per unit of gas
+	accounted bool     // Whether the fee was recorded as gas spend already
+}
+
+// ID returns the hash of the transaction.
+func (tx *evmTx) ID() string {
+	return tx.Hash().Hex()
+}
+
+func newEVMBackend(client evmClient, key *ecdsa.PrivateKey, chainID *big.Int) *evmBackend {
+	return &evmBackend{
+		client:  client,
+		key:     key,
+		from:    crypto.PubkeyToAddress(key.PublicKey),
+		chainID: chainID,
+	}
+}
+
+// ParseAddress checks a hex address, with or without 0x prefix and in any case,
+// returning it checksummed. The zero address is rejected, as funds sent there
+// are burned.
+func (b *evmBackend) ParseAddress(addr string) (string, error) {
+	if !common.IsHexAddress(addr) {
+		return "", fmt.Errorf("invalid address %q", addr)
+	}
+	parsed := common.HexToAddress(addr)
+	if parsed == (common.Address{}) {
+		return "", errors.New("zero address")
+	}
+	return parsed.Hex(), nil
+}
+
+// BuildTx creates a transfer to a hex address. Plain transfers to accounts on L1
+// use a fixed gas limit, anything else is estimated with some headroom (L2
+// transfers may cost more than execution gas, e.g. Arbitrum charges the L1
+// calldata as gas, and smart contract wallets run code on receiving funds).
+// Transactions are simulated against the pending state beforehand, so reverts
+// are reported with their reason instead of burning gas on chain: gas estimation
+// does so for estimated ones, plain transfers are simulated with --rpc.simulate.
+func (b *evmBackend) BuildTx(ctx context.Context, to string, amount *big.Int, data []byte) (ChainTx, error) {
+	if !common.IsHexAddress(to) {
+		return nil, fmt.Errorf("invalid recipient address %q", to)
+	}
+	recipient := common.HexToAddress(to)
+	call := ethereum.CallMsg{From: b.from, To: &recipient, Value: amount, Data: data}
+
+	var contract bool
+	if data == nil {
+		var err error
+		if contract, err = b.IsContract(ctx, to); err != nil {
+			log.Error("Failed to check for contract wallet err: ", err)
+			return nil, err
+		}
+	}
+	gasLimit := uint64(21000)
+	if data != nil || *l2Flag != "" || contract {
+		rctx, cancel := rpcContext(ctx)
+		gas, err := b.client.EstimateGas(rctx, call)
+		cancel()
+		if err != nil {
+			log.Error("Failed to estimate transaction gas err: ", err)
+			return nil, simulationError(err)
+		}
+		gasLimit = gas + gas/5
+	} else if *simulateFlag {
+		rctx, cancel := rpcContext(ctx)
+		_, err := b.client.PendingCallContract(rctx, call)
+		cancel()
+		if err != nil {
+			return nil, simulationError(err)
+		}
+	}
+	if contract && gasLimit > *contractGasFlag {
+		log.Info("Contract wallet needs too much gas: ", recipient.Hex(), " gas: ", gasLimit)
+		return nil, newUserError("Your smart contract wallet needs too much gas to receive funds")
+	}
+	nonce, err := b.pendingNonce(ctx)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	if *l2Flag != "" {
+		tx, feeCap, err := b.newL2Transaction(ctx, nonce, recipient, amount, data, gasLimit)
+		if err != nil {
+			log.Error(err)
+			return nil, err
+		}
+		return &evmTx{Transaction: tx, gasPrice: feeCap}, nil
+	}
+	gasPrice, err := b.suggestGasPrice(ctx)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	return &evmTx{Transaction: types.NewTransaction(nonce, recipient, amount, gasLimit, gasPrice, data), gasPrice: gasPrice}, nil
+}
+
+// Sign signs a transaction with the faucet key, once the node is verified to
+// still be on the expected chain.
+func (b *evmBackend) Sign(tx ChainTx) (ChainTx, error) {
+	if err := b.verifyChain(); err != nil {
+		return nil, err
+	}
+	etx := tx.(*evmTx)
+	signed, err := types.SignTx(etx.Transaction, types.LatestSignerForChainID(b.chainID), b.key)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	return &evmTx{Transaction: signed, gasPrice: etx.gasPrice}, nil
+}
+
+// Broadcast submits a signed transaction and accounts for its gas spend, once
+// per transaction however often it is rebroadcast. The transaction is also
+// submitted to the broadcast relays, if any, and counts as broadcast if either
+// the node or a relay accepted it.
+func (b *evmBackend) Broadcast(ctx context.Context, tx ChainTx) error {
+	etx := tx.(*evmTx)
+	log.Info("tx hash: ", etx.ID())
+
+	raw, err := etx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	relayed := relayTx(etx.ID(), raw)
+
+	tctx, trace := withProviderTrace(ctx)
+	rctx, cancel := rpcContext(tctx)
+	defer cancel()
+	err = b.client.SendTransaction(rctx, etx.Transaction)
+	if err == nil {
+		trackBroadcast(etx.ID(), trace.get())
+	}
+	if err != nil && len(relays) > 0 && <-relayed {
+		log.Info("Node rejected transaction accepted by a relay: ", etx.ID(), " err: ", err)
+		err = nil
+	}
+	b.broadcasted(etx.Nonce(), err)
+	if err != nil {
+		return err
+	}
+	if etx.accounted {
+		return nil // Rebroadcast, the fee was recorded when first sent
+	}
+	etx.accounted = true
+	recordGasSpend(etx.Gas(), etx.gasPrice)
+	if *l2Flag == "optimism" {
+		b.recordL1Fee(ctx, etx.Transaction)
+	}
+	return nil
+}
+
+// Confirm polls for the receipt of a transaction until it is included in a
+// block or the context is cancelled, each poll bounded by the RPC deadline.
+// With batching enabled, the receipts of all pending transactions are polled
+// for together.
+func (b *evmBackend) Confirm(ctx context.Context, tx ChainTx) (*ChainReceipt, error) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	etx := tx.(*evmTx)
+	for {
+		var (
+			receipt *types.Receipt
+			err     error
+		)
+		if b.receipts != nil {
+			if receipt, err = b.receipts.wait(ctx, etx.Hash()); err != nil {
+				return nil, err
+			}
+		} else {
+			rctx, cancel := rpcContext(ctx)
+			receipt, err = b.client.TransactionReceipt(rctx, etx.Hash())
+			cancel()
+		}
+		if err == nil {
+			trackInclusion(etx.ID())
+			result := &ChainReceipt{
+				Block:   receipt.BlockNumber.Uint64(),
+				Success: receipt.Status == types.ReceiptStatusSuccessful,
+			}
+			for _, entry := range receipt.Logs {
+				event := ChainLog{Contract: entry.Address.Hex(), Data: entry.Data}
+				for _, topic := range entry.Topics {
+					event.Topics = append(event.Topics, topic.Bytes())
+				}
+				result.Logs = append(result.Logs, event)
+			}
+			if !result.Success {
+				result.Reason = b.failureReason(ctx, etx, receipt)
+			}
+			return result, nil
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			log.Error("Failed to retrieve receipt err: ", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Balance retrieves the balance of the faucet account.
+func (b *evmBackend) Balance(ctx context.Context) (*big.Int, error) {
+	rctx, cancel := rpcContext(ctx)
+	defer cancel()
+
+	return b.client.BalanceAt(rctx, b.from, nil)
+}
+
+// BalanceOf retrieves the balance of a hex address.
+func (b *evmBackend) BalanceOf(ctx context.Context, addr string) (*big.Int, error) {
+	rctx, cancel := rpcContext(ctx)
+	defer cancel()
+
+	return b.client.BalanceAt(rctx, common.HexToAddress(addr), nil)
+}
+
+// TxCount retrieves the nonce of a hex address.
+func (b *evmBackend) TxCount(ctx context.Context, addr string, block *uint64) (uint64, error) {
+	var number *big.Int
+	if block != nil {
+		number = new(big.Int).SetUint64(*block)
+	}
+	rctx, cancel := rpcContext(ctx)
+	defer cancel()
+
+	return b.client.NonceAt(rctx, common.HexToAddress(addr), number)
+}
+
+// Head retrieves the number of the latest block.
+func (b *evmBackend) Head(ctx context.Context) (uint64, error) {
+	rctx, cancel := rpcContext(ctx)
+	defer cancel()
+
+	header, err := b.client.HeaderByNumber(rctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	return header.Number.Uint64(), nil
+}
+
+// DecodeTx restores a signed transaction from its binary encoding.
+func (b *evmBackend) DecodeTx(raw []byte) (ChainTx, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return nil, err
+	}
+	// Transactions are only restored from the journal, which they entered when
+	// first sent, so their fees were recorded then
+	return &evmTx{Transaction: tx, gasPrice: tx.GasFeeCap(), accounted: true}, nil
+}
+
+// failureReason figures out why a mined transaction failed: either it used up
+// all its gas, or it reverted, in which case it's replayed on top of the parent
+// block to recover the revert reason.
+func (b *evmBackend) failureReason(ctx context.Context, tx *evmTx, receipt *types.Receipt) string {
+	if receipt.GasUsed >= tx.Gas() {
+		return "out of gas"
+	}
+	rctx, cancel := rpcContext(ctx)
+	defer cancel()
+
+	call := ethereum.CallMsg{From: b.from, To: tx.To(), Gas: tx.Gas(), Value: tx.Value(), Data: tx.Data()}
+	_, err := b.client.CallContract(rctx, call, new(big.Int).Sub(receipt.BlockNumber, common.Big1))
+	if err == nil {
+		return "" // Only failed in the state of the block itself
+	}
+	reason, _ := revertReason(err)
+	return reason
+}
+
+// simulationError turns a failed simulation of a transaction into a user error
+// carrying the revert reason, unless the node itself failed.
+func simulationError(err error) error {
+	reason, ok := revertReason(err)
+	if !ok {
+		return err
+	}
+	log.Info("Transaction would revert: ", reason)
+	return newUserError("Payout would fail: %s", reason)
+}
+
+// revertReason extracts the reason from the error of a reverted call, reporting
+// whether the call reverted at all rather than the node failing.
+func revertReason(err error) (string, bool) {
+	var dataErr ethrpc.DataError
+	if !errors.As(err, &dataErr) && !strings.Contains(err.Error(), "revert") {
+		return "", false
+	}
+	reason := err.Error()
+	if dataErr != nil {
+		if hex, ok := dataErr.ErrorData().(string); ok {
+			if blob, err := hexutil.Decode(hex); err == nil {
+				if unpacked, err := abi.UnpackRevert(blob); err == nil {
+					reason = unpacked
+				}
+			}
+		}
+	}
+	return reason, true
+}
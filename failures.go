@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// txFailedError is returned when the funding transaction made it on chain but
+// failed, carrying a machine readable reason for API clients.
+type txFailedError struct {
+	error
+	reason string // "out-of-gas", "insufficient-balance", "paused" or "reverted"
+}
+
+// newTxFailure maps the failure reason of a funding transaction reported by the
+// chain backend to a structured error the user can make sense of.
+func newTxFailure(tx ChainTx, reason string) error {
+	lower := strings.ToLower(reason)
+	switch {
+	case lower == "out of gas":
+		return &txFailedError{newUserError("Funding transaction %s ran out of gas, please try again", tx.ID()), "out-of-gas"}
+	case strings.Contains(lower, "exceeds balance") || strings.Contains(lower, "insufficient balance"):
+		return &txFailedError{newUserError("Funding transaction %s failed, the faucet is out of tokens", tx.ID()), "insufficient-balance"}
+	case strings.Contains(lower, "paused"):
+		return &txFailedError{newUserError("Funding transaction %s failed, the token is paused", tx.ID()), "paused"}
+	case reason != "":
+		return &txFailedError{newUserError("Funding transaction %s reverted: %s", tx.ID(), reason), "reverted"}
+	}
+	return &txFailedError{newUserError("Funding transaction %s failed", tx.ID()), "reverted"}
+}
+
+// refundable reports whether a claim ended in an error without paying out, so
+// the allowance it consumed is to be handed back to the claimant.
+func refundable(c *Claim, err error) bool {
+	if err == nil {
+		return false
+	}
+	var failed *txFailedError
+	return c.Tx == nil || errors.As(err, &failed)
+}
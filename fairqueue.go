@@ -0,0 +1,142 @@
+package main
+
+import (
+	"flag"
+	"math/big"
+	"sync"
+)
+
+var queueFairFlag = flag.Bool("queue.fair", true, "Schedule queued claims fairly across client IPs (deficit round robin) instead of first come, first served")
+
+// jobQueue is the funding queue of the sender. Claims are kept in a flow per
+// client IP and the flows are served by deficit round robin: every round a
+// flow earns one base payout worth of credit and may spend it on its claims,
+// so a client flooding the queue only delays its own claims, and clients of
+// larger tiers get proportionally fewer turns.
+type jobQueue struct {
+	lock     sync.Mutex
+	ready    *sync.Cond
+	flows    map[string]*jobFlow
+	active   []*jobFlow // Flows with waiting claims, in serving order
+	size     int
+	capacity int
+}
+
+// jobFlow holds the waiting claims of a single client.
+type jobFlow struct {
+	key     string
+	jobs    []*fundJob
+	deficit int64 // Credit left to spend, in base payouts
+	visited bool  // Whether the flow earned its credit this round
+}
+
+// newJobQueue creates a funding queue holding up to capacity claims.
+func newJobQueue(capacity int) *jobQueue {
+	q := &jobQueue{flows: make(map[string]*jobFlow), capacity: capacity}
+	q.ready = sync.NewCond(&q.lock)
+	return q
+}
+
+// jobCost is the number of base payouts (the first tier) a claim pays out,
+// rounded up, which is what it costs its flow to be served.
+func jobCost(job *fundJob) int64 {
+	if job.claim.Amount == nil || len(payoutTiers) == 0 || payoutTiers[0].Amount.Sign() <= 0 {
+		return 1
+	}
+	base := payoutTiers[0].Amount
+	cost := new(big.Int).Add(job.claim.Amount, new(big.Int).Sub(base, big.NewInt(1)))
+	cost.Div(cost, base)
+	if !cost.IsInt64() || cost.Int64() < 1 {
+		return 1
+	}
+	return cost.Int64()
+}
+
+// push adds a claim to the queue of its client, reporting false if full.
+func (q *jobQueue) push(job *fundJob) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.size >= q.capacity {
+		return false
+	}
+	key := ""
+	if *queueFairFlag {
+		key = remoteHost(job.claim.IP)
+	}
+	flow, ok := q.flows[key]
+	if !ok {
+		flow = &jobFlow{key: key}
+		q.flows[key] = flow
+		q.active = append(q.active, flow)
+	}
+	flow.jobs = append(flow.jobs, job)
+	q.size++
+	q.ready.Signal()
+	return true
+}
+
+// pop waits for a claim and removes it from the queue.
+func (q *jobQueue) pop() *fundJob {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for q.size == 0 {
+		q.ready.Wait()
+	}
+	return q.next()
+}
+
+// tryPop removes the next claim from the queue, if any is waiting.
+func (q *jobQueue) tryPop() *fundJob {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.size == 0 {
+		return nil
+	}
+	return q.next()
+}
+
+// next picks the claim to serve next by deficit round robin. The lock must be
+// held and the queue must not be empty.
+func (q *jobQueue) next() *fundJob {
+	for {
+		flow := q.active[0]
+		if !flow.visited {
+			flow.deficit, flow.visited = flow.deficit+1, true
+		}
+		job, cost := flow.jobs[0], jobCost(flow.jobs[0])
+		if cost > flow.deficit {
+			// Not enough credit yet, let the other flows have their turn
+			if len(q.active) > 1 {
+				flow.visited = false
+				q.active = append(q.active[1:], flow)
+				continue
+			}
+			flow.deficit = cost // Nobody else is waiting, no need to save up
+		}
+		flow.deficit -= cost
+		flow.jobs[0] = nil
+		flow.jobs = flow.jobs[1:]
+		q.size--
+
+		if len(flow.jobs) == 0 {
+			delete(q.flows, flow.key)
+			q.active = q.active[1:]
+		}
+		return job
+	}
+}
+
+// len returns the number of claims waiting in the queue.
+func (q *jobQueue) len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.size
+}
+
+// cap returns the maximum number of claims the queue holds.
+func (q *jobQueue) cap() int {
+	return q.capacity
+}
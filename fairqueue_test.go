@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func BenchmarkJobQueue(b *testing.B) {
+	for _, flows := range []int{1, 16, 1024} {
+		b.Run(fmt.Sprintf("flows=%d", flows), func(b *testing.B) {
+			q := newJobQueue(flows * 4)
+			jobs := make([]*fundJob, flows*4)
+			for i := range jobs {
+				jobs[i] = &fundJob{claim: benchClaim(nil, i%flows)}
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for _, job := range jobs {
+					if !q.push(job) {
+						b.Fatal("queue full")
+					}
+				}
+				for range jobs {
+					q.pop()
+				}
+			}
+		})
+	}
+}
@@ -19,14 +19,16 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"flag"
 	"fmt"
 	"html/template"
-	"math"
+	"math/big"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
-	"syscall"
+	"time"
 
 	"github.com/sunvim/utils/log"
 )
@@ -37,19 +39,45 @@ var (
 	apiAddr  = flag.String("apiaddr", "127.0.0.1", "Listener Address")
 	apiHttps = flag.Bool("https", false, "https service flag")
 
-	priKey       = flag.String("pri_key", "d57caa3e1da880fdef9d1c586c72d4ab99f0acccee6fb8b2e53dd6251c9c6cd5", "private key")
-	key          = flag.String("key", "tls.key", "certificate key")
-	crt          = flag.String("crt", "tls.crt", "certificate file")
-	captchaToken = flag.String("captcha.token", "", "Recaptcha site key to authenticate client side")
-	tiersFlag    = flag.Int("faucet.tiers", 2, "Number of funding tiers to enable (x3 time, x2.5 funds)")
-	startFlag    = flag.Float64("faucet.start", 0.1, "Number of funding tiers to enable (x3 time, x2.5 funds)")
-	UnitFlag     = flag.String("unit", "Edge", "token unit")
-	payoutFlag   = flag.Float64("faucet.amount", 1.0, "Number of unit to pay out per user request")
-	minutesFlag  = flag.Int("faucet.minutes", 1440, "Number of minutes to wait between funding rounds")
-	rpc          = flag.String("rpc", "https://meta-ape-edge-testnet-01.ankr.com", "rpc url")
-	chainID      = flag.Int64("chain_id", 100, "chain id")
+	priKey        = flag.String("pri_key", "d57caa3e1da880fdef9d1c586c72d4ab99f0acccee6fb8b2e53dd6251c9c6cd5", "private key")
+	key           = flag.String("key", "tls.key", "certificate key")
+	crt           = flag.String("crt", "tls.crt", "certificate file")
+	captchaToken  = flag.String("captcha.token", "", "Recaptcha site key to authenticate client side")
+	captchaSecret = flag.String("captcha.secret", "", "Recaptcha secret key to authenticate server side")
+	tiersFlag     = flag.Int("faucet.tiers", 2, "Number of funding tiers to enable (x3 cooldown per tier)")
+	startFlag     = flag.Float64("faucet.start", 0.1, "Unit of the tier payouts, tier i paying (faucet.amount + i) * faucet.start")
+	UnitFlag      = flag.String("unit", "Edge", "token unit")
+	payoutFlag    = flag.Float64("faucet.amount", 1.0, "Number of unit to pay out per user request")
+	minutesFlag   = flag.Int("faucet.minutes", 1440, "Number of minutes to wait between funding rounds")
+	rpc           = flag.String("rpc", "https://meta-ape-edge-testnet-01.ankr.com", "rpc url")
+	chainID       = flag.Int64("chain_id", 100, "chain id")
+
+	rpcTimeoutFlag     = flag.Duration("rpc.timeout", 10*time.Second, "Deadline for each individual RPC call")
+	receiptTimeoutFlag = flag.Duration("rpc.receipt.timeout", 0, "Time to wait for the funding transaction to be mined (0 = don't wait)")
+	queueSizeFlag      = flag.Int("queue.size", 1024, "Number of funding requests allowed to wait for the sender")
+	riskThresholdFlag  = flag.Float64("risk.threshold", 1.0, "Risk score above which funding requests are rejected")
+	rlimitFlag         = flag.Uint64("rlimit.nofile", 0, "Open file limit to raise to (0 = as high as permitted)")
+	maxMessageFlag     = flag.Int64("api.maxmessage", 16*1024, "Maximum size of a websocket message in bytes")
+	maxBodyFlag        = flag.Int64("api.maxbody", 64*1024, "Maximum size of an HTTP request body in bytes")
+	apiPrefixFlag      = flag.String("api.prefix", "", "Path prefix the faucet is served under by a reverse proxy, e.g. /sepolia")
+	apiProxyFlag       = flag.Bool("api.proxy", false, "Trust the X-Forwarded-For header of requests from loopback, as set by a local reverse proxy")
+)
+
+var websiteTTLFlag = flag.Duration("website.ttl", time.Minute, "Time to serve a rendered website for before rendering it again with fresh payouts")
+
+var (
+	websiteTemplate string // Raw faucet website template
+
 )
 
+// cachedWebsite is a rendered website along with its time of rendering.
+type cachedWebsite struct {
+	page     []byte
+	gzipped  []byte // Page compressed for clients accepting gzip
+	etag     string
+	rendered time.Time
+}
+
 var (
 	// ether = new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
 	ether = 1000_000_000_000_000_000
@@ -58,78 +86,411 @@ var (
 func main() {
 	log.SetLevel(log.LevelInfo)
 	log.SetLogPrefix("Faucet")
-	setupRLimit()
 	// Parse the flags and set up the logger to print everything requested
 	flag.Parse()
-	initFaucet()
-
-	// Construct the payout tiers
-	amounts := make([]string, *tiersFlag)
-	periods := make([]string, *tiersFlag)
-	for i := 0; i < *tiersFlag; i++ {
-		// Calculate the amount for the next tier and format it
-		amount := (*payoutFlag + float64(i)) * (*startFlag)
-		amounts[i] = fmt.Sprintf("%s %s", strconv.FormatFloat(amount, 'f', -1, 64), *UnitFlag)
-		if amount == 1 {
-			amounts[i] = strings.TrimSuffix(amounts[i], "s")
-		}
-		// Calculate the period for the next tier and format it
-		period := *minutesFlag * int(math.Pow(3, float64(i)))
-		periods[i] = fmt.Sprintf("%d mins", period)
-		if period%60 == 0 {
-			period /= 60
-			periods[i] = fmt.Sprintf("%d hours", period)
-
-			if period%24 == 0 {
-				period /= 24
-				periods[i] = fmt.Sprintf("%d days", period)
-			}
-		}
-		if period == 1 {
-			periods[i] = strings.TrimSuffix(periods[i], "s")
-		}
+	if err := setupLogging(); err != nil {
+		log.Fatal("Invalid logging configuration: ", err)
+	}
+	if err := loadSecrets(); err != nil {
+		log.Fatal("Failed to load secrets: ", err)
+	}
+	if flag.Arg(0) == "check" {
+		os.Exit(runCheck())
+	}
+	if flag.Arg(0) == "init" {
+		os.Exit(runInit())
+	}
+	if flag.Arg(0) == "loadtest" {
+		os.Exit(runLoadTest())
+	}
+	if flag.Arg(0) == "config" && flag.Arg(1) == "dump" {
+		fmt.Print(dumpConfig())
+		return
+	}
+	if *tenantsFlag != "" {
+		runTenants()
+		return
+	}
+	log.Info("Resolved configuration:\n", dumpConfig())
+	setupRLimit(*rlimitFlag)
+	s := setupFaucet()
+	s.snapshotConfig()
+
+	startAdmin(s)
+	startStatusSync(s)
+	if !*readOnlyFlag {
+		startMirror()
+		startStandby(s)
+		startAutoFund(s)
+		startStreams(s)
+		checkNonces(s)
+		startJournal(s)
+		startVelocity()
+		startDrips(s)
+		startClaimLinks()
+		startFinality(s)
+		startActivity()
+	}
+
+	address := strings.Join([]string{*apiAddr, ":", *apiPort}, "")
+	listener, err := listen("api", address)
+	if err != nil {
+		log.Fatal("Failed to listen for API connections: ", err)
+	}
+	log.Infof("service booting with %s \n", listener.Addr())
+
+	// Everything's initialized, let the service manager (or the process being
+	// upgraded) know we're up
+	upgradeReady()
+	sdNotify("READY=1")
+	watchUpgrades()
+
+	if err := serve("api", listener, newAPIHandler(s), serverTLS(*apiHttps, *apiClientCAFlag)); err != http.ErrServerClosed {
+		log.Fatal("API server failed: ", err)
+	}
+	// The listeners were handed over to an upgraded binary, finish up and leave
+	waitPending()
+}
+
+// setupFaucet validates the configuration and sets up the chain connection,
+// the store and the pipeline along with the website, everything needed to
+// serve claims, returning the faucet. Background services are left for the
+// caller to start.
+func setupFaucet() *Server {
+	var err error
+	switch *chainBackendFlag {
+	case "evm":
+	case "sim":
+		*chainID = simChainID.Int64()
+	default:
+		log.Fatal("Invalid chain backend: ", *chainBackendFlag)
+	}
+	if store, err = openStore(*storePathFlag); err != nil {
+		log.Fatal("Failed to open the faucet store: ", err)
+	}
+	if err = setupReadOnly(); err != nil {
+		log.Fatal("Invalid read-only mode: ", err)
+	}
+	if err := validateL2(); err != nil {
+		log.Fatal("Invalid L2 configuration: ", err)
+	}
+	if *apiClientCAFlag != "" && !*apiHttps {
+		log.Fatal("Client certificates of the API require --https")
+	}
+	if err := validateBasePath(*basePathFlag); err != nil {
+		log.Fatal("Invalid --base-path: ", err)
+	}
+	if confirmations, err = parseConfirmations(*confirmationsFlag, *chainID); err != nil {
+		log.Fatal("Invalid confirmation depth: ", err)
+	}
+	if *sloSuccessFlag <= 0 || *sloSuccessFlag > 1 || *sloWindowFlag <= 0 {
+		log.Fatal("Invalid SLO: success target must be within (0, 1] and the window positive")
+	}
+	if err = setupGeo(); err != nil {
+		log.Fatal("Invalid country payouts: ", err)
+	}
+	if err = setupTrusted(); err != nil {
+		log.Fatal("Invalid trusted networks: ", err)
+	}
+	if err = loadTerms(); err != nil {
+		log.Fatal("Invalid terms of service: ", err)
+	}
+	if err = loadFormFields(); err != nil {
+		log.Fatal("Invalid form fields: ", err)
+	}
+	if err = setupErrorReporting(); err != nil {
+		log.Fatal("Invalid error reporting: ", err)
+	}
+	if siblings, err = parseSiblings(*siblingsFlag, *chainID); err != nil {
+		log.Fatal("Invalid sibling faucets: ", err)
+	}
+	if err = setupRelays(); err != nil {
+		log.Fatal("Invalid broadcast relays: ", err)
 	}
+	s := newServer(nil)
+	if *readOnlyFlag {
+		// Mirrors never queue claims, the idle queue is kept for the metrics
+		s.queue = newJobQueue(*queueSizeFlag)
+	} else {
+		s = initFaucet()
+	}
+	registerQueueMetrics(s)
+	registerStandbyMetrics(s)
+
+	// Parse the operating hours of the faucet
+	if hours, err = parseSchedule(*hoursFlag, *hoursTZFlag); err != nil {
+		log.Fatal("Invalid operating hours: ", err)
+	}
+	if captchaCurve, err = parseScoreCurve(*captchaCurveFlag); err != nil {
+		log.Fatal("Invalid captcha score curve: ", err)
+	}
+	if payoutTiers, err = parseTiers(*tierListFlag); err != nil {
+		log.Fatal("Invalid funding tiers: ", err)
+	}
+	if payoutCall, err = parsePayoutCall(); err != nil {
+		log.Fatal("Invalid payout call: ", err)
+	}
+	if tierContracts, err = parseTierContracts(*tierContractsFlag); err != nil {
+		log.Fatal("Invalid tier activity contracts: ", err)
+	}
+	if passportScores, err = parsePassportScores(*passportScoresFlag, len(payoutTiers)); err != nil {
+		log.Fatal("Invalid passport scores: ", err)
+	}
+	s.seedCooldowns()
 
-	// Load up and render the faucet website
+	// Load up and render the faucet website in every supported language
 	tmpl, err := Asset("faucet.html")
 	if err != nil {
 		log.Fatal("Failed to load the faucet template", err)
 	}
-	website := new(bytes.Buffer)
-	err = template.Must(template.New("").Parse(string(tmpl))).Execute(website, map[string]interface{}{
-		"Name":      *apiName,
-		"Amounts":   amounts,
-		"Periods":   periods,
-		"Recaptcha": *captchaToken,
-	})
-	if err != nil {
-		log.Fatal("Failed to render the faucet template", err)
+	websiteTemplate = string(tmpl)
+	for _, lang := range languages {
+		if _, err = s.website(lang.Code); err != nil {
+			log.Fatal("Failed to render the faucet template", err)
+		}
 	}
+	return s
+}
 
+// newAPIHandler creates the handler serving the public website and API. It is
+// separate from main so the faucet can be served from e.g. an httptest server.
+func newAPIHandler(s *Server) http.Handler {
 	mux := &http.ServeMux{}
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Write(website.Bytes())
-	})
-	mux.HandleFunc("/api", OnWebsocket)
+	mux.HandleFunc("/", s.onWebsite)
+	if !*readOnlyFlag {
+		// Read-only mirrors serve the status of the faucet only, claims go to
+		// the sending instance
+		mux.HandleFunc("/api", s.onWebsocket)
+		mux.HandleFunc("/api/challenge", onChallenge)
+		mux.HandleFunc("/api/token", onClaimToken)
+		if *csrfFlag {
+			mux.HandleFunc("/api/csrf", onCSRFToken)
+		}
+		mux.HandleFunc("/api/claim", s.onClaim)
+		mux.HandleFunc("/claim", s.onClaimLink)
+	}
+	mux.HandleFunc("/api/info", s.onInfo)
+	if *statsFlag && *readOnlyFlag {
+		mux.HandleFunc("/api/stats", onMirroredStats)
+	} else if *statsFlag {
+		mux.HandleFunc("/api/stats", onStats)
+	}
+	if terms.text != "" {
+		mux.HandleFunc("/api/terms", onTerms)
+	}
+	if *schemaFlag {
+		mux.HandleFunc("/api/schema", onSchema)
+	}
+	if *dripsFlag && !*readOnlyFlag {
+		mux.HandleFunc("/api/drips", s.onDrips)
+	}
+	if *appealsFlag && !*readOnlyFlag {
+		mux.HandleFunc("/api/appeal", onAppeal)
+	}
+	if *l2Flag != "" {
+		mux.HandleFunc("/api/finality", onFinality)
+	}
+	if *activityFlag {
+		mux.HandleFunc("/activity", onActivityPage)
+		mux.HandleFunc("/api/activity", s.onActivityAPI)
+	}
+	return forwardedFor(logAccess(recoverPanics(compress(limitBody(withBasePath(mux))))))
+}
 
-	address := strings.Join([]string{*apiAddr, ":", *apiPort}, "")
-	log.Infof("service booting with %s \n", address)
+// onWebsite serves the pre-rendered faucet website in the language of the user.
+// Form posts from browsers without JavaScript are funded and answered with the
+// website rendered along with the outcome, except on read-only mirrors.
+func (s *Server) onWebsite(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if r.Method == http.MethodPost && *readOnlyFlag {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "claims are not served by read-only mirrors", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Method != http.MethodPost {
+		cookies := (*claimTokenFlag || *botMinTimeFlag > 0 || *csrfFlag) && !*readOnlyFlag
+		if cookies && *claimTokenFlag {
+			setClaimToken(w, r)
+		}
+		if cookies && *botMinTimeFlag > 0 {
+			setFormNonce(w, r)
+		}
+		if cookies && *csrfFlag {
+			setSession(w, r)
+		}
+		cached, err := s.website(negotiateLanguage(r))
+		if err != nil {
+			log.Error("Failed to render the faucet template", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// Let browsers revalidate their copy instead of downloading it again
+		w.Header().Set("ETag", cached.etag)
+		w.Header().Set("Cache-Control", "no-cache")
+		if cookies {
+			w.Header().Set("Cache-Control", "private, no-cache") // Carries per-client cookies
+		}
+		w.Header().Add("Vary", "Accept-Language")
+		if notModified(r, cached.etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if cached.gzipped != nil && acceptsGzip(r) {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(cached.gzipped)
+			return
+		}
+		w.Write(cached.page)
+		return
+	}
+	lang := supportedLanguage(r.PostFormValue("lang"))
+	if lang == "" {
+		lang = negotiateLanguage(r)
+	}
+	tier, _ := strconv.ParseUint(r.PostFormValue("tier"), 10, 32)
+	msg := &fundRequest{
+		URL:      strings.TrimSpace(r.PostFormValue("url")),
+		Tier:     uint(tier),
+		Captcha:  r.PostFormValue("g-recaptcha-response"),
+		Voucher:  r.PostFormValue("voucher"),
+		Referral: r.PostFormValue("referral"),
+		Website:  r.PostFormValue("website"),
+		Terms:    r.PostFormValue("acceptTerms") != "",
+		Fields:   formValues(r),
+	}
+	log.Info("Faucet funds requested via form: ", "url: ", msg.URL, " tier: ", msg.Tier)
 
-	if !*apiHttps {
-		http.ListenAndServe(address, mux)
+	var failure, success string
+	claim := s.newClaim(r.Context(), r, msg, lang)
+	claim.website = true
+	if err := checkCSRF(r, true); err != nil {
+		failure = localizeError(lang, err).Error()
+	} else if err := s.handle(claim); err != nil {
+		failure = localizeError(lang, err).Error() + siblingsNote(claim)
 	} else {
-		http.ListenAndServeTLS(address, *key, *crt, mux)
+		success = successMessage(claim)
+	}
+	website, err := s.renderWebsite(lang, failure, success)
+	if err != nil {
+		log.Error("Failed to render the faucet template", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
+	if *claimTokenFlag {
+		setClaimToken(w, r)
+	}
+	w.Write(website)
+}
+
+// website returns the faucet website in the requested language, rendering it
+// anew if the cached one expired. Rendering may query the faucet balance, so it
+// happens outside the lock, not to hold up the pages of other languages (or the
+// cached ones) on a slow node.
+func (s *Server) website(lang string) (*cachedWebsite, error) {
+	s.websites.lock.Lock()
+	cached, ok := s.websites.pages[lang]
+	s.websites.lock.Unlock()
+
+	if ok && time.Since(cached.rendered) < *websiteTTLFlag {
+		return cached, nil
+	}
+	page, err := s.renderWebsite(lang, "", "")
+	if err != nil {
+		return nil, err
+	}
+	cached = &cachedWebsite{page: page, etag: pageETag(page), rendered: time.Now()}
+	if *compressFlag {
+		cached.gzipped = gzipPage(page)
+	}
+	s.websites.lock.Lock()
+	s.websites.pages[lang] = cached
+	s.websites.lock.Unlock()
 
+	return cached, nil
 }
 
-func setupRLimit() {
-	var rlimit syscall.Rlimit
-	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
-		panic(err)
+// renderWebsite renders the faucet website in the requested language, along
+// with the outcome of a form submission, if any.
+func (s *Server) renderWebsite(lang string, failure string, success string) ([]byte, error) {
+	// Construct the payout tiers, showing the payouts currently in effect
+	ctx, cancel := rpcContext(context.Background())
+	limit, err := s.payoutCap(ctx)
+	cancel()
+	if err != nil {
+		log.Error("Failed to retrieve faucet balance err: ", err)
+	}
+	amounts := make([]string, len(payoutTiers))
+	periods := make([]string, len(payoutTiers))
+	durations := make([]string, len(payoutTiers))
+	scores := make([]string, len(payoutTiers))
+	for i, tier := range payoutTiers {
+		// Format the amount of the next tier
+		amount := tier.Amount
+		if limit != nil && amount.Cmp(limit) > 0 {
+			amount = limit
+		}
+		amounts[i] = fmt.Sprintf("%s %s", fromWei(amount), *UnitFlag)
+		if amount.Cmp(toWei(1)) == 0 {
+			amounts[i] = strings.TrimSuffix(amounts[i], "s")
+		}
+		// Format the period of the next tier
+		periods[i] = prettyDuration(tier.Cooldown).localize(lang)
+		durations[i] = isoDuration(tier.Cooldown)
+
+		// Format the passport score unlocking the next tier, if any
+		if passportScores != nil && passportScores[i] > 0 {
+			scores[i] = translate(lang, "Passport score %s+", formatScore(passportScores[i]))
+		}
+	}
+	t, err := template.New("").Funcs(template.FuncMap{
+		"T": func(format string, args ...interface{}) string {
+			return translate(lang, format, args...)
+		},
+	}).Parse(websiteTemplate)
+	if err != nil {
+		return nil, err
 	}
-	rlimit.Cur = rlimit.Max - 100
-	if err := syscall.Setrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
-		panic(err)
+	website := new(bytes.Buffer)
+	err = t.Execute(website, map[string]interface{}{
+		"Name":      *apiName,
+		"Prefix":    publicPrefix(),
+		"Amounts":   amounts,
+		"Periods":   periods,
+		"Durations": durations,
+		"Fields":    formFields,
+		"Scores":    scores,
+		"Recaptcha": *captchaToken,
+		"V3":        *captchaV3Flag,
+		"Hours":     hours.describe(),
+		"Lang":      lang,
+		"Signature": *signatureFlag,
+		"Token":     *claimTokenFlag,
+		"Honeypot":  *honeypotFlag,
+		"Chain":     newWalletChain(),
+		"Asset":     newWalletAsset(),
+		"Languages": languages,
+		"Error":     failure,
+		"Success":   success,
+		"Terms":     terms.text,
+		"ReadOnly":  *readOnlyFlag,
+		"Faucet":    *readOnlyFaucetFlag,
+	})
+	if err != nil {
+		return nil, err
 	}
+	return website.Bytes(), nil
+}
+
+// toWei converts an amount of token units into wei, using the shortest decimal
+// representation of the amount to avoid binary floating point artifacts.
+func toWei(units float64) *big.Int {
+	amount, _ := new(big.Rat).SetString(strconv.FormatFloat(units, 'f', -1, 64))
+	amount.Mul(amount, new(big.Rat).SetInt64(int64(ether)))
+	return new(big.Int).Quo(amount.Num(), amount.Denom())
+}
+
+// fromWei formats an amount of wei in token units.
+func fromWei(wei *big.Int) string {
+	units := new(big.Rat).SetFrac(wei, big.NewInt(int64(ether))).FloatString(18)
+	return strings.TrimSuffix(strings.TrimRight(units, "0"), ".")
 }
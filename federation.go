@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var siblingsFlag = flag.String("federation.siblings", "", "Sibling faucets to suggest when this one is dry or the requester is on cooldown, as chainid=url pairs, e.g. 11155111=https://other.example (siblings on other chains are ignored)")
+
+// siblings are the URLs of the sibling faucets on the funded chain. They're
+// parsed on startup.
+var siblings []string
+
+// siblingsCache holds the siblings recently found available, so not every
+// failed claim probes all of them.
+var siblingsCache = struct {
+	lock      sync.Mutex
+	available []string
+	checked   time.Time
+}{}
+
+// parseSiblings picks the sibling faucets of the funded chain from the list.
+func parseSiblings(spec string, chain int64) ([]string, error) {
+	var urls []string
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	for _, item := range strings.Split(spec, ",") {
+		parts := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[1], "http") {
+			return nil, fmt.Errorf("invalid sibling %q, want chainid=url", item)
+		}
+		id, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chain id %q", parts[0])
+		}
+		if id == chain {
+			urls = append(urls, strings.TrimSuffix(parts[1], "/"))
+		}
+	}
+	return urls, nil
+}
+
+// federationStage points requesters turned away because the faucet is dry, or
+// because of their cooldown, to the sibling faucets currently available.
+func federationStage(next Handler) Handler {
+	return func(c *Claim) error {
+		err := next(c)
+		if err == nil || len(siblings) == 0 {
+			return err
+		}
+		var (
+			throttled *throttledError
+			uerr      *userError
+		)
+		dry := strings.Contains(err.Error(), "insufficient funds") ||
+			(errors.As(err, &uerr) && (uerr.format == "Faucet is out of funds" || uerr.format == "Faucet paused, daily gas budget exhausted" ||
+				uerr.format == "Faucet backend unavailable, please retry in %s"))
+
+		if dry || errors.As(err, &throttled) {
+			c.Siblings = availableSiblings(c.ctx)
+		}
+		return err
+	}
+}
+
+// availableSiblings probes the info endpoints of the sibling faucets, returning
+// the ones responding.
+func availableSiblings(ctx context.Context) []string {
+	siblingsCache.lock.Lock()
+	defer siblingsCache.lock.Unlock()
+
+	if time.Since(siblingsCache.checked) < time.Minute {
+		return siblingsCache.available
+	}
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	up := make([]bool, len(siblings))
+	var wg sync.WaitGroup
+	for i, sibling := range siblings {
+		wg.Add(1)
+		go func(i int, sibling string) {
+			defer wg.Done()
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, sibling+"/api/info", nil)
+			if err != nil {
+				return
+			}
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				log.Info("Sibling faucet unavailable: ", sibling, " err: ", err)
+				return
+			}
+			res.Body.Close()
+			up[i] = res.StatusCode == http.StatusOK
+		}(i, sibling)
+	}
+	wg.Wait()
+
+	available := []string{}
+	for i, sibling := range siblings {
+		if up[i] {
+			available = append(available, sibling)
+		}
+	}
+	siblingsCache.available, siblingsCache.checked = available, time.Now()
+	return available
+}
+
+// siblingsNote suggests the available sibling faucets to the requester, if any.
+func siblingsNote(c *Claim) string {
+	if len(c.Siblings) == 0 {
+		return ""
+	}
+	return ". " + translate(c.Lang, "Try another faucet: %s", strings.Join(c.Siblings, ", "))
+}
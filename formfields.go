@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	formFieldsFlag  = flag.String("form.fields", "", "JSON file of extra fields requesters fill in with their claims, e.g. a project name (empty = none)")
+	formWebhookFlag = flag.String("form.webhook", "", "Webhook to POST funded claims to as JSON, along with their extra form fields (empty = disabled)")
+)
+
+// formFieldMaxLength is the length values of extra form fields are capped at
+// unless configured otherwise.
+const formFieldMaxLength = 200
+
+// formFieldName is the syntax of the names of extra form fields.
+var formFieldName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// formField is an operator defined field of the claim form.
+type formField struct {
+	Name        string `json:"name"`                  // Key of the value in the claim's fields
+	Label       string `json:"label"`                 // Human readable name shown to the requester
+	Placeholder string `json:"placeholder,omitempty"` // Hint shown in the empty input
+	Required    bool   `json:"required,omitempty"`
+	Pattern     string `json:"pattern,omitempty"`   // Regular expression values must match in full
+	MaxLength   int    `json:"maxLength,omitempty"` // Maximum length of values in characters
+
+	pattern *regexp.Regexp
+}
+
+// formFields are the extra fields of the claim form, loaded on startup.
+var formFields []*formField
+
+// loadFormFields reads and validates the extra form fields, if configured.
+func loadFormFields() error {
+	if *formFieldsFlag == "" {
+		return nil
+	}
+	blob, err := ioutil.ReadFile(*formFieldsFlag)
+	if err != nil {
+		return err
+	}
+	var config struct {
+		Fields []*formField `json:"fields"`
+	}
+	if err := json.Unmarshal(blob, &config); err != nil {
+		return err
+	}
+	names := make(map[string]bool)
+	for _, field := range config.Fields {
+		if !formFieldName.MatchString(field.Name) || names[field.Name] {
+			return fmt.Errorf("field names must be unique lowercase identifiers: %q", field.Name)
+		}
+		names[field.Name] = true
+
+		if field.Label == "" {
+			return fmt.Errorf("field %s: missing label", field.Name)
+		}
+		if field.MaxLength <= 0 {
+			field.MaxLength = formFieldMaxLength
+		}
+		if field.Pattern != "" {
+			if field.pattern, err = regexp.Compile("^(?:" + field.Pattern + ")$"); err != nil {
+				return fmt.Errorf("field %s: %v", field.Name, err)
+			}
+		}
+	}
+	formFields = config.Fields
+	return nil
+}
+
+// formValues extracts the extra fields from a form post.
+func formValues(r *http.Request) map[string]string {
+	if len(formFields) == 0 {
+		return nil
+	}
+	values := make(map[string]string)
+	for _, field := range formFields {
+		if value := r.PostFormValue("field." + field.Name); value != "" {
+			values[field.Name] = value
+		}
+	}
+	return values
+}
+
+// formFieldsStage validates the extra form fields of claims, normalizing their
+// values in place.
+func formFieldsStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if len(formFields) == 0 && len(c.Fields) == 0 && *formWebhookFlag == "" {
+			return next(c)
+		}
+		values := make(map[string]string)
+		for _, field := range formFields {
+			value := strings.TrimSpace(c.Fields[field.Name])
+			switch {
+			case value == "" && field.Required:
+				return newUserError("Please fill in %s", field.Label)
+			case value == "":
+				continue
+			case utf8.RuneCountInString(value) > field.MaxLength:
+				return newUserError("%s is too long, at most %d characters", field.Label, field.MaxLength)
+			case field.pattern != nil && !field.pattern.MatchString(value):
+				return newUserError("Invalid %s", field.Label)
+			}
+			values[field.Name] = value
+		}
+		for name := range c.Fields {
+			if !knownFormField(name) {
+				return newUserError("Invalid request, unknown field %s", "fields."+name)
+			}
+		}
+		c.Fields = values
+
+		err := next(c)
+		if c.Tx != nil && !c.decoy && *formWebhookFlag != "" {
+			notifyClaim(c)
+		}
+		return err
+	}
+}
+
+// knownFormField reports whether an extra form field of the given name exists.
+func knownFormField(name string) bool {
+	for _, field := range formFields {
+		if field.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// claimNotification is the body of the funded claims posted to the webhook.
+type claimNotification struct {
+	Time    time.Time         `json:"time"`
+	ID      string            `json:"id"`
+	Address string            `json:"address"`
+	Amount  string            `json:"amount"` // Payout in token units
+	Tier    uint              `json:"tier"`
+	Tx      string            `json:"tx"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// notifyClaim forwards a funded claim to the webhook in the background.
+func notifyClaim(c *Claim) {
+	notification := &claimNotification{
+		Time:    time.Now().UTC(),
+		ID:      c.ID,
+		Address: c.Address,
+		Amount:  fromWei(c.Amount),
+		Tier:    c.Tier,
+		Tx:      c.Tx.ID(),
+		Fields:  c.Fields,
+	}
+	go func() {
+		if err := postWebhook(*formWebhookFlag, notification); err != nil {
+			log.Error("Failed to deliver claim to webhook err: ", err)
+		}
+	}()
+}
+
+// onAdminClaims lists the most recent funded claims along with their extra
+// form fields (GET ?limit=, default 100).
+func onAdminClaims(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = 100
+	}
+	summary, err := loadSummary()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	claims := []*claimRecord{}
+	for seq := summary.Next; seq > summary.First && len(claims) < limit; seq-- {
+		rec := new(claimRecord)
+		if err := getJSON(store, claimsBucket, claimKey(seq-1), rec); err != nil {
+			continue
+		}
+		claims = append(claims, rec)
+	}
+	writeJSON(w, http.StatusOK, claims)
+}
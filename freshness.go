@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"math/big"
+	"strconv"
+
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	freshNonceFlag  = flag.Uint64("fresh.nonce", 0, "Transactions an address must have sent not to count as brand-new (0 = disabled)")
+	freshAgeFlag    = flag.Uint64("fresh.age", 0, "Blocks since its first transaction an address must have not to count as brand-new, needs an archive node (0 = disabled)")
+	freshPayoutFlag = flag.Float64("fresh.payout", 1, "Factor to scale the payouts of brand-new addresses by")
+	freshRiskFlag   = flag.Float64("fresh.risk", 0, "Risk score added to claims of brand-new addresses, see --risk.threshold")
+)
+
+// firstSeenBucket is the store bucket caching the block of the first transaction
+// sent by an address.
+const firstSeenBucket = "first-seen"
+
+// freshnessStage treats brand-new addresses, typical of farming scripts, with
+// more suspicion: their payout is reduced and their risk score raised.
+func freshnessStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if *freshNonceFlag == 0 && *freshAgeFlag == 0 {
+			return next(c)
+		}
+		fresh, err := c.server.isFreshAddress(c.ctx, c.Address)
+		if err != nil {
+			log.Error("Failed to check address activity err: ", err)
+			return next(c)
+		}
+		if fresh {
+			log.Info("Claim for brand-new address: ", c.Address)
+			c.Risk += *freshRiskFlag
+			if *freshPayoutFlag != 1 {
+				c.Amount, _ = new(big.Float).Mul(new(big.Float).SetInt(c.Amount), big.NewFloat(*freshPayoutFlag)).Int(nil)
+				c.Notes = append(c.Notes, translate(c.Lang, "Payout reduced for new addresses, use your address on chain to receive the full amount"))
+			}
+		}
+		return next(c)
+	}
+}
+
+// isFreshAddress reports whether an address sent too few transactions, or only
+// started sending too recently, to be trusted.
+func (s *Server) isFreshAddress(ctx context.Context, addr string) (bool, error) {
+	nonce, err := s.chain.TxCount(ctx, addr, nil)
+	if err != nil {
+		return false, err
+	}
+	if nonce < *freshNonceFlag || (*freshAgeFlag > 0 && nonce == 0) {
+		return true, nil
+	}
+	if *freshAgeFlag == 0 {
+		return false, nil
+	}
+	head, err := s.chain.Head(ctx)
+	if err != nil {
+		return false, err
+	}
+	first, err := s.firstSeenBlock(ctx, addr, head)
+	if err != nil {
+		return false, err
+	}
+	return head-first < *freshAgeFlag, nil
+}
+
+// firstSeenBlock finds the block an address sent its first transaction in, by
+// bisecting its historical nonces. The result is cached as it never changes.
+func (s *Server) firstSeenBlock(ctx context.Context, addr string, head uint64) (uint64, error) {
+	if blob, err := store.Get(firstSeenBucket, addr); err == nil {
+		if block, err := strconv.ParseUint(string(blob), 10, 64); err == nil {
+			return block, nil
+		}
+	}
+	// Find the first block with a non-zero nonce at its end
+	lo, hi := uint64(0), head
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+
+		nonce, err := s.chain.TxCount(ctx, addr, &mid)
+		if err != nil {
+			return 0, err
+		}
+		if nonce > 0 {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	if err := store.Put(firstSeenBucket, addr, []byte(strconv.FormatUint(lo, 10))); err != nil {
+		log.Error("Failed to cache first seen block err: ", err)
+	}
+	return lo, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"math/big"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var gasBudgetFlag = flag.Float64("gas.budget", 0, "Maximum amount of units to spend on gas per day (UTC), pausing funding when exceeded (0 = unlimited)")
+
+// gasSpendBucket is the store bucket tracking the gas spent per day (in wei).
+const gasSpendBucket = "gas-spend"
+
+func init() {
+	registerGauge("faucet_gas_spent_today", "Units spent on gas today (UTC).", func() float64 {
+		spent, _ := new(big.Float).Quo(new(big.Float).SetInt(gasSpentToday()), big.NewFloat(float64(ether))).Float64()
+		return spent
+	})
+}
+
+// gasDay returns the key of the current day in the gas spend bucket.
+func gasDay() string {
+	return time.Now().UTC().Format("2006-01-02")
+}
+
+// gasSpentToday returns the wei spent on gas today.
+func gasSpentToday() *big.Int {
+	spent := new(big.Int)
+	if blob, err := store.Get(gasSpendBucket, gasDay()); err == nil {
+		spent.SetString(string(blob), 10)
+	}
+	return spent
+}
+
+// recordGasSpend adds the maximum fee of a broadcast transaction to the gas spent
+// today. Charging the full gas limit errs on the safe side of the budget.
+func recordGasSpend(gasLimit uint64, gasPrice *big.Int) {
+	addGasSpend(new(big.Int).Mul(new(big.Int).SetUint64(gasLimit), gasPrice))
+}
+
+// addGasSpend adds a fee (in wei) to the gas spent today.
+func addGasSpend(fee *big.Int) {
+	err := store.Update(gasSpendBucket, gasDay(), func(blob []byte) ([]byte, error) {
+		spent := new(big.Int)
+		if blob != nil {
+			spent.SetString(string(blob), 10)
+		}
+		return []byte(spent.Add(spent, fee).String()), nil
+	})
+	if err != nil {
+		log.Error("Failed to record gas spend err: ", err)
+	}
+}
+
+// budgetStage pauses funding once the daily gas budget is spent, alerting the
+// operator.
+func budgetStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if *gasBudgetFlag <= 0 {
+			return next(c)
+		}
+		if spent := gasSpentToday(); spent.Cmp(toWei(*gasBudgetFlag)) >= 0 {
+			alert("gas-budget", "daily gas budget of %v %s exhausted (spent %s), funding paused", *gasBudgetFlag, *UnitFlag, fromWei(spent))
+			return newUserError("Faucet paused, daily gas budget exhausted")
+		}
+		return next(c)
+	}
+}
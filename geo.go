@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	geoDBFlag      = flag.String("geo.db", "", "CSV file of IP ranges and their countries (start,end,country as in the DB-IP or IP2Location LITE country databases)")
+	geoHeaderFlag  = flag.String("geo.header", "", "Header carrying the country of the requester as set by a trusted reverse proxy or CDN, e.g. CF-IPCountry (requires --api.proxy)")
+	geoPayoutsFlag = flag.String("geo.payouts", "", "Payout and cooldown multipliers by country as country:payout:cooldown triples, e.g. XX:0.5:2,YY:0.25:4 (empty = same payouts everywhere)")
+)
+
+// geoAdjusted counts the claims whose payouts were adjusted for their country.
+var geoAdjusted = newCounterVec("faucet_geo_adjusted_total", "Claims whose payout was adjusted for the country of the requester.", "country")
+
+// geoRule adjusts the payouts to requesters of a country.
+type geoRule struct {
+	payout   float64 // Payout multiplier
+	cooldown float64 // Cooldown multiplier
+}
+
+// apply scales a payout and its cooldown by the rule. Cooldowns are kept at
+// whole minutes, as the tiers themselves are.
+func (r geoRule) apply(amount *big.Int, cooldown time.Duration) (*big.Int, time.Duration) {
+	amount, _ = new(big.Float).Mul(new(big.Float).SetInt(amount), big.NewFloat(r.payout)).Int(nil)
+	cooldown = time.Duration(float64(cooldown) * r.cooldown).Round(time.Minute)
+	if cooldown < time.Minute {
+		cooldown = time.Minute
+	}
+	return amount, cooldown
+}
+
+// geoRange is a range of IP addresses located in a country, both ends inclusive
+// and in 16 byte form.
+type geoRange struct {
+	start, end net.IP
+	country    string
+}
+
+// geo holds the country specific payout configuration, set up on startup.
+var geo struct {
+	rules  map[string]geoRule // Payout adjustments by country code
+	ranges []geoRange         // IP ranges sorted by their start
+}
+
+// setupGeo parses the country payout rules and loads the IP database, if any.
+func setupGeo() error {
+	rules, err := parseGeoRules(*geoPayoutsFlag)
+	if err != nil {
+		return err
+	}
+	geo.rules = rules
+	if *geoHeaderFlag != "" && !*apiProxyFlag {
+		return fmt.Errorf("--geo.header requires --api.proxy, the header can't be trusted otherwise")
+	}
+	if *geoDBFlag != "" {
+		file, err := os.Open(*geoDBFlag)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if geo.ranges, err = parseGeoDB(file); err != nil {
+			return fmt.Errorf("%s: %v", *geoDBFlag, err)
+		}
+		log.Info("Loaded ", len(geo.ranges), " IP ranges from the GeoIP database")
+	}
+	if len(geo.rules) > 0 && *geoDBFlag == "" && *geoHeaderFlag == "" {
+		return fmt.Errorf("--geo.payouts requires --geo.db or --geo.header to locate requesters")
+	}
+	return nil
+}
+
+// parseGeoRules parses the payout multipliers by country.
+func parseGeoRules(spec string) (map[string]geoRule, error) {
+	rules := make(map[string]geoRule)
+	if strings.TrimSpace(spec) == "" {
+		return rules, nil
+	}
+	for _, item := range strings.Split(spec, ",") {
+		parts := strings.Split(strings.TrimSpace(item), ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid rule %q, want country:payout:cooldown", item)
+		}
+		country := strings.ToUpper(strings.TrimSpace(parts[0]))
+		if len(country) != 2 {
+			return nil, fmt.Errorf("invalid country %q, want an ISO 3166-1 alpha-2 code", parts[0])
+		}
+		payout, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || payout <= 0 {
+			return nil, fmt.Errorf("invalid payout multiplier %q", parts[1])
+		}
+		cooldown, err := strconv.ParseFloat(strings.TrimSpace(parts[2]), 64)
+		if err != nil || cooldown <= 0 {
+			return nil, fmt.Errorf("invalid cooldown multiplier %q", parts[2])
+		}
+		if _, ok := rules[country]; ok {
+			return nil, fmt.Errorf("duplicate rule for country %s", country)
+		}
+		rules[country] = geoRule{payout: payout, cooldown: cooldown}
+	}
+	return rules, nil
+}
+
+// parseGeoDB parses a CSV database of IP ranges. The ends of the ranges may be
+// given as addresses or, as in IP2Location, as decimal numbers; columns beyond
+// the country are ignored.
+func parseGeoDB(r io.Reader) ([]geoRange, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	var ranges []geoRange
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 3 {
+			return nil, fmt.Errorf("line %d: want start,end,country", line)
+		}
+		start, end := parseGeoIP(record[0]), parseGeoIP(record[1])
+		if start == nil || end == nil || bytes.Compare(start, end) > 0 {
+			if line == 1 {
+				continue // Header
+			}
+			return nil, fmt.Errorf("line %d: invalid IP range %s-%s", line, record[0], record[1])
+		}
+		country := strings.ToUpper(strings.TrimSpace(record[2]))
+		if len(country) != 2 {
+			continue // Unassigned ranges are marked "-" or "ZZ"
+		}
+		ranges = append(ranges, geoRange{start: start, end: end, country: country})
+	}
+	sort.Slice(ranges, func(i, j int) bool { return bytes.Compare(ranges[i].start, ranges[j].start) < 0 })
+	return ranges, nil
+}
+
+// parseGeoIP parses an end of an IP range into 16 byte form.
+func parseGeoIP(s string) net.IP {
+	s = strings.TrimSpace(s)
+	if ip := net.ParseIP(s); ip != nil {
+		return ip.To16()
+	}
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok || n.Sign() < 0 || n.BitLen() > 128 {
+		return nil
+	}
+	ip := make(net.IP, net.IPv6len)
+	if n.BitLen() <= 32 {
+		// IPv4 databases number the addresses themselves, not their mapped form
+		copy(ip, net.IPv4zero.To16())
+		n.FillBytes(ip[12:])
+		return ip
+	}
+	n.FillBytes(ip)
+	return ip
+}
+
+// lookupCountry locates an IP address in the GeoIP database.
+func lookupCountry(host string) string {
+	ip := net.ParseIP(host)
+	if ip == nil || len(geo.ranges) == 0 {
+		return ""
+	}
+	ip = ip.To16()
+
+	i := sort.Search(len(geo.ranges), func(i int) bool { return bytes.Compare(geo.ranges[i].start, ip) > 0 })
+	if i == 0 || bytes.Compare(ip, geo.ranges[i-1].end) > 0 {
+		return ""
+	}
+	return geo.ranges[i-1].country
+}
+
+// requestCountry returns the country a request originates from, preferring the
+// one reported by the reverse proxy over the GeoIP database. Empty if unknown.
+func requestCountry(r *http.Request) string {
+	if *geoHeaderFlag != "" {
+		if country := strings.ToUpper(strings.TrimSpace(r.Header.Get(*geoHeaderFlag))); len(country) == 2 && country != "XX" {
+			return country
+		}
+	}
+	return lookupCountry(remoteHost(r.RemoteAddr))
+}
+
+// geoStage adjusts the payout and cooldown of claims to the rule of the country
+// they originate from, if any.
+func geoStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if rule, ok := geo.rules[c.Country]; ok {
+			c.Amount, c.Cooldown = rule.apply(c.Amount, c.Cooldown)
+			geoAdjusted.With(c.Country).Inc()
+		}
+		return next(c)
+	}
+}
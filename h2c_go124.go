@@ -0,0 +1,14 @@
+//go:build go1.24
+// +build go1.24
+
+package main
+
+import "net/http"
+
+// enableH2C lets the server accept HTTP/2 without TLS alongside HTTP/1.
+func enableH2C(srv *http.Server) {
+	srv.Protocols = new(http.Protocols)
+	srv.Protocols.SetHTTP1(true)
+	srv.Protocols.SetHTTP2(true)
+	srv.Protocols.SetUnencryptedHTTP2(true)
+}
@@ -0,0 +1,15 @@
+//go:build !go1.24
+// +build !go1.24
+
+package main
+
+import (
+	"net/http"
+
+	"github.com/sunvim/utils/log"
+)
+
+// enableH2C fails as the standard library only supports h2c since Go 1.24.
+func enableH2C(srv *http.Server) {
+	log.Fatal("HTTP/2 without TLS requires a faucet built with Go 1.24 or later")
+}
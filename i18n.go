@@ -0,0 +1,466 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultLanguage is the language served if the client accepts none of ours.
+const defaultLanguage = "en"
+
+// languages lists the supported languages by code, along with their names as
+// displayed in the language switcher.
+var languages = []struct {
+	Code string
+	Name string
+}{
+	{"en", "English"},
+	{"zh", "中文"},
+	{"es", "Español"},
+	{"ja", "日本語"},
+}
+
+// catalogs maps the English messages (used as lookup keys) to their localized
+// variants for every supported language besides English.
+var catalogs = map[string]map[string]string{
+	"zh": {
+		"%s Faucet":                           "%s 水龙头",
+		"Please input your wallet address...": "请输入您的钱包地址...",
+		"Give me":                             "领取",
+		"Voucher code (optional)":             "兑换码（可选）",
+		"Open %s":                             "开放时间 %s",
+		"%d min":                              "%d 分钟",
+		"%d mins":                             "%d 分钟",
+		"%d hour":                             "%d 小时",
+		"%d hours":                            "%d 小时",
+		"%d day":                              "%d 天",
+		"%d days":                             "%d 天",
+		"Invalid funding tier requested":      "请求的领取档位无效",
+		"Invalid address to fund":             "无效的领取地址",
+		"Captcha verification unavailable":    "验证码服务暂不可用",
+		"Beep-bop, you're a robot!":           "哔哔，您是机器人！",
+		"%s left until next allowance":        "距离下次领取还需 %s",
+		"Request denied":                      "请求被拒绝",
+		"Faucet is closed":                    "水龙头已关闭",
+		"Faucet is closed, reopens at %s (in %s)":              "水龙头已关闭，将于 %s 重新开放（%s 后）",
+		"Invalid voucher code":                                 "无效的兑换码",
+		"Voucher already redeemed":                             "兑换码已被使用",
+		"Funding transaction %s not confirmed yet":             "转账交易 %s 尚未确认",
+		"Funding transaction %s failed":                        "转账交易 %s 失败",
+		"Funding request accepted for Faucet into %s":          "已受理向 %s 的转账请求",
+		"Share referral code %s to shorten your next cooldown": "分享推荐码 %s 可缩短您的下次等待时间",
+		"Eligibility check unavailable, try again later":       "资格检查暂不可用，请稍后再试",
+		"Skip to the funding form":                             "跳转到领取表单",
+		"Wallet address":                                       "钱包地址",
+		"Choose an amount":                                     "选择领取数额",
+		"Please enable JavaScript to pass the captcha":         "请启用 JavaScript 以完成验证码",
+		"Language":       "语言",
+		"Connect wallet": "连接钱包",
+		"Please sign the ownership challenge with your wallet":                                   "请使用钱包签名所有权验证消息",
+		"Ownership challenge invalid or expired, please retry":                                   "所有权验证消息无效或已过期，请重试",
+		"Signature does not match the address to fund":                                           "签名与领取地址不匹配",
+		"Add %s network to MetaMask":                                                             "将 %s 网络添加到 MetaMask",
+		"Add %s to MetaMask":                                                                     "将 %s 添加到 MetaMask",
+		"Position %d in the funding queue":                                                       "您在领取队列中排第 %d 位",
+		"Position %d in the funding queue, about %s left":                                        "您在领取队列中排第 %d 位，预计还需 %s",
+		"Already processing request %s, please wait for it to complete":                          "请求 %s 正在处理中，请等待其完成",
+		"Session expired, please reload the page":                                                "会话已过期，请刷新页面",
+		"Please sign in to request funds":                                                        "请先登录再领取",
+		"Sign-in invalid or expired, please sign in again":                                       "登录无效或已过期，请重新登录",
+		"Funding tier not permitted for your account":                                            "您的账户无权使用该领取档位",
+		"Quota of %d requests exhausted, renews at %s":                                           "%d 次领取额度已用完，将于 %s 恢复",
+		"Faucet is restarting, please retry":                                                     "水龙头正在重启，请重试",
+		"Faucet is busy, please retry in %s":                                                     "水龙头繁忙，请在 %s 后重试",
+		"Faucet paused, daily gas budget exhausted":                                              "水龙头已暂停，今日 gas 预算已用完",
+		"Faucet is out of funds":                                                                 "水龙头余额不足",
+		"Payout reduced to %s %s while the faucet is running low":                                "水龙头余额不足，领取数额已降至 %s %s",
+		"Included in L2 block %d, awaiting L1 batch":                                             "已包含在 L2 区块 %d 中，等待提交至 L1",
+		"Included in L2 block %d, posted to L1 and awaiting finality":                            "已包含在 L2 区块 %d 中，已提交至 L1，等待最终确认",
+		"Included in L2 block %d, finalized on L1":                                               "已包含在 L2 区块 %d 中，已在 L1 上最终确认",
+		"Invalid request, malformed JSON":                                                        "无效请求，JSON 格式错误",
+		"Invalid request, malformed CBOR":                                                        "无效请求，CBOR 格式错误",
+		"Invalid request, unknown field %s":                                                      "无效请求，未知字段 %s",
+		"Invalid request, field %s must be a string":                                             "无效请求，字段 %s 必须是字符串",
+		"Invalid request, field %s must be a non-negative integer":                               "无效请求，字段 %s 必须是非负整数",
+		"Faucet is on standby, please retry shortly":                                             "水龙头处于备用状态，请稍后重试",
+		"Cooldown extended to %s for repeated early requests":                                    "由于多次提前请求，冷却时间延长至 %s",
+		"Payout reduced for new addresses, use your address on chain to receive the full amount": "新地址的领取数额已降低，在链上使用您的地址后即可领取全额",
+		"Address %s has no mainnet activity, only addresses used on mainnet are funded":          "地址 %s 在主网上没有活动记录，仅为在主网上使用过的地址提供资金",
+		"Passport scoring unavailable, try again later":                                          "Passport 评分服务不可用，请稍后重试",
+		"Passport score %s too low for this tier, %s required":                                   "Passport 分数 %s 过低，此档位需要 %s",
+		"Passport score %s+":                                                                     "Passport 分数 %s+",
+		"Please verify with World ID to request funds":                                           "请先通过 World ID 验证再申请资金",
+		"World ID proof invalid":                                                                 "World ID 证明无效",
+		"World ID verification unavailable, try again later":                                     "World ID 验证服务不可用，请稍后重试",
+		"Invalid request, field %s must be an object":                                            "无效请求，字段 %s 必须是对象",
+		"Invalid request, field %s must be true or false":                                        "无效请求，字段 %s 必须是 true 或 false",
+		"Payout too small to stream":                                                             "发放金额太小，无法流式支付",
+		"Streaming over %s":                                                                      "将在 %s 内持续流式发放",
+		"Failed to mint NFT, try again later":                                                    "NFT 铸造失败，请稍后重试",
+		"NFT mint %s pending":                                                                    "NFT 铸造交易 %s 待确认",
+		"Minted NFT #%s":                                                                         "已铸造 NFT #%s",
+		"Payout would fail: %s":                                                                  "发放交易将会失败：%s",
+		"Funding transaction %s ran out of gas, please try again":                                "资金交易 %s 耗尽了 gas，请重试",
+		"Funding transaction %s failed, the faucet is out of tokens":                             "资金交易 %s 失败，水龙头的代币已用完",
+		"Funding transaction %s failed, the token is paused":                                     "资金交易 %s 失败，代币已暂停",
+		"Funding transaction %s reverted: %s":                                                    "资金交易 %s 被回滚：%s",
+		"Included in block %d, waiting for %d confirmations":                                     "已包含在区块 %d 中，等待 %d 个确认",
+		"Funding transaction %s dropped by a reorg, rebroadcasting":                              "资金交易 %s 因链重组被丢弃，正在重新广播",
+		"Claim links are disabled on this faucet":                                                "此水龙头未启用领取链接",
+		"Claim link invalid or expired":                                                          "领取链接无效或已过期",
+		"Claim link already used":                                                                "领取链接已被使用",
+		"Unknown funding transaction":                                                            "未知的转账交易",
+		"Try another faucet: %s":                                                                 "请尝试其他水龙头：%s",
+		"Faucet backend unavailable, please retry in %s":                                         "水龙头后端不可用，请在 %s 后重试",
+		"Please claim the first tier before the higher ones":                                     "请先领取第一档，再领取更高档位",
+		"Higher tiers unlock after %d transactions since your last claim, %d so far":             "更高档位需要在上次领取后发送 %d 笔交易，目前为 %d 笔",
+		"Claim session expired, please check your balance before claiming again":                 "领取会话已过期，请先检查余额再重新领取",
+		"Internal error, please try again later":                                                 "内部错误，请稍后再试",
+		"Request denied, you may appeal the decision":                                            "请求被拒绝，您可以对此决定提出申诉",
+		"Please explain your appeal in at most %d characters":                                    "请用最多 %d 个字符说明您的申诉",
+		"Please leave a way to contact you":                                                      "请留下您的联系方式",
+		"An appeal of yours is already awaiting review":                                          "您的申诉正在等待审核",
+		"Appeal submitted, we'll get back to you":                                                "申诉已提交，我们会尽快回复您",
+		"Terms of service":                                                                       "服务条款",
+		"I accept the terms of service":                                                          "我接受服务条款",
+		"Please accept the terms of service":                                                     "请接受服务条款",
+		"Terms of service unavailable, please try again later":                                   "服务条款暂不可用，请稍后再试",
+		"%d sec":                                "%d 秒",
+		"%d secs":                               "%d 秒",
+		"Please fill in %s":                     "请填写%s",
+		"%s is too long, at most %d characters": "%s过长，最多 %d 个字符",
+		"Invalid %s":                            "%s无效",
+		"Smart contract wallets are not supported, please use a regular account":  "不支持智能合约钱包，请使用普通账户",
+		"Your smart contract wallet needs too much gas to receive funds":          "您的智能合约钱包接收资金所需的 gas 过多",
+		"This page mirrors the status of the faucet, claims are not served here.": "此页面为水龙头状态的镜像，此处不受理领取请求。",
+		"Go to the faucet": "前往水龙头",
+	},
+	"es": {
+		"%s Faucet":                           "Grifo de %s",
+		"Please input your wallet address...": "Introduce la dirección de tu billetera...",
+		"Give me":                             "Dame",
+		"Voucher code (optional)":             "Código de cupón (opcional)",
+		"Open %s":                             "Abierto %s",
+		"%d min":                              "%d min",
+		"%d mins":                             "%d min",
+		"%d hour":                             "%d hora",
+		"%d hours":                            "%d horas",
+		"%d day":                              "%d día",
+		"%d days":                             "%d días",
+		"Invalid funding tier requested":      "Nivel de financiación solicitado no válido",
+		"Invalid address to fund":             "Dirección a financiar no válida",
+		"Captcha verification unavailable":    "Verificación captcha no disponible",
+		"Beep-bop, you're a robot!":           "¡Bip-bop, eres un robot!",
+		"%s left until next allowance":        "Faltan %s para la próxima asignación",
+		"Request denied":                      "Solicitud denegada",
+		"Faucet is closed":                    "El grifo está cerrado",
+		"Faucet is closed, reopens at %s (in %s)":              "El grifo está cerrado, reabre el %s (en %s)",
+		"Invalid voucher code":                                 "Código de cupón no válido",
+		"Voucher already redeemed":                             "El cupón ya ha sido canjeado",
+		"Funding transaction %s not confirmed yet":             "La transacción de financiación %s aún no está confirmada",
+		"Funding transaction %s failed":                        "La transacción de financiación %s ha fallado",
+		"Funding request accepted for Faucet into %s":          "Solicitud de financiación aceptada para %s",
+		"Share referral code %s to shorten your next cooldown": "Comparte el código de referido %s para acortar tu próxima espera",
+		"Eligibility check unavailable, try again later":       "Comprobación de elegibilidad no disponible, inténtalo más tarde",
+		"Skip to the funding form":                             "Ir al formulario de financiación",
+		"Wallet address":                                       "Dirección de la billetera",
+		"Choose an amount":                                     "Elige una cantidad",
+		"Please enable JavaScript to pass the captcha":         "Activa JavaScript para superar el captcha",
+		"Language":       "Idioma",
+		"Connect wallet": "Conectar billetera",
+		"Please sign the ownership challenge with your wallet":                                   "Firma el desafío de propiedad con tu billetera",
+		"Ownership challenge invalid or expired, please retry":                                   "Desafío de propiedad no válido o caducado, inténtalo de nuevo",
+		"Signature does not match the address to fund":                                           "La firma no coincide con la dirección a financiar",
+		"Add %s network to MetaMask":                                                             "Añadir la red %s a MetaMask",
+		"Add %s to MetaMask":                                                                     "Añadir %s a MetaMask",
+		"Position %d in the funding queue":                                                       "Posición %d en la cola de financiación",
+		"Position %d in the funding queue, about %s left":                                        "Posición %d en la cola de financiación, faltan unos %s",
+		"Already processing request %s, please wait for it to complete":                          "Ya se está procesando la solicitud %s, espera a que termine",
+		"Session expired, please reload the page":                                                "La sesión ha caducado, recarga la página",
+		"Please sign in to request funds":                                                        "Inicia sesión para solicitar fondos",
+		"Sign-in invalid or expired, please sign in again":                                       "Sesión no válida o caducada, vuelve a iniciar sesión",
+		"Funding tier not permitted for your account":                                            "Nivel de financiación no permitido para tu cuenta",
+		"Quota of %d requests exhausted, renews at %s":                                           "Cuota de %d solicitudes agotada, se renueva el %s",
+		"Faucet is restarting, please retry":                                                     "El grifo se está reiniciando, inténtalo de nuevo",
+		"Faucet is busy, please retry in %s":                                                     "El grifo está ocupado, inténtalo de nuevo en %s",
+		"Faucet paused, daily gas budget exhausted":                                              "Grifo en pausa, presupuesto diario de gas agotado",
+		"Faucet is out of funds":                                                                 "El grifo se ha quedado sin fondos",
+		"Payout reduced to %s %s while the faucet is running low":                                "Pago reducido a %s %s mientras el grifo tiene pocos fondos",
+		"Included in L2 block %d, awaiting L1 batch":                                             "Incluida en el bloque L2 %d, esperando el lote en L1",
+		"Included in L2 block %d, posted to L1 and awaiting finality":                            "Incluida en el bloque L2 %d, publicada en L1 y esperando finalidad",
+		"Included in L2 block %d, finalized on L1":                                               "Incluida en el bloque L2 %d, finalizada en L1",
+		"Invalid request, malformed JSON":                                                        "Solicitud no válida, JSON mal formado",
+		"Invalid request, malformed CBOR":                                                        "Solicitud no válida, CBOR mal formado",
+		"Invalid request, unknown field %s":                                                      "Solicitud no válida, campo desconocido %s",
+		"Invalid request, field %s must be a string":                                             "Solicitud no válida, el campo %s debe ser una cadena",
+		"Invalid request, field %s must be a non-negative integer":                               "Solicitud no válida, el campo %s debe ser un entero no negativo",
+		"Faucet is on standby, please retry shortly":                                             "El grifo está en espera, vuelve a intentarlo en breve",
+		"Cooldown extended to %s for repeated early requests":                                    "Tiempo de espera ampliado a %s por solicitudes anticipadas repetidas",
+		"Payout reduced for new addresses, use your address on chain to receive the full amount": "Pago reducido para direcciones nuevas, usa tu dirección en la cadena para recibir el importe completo",
+		"Address %s has no mainnet activity, only addresses used on mainnet are funded":          "La dirección %s no tiene actividad en la red principal, solo se financian direcciones usadas en ella",
+		"Passport scoring unavailable, try again later":                                          "Puntuación de Passport no disponible, inténtalo más tarde",
+		"Passport score %s too low for this tier, %s required":                                   "Puntuación de Passport %s demasiado baja para este nivel, se requiere %s",
+		"Passport score %s+":                                                                     "Puntuación de Passport %s+",
+		"Please verify with World ID to request funds":                                           "Verifica con World ID para solicitar fondos",
+		"World ID proof invalid":                                                                 "Prueba de World ID no válida",
+		"World ID verification unavailable, try again later":                                     "Verificación de World ID no disponible, inténtalo más tarde",
+		"Invalid request, field %s must be an object":                                            "Solicitud no válida, el campo %s debe ser un objeto",
+		"Invalid request, field %s must be true or false":                                        "Solicitud no válida, el campo %s debe ser true o false",
+		"Payout too small to stream":                                                             "Pago demasiado pequeño para transmitirse",
+		"Streaming over %s":                                                                      "Transmitiéndose durante %s",
+		"Failed to mint NFT, try again later":                                                    "No se pudo acuñar el NFT, inténtalo más tarde",
+		"NFT mint %s pending":                                                                    "Acuñación del NFT %s pendiente",
+		"Minted NFT #%s":                                                                         "NFT #%s acuñado",
+		"Payout would fail: %s":                                                                  "El pago fallaría: %s",
+		"Funding transaction %s ran out of gas, please try again":                                "La transacción de financiación %s se quedó sin gas, inténtalo de nuevo",
+		"Funding transaction %s failed, the faucet is out of tokens":                             "La transacción de financiación %s falló, el grifo se quedó sin tokens",
+		"Funding transaction %s failed, the token is paused":                                     "La transacción de financiación %s falló, el token está en pausa",
+		"Funding transaction %s reverted: %s":                                                    "La transacción de financiación %s se revirtió: %s",
+		"Included in block %d, waiting for %d confirmations":                                     "Incluida en el bloque %d, esperando %d confirmaciones",
+		"Funding transaction %s dropped by a reorg, rebroadcasting":                              "La transacción de financiación %s fue descartada por una reorganización, retransmitiendo",
+		"Claim links are disabled on this faucet":                                                "Los enlaces de reclamo están desactivados en este grifo",
+		"Claim link invalid or expired":                                                          "Enlace de reclamo no válido o caducado",
+		"Claim link already used":                                                                "Enlace de reclamo ya utilizado",
+		"Unknown funding transaction":                                                            "Transacción de financiación desconocida",
+		"Try another faucet: %s":                                                                 "Prueba otro grifo: %s",
+		"Faucet backend unavailable, please retry in %s":                                         "El servidor del grifo no está disponible, inténtalo de nuevo en %s",
+		"Please claim the first tier before the higher ones":                                     "Solicita primero el primer nivel antes de los superiores",
+		"Higher tiers unlock after %d transactions since your last claim, %d so far":             "Los niveles superiores se desbloquean tras %d transacciones desde tu última solicitud, llevas %d",
+		"Claim session expired, please check your balance before claiming again":                 "La sesión de la solicitud expiró, revisa tu saldo antes de volver a solicitar",
+		"Internal error, please try again later":                                                 "Error interno, inténtalo de nuevo más tarde",
+		"Request denied, you may appeal the decision":                                            "Solicitud denegada, puedes apelar la decisión",
+		"Please explain your appeal in at most %d characters":                                    "Explica tu apelación en %d caracteres como máximo",
+		"Please leave a way to contact you":                                                      "Deja una forma de contactarte",
+		"An appeal of yours is already awaiting review":                                          "Ya tienes una apelación pendiente de revisión",
+		"Appeal submitted, we'll get back to you":                                                "Apelación enviada, te responderemos pronto",
+		"Terms of service":                                                                       "Términos del servicio",
+		"I accept the terms of service":                                                          "Acepto los términos del servicio",
+		"Please accept the terms of service":                                                     "Acepta los términos del servicio",
+		"Terms of service unavailable, please try again later":                                   "Términos del servicio no disponibles, inténtalo de nuevo más tarde",
+		"%d sec":                                "%d segundo",
+		"%d secs":                               "%d segundos",
+		"Please fill in %s":                     "Por favor, completa %s",
+		"%s is too long, at most %d characters": "%s es demasiado largo, máximo %d caracteres",
+		"Invalid %s":                            "%s no válido",
+		"Smart contract wallets are not supported, please use a regular account":  "Las billeteras de contrato inteligente no son compatibles, usa una cuenta normal",
+		"Your smart contract wallet needs too much gas to receive funds":          "Tu billetera de contrato inteligente necesita demasiado gas para recibir fondos",
+		"This page mirrors the status of the faucet, claims are not served here.": "Esta página refleja el estado del grifo, aquí no se atienden solicitudes.",
+		"Go to the faucet": "Ir al grifo",
+	},
+	"ja": {
+		"%s Faucet":                           "%s フォーセット",
+		"Please input your wallet address...": "ウォレットアドレスを入力してください...",
+		"Give me":                             "受け取る",
+		"Voucher code (optional)":             "クーポンコード（任意）",
+		"Open %s":                             "受付時間 %s",
+		"%d min":                              "%d 分",
+		"%d mins":                             "%d 分",
+		"%d hour":                             "%d 時間",
+		"%d hours":                            "%d 時間",
+		"%d day":                              "%d 日",
+		"%d days":                             "%d 日",
+		"Invalid funding tier requested":      "無効な受け取りティアです",
+		"Invalid address to fund":             "無効なアドレスです",
+		"Captcha verification unavailable":    "キャプチャ認証を利用できません",
+		"Beep-bop, you're a robot!":           "ピポパ、あなたはロボットですね！",
+		"%s left until next allowance":        "次の受け取りまであと %s",
+		"Request denied":                      "リクエストが拒否されました",
+		"Faucet is closed":                    "フォーセットは閉鎖中です",
+		"Faucet is closed, reopens at %s (in %s)":              "フォーセットは閉鎖中です。%s に再開します（あと %s）",
+		"Invalid voucher code":                                 "無効なクーポンコードです",
+		"Voucher already redeemed":                             "このクーポンは使用済みです",
+		"Funding transaction %s not confirmed yet":             "送金トランザクション %s はまだ承認されていません",
+		"Funding transaction %s failed":                        "送金トランザクション %s は失敗しました",
+		"Funding request accepted for Faucet into %s":          "%s への送金リクエストを受け付けました",
+		"Share referral code %s to shorten your next cooldown": "紹介コード %s を共有すると次の待ち時間が短くなります",
+		"Eligibility check unavailable, try again later":       "資格確認を利用できません。後でもう一度お試しください",
+		"Skip to the funding form":                             "受け取りフォームへ移動",
+		"Wallet address":                                       "ウォレットアドレス",
+		"Choose an amount":                                     "金額を選択",
+		"Please enable JavaScript to pass the captcha":         "キャプチャを通過するには JavaScript を有効にしてください",
+		"Language":       "言語",
+		"Connect wallet": "ウォレットを接続",
+		"Please sign the ownership challenge with your wallet":                                   "ウォレットで所有権確認メッセージに署名してください",
+		"Ownership challenge invalid or expired, please retry":                                   "所有権確認メッセージが無効か期限切れです。もう一度お試しください",
+		"Signature does not match the address to fund":                                           "署名がアドレスと一致しません",
+		"Add %s network to MetaMask":                                                             "%s ネットワークを MetaMask に追加",
+		"Add %s to MetaMask":                                                                     "%s を MetaMask に追加",
+		"Position %d in the funding queue":                                                       "送金待ちの %d 番目です",
+		"Position %d in the funding queue, about %s left":                                        "送金待ちの %d 番目です。残り約 %s",
+		"Already processing request %s, please wait for it to complete":                          "リクエスト %s を処理中です。完了するまでお待ちください",
+		"Session expired, please reload the page":                                                "セッションの有効期限が切れました。ページを再読み込みしてください",
+		"Please sign in to request funds":                                                        "受け取るにはサインインしてください",
+		"Sign-in invalid or expired, please sign in again":                                       "サインインが無効か期限切れです。もう一度サインインしてください",
+		"Funding tier not permitted for your account":                                            "このアカウントではこのティアを利用できません",
+		"Quota of %d requests exhausted, renews at %s":                                           "%d 回の受け取り枠を使い切りました。%s に更新されます",
+		"Faucet is restarting, please retry":                                                     "フォーセットを再起動中です。もう一度お試しください",
+		"Faucet is busy, please retry in %s":                                                     "フォーセットが混雑しています。%s 後にもう一度お試しください",
+		"Faucet paused, daily gas budget exhausted":                                              "本日のガス予算を使い切ったため、フォーセットは一時停止中です",
+		"Faucet is out of funds":                                                                 "フォーセットの残高がありません",
+		"Payout reduced to %s %s while the faucet is running low":                                "フォーセットの残高が少ないため、受け取り額を %s %s に減らしました",
+		"Included in L2 block %d, awaiting L1 batch":                                             "L2 ブロック %d に取り込まれました。L1 へのバッチ送信待ちです",
+		"Included in L2 block %d, posted to L1 and awaiting finality":                            "L2 ブロック %d に取り込まれました。L1 に送信済みで、ファイナリティ待ちです",
+		"Included in L2 block %d, finalized on L1":                                               "L2 ブロック %d に取り込まれ、L1 でファイナライズされました",
+		"Invalid request, malformed JSON":                                                        "無効なリクエストです。JSON の形式が正しくありません",
+		"Invalid request, malformed CBOR":                                                        "無効なリクエストです。CBOR の形式が正しくありません",
+		"Invalid request, unknown field %s":                                                      "無効なリクエストです。不明なフィールド %s",
+		"Invalid request, field %s must be a string":                                             "無効なリクエストです。フィールド %s は文字列である必要があります",
+		"Invalid request, field %s must be a non-negative integer":                               "無効なリクエストです。フィールド %s は 0 以上の整数である必要があります",
+		"Faucet is on standby, please retry shortly":                                             "フォーセットは待機中です。しばらくしてから再度お試しください",
+		"Cooldown extended to %s for repeated early requests":                                    "早すぎるリクエストが繰り返されたため、待機時間を %s に延長しました",
+		"Payout reduced for new addresses, use your address on chain to receive the full amount": "新しいアドレスへの受け取り額は減額されます。チェーン上でアドレスを使用すると全額を受け取れます",
+		"Address %s has no mainnet activity, only addresses used on mainnet are funded":          "アドレス %s にはメインネットでの利用履歴がありません。メインネットで使用されたアドレスのみが対象です",
+		"Passport scoring unavailable, try again later":                                          "Passport のスコアリングを利用できません。後でもう一度お試しください",
+		"Passport score %s too low for this tier, %s required":                                   "Passport スコア %s はこの段階には低すぎます（%s 以上が必要）",
+		"Passport score %s+":                                                                     "Passport スコア %s+",
+		"Please verify with World ID to request funds":                                           "資金をリクエストするには World ID で認証してください",
+		"World ID proof invalid":                                                                 "World ID の証明が無効です",
+		"World ID verification unavailable, try again later":                                     "World ID の認証を利用できません。後でもう一度お試しください",
+		"Invalid request, field %s must be an object":                                            "無効なリクエストです。フィールド %s はオブジェクトである必要があります",
+		"Invalid request, field %s must be true or false":                                        "無効なリクエストです。フィールド %s は true または false である必要があります",
+		"Payout too small to stream":                                                             "支払額が小さすぎるためストリーミングできません",
+		"Streaming over %s":                                                                      "%s にわたってストリーミングで支払われます",
+		"Failed to mint NFT, try again later":                                                    "NFT のミントに失敗しました。後でもう一度お試しください",
+		"NFT mint %s pending":                                                                    "NFT のミント %s は保留中です",
+		"Minted NFT #%s":                                                                         "NFT #%s をミントしました",
+		"Payout would fail: %s":                                                                  "支払いは失敗します: %s",
+		"Funding transaction %s ran out of gas, please try again":                                "資金提供トランザクション %s のガスが不足しました。もう一度お試しください",
+		"Funding transaction %s failed, the faucet is out of tokens":                             "資金提供トランザクション %s が失敗しました。フォーセットのトークンが不足しています",
+		"Funding transaction %s failed, the token is paused":                                     "資金提供トランザクション %s が失敗しました。トークンが一時停止されています",
+		"Funding transaction %s reverted: %s":                                                    "資金提供トランザクション %s がリバートされました: %s",
+		"Included in block %d, waiting for %d confirmations":                                     "ブロック %d に含まれました。%d 回の承認を待っています",
+		"Funding transaction %s dropped by a reorg, rebroadcasting":                              "資金提供トランザクション %s がリオーグで破棄されたため、再ブロードキャストしています",
+		"Claim links are disabled on this faucet":                                                "このフォーセットでは請求リンクが無効です",
+		"Claim link invalid or expired":                                                          "請求リンクが無効か期限切れです",
+		"Claim link already used":                                                                "請求リンクは既に使用されています",
+		"Unknown funding transaction":                                                            "不明な送金トランザクションです",
+		"Try another faucet: %s":                                                                 "他のフォーセットをお試しください: %s",
+		"Faucet backend unavailable, please retry in %s":                                         "フォーセットのバックエンドが利用できません。%s 後にもう一度お試しください",
+		"Please claim the first tier before the higher ones":                                     "上位のティアの前に、まず最初のティアを申請してください",
+		"Higher tiers unlock after %d transactions since your last claim, %d so far":             "上位のティアは前回の申請以降 %d 件のトランザクションで解放されます（現在 %d 件）",
+		"Claim session expired, please check your balance before claiming again":                 "申請セッションの有効期限が切れました。再度申請する前に残高を確認してください",
+		"Internal error, please try again later":                                                 "内部エラーです。しばらくしてからもう一度お試しください",
+		"Request denied, you may appeal the decision":                                            "リクエストは拒否されました。この決定に異議を申し立てることができます",
+		"Please explain your appeal in at most %d characters":                                    "異議の内容を %d 文字以内で説明してください",
+		"Please leave a way to contact you":                                                      "連絡先を入力してください",
+		"An appeal of yours is already awaiting review":                                          "異議申し立てはすでに審査待ちです",
+		"Appeal submitted, we'll get back to you":                                                "異議申し立てを受け付けました。追ってご連絡します",
+		"Terms of service":                                                                       "利用規約",
+		"I accept the terms of service":                                                          "利用規約に同意します",
+		"Please accept the terms of service":                                                     "利用規約に同意してください",
+		"Terms of service unavailable, please try again later":                                   "利用規約を利用できません。しばらくしてからもう一度お試しください",
+		"%d sec":                                "%d 秒",
+		"%d secs":                               "%d 秒",
+		"Please fill in %s":                     "%sを入力してください",
+		"%s is too long, at most %d characters": "%sが長すぎます（最大 %d 文字）",
+		"Invalid %s":                            "%sが無効です",
+		"Smart contract wallets are not supported, please use a regular account":  "スマートコントラクトウォレットには対応していません。通常のアカウントをご利用ください",
+		"Your smart contract wallet needs too much gas to receive funds":          "お使いのスマートコントラクトウォレットは受け取りに必要なガスが多すぎます",
+		"This page mirrors the status of the faucet, claims are not served here.": "このページはフォーセットの状態のミラーです。ここでは請求を受け付けていません。",
+		"Go to the faucet": "フォーセットへ移動",
+	},
+}
+
+// localizer is a message argument rendered differently per language.
+type localizer interface {
+	localize(lang string) string
+}
+
+// translate formats a message in the requested language, falling back to the
+// English original if there's no translation for it.
+func translate(lang string, format string, args ...interface{}) string {
+	if localized, ok := catalogs[lang][format]; ok {
+		format = localized
+	}
+	if len(args) == 0 {
+		return format
+	}
+	localized := make([]interface{}, len(args))
+	for i, arg := range args {
+		if l, ok := arg.(localizer); ok {
+			arg = l.localize(lang)
+		}
+		localized[i] = arg
+	}
+	return fmt.Sprintf(format, localized...)
+}
+
+// userError is an error meant to be displayed to the user. It retains the
+// message format and arguments so it can be localized for the requester.
+type userError struct {
+	format string
+	args   []interface{}
+}
+
+// newUserError creates an error to be displayed to the user, formatted from an
+// English message that doubles as the key into the message catalogs.
+func newUserError(format string, args ...interface{}) error {
+	return &userError{format: format, args: args}
+}
+
+func (e *userError) Error() string {
+	return translate(defaultLanguage, e.format, e.args...)
+}
+
+// localizeError renders an error in the requested language if it's meant for
+// the user, or as is otherwise.
+func localizeError(lang string, err error) error {
+	var uerr *userError
+	if errors.As(err, &uerr) {
+		return errors.New(translate(lang, uerr.format, uerr.args...))
+	}
+	return err
+}
+
+// supportedLanguage returns the supported language matching a language tag
+// (e.g. "es-MX"), or an empty string if there's none.
+func supportedLanguage(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	for _, lang := range languages {
+		if lang.Code == tag {
+			return lang.Code
+		}
+	}
+	return ""
+}
+
+// negotiateLanguage picks the language to serve a request in, preferring an
+// explicit ?lang= choice over the browser's Accept-Language preferences.
+func negotiateLanguage(r *http.Request) string {
+	if lang := supportedLanguage(r.URL.Query().Get("lang")); lang != "" {
+		return lang
+	}
+	type preference struct {
+		lang    string
+		quality float64
+	}
+	var prefs []preference
+	for _, item := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		parts := strings.Split(item, ";")
+		lang := supportedLanguage(parts[0])
+		if lang == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range parts[1:] {
+			if q := strings.TrimSpace(param); strings.HasPrefix(q, "q=") {
+				if v, err := strconv.ParseFloat(q[2:], 64); err == nil {
+					quality = v
+				}
+			}
+		}
+		prefs = append(prefs, preference{lang, quality})
+	}
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].quality > prefs[j].quality })
+	if len(prefs) > 0 && prefs[0].quality > 0 {
+		return prefs[0].lang
+	}
+	return defaultLanguage
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	jwksFlag        = flag.String("auth.jwks", "", "JWKS URL of the identity provider whose JWTs every claim must carry (empty = public faucet)")
+	jwtIssuerFlag   = flag.String("auth.jwt.issuer", "", "Required issuer of the JWTs (empty = any)")
+	jwtAudienceFlag = flag.String("auth.jwt.audience", "", "Required audience of the JWTs (empty = any)")
+	jwtQuotaFlag    = flag.Int("auth.jwt.quota", 5, "Number of claims per subject within the quota window, unless the JWT grants otherwise")
+	jwtWindowFlag   = flag.Duration("auth.jwt.window", 24*time.Hour, "Window the per subject quota applies to")
+)
+
+// subjectsBucket is the store bucket tracking the quota usage of JWT subjects.
+const subjectsBucket = "subjects"
+
+// identityClaims are the JWT claims the faucet understands. Besides the standard
+// ones, the operator may grant per subject entitlements.
+type identityClaims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	Expiry    int64    `json:"exp"`
+	NotBefore int64    `json:"nbf"`
+
+	MaxAmount float64 `json:"faucet_max_amount"` // Payout cap in token units, 0 = uncapped
+	MaxTier   *uint   `json:"faucet_max_tier"`   // Highest funding tier allowed
+	Quota     *int    `json:"faucet_quota"`      // Claims allowed within the quota window
+}
+
+// audience is the JWT "aud" claim, which may be a single string or a list.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(a))
+}
+
+// subjectUsage is the quota usage of a JWT subject.
+type subjectUsage struct {
+	Window time.Time `json:"window"` // Start of the current quota window
+	Count  int       `json:"count"`  // Claims within the window
+}
+
+// jwks caches the signing keys of the identity provider by key ID. The key set
+// is downloaded without holding the lock, so claims with known keys never wait
+// for a slow identity provider.
+var jwks = struct {
+	lock     sync.Mutex
+	keys     map[string]crypto.PublicKey
+	fetched  time.Time     // Time of the last successful refresh
+	tried    time.Time     // Time of the last refresh attempt
+	inflight chan struct{} // Closed once the running refresh is done, nil if none
+}{}
+
+// jwk is a single JSON Web Key, as far as RSA and EC signing keys go.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey converts the JWK into a Go public key.
+func (k *jwk) publicKey() (crypto.PublicKey, error) {
+	decode := func(s string) (*big.Int, error) {
+		raw, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(raw), nil
+	}
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+	}
+}
+
+// jwksKey returns the signing key with the given ID, refreshing the key set if
+// it's stale or doesn't know the key yet (at most every 30 seconds).
+func jwksKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	jwks.lock.Lock()
+	key, ok := jwks.keys[kid]
+	if ok && time.Since(jwks.fetched) < 10*time.Minute {
+		jwks.lock.Unlock()
+		return key, nil
+	}
+	done := jwks.inflight
+	if done == nil && time.Since(jwks.tried) > 30*time.Second {
+		// Refresh the key set, letting other claims wait for it if they need
+		done = make(chan struct{})
+		jwks.inflight, jwks.tried = done, time.Now()
+		jwks.lock.Unlock()
+
+		keys, err := fetchJWKS(ctx)
+
+		jwks.lock.Lock()
+		if err != nil {
+			log.Error("Failed to refresh JWKS err: ", err)
+		} else {
+			jwks.keys, jwks.fetched = keys, time.Now()
+		}
+		jwks.inflight = nil
+		close(done)
+		key, ok = jwks.keys[kid]
+	} else if done != nil && !ok {
+		// Unknown keys may be in the key set being downloaded, wait for it
+		jwks.lock.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		jwks.lock.Lock()
+		key, ok = jwks.keys[kid]
+	}
+	jwks.lock.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// fetchJWKS downloads the key set of the identity provider.
+func fetchJWKS(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, *jwksFlag, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected JWKS status %s", res.Status)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range set.Keys {
+		key, err := k.publicKey()
+		if err != nil {
+			log.Info("Skipping JWKS key ", k.Kid, ": ", err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+// verifyJWT checks the signature and validity of a JWT issued by the identity
+// provider and returns its claims.
+func verifyJWT(ctx context.Context, token string) (*identityClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	blob, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(blob, &header); err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	key, err := jwksKey(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		if header.Alg != "RS256" {
+			return nil, fmt.Errorf("unexpected algorithm %s", header.Alg)
+		}
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash[:], sig); err != nil {
+			return nil, err
+		}
+	case *ecdsa.PublicKey:
+		if header.Alg != "ES256" || len(sig) != 64 {
+			return nil, fmt.Errorf("unexpected algorithm %s", header.Alg)
+		}
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pub, hash[:], r, s) {
+			return nil, errors.New("invalid signature")
+		}
+	default:
+		return nil, errors.New("unsupported signing key")
+	}
+	if blob, err = base64.RawURLEncoding.DecodeString(parts[1]); err != nil {
+		return nil, err
+	}
+	claims := new(identityClaims)
+	if err := json.Unmarshal(blob, claims); err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	if claims.Expiry == 0 || now >= claims.Expiry {
+		return nil, errors.New("token expired")
+	}
+	if claims.NotBefore != 0 && now < claims.NotBefore {
+		return nil, errors.New("token not yet valid")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("token without subject")
+	}
+	if *jwtIssuerFlag != "" && claims.Issuer != *jwtIssuerFlag {
+		return nil, fmt.Errorf("unexpected issuer %s", claims.Issuer)
+	}
+	if *jwtAudienceFlag != "" {
+		var found bool
+		for _, aud := range claims.Audience {
+			found = found || aud == *jwtAudienceFlag
+		}
+		if !found {
+			return nil, errors.New("unexpected audience")
+		}
+	}
+	return claims, nil
+}
+
+// bearerToken returns the bearer token in the Authorization header of a request.
+func bearerToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return ""
+}
+
+// identityStage restricts the faucet to holders of a JWT issued by the operator,
+// if configured. The JWT's entitlements cap the tier and payout, and the per
+// subject quota replaces the per address cooldown.
+func identityStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if *jwksFlag == "" {
+			return next(c)
+		}
+		if c.JWT == "" {
+			return newUserError("Please sign in to request funds")
+		}
+		claims, err := verifyJWT(c.ctx, c.JWT)
+		if err != nil {
+			log.Info("Rejecting claim with invalid JWT: ", err)
+			return newUserError("Sign-in invalid or expired, please sign in again")
+		}
+		if claims.MaxTier != nil && c.Tier > *claims.MaxTier {
+			return newUserError("Funding tier not permitted for your account")
+		}
+		if claims.MaxAmount > 0 {
+			if limit := toWei(claims.MaxAmount); c.Amount.Cmp(limit) > 0 {
+				c.Amount = limit
+			}
+		}
+		quota := *jwtQuotaFlag
+		if claims.Quota != nil {
+			quota = *claims.Quota
+		}
+		// Reserve a slot of the quota up front so concurrent claims can't exceed it
+		var window time.Time
+		err = store.Update(subjectsBucket, claims.Subject, func(blob []byte) ([]byte, error) {
+			var usage subjectUsage
+			if blob != nil {
+				if err := json.Unmarshal(blob, &usage); err != nil {
+					return nil, err
+				}
+			}
+			if time.Since(usage.Window) > *jwtWindowFlag {
+				usage.Window, usage.Count = time.Now(), 0
+			}
+			if usage.Count >= quota {
+				renew := usage.Window.Add(*jwtWindowFlag)
+				return nil, &throttledError{
+					error: newUserError("Quota of %d requests exhausted, renews at %s", quota, renew.UTC().Format("2006-01-02 15:04 MST")),
+					limit: quota,
+					retry: time.Until(renew),
+				}
+			}
+			usage.Count++
+			window = usage.Window
+			return json.Marshal(&usage)
+		})
+		if err != nil {
+			return err
+		}
+		c.Subject = claims.Subject
+		c.SkipCooldown = true
+
+		// Claims taken by the sender are waited for even if the requester left,
+		// so only claims that were never paid out are handed back. Slots of a
+		// window that renewed meanwhile aren't handed back to the next one.
+		err = next(c)
+		if refundable(c, err) {
+			store.Update(subjectsBucket, claims.Subject, func(blob []byte) ([]byte, error) {
+				var usage subjectUsage
+				if blob == nil || json.Unmarshal(blob, &usage) != nil {
+					return blob, nil
+				}
+				if usage.Count > 0 && usage.Window.Equal(window) {
+					usage.Count--
+				}
+				return json.Marshal(&usage)
+			})
+		}
+		return err
+	}
+}
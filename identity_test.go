@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testIssuer is an identity provider serving its keys as a JWKS and signing
+// tokens with them.
+type testIssuer struct {
+	rsa *rsa.PrivateKey
+	ec  *ecdsa.PrivateKey
+
+	lock    sync.Mutex
+	kids    []string // Keys published in the JWKS, of "rsa" and "ec"
+	fetches int32
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testIssuer{rsa: rsaKey, ec: ecKey, kids: []string{"rsa", "ec"}}
+}
+
+func (iss *testIssuer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt32(&iss.fetches, 1)
+
+	encode := func(n *big.Int) string { return base64.RawURLEncoding.EncodeToString(n.Bytes()) }
+
+	iss.lock.Lock()
+	defer iss.lock.Unlock()
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	for _, kid := range iss.kids {
+		switch kid {
+		case "rsa":
+			set.Keys = append(set.Keys, jwk{Kty: "RSA", Kid: kid, N: encode(iss.rsa.N), E: encode(big.NewInt(int64(iss.rsa.E)))})
+		case "ec":
+			set.Keys = append(set.Keys, jwk{Kty: "EC", Kid: kid, Crv: "P-256", X: encode(iss.ec.X), Y: encode(iss.ec.Y)})
+		}
+	}
+	json.NewEncoder(w).Encode(&set)
+}
+
+// sign issues a token with the given header and claims, signed with the key
+// matching the algorithm.
+func (iss *testIssuer) sign(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	hash := sha256.Sum256([]byte(input))
+
+	var sig []byte
+	switch alg {
+	case "RS256":
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, iss.rsa, crypto.SHA256, hash[:]); err != nil {
+			t.Fatal(err)
+		}
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, iss.ec, hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// resetJWKS forgets the cached key set.
+func resetJWKS() {
+	jwks.lock.Lock()
+	jwks.keys, jwks.fetched, jwks.tried = nil, time.Time{}, time.Time{}
+	jwks.lock.Unlock()
+}
+
+func TestVerifyJWT(t *testing.T) {
+	iss := newTestIssuer(t)
+	srv := httptest.NewServer(iss)
+	defer srv.Close()
+
+	defer func(url, issuer, audience string) {
+		*jwksFlag, *jwtIssuerFlag, *jwtAudienceFlag = url, issuer, audience
+		resetJWKS()
+	}(*jwksFlag, *jwtIssuerFlag, *jwtAudienceFlag)
+	*jwksFlag, *jwtIssuerFlag, *jwtAudienceFlag = srv.URL, "https://id.example.org", "faucet"
+	resetJWKS()
+
+	now := time.Now().Unix()
+	valid := func(overrides map[string]interface{}) map[string]interface{} {
+		claims := map[string]interface{}{
+			"sub": "alice",
+			"iss": "https://id.example.org",
+			"aud": []string{"other", "faucet"},
+			"exp": now + 600,
+			"nbf": now - 60,
+		}
+		for key, value := range overrides {
+			if value == nil {
+				delete(claims, key)
+			} else {
+				claims[key] = value
+			}
+		}
+		return claims
+	}
+	tests := []struct {
+		name  string
+		token func() string
+		err   string // Substring of the expected error, empty if valid
+	}{
+		{"rs256", func() string { return iss.sign(t, "RS256", "rsa", valid(nil)) }, ""},
+		{"es256", func() string { return iss.sign(t, "ES256", "ec", valid(nil)) }, ""},
+		{"single audience", func() string { return iss.sign(t, "RS256", "rsa", valid(map[string]interface{}{"aud": "faucet"})) }, ""},
+		{"expired", func() string { return iss.sign(t, "RS256", "rsa", valid(map[string]interface{}{"exp": now - 1})) }, "expired"},
+		{"no expiry", func() string { return iss.sign(t, "RS256", "rsa", valid(map[string]interface{}{"exp": nil})) }, "expired"},
+		{"not yet valid", func() string { return iss.sign(t, "RS256", "rsa", valid(map[string]interface{}{"nbf": now + 600})) }, "not yet valid"},
+		{"no subject", func() string { return iss.sign(t, "RS256", "rsa", valid(map[string]interface{}{"sub": nil})) }, "subject"},
+		{"wrong issuer", func() string {
+			return iss.sign(t, "RS256", "rsa", valid(map[string]interface{}{"iss": "https://evil.example.org"}))
+		}, "issuer"},
+		{"wrong audience", func() string { return iss.sign(t, "RS256", "rsa", valid(map[string]interface{}{"aud": "other"})) }, "audience"},
+		{"alg none", func() string {
+			token := iss.sign(t, "RS256", "rsa", valid(nil))
+			header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"rsa"}`))
+			parts := strings.Split(token, ".")
+			return header + "." + parts[1] + "."
+		}, "algorithm"},
+		{"alg mismatching rsa key", func() string { return iss.sign(t, "ES256", "rsa", valid(nil)) }, "algorithm"},
+		{"alg mismatching ec key", func() string { return iss.sign(t, "RS256", "ec", valid(nil)) }, "algorithm"},
+		{"bad rsa signature", func() string {
+			parts := strings.Split(iss.sign(t, "RS256", "rsa", valid(nil)), ".")
+			other := strings.Split(iss.sign(t, "RS256", "rsa", valid(map[string]interface{}{"sub": "mallory"})), ".")
+			return parts[0] + "." + other[1] + "." + parts[2]
+		}, "verification error"},
+		{"bad ec signature", func() string {
+			parts := strings.Split(iss.sign(t, "ES256", "ec", valid(nil)), ".")
+			other := strings.Split(iss.sign(t, "ES256", "ec", valid(map[string]interface{}{"sub": "mallory"})), ".")
+			return parts[0] + "." + other[1] + "." + parts[2]
+		}, "invalid signature"},
+		{"unknown key", func() string { return iss.sign(t, "RS256", "gone", valid(nil)) }, "unknown signing key"},
+		{"malformed", func() string { return "not.a-token" }, "malformed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifyJWT(context.Background(), tt.token())
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("valid token rejected: %v", err)
+			case tt.err == "" && claims.Subject != "alice":
+				t.Fatalf("subject %q, want alice", claims.Subject)
+			case tt.err != "" && err == nil:
+				t.Fatalf("invalid token accepted: %+v", claims)
+			case tt.err != "" && !strings.Contains(err.Error(), tt.err):
+				t.Fatalf("error %q, want one about %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestJWKSRefresh(t *testing.T) {
+	iss := newTestIssuer(t)
+	iss.kids = []string{"rsa"}
+	srv := httptest.NewServer(iss)
+	defer srv.Close()
+
+	defer func(url, issuer, audience string) {
+		*jwksFlag, *jwtIssuerFlag, *jwtAudienceFlag = url, issuer, audience
+		resetJWKS()
+	}(*jwksFlag, *jwtIssuerFlag, *jwtAudienceFlag)
+	*jwksFlag, *jwtIssuerFlag, *jwtAudienceFlag = srv.URL, "", ""
+	resetJWKS()
+
+	claims := map[string]interface{}{"sub": "alice", "exp": time.Now().Unix() + 600}
+	if _, err := verifyJWT(context.Background(), iss.sign(t, "RS256", "rsa", claims)); err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+	// A key rotated in is picked up by refreshing the key set, once the last
+	// attempt is long enough ago
+	iss.lock.Lock()
+	iss.kids = append(iss.kids, "ec")
+	iss.lock.Unlock()
+
+	token := iss.sign(t, "ES256", "ec", claims)
+	if _, err := verifyJWT(context.Background(), token); err == nil {
+		t.Fatal("token of a new key accepted before the refresh throttle passed")
+	}
+	jwks.lock.Lock()
+	jwks.tried = time.Now().Add(-time.Minute)
+	jwks.lock.Unlock()
+
+	if _, err := verifyJWT(context.Background(), token); err != nil {
+		t.Fatalf("token of a rotated in key rejected: %v", err)
+	}
+	if fetches := atomic.LoadInt32(&iss.fetches); fetches != 2 {
+		t.Errorf("key set fetched %d times, want 2", fetches)
+	}
+	// Known keys are served from the cache
+	if _, err := verifyJWT(context.Background(), iss.sign(t, "RS256", "rsa", claims)); err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+	if fetches := atomic.LoadInt32(&iss.fetches); fetches != 2 {
+		t.Errorf("key set fetched %d times, want 2", fetches)
+	}
+}
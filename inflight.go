@@ -0,0 +1,66 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"sync"
+
+	"github.com/sunvim/utils/log"
+)
+
+var inflightFlag = flag.Bool("inflight.limit", false, "Allow at most one funding request in flight per IP and per address")
+
+// inflight tracks the funding jobs currently being sent or confirmed, keyed by
+// both the requester's IP and the funded address.
+var inflight = struct {
+	lock sync.Mutex
+	jobs map[string]string // "ip:<host>" or "addr:<address>" -> job ID
+}{
+	jobs: make(map[string]string),
+}
+
+// newJobID generates a short random identifier for a funding job.
+func newJobID() string {
+	raw := make([]byte, 8)
+	if _, err := rand.Read(raw); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(raw)
+}
+
+// inflightStage assigns the claim a job ID and, if enabled, rejects it while an
+// earlier claim from the same IP or for the same address is still in flight.
+func inflightStage(next Handler) Handler {
+	return func(c *Claim) error {
+		c.ID = newJobID()
+		if !*inflightFlag {
+			return next(c)
+		}
+		keys := []string{"addr:" + c.Address}
+		if host := remoteHost(c.IP); host != "" {
+			keys = append(keys, "ip:"+host)
+		}
+		inflight.lock.Lock()
+		for _, key := range keys {
+			if id, ok := inflight.jobs[key]; ok {
+				inflight.lock.Unlock()
+				log.Info("Rejecting concurrent claim: ", key, " job: ", id)
+				return newUserError("Already processing request %s, please wait for it to complete", id)
+			}
+		}
+		for _, key := range keys {
+			inflight.jobs[key] = c.ID
+		}
+		inflight.lock.Unlock()
+
+		defer func() {
+			inflight.lock.Lock()
+			for _, key := range keys {
+				delete(inflight.jobs, key)
+			}
+			inflight.lock.Unlock()
+		}()
+		return next(c)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/sunvim/utils/log"
+)
+
+// Build details, injected at link time via:
+//
+//	go build -ldflags "-X main.version=v1.2.3 -X main.commit=$(git rev-parse HEAD)"
+//
+// If unset, the commit falls back to the VCS details embedded by the Go toolchain.
+var (
+	version = "dev"
+	commit  = ""
+)
+
+// faucetInfo describes the faucet configuration to frontends and clients.
+type faucetInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Commit  string `json:"commit,omitempty"`
+
+	Chain    *walletChain `json:"chain"`    // Lists only the explicitly public --chain.rpc
+	Unit     string       `json:"unit"`     // Native currency paid out
+	Decimals int          `json:"decimals"` // Decimals of the native currency
+	Token    *walletAsset `json:"token,omitempty"`
+
+	Tiers        []tierInfo       `json:"tiers"`
+	Verification verificationInfo `json:"verification"`
+	Hours        string           `json:"hours,omitempty"`
+	Fields       []*formField     `json:"fields,omitempty"` // Extra form fields to fill in
+	Links        infoLinks        `json:"links"`
+
+	ReceiptSigner string `json:"receiptSigner,omitempty"` // Address signing claim receipts
+	Terms         string `json:"terms,omitempty"`         // Version of the terms of service claims must accept
+}
+
+// infoLinks are the absolute URLs of the faucet endpoints, as reachable by the
+// client behind any reverse proxy.
+type infoLinks struct {
+	Website   string `json:"website"`
+	Websocket string `json:"websocket"`
+	Claim     string `json:"claim"` // REST API funding endpoint
+	Activity  string `json:"activity,omitempty"`
+	Terms     string `json:"terms,omitempty"`    // Terms of service to accept
+	Finality  string `json:"finality,omitempty"` // L1 status of funding transactions on an L2
+}
+
+// tierInfo is a single funding tier.
+type tierInfo struct {
+	Amount   string  `json:"amount"`          // Payout in token units
+	Cooldown int64   `json:"cooldown"`        // Wait until the next request in seconds
+	Score    float64 `json:"score,omitempty"` // Minimum passport score unlocking the tier
+}
+
+// verificationInfo lists the verification methods requests are subject to.
+type verificationInfo struct {
+	Captcha       string `json:"captcha,omitempty"`    // "recaptcha" or "recaptcha-v3"
+	CaptchaKey    string `json:"captchaKey,omitempty"` // Site key of the captcha
+	Signature     bool   `json:"signature"`            // Ownership proof via /api/challenge
+	Token         bool   `json:"token"`                // Claim token via /api/token
+	JWT           bool   `json:"jwt"`                  // Identity token of the operator
+	Vouchers      bool   `json:"vouchers"`
+	Referrals     bool   `json:"referrals"`
+	Passport      bool   `json:"passport"`                // Gitcoin Passport score of the funded address
+	WorldID       string `json:"worldId,omitempty"`       // World ID app proofs must be generated for
+	WorldIDAction string `json:"worldIdAction,omitempty"` // World ID action, with the address as signal
+}
+
+// buildCommit returns the commit the binary was built from, if known.
+func buildCommit() string {
+	if commit != "" {
+		return commit
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				return setting.Value
+			}
+		}
+	}
+	return ""
+}
+
+// onInfo describes the faucet configuration so clients can set themselves up.
+func (s *Server) onInfo(w http.ResponseWriter, r *http.Request) {
+	info := &faucetInfo{
+		Name:     *apiName,
+		Version:  version,
+		Commit:   buildCommit(),
+		Chain:    newWalletChain(),
+		Unit:     *UnitFlag,
+		Decimals: 18,
+		Token:    newWalletAsset(),
+		Hours:    hours.describe(),
+		Fields:   formFields,
+		Links: infoLinks{
+			Website:   publicURL(r, "/"),
+			Websocket: "ws" + strings.TrimPrefix(faucetURL(r, "/api"), "http"),
+			Claim:     faucetURL(r, "/api/claim"),
+		},
+		Verification: verificationInfo{
+			Signature: *signatureFlag,
+			Token:     *claimTokenFlag,
+			JWT:       *jwksFlag != "",
+			Vouchers:  *adminAddrFlag != "",
+			Referrals: *referralFlag,
+			Passport:  passportScores != nil,
+			WorldID:   *worldIDAppFlag,
+		},
+	}
+	if *claimReceiptsFlag {
+		info.ReceiptSigner = s.faucetAddress()
+	}
+	if *activityFlag {
+		info.Links.Activity = publicURL(r, "/activity")
+	}
+	if *l2Flag != "" {
+		info.Links.Finality = publicURL(r, "/api/finality")
+	}
+	if terms.text != "" {
+		info.Terms, info.Links.Terms = terms.version, publicURL(r, "/api/terms")
+	}
+	if *worldIDAppFlag != "" {
+		info.Verification.WorldIDAction = *worldIDActionFlag
+	}
+	// Requesters on trusted networks are exempt from captchas and claim tokens
+	trusted := trustedRemote(r.RemoteAddr)
+	if trusted {
+		info.Verification.Token = false
+	}
+	if *captchaToken != "" && *captchaSecret != "" && !trusted {
+		info.Verification.Captcha, info.Verification.CaptchaKey = "recaptcha", *captchaToken
+		if *captchaV3Flag {
+			info.Verification.Captcha = "recaptcha-v3"
+		}
+	}
+	limit, err := s.payoutCap(r.Context())
+	if err != nil {
+		log.Error("Failed to retrieve faucet balance err: ", err)
+	}
+	// Report the tiers as paid out to the country of the requester
+	rule, adjusted := geo.rules[requestCountry(r)]
+	for i, tier := range payoutTiers {
+		amount, cooldown := tier.Amount, tier.Cooldown
+		if adjusted {
+			amount, cooldown = rule.apply(amount, cooldown)
+		}
+		if trusted {
+			cooldown = trustedCooldown(cooldown)
+		}
+		if limit != nil && amount.Cmp(limit) > 0 {
+			amount = limit
+		}
+		ti := tierInfo{
+			Amount:   fromWei(amount),
+			Cooldown: int64(cooldown.Seconds()),
+		}
+		if passportScores != nil {
+			ti.Score = passportScores[i]
+		}
+		info.Tiers = append(info.Tiers, ti)
+	}
+	writeJSON(w, http.StatusOK, info)
+}
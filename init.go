@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/google/uuid"
+)
+
+var (
+	initDirFlag       = flag.String("init.dir", ".", "Directory `faucet init` writes the key and starter configuration to")
+	initKeystoreFlag  = flag.String("init.keystore", "", "File with a password to also write an encrypted keystore backup of the generated key with (empty = none)")
+	initTokenFlag     = flag.String("init.token", "", "File with the hex creation bytecode of an ERC-20 token for `faucet init` to deploy once funded (empty = none)")
+	initDisperserFlag = flag.String("init.disperser", "", "File with the hex creation bytecode of a disperser contract for `faucet init` to deploy once funded (empty = none)")
+	initWaitFlag      = flag.Duration("init.wait", 30*time.Minute, "Time for `faucet init` to wait for the new account to be funded before deploying contracts")
+)
+
+// runInit generates a fresh faucet key, prints the account to fund and writes a
+// starter configuration for it, deploying the requested contracts once funded.
+// It returns the process exit code.
+func runInit() int {
+	dir, err := filepath.Abs(*initDirFlag)
+	if err != nil {
+		fmt.Println("Invalid --init.dir:", err)
+		return 1
+	}
+	keyPath, envPath := filepath.Join(dir, "faucet.key"), filepath.Join(dir, "faucet.env")
+	for _, path := range []string{keyPath, envPath} {
+		if _, err := os.Stat(path); err == nil {
+			fmt.Printf("%s already exists, refusing to overwrite it\n", path)
+			return 1
+		}
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		fmt.Println("Failed to create --init.dir:", err)
+		return 1
+	}
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		fmt.Println("Failed to generate key:", err)
+		return 1
+	}
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	if err := ioutil.WriteFile(keyPath, []byte(hexutil.Encode(crypto.FromECDSA(key))[2:]+"\n"), 0600); err != nil {
+		fmt.Println("Failed to write key:", err)
+		return 1
+	}
+	fmt.Println("Wrote key to", keyPath)
+
+	if *initKeystoreFlag != "" {
+		path, err := writeKeystore(dir, key)
+		if err != nil {
+			fmt.Println("Failed to write keystore:", err)
+			return 1
+		}
+		fmt.Println("Wrote encrypted keystore backup to", path)
+	}
+	// Print the account along with an EIP-681 URI, which wallets open as a
+	// prefilled transfer and QR tools such as `qrencode -t ansiutf8` render
+	uri := fmt.Sprintf("ethereum:%s@%d", addr.Hex(), *chainID)
+	fmt.Println()
+	fmt.Println("Faucet account:", addr.Hex())
+	fmt.Println("Fund it via:   ", uri)
+	fmt.Println()
+
+	args := []string{"--rpc", *rpc, "--chain_id", fmt.Sprint(*chainID), "--store.path", filepath.Join(dir, "state.json")}
+	if *initTokenFlag != "" || *initDisperserFlag != "" {
+		deployed, err := deployContracts(key)
+		if err != nil {
+			fmt.Println("Failed to deploy contracts:", err)
+			fmt.Println("The key was kept, rerun the deployment by hand or configure existing contracts")
+		}
+		if token, ok := deployed[*initTokenFlag]; ok {
+			args = append(args, "--token.address", token.Hex())
+		}
+		if disperser, ok := deployed[*initDisperserFlag]; ok {
+			args = append(args, "--batch.contract", disperser.Hex())
+		}
+	}
+	config := fmt.Sprintf("# Starter faucet configuration written by `faucet init`, for use as a systemd\n"+
+		"# EnvironmentFile with ExecStart=/usr/local/bin/faucet $FAUCET_ARGS\n"+
+		"%s_FILE=%s\n"+
+		"FAUCET_ARGS=\"%s\"\n", secretEnv("pri_key"), keyPath, strings.Join(args, " "))
+
+	if err := ioutil.WriteFile(envPath, []byte(config), 0600); err != nil {
+		fmt.Println("Failed to write starter configuration:", err)
+		return 1
+	}
+	fmt.Println("Wrote starter configuration to", envPath)
+	fmt.Printf("Once funded, verify the setup with: (set -a; . %s; faucet $FAUCET_ARGS check)\n", envPath)
+	return 0
+}
+
+// writeKeystore encrypts the key with the password in --init.keystore into a
+// keystore file of the usual naming in dir, returning its path.
+func writeKeystore(dir string, key *ecdsa.PrivateKey) (string, error) {
+	blob, err := ioutil.ReadFile(*initKeystoreFlag)
+	if err != nil {
+		return "", err
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+	k := &keystore.Key{Id: id, Address: crypto.PubkeyToAddress(key.PublicKey), PrivateKey: key}
+	encrypted, err := keystore.EncryptKey(k, strings.TrimRight(string(blob), "\r\n"), keystore.StandardScryptN, keystore.StandardScryptP)
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("UTC--%s--%x", time.Now().UTC().Format("2006-01-02T15-04-05.000000000Z"), k.Address)
+	path := filepath.Join(dir, name)
+	return path, ioutil.WriteFile(path, encrypted, 0600)
+}
+
+// deployContracts waits for the account of the key to be funded, then deploys
+// the contracts in --init.token and --init.disperser from it. It returns the
+// addresses of the deployed contracts by bytecode file, even if a later one of
+// them failed.
+func deployContracts(key *ecdsa.PrivateKey) (map[string]common.Address, error) {
+	deployed := make(map[string]common.Address)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *initWaitFlag)
+	defer cancel()
+
+	client, err := ethclient.DialContext(ctx, *rpc)
+	if err != nil {
+		return deployed, fmt.Errorf("%v, check --rpc", err)
+	}
+	defer client.Close()
+
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	fmt.Println("Waiting up to", *initWaitFlag, "for the faucet account to be funded to deploy contracts...")
+	for {
+		balance, err := client.BalanceAt(ctx, addr, nil)
+		if err == nil && balance.Sign() > 0 {
+			fmt.Printf("Funded with %s %s\n", fromWei(balance), *UnitFlag)
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return deployed, fmt.Errorf("account not funded within %v", *initWaitFlag)
+		case <-time.After(5 * time.Second):
+		}
+	}
+	for _, path := range []string{*initTokenFlag, *initDisperserFlag} {
+		if path == "" {
+			continue
+		}
+		contract, err := deployContract(ctx, client, key, path)
+		if err != nil {
+			return deployed, fmt.Errorf("%s: %v", path, err)
+		}
+		fmt.Println("Deployed", path, "at", contract.Hex())
+		deployed[path] = contract
+	}
+	return deployed, nil
+}
+
+// deployContract deploys the hex creation bytecode in a file, constructor
+// arguments included, and waits for it to be mined.
+func deployContract(ctx context.Context, client *ethclient.Client, key *ecdsa.PrivateKey, path string) (common.Address, error) {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return common.Address{}, err
+	}
+	code, err := hexutil.Decode("0x" + strings.TrimPrefix(strings.TrimSpace(string(blob)), "0x"))
+	if err != nil {
+		return common.Address{}, fmt.Errorf("invalid bytecode: %v", err)
+	}
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	nonce, err := client.PendingNonceAt(ctx, from)
+	if err != nil {
+		return common.Address{}, err
+	}
+	price, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return common.Address{}, err
+	}
+	gas, err := client.EstimateGas(ctx, ethereum.CallMsg{From: from, Data: code})
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to estimate gas: %v", err)
+	}
+	tx, err := types.SignTx(types.NewContractCreation(nonce, new(big.Int), gas, price, code), types.LatestSignerForChainID(big.NewInt(*chainID)), key)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if err := client.SendTransaction(ctx, tx); err != nil {
+		return common.Address{}, err
+	}
+	fmt.Println("Sent deployment", tx.Hash().Hex(), "of", path)
+	return bind.WaitDeployed(ctx, client, tx)
+}
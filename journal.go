@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/sunvim/utils/log"
+)
+
+// journalBucket is the store bucket holding the signed transactions not known
+// to be mined yet, keyed by transaction ID.
+const journalBucket = "journal"
+
+// journalEntry is a signed transaction persisted ahead of its broadcast.
+type journalEntry struct {
+	Raw    string    `json:"raw"`             // Signed transaction, hex encoded
+	Nonce  *uint64   `json:"nonce,omitempty"` // Account nonce, on chains having them
+	To     string    `json:"to"`
+	Amount string    `json:"amount"` // Value in wei, decimal
+	Time   time.Time `json:"time"`
+}
+
+// journalTx persists a signed transaction before it's broadcast, so it can be
+// recovered if the faucet crashes before knowing its fate.
+func journalTx(tx ChainTx, to string, amount *big.Int) error {
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	entry := &journalEntry{Raw: hexutil.Encode(raw), To: to, Amount: amount.String(), Time: time.Now()}
+	if n, ok := tx.(interface{ Nonce() uint64 }); ok {
+		nonce := n.Nonce()
+		entry.Nonce = &nonce
+	}
+	return putJSON(store, journalBucket, tx.ID(), entry)
+}
+
+// unjournalTx drops a transaction from the journal once its fate is known.
+func unjournalTx(tx ChainTx) {
+	if err := store.Delete(journalBucket, tx.ID()); err != nil {
+		log.Error("Failed to drop transaction from journal err: ", err)
+	}
+}
+
+// startJournal starts settling the journaled transactions, beginning with the
+// ones left behind by a previous run.
+func startJournal(s *Server) {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for ; ; <-ticker.C {
+			if s.isPassive() {
+				continue // The primary settles its own transactions
+			}
+			s.settleJournal()
+		}
+	}()
+}
+
+// settleJournal checks every journaled transaction on chain, dropping the mined
+// ones and broadcasting the others again.
+func (s *Server) settleJournal() {
+	var ids []string
+	store.Iterate(journalBucket, func(key string, _ []byte) bool {
+		ids = append(ids, key)
+		return true
+	})
+	for _, id := range ids {
+		var entry journalEntry
+		if err := getJSON(store, journalBucket, id, &entry); err != nil {
+			continue
+		}
+		if time.Since(entry.Time) < time.Minute {
+			continue // Still being confirmed by its claim
+		}
+		raw, err := hexutil.Decode(entry.Raw)
+		if err != nil {
+			log.Error("Dropping undecodable journal entry ", id, " err: ", err)
+			store.Delete(journalBucket, id)
+			continue
+		}
+		tx, err := s.chain.DecodeTx(raw)
+		if err != nil {
+			log.Error("Dropping undecodable journal entry ", id, " err: ", err)
+			store.Delete(journalBucket, id)
+			continue
+		}
+		ctx, cancel := rpcContext(context.Background())
+		_, err = s.chain.Confirm(ctx, tx)
+		cancel()
+		if err == nil {
+			unjournalTx(tx)
+			continue
+		}
+		err = s.chain.Broadcast(context.Background(), tx)
+		switch {
+		case err == nil:
+			log.Info("Rebroadcast journaled transaction ", id, " to ", entry.To)
+		case strings.Contains(err.Error(), "nonce too low"):
+			// Its nonce was taken by another transaction, it'll never be mined
+			log.Info("Dropping replaced journaled transaction ", id)
+			unjournalTx(tx)
+		case !strings.Contains(err.Error(), "known"):
+			log.Error("Failed to rebroadcast journaled transaction ", id, " err: ", err)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var k8sLeaseFlag = flag.String("standby.k8s.lease", "", "Name of the Kubernetes Lease (in the namespace of the pod) replicas compete for, only the holder sends (empty = disabled)")
+
+// Locations of the credentials Kubernetes mounts into every pod.
+const (
+	k8sTokenPath     = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	k8sCAPath        = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+	k8sNamespacePath = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// k8sMicroTime is the timestamp format of Lease objects.
+const k8sMicroTime = "2006-01-02T15:04:05.000000Z07:00"
+
+// k8sLease is the subset of a coordination.k8s.io/v1 Lease the faucet uses.
+type k8sLease struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name            string `json:"name"`
+		Namespace       string `json:"namespace,omitempty"`
+		ResourceVersion string `json:"resourceVersion,omitempty"`
+	} `json:"metadata"`
+	Spec struct {
+		HolderIdentity       string `json:"holderIdentity,omitempty"`
+		LeaseDurationSeconds int    `json:"leaseDurationSeconds,omitempty"`
+		AcquireTime          string `json:"acquireTime,omitempty"`
+		RenewTime            string `json:"renewTime,omitempty"`
+		LeaseTransitions     int    `json:"leaseTransitions,omitempty"`
+	} `json:"spec"`
+}
+
+// expired reports whether the holder of the lease failed to renew it in time.
+func (l *k8sLease) expired(now time.Time) bool {
+	renewed, err := time.Parse(k8sMicroTime, l.Spec.RenewTime)
+	if err != nil {
+		return true
+	}
+	return now.After(renewed.Add(time.Duration(l.Spec.LeaseDurationSeconds) * time.Second))
+}
+
+// errLeaseConflict signals that another replica modified the lease concurrently.
+var errLeaseConflict = errors.New("lease modified concurrently")
+
+// k8sClient talks to the API server of the cluster the faucet runs in, with
+// the credentials of the pod's service account.
+type k8sClient struct {
+	http      *http.Client
+	base      string
+	namespace string
+}
+
+// newK8sClient creates an API server client from the in-cluster configuration.
+func newK8sClient() (*k8sClient, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("not running in a Kubernetes pod")
+	}
+	namespace, err := ioutil.ReadFile(k8sNamespacePath)
+	if err != nil {
+		return nil, err
+	}
+	ca, err := ioutil.ReadFile(k8sCAPath)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("no certificates found in the cluster CA bundle")
+	}
+	return &k8sClient{
+		http: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		},
+		base:      "https://" + host + ":" + port,
+		namespace: strings.TrimSpace(string(namespace)),
+	}, nil
+}
+
+// do sends a request to the API server, decoding the response into out. It
+// reports whether the object exists.
+func (c *k8sClient) do(ctx context.Context, method, path string, in interface{}, out *k8sLease) (bool, error) {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return false, err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	// Service account tokens are rotated by the kubelet, read the current one
+	token, err := ioutil.ReadFile(k8sTokenPath)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return false, nil
+	case res.StatusCode == http.StatusConflict:
+		return false, errLeaseConflict
+	case res.StatusCode >= 300:
+		blob, _ := ioutil.ReadAll(res.Body)
+		return false, fmt.Errorf("%s %s: %s: %s", method, path, res.Status, strings.TrimSpace(string(blob)))
+	}
+	return true, json.NewDecoder(res.Body).Decode(out)
+}
+
+// leasePath is the API path of the named lease, or of the lease collection.
+func (c *k8sClient) leasePath(name string) string {
+	path := "/apis/coordination.k8s.io/v1/namespaces/" + c.namespace + "/leases"
+	if name != "" {
+		path += "/" + name
+	}
+	return path
+}
+
+// acquire creates, renews or takes over the lease for the given holder, and
+// reports whether the holder holds it afterwards.
+func (c *k8sClient) acquire(ctx context.Context, name, holder string, duration time.Duration) (bool, error) {
+	now := time.Now()
+	lease := new(k8sLease)
+	found, err := c.do(ctx, http.MethodGet, c.leasePath(name), nil, lease)
+	if err != nil {
+		return false, err
+	}
+	if found && lease.Spec.HolderIdentity != holder && !lease.expired(now) {
+		return false, nil
+	}
+	if !found {
+		lease.APIVersion, lease.Kind = "coordination.k8s.io/v1", "Lease"
+		lease.Metadata.Name, lease.Metadata.Namespace = name, c.namespace
+	}
+	if lease.Spec.HolderIdentity != holder {
+		if found {
+			lease.Spec.LeaseTransitions++
+		}
+		lease.Spec.HolderIdentity = holder
+		lease.Spec.AcquireTime = now.UTC().Format(k8sMicroTime)
+	}
+	lease.Spec.LeaseDurationSeconds = int((duration + time.Second - 1) / time.Second)
+	lease.Spec.RenewTime = now.UTC().Format(k8sMicroTime)
+
+	// The resource version carried along makes concurrent takeovers conflict
+	if found {
+		_, err = c.do(ctx, http.MethodPut, c.leasePath(name), lease, new(k8sLease))
+	} else {
+		_, err = c.do(ctx, http.MethodPost, c.leasePath(""), lease, new(k8sLease))
+	}
+	if errors.Is(err, errLeaseConflict) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// startK8sLease runs the faucet as one of several replicas competing for a
+// Kubernetes Lease, sending only while holding it.
+func startK8sLease(s *Server) {
+	client, err := newK8sClient()
+	if err != nil {
+		log.Fatal("Failed to set up the Kubernetes lease: ", err)
+	}
+	atomic.StoreUint32(&s.passive, 1)
+	log.Info("Competing for Kubernetes lease ", client.namespace, "/", *k8sLeaseFlag)
+	go s.watchK8sLease(client)
+}
+
+// watchK8sLease periodically acquires or renews the lease, switching between
+// sending and standing by as it is won or lost.
+func (s *Server) watchK8sLease(client *k8sClient) {
+	ticker := time.NewTicker(*standbyIntervalFlag)
+	defer ticker.Stop()
+
+	var (
+		owner    = leaseOwner()
+		duration = 3 * (*standbyIntervalFlag)
+		renewed  time.Time
+	)
+	for ; ; <-ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), *standbyIntervalFlag)
+		held, err := client.acquire(ctx, *k8sLeaseFlag, owner, duration)
+		cancel()
+
+		if err != nil {
+			log.Error("Failed to renew Kubernetes lease err: ", err)
+			// Keep sending until the lease may have expired for the others
+			if s.isPassive() || time.Since(renewed) < duration-*standbyIntervalFlag {
+				continue
+			}
+			held = false
+		}
+		if held {
+			renewed = time.Now()
+			if atomic.CompareAndSwapUint32(&s.passive, 1, 0) {
+				log.Info("Acquired Kubernetes lease ", *k8sLeaseFlag, ", sending")
+				alert("failover", "replica %s acquired the sender lease %s", owner, *k8sLeaseFlag)
+			}
+			continue
+		}
+		if atomic.CompareAndSwapUint32(&s.passive, 0, 1) {
+			log.Error("Lost Kubernetes lease ", *k8sLeaseFlag, ", standing by")
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/sunvim/utils/log"
+)
+
+var l2Flag = flag.String("chain.l2", "", "L2 stack of the funded chain, enabling L2 fee estimation and L1 finality reporting (optimism, arbitrum)")
+
+// gasPriceOracle is the OP-stack predeploy quoting the L1 data fee of a transaction.
+var gasPriceOracle = common.HexToAddress("0x420000000000000000000000000000000000000F")
+
+// gasPriceOracleABI is the part of the OP-stack gas price oracle used by the faucet.
+var gasPriceOracleABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(`[{"type":"function","name":"getL1Fee","stateMutability":"view","inputs":[{"name":"data","type":"bytes"}],"outputs":[{"name":"","type":"uint256"}]}]`))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// validateL2 checks the configured L2 stack, if any.
+func validateL2() error {
+	switch *l2Flag {
+	case "", "optimism", "arbitrum":
+		return nil
+	}
+	return fmt.Errorf("unknown L2 stack %q (optimism, arbitrum)", *l2Flag)
+}
+
+// newL2Transaction creates a dynamic fee transaction, which both supported L2
+// stacks price more accurately than legacy ones. The returned gas price is the
+// maximum paid per unit of gas.
+func (b *evmBackend) newL2Transaction(ctx context.Context, nonce uint64, to common.Address, amount *big.Int, data []byte, gasLimit uint64) (*types.Transaction, *big.Int, error) {
+	rctx, cancel := rpcContext(ctx)
+	tip, err := b.client.SuggestGasTipCap(rctx)
+	cancel()
+	if err != nil {
+		return nil, nil, err
+	}
+	rctx, cancel = rpcContext(ctx)
+	head, err := b.client.HeaderByNumber(rctx, nil)
+	cancel()
+	if err != nil {
+		return nil, nil, err
+	}
+	if head.BaseFee == nil {
+		return nil, nil, fmt.Errorf("chain does not support dynamic fee transactions")
+	}
+	feeCap := new(big.Int).Add(new(big.Int).Mul(head.BaseFee, big.NewInt(2)), tip)
+
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(*chainID),
+		Nonce:     nonce,
+		GasTipCap: tip,
+		GasFeeCap: feeCap,
+		Gas:       gasLimit,
+		To:        &to,
+		Value:     amount,
+		Data:      data,
+	})
+	return tx, feeCap, nil
+}
+
+// recordL1Fee adds the L1 data fee of an OP-stack transaction to the gas spent
+// today, as it is charged on top of the L2 execution gas.
+func (b *evmBackend) recordL1Fee(ctx context.Context, tx *types.Transaction) {
+	blob, err := tx.MarshalBinary()
+	if err != nil {
+		log.Error("Failed to encode transaction for L1 fee err: ", err)
+		return
+	}
+	input, err := gasPriceOracleABI.Pack("getL1Fee", blob)
+	if err != nil {
+		log.Error("Failed to pack L1 fee query err: ", err)
+		return
+	}
+	rctx, cancel := rpcContext(ctx)
+	output, err := b.client.CallContract(rctx, ethereum.CallMsg{To: &gasPriceOracle, Data: input}, nil)
+	cancel()
+	if err != nil {
+		log.Error("Failed to query L1 data fee err: ", err)
+		return
+	}
+	fee := new(big.Int).SetBytes(output)
+	log.Info("L1 data fee of ", tx.Hash().Hex(), ": ", fee)
+	addGasSpend(fee)
+}
+
+// FinalityReporter is implemented by chain backends able to tell how far the
+// chain is secured, so that L2 payouts can be followed until finalized on L1.
+type FinalityReporter interface {
+	// Finality retrieves the numbers of the latest finalized and safe blocks.
+	// On an L2, "safe" blocks have their data posted to L1 and "finalized"
+	// blocks are backed by a finalized L1 block.
+	Finality(ctx context.Context) (finalized uint64, safe uint64, err error)
+}
+
+// Finality retrieves the latest blocks with the finalized and safe tags.
+func (b *evmBackend) Finality(ctx context.Context) (finalized uint64, safe uint64, err error) {
+	if finalized, err = b.blockTag(ctx, "finalized"); err != nil {
+		return 0, 0, err
+	}
+	if safe, err = b.blockTag(ctx, "safe"); err != nil {
+		return 0, 0, err
+	}
+	return finalized, safe, nil
+}
+
+// blockTag retrieves the number of the latest block with the given tag.
+func (b *evmBackend) blockTag(ctx context.Context, tag string) (uint64, error) {
+	if b.rpc == nil {
+		return 0, errors.New("no raw RPC access")
+	}
+	var head struct {
+		Number hexutil.Uint64 `json:"number"`
+	}
+	rctx, cancel := rpcContext(ctx)
+	defer cancel()
+
+	if err := b.rpc.CallContext(rctx, &head, "eth_getBlockByNumber", tag, false); err != nil {
+		return 0, err
+	}
+	return uint64(head.Number), nil
+}
+
+// finalityBucket is the store bucket tracking the L1 status of the mined funding
+// transactions until they are finalized, and a day beyond for clients to see.
+const finalityBucket = "finality"
+
+// L1 statuses of a mined L2 funding transaction.
+const (
+	finalityPending   = "pending"   // Awaiting its L1 batch
+	finalityPosted    = "posted"    // Posted to L1, awaiting finality
+	finalityFinalized = "finalized" // Finalized on L1
+)
+
+// finalityNotes are the notes telling users about the L1 status of their payout.
+var finalityNotes = map[string]string{
+	finalityPending:   "Included in L2 block %d, awaiting L1 batch",
+	finalityPosted:    "Included in L2 block %d, posted to L1 and awaiting finality",
+	finalityFinalized: "Included in L2 block %d, finalized on L1",
+}
+
+// finalityRecord is the L1 status of a mined L2 funding transaction.
+type finalityRecord struct {
+	Tx      string    `json:"tx"`
+	Block   uint64    `json:"block"`  // L2 block the transaction was included in
+	Status  string    `json:"status"` // pending, posted or finalized
+	Updated time.Time `json:"updated"`
+}
+
+// l1Heads retrieves the latest L2 blocks finalized on and posted to L1.
+func (s *Server) l1Heads(ctx context.Context) (finalized uint64, safe uint64, err error) {
+	reporter, ok := unwrapChain(s.chain).(FinalityReporter)
+	if !ok {
+		return 0, 0, errors.New("chain backend doesn't report finality")
+	}
+	return reporter.Finality(ctx)
+}
+
+// l1Status returns the L1 status of an L2 block, given the latest finalized and
+// safe blocks.
+func l1Status(block, finalized, safe uint64) string {
+	switch {
+	case block <= finalized:
+		return finalityFinalized
+	case block <= safe:
+		return finalityPosted
+	default:
+		return finalityPending
+	}
+}
+
+// finalityStage reports the L1 status of the mined funding transaction, so
+// users of an L2 know whether their funds may still be reorged away, and has
+// it tracked until finalized.
+func finalityStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if *l2Flag == "" || c.Receipt == nil {
+			return next(c)
+		}
+		rec := &finalityRecord{Tx: c.Tx.ID(), Block: c.Receipt.Block, Status: finalityPending, Updated: time.Now()}
+
+		finalized, safe, err := c.server.l1Heads(c.ctx)
+		if err != nil {
+			log.Error("Failed to retrieve L1 status of L2 blocks err: ", err)
+		} else {
+			rec.Status = l1Status(rec.Block, finalized, safe)
+			c.Notes = append(c.Notes, translate(c.Lang, finalityNotes[rec.Status], rec.Block))
+		}
+		if err := putJSON(store, finalityBucket, rec.Tx, rec); err != nil {
+			log.Error("Failed to track L1 status err: ", err)
+		}
+		return next(c)
+	}
+}
+
+// startFinality starts updating the L1 status of the tracked funding
+// transactions until they are finalized.
+func startFinality(s *Server) {
+	if *l2Flag == "" {
+		return
+	}
+	go func() {
+		for range time.Tick(time.Minute) {
+			s.trackFinality(time.Now())
+		}
+	}()
+}
+
+// trackFinality updates the L1 status of the tracked funding transactions,
+// dropping the ones finalized over a day ago.
+func (s *Server) trackFinality(now time.Time) {
+	var (
+		updated []*finalityRecord
+		expired []string
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	finalized, safe, err := s.l1Heads(ctx)
+	if err != nil {
+		log.Error("Failed to retrieve L1 status of L2 blocks err: ", err)
+		return
+	}
+	store.Iterate(finalityBucket, func(key string, blob []byte) bool {
+		rec := new(finalityRecord)
+		if err := json.Unmarshal(blob, rec); err != nil {
+			expired = append(expired, key)
+			return true
+		}
+		if rec.Status == finalityFinalized {
+			if now.Sub(rec.Updated) > 24*time.Hour {
+				expired = append(expired, key)
+			}
+			return true
+		}
+		if status := l1Status(rec.Block, finalized, safe); status != rec.Status {
+			rec.Status, rec.Updated = status, now
+			updated = append(updated, rec)
+		}
+		return true
+	})
+	for _, rec := range updated {
+		if err := putJSON(store, finalityBucket, rec.Tx, rec); err != nil {
+			log.Error("Failed to update L1 status err: ", err)
+		}
+	}
+	for _, key := range expired {
+		store.Delete(finalityBucket, key)
+	}
+}
+
+// onFinality reports the current L1 status of a funding transaction (GET
+// /api/finality?tx=0x...).
+func onFinality(w http.ResponseWriter, r *http.Request) {
+	var rec finalityRecord
+	if err := getJSON(store, finalityBucket, r.URL.Query().Get("tx"), &rec); err != nil {
+		writeJSONError(w, http.StatusNotFound, localizeError(negotiateLanguage(r), newUserError("Unknown funding transaction")))
+		return
+	}
+	writeJSON(w, http.StatusOK, &rec)
+}
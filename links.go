@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	claimLinksFlag   = flag.Bool("claim.links", false, "Accept claims via wallet signed links (GET /claim?address=&ts=&sig=)")
+	claimLinkTTLFlag = flag.Duration("claim.links.ttl", 15*time.Minute, "Validity of a signed claim link")
+)
+
+// claimLinksBucket is the store bucket remembering the claim links already used,
+// until they expire and are pruned.
+const claimLinksBucket = "claim-links"
+
+// claimLinkMessage is the message the owner of an address signs (personal_sign)
+// to create a claim link for it.
+func claimLinkMessage(address common.Address, tier uint, ts int64) string {
+	return fmt.Sprintf("%s faucet claim\nAddress: %s\nTier: %d\nTimestamp: %d", *apiName, address.Hex(), tier, ts)
+}
+
+// onClaimLink serves claims made by opening a link signed by the wallet of the
+// funded address, answering with the website showing the outcome.
+func (s *Server) onClaimLink(w http.ResponseWriter, r *http.Request) {
+	lang := negotiateLanguage(r)
+
+	var failure, success string
+	claim, err := s.verifyClaimLink(r, lang)
+	if err == nil {
+		log.Info("Faucet funds requested via link: ", claim.Address, " tier: ", claim.Tier)
+		err = s.handle(claim)
+	}
+	status := http.StatusOK
+	if err != nil {
+		failure = localizeError(lang, err).Error()
+		if claim != nil {
+			failure += siblingsNote(claim)
+		}
+		status = http.StatusBadRequest
+	} else {
+		success = successMessage(claim)
+	}
+	website, err := s.renderWebsite(lang, failure, success)
+	if err != nil {
+		log.Error("Failed to render the faucet template", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(website)
+}
+
+// verifyClaimLink checks the signature and freshness of a claim link and marks
+// it used, returning the claim it stands for.
+func (s *Server) verifyClaimLink(r *http.Request, lang string) (*Claim, error) {
+	if !*claimLinksFlag {
+		return nil, newUserError("Claim links are disabled on this faucet")
+	}
+	query := r.URL.Query()
+	if !common.IsHexAddress(query.Get("address")) {
+		return nil, newUserError("Invalid address to fund")
+	}
+	address := common.HexToAddress(query.Get("address"))
+
+	var tier uint64
+	if query.Get("tier") != "" {
+		var err error
+		if tier, err = strconv.ParseUint(query.Get("tier"), 10, 32); err != nil {
+			return nil, newUserError("Invalid funding tier requested")
+		}
+	}
+	ts, err := strconv.ParseInt(query.Get("ts"), 10, 64)
+	if err != nil {
+		return nil, newUserError("Claim link invalid or expired")
+	}
+	if age := time.Since(time.Unix(ts, 0)); age < -time.Minute || age > *claimLinkTTLFlag {
+		return nil, newUserError("Claim link invalid or expired")
+	}
+	signer, err := recoverSigner(claimLinkMessage(address, uint(tier), ts), query.Get("sig"))
+	if err != nil || signer != address {
+		return nil, newUserError("Signature does not match the address to fund")
+	}
+	// Burn the link, so it can't be replayed within its validity
+	err = store.Update(claimLinksBucket, fmt.Sprintf("%s:%d", address.Hex(), ts), func(blob []byte) ([]byte, error) {
+		if blob != nil {
+			return nil, newUserError("Claim link already used")
+		}
+		return []byte(strconv.FormatInt(ts, 10)), nil
+	})
+	var uerr *userError
+	if errors.As(err, &uerr) {
+		return nil, err
+	}
+	if err != nil {
+		log.Error("Failed to burn claim link err: ", err)
+		return nil, newUserError("Claim link invalid or expired")
+	}
+	claim := s.newClaim(r.Context(), r, &fundRequest{URL: address.Hex(), Tier: uint(tier)}, lang)
+	claim.owned, claim.link = true, true
+	return claim, nil
+}
+
+// startClaimLinks starts dropping the used claim links once they expired, as
+// they can't be replayed anymore by then.
+func startClaimLinks() {
+	if !*claimLinksFlag {
+		return
+	}
+	go func() {
+		for range time.Tick(time.Minute) {
+			pruneClaimLinks(time.Now())
+		}
+	}()
+}
+
+// pruneClaimLinks drops the used claim links expired by the given time.
+func pruneClaimLinks(now time.Time) {
+	var expired []string
+	store.Iterate(claimLinksBucket, func(key string, blob []byte) bool {
+		ts, err := strconv.ParseInt(string(blob), 10, 64)
+		if err != nil || now.Sub(time.Unix(ts, 0)) > *claimLinkTTLFlag {
+			expired = append(expired, key)
+		}
+		return true
+	})
+	for _, key := range expired {
+		if err := store.Delete(claimLinksBucket, key); err != nil {
+			log.Error("Failed to drop expired claim link err: ", err)
+		}
+	}
+}
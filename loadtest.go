@@ -0,0 +1,175 @@
+package main
+
+import (
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gorilla/websocket"
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	loadTargetFlag  = flag.String("loadtest.target", "", "Websocket API of the faucet to load test, e.g. wss://faucet.example.org/api (empty = an in-process faucet on the simulated chain)")
+	loadClientsFlag = flag.Int("loadtest.clients", 10, "Number of concurrent synthetic websocket clients")
+	loadClaimsFlag  = flag.Int("loadtest.claims", 10, "Number of claims each synthetic client makes, one after the other")
+	loadTierFlag    = flag.Uint("loadtest.tier", 0, "Funding tier the synthetic clients claim")
+	loadTimeoutFlag = flag.Duration("loadtest.timeout", time.Minute, "Time to wait for the outcome of a single claim")
+)
+
+// loadResult is the outcome of a single synthetic claim.
+type loadResult struct {
+	latency time.Duration
+	err     string // Error reported by the faucet or the connection, if any
+}
+
+// runLoadTest runs synthetic websocket clients claiming funds against a faucet
+// and prints a report of the claim latencies and errors. It returns the process
+// exit code: nonzero if the load test could not be run at all.
+func runLoadTest() int {
+	target := *loadTargetFlag
+	if target == "" {
+		var err error
+		if target, err = startLoadTestFaucet(); err != nil {
+			fmt.Println("Failed to start the in-process faucet:", err)
+			return 1
+		}
+	}
+	fmt.Printf("Load testing %s with %d clients making %d claims each\n", target, *loadClientsFlag, *loadClaimsFlag)
+
+	var (
+		lock    sync.Mutex
+		results []loadResult
+		wg      sync.WaitGroup
+	)
+	start := time.Now()
+	for i := 0; i < *loadClientsFlag; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, res := range runLoadClient(target) {
+				lock.Lock()
+				results = append(results, res)
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	fmt.Print(loadReport(results, time.Since(start)))
+	return 0
+}
+
+// startLoadTestFaucet serves a faucet on the simulated chain on a loopback port,
+// trusting forwarded client IPs so every synthetic client has its own.
+func startLoadTestFaucet() (string, error) {
+	log.SetLevel(log.LevelError)
+	*chainBackendFlag = "sim"
+	*apiProxyFlag = true
+	s := setupFaucet()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	go http.Serve(listener, newAPIHandler(s))
+	return "ws://" + listener.Addr().String() + publicPrefix() + "/api", nil
+}
+
+// runLoadClient makes the claims of a single synthetic client over one
+// websocket connection, each for a fresh random address.
+func runLoadClient(target string) []loadResult {
+	results := make([]loadResult, 0, *loadClaimsFlag)
+	fail := func(err error) []loadResult {
+		for len(results) < *loadClaimsFlag {
+			results = append(results, loadResult{err: err.Error()})
+		}
+		return results
+	}
+	ip := make([]byte, 4)
+	rand.Read(ip)
+	header := http.Header{"X-Forwarded-For": {net.IP(ip).String()}}
+
+	conn, _, err := websocket.DefaultDialer.Dial(target, header)
+	if err != nil {
+		return fail(err)
+	}
+	defer conn.Close()
+
+	for len(results) < *loadClaimsFlag {
+		var address common.Address
+		rand.Read(address[:])
+
+		start := time.Now()
+		conn.SetWriteDeadline(start.Add(*loadTimeoutFlag))
+		if err := conn.WriteJSON(map[string]interface{}{"url": address.Hex(), "tier": *loadTierFlag}); err != nil {
+			return fail(err)
+		}
+		conn.SetReadDeadline(start.Add(*loadTimeoutFlag))
+		for {
+			var reply map[string]interface{}
+			if err := conn.ReadJSON(&reply); err != nil {
+				return fail(err)
+			}
+			if msg, ok := reply["error"]; ok {
+				results = append(results, loadResult{latency: time.Since(start), err: fmt.Sprint(msg)})
+				break
+			}
+			if _, ok := reply["success"]; ok {
+				results = append(results, loadResult{latency: time.Since(start)})
+				break
+			}
+			// Queue positions and status updates, keep waiting for the outcome
+		}
+	}
+	return results
+}
+
+// loadReport summarizes the results of a load test: throughput, latency
+// percentiles of the successful claims and the most frequent errors.
+func loadReport(results []loadResult, elapsed time.Duration) string {
+	var (
+		latencies []time.Duration
+		errs      = make(map[string]int)
+	)
+	for _, res := range results {
+		if res.err != "" {
+			errs[res.err]++
+			continue
+		}
+		latencies = append(latencies, res.latency)
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Claims:     %d in %s (%.1f/s)\n", len(results), elapsed.Round(time.Millisecond), float64(len(results))/elapsed.Seconds())
+	fmt.Fprintf(&b, "Succeeded:  %d\n", len(latencies))
+	fmt.Fprintf(&b, "Failed:     %d (%.1f%%)\n", len(results)-len(latencies), 100*float64(len(results)-len(latencies))/float64(len(results)))
+	if len(latencies) > 0 {
+		percentile := func(p float64) time.Duration {
+			return latencies[int(p*float64(len(latencies)-1))].Round(time.Millisecond)
+		}
+		fmt.Fprintf(&b, "Latency:    p50 %s, p90 %s, p99 %s, max %s\n", percentile(0.5), percentile(0.9), percentile(0.99), percentile(1))
+	}
+	if len(errs) > 0 {
+		messages := make([]string, 0, len(errs))
+		for msg := range errs {
+			messages = append(messages, msg)
+		}
+		sort.Slice(messages, func(i, j int) bool { return errs[messages[i]] > errs[messages[j]] })
+		if len(messages) > 5 {
+			messages = messages[:5]
+		}
+		b.WriteString("Top errors:\n")
+		for _, msg := range messages {
+			fmt.Fprintf(&b, "  %6d  %s\n", errs[msg], msg)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	logFormatFlag         = flag.String("log.format", "text", "Format of the faucet log written to stderr (text, json)")
+	logSampleFlag         = flag.Int("log.sample", 0, "Lines of the same kind logged per sampling interval, further ones are only counted (0 = log everything)")
+	logSampleIntervalFlag = flag.Duration("log.sample.interval", time.Minute, "Interval over which similar log lines are sampled")
+)
+
+// sampleReport is the log line reporting suppressed lines, which is never
+// sampled itself.
+const sampleReport = "Suppressed %d log lines like %q over the last %s"
+
+// setupLogging installs the configured log format and sampling on top of the
+// logger in place. Builds embedding the faucet may install their own logger
+// with log.SetLogger from an init function, which is then sampled the same.
+func setupLogging() error {
+	switch *logFormatFlag {
+	case "text":
+	case "json":
+		log.SetLogger(&jsonLogger{enc: json.NewEncoder(os.Stderr)})
+	default:
+		return fmt.Errorf("unknown log format %q", *logFormatFlag)
+	}
+	if *logSampleFlag > 0 {
+		if *logSampleIntervalFlag <= 0 {
+			return fmt.Errorf("sampling interval must be positive")
+		}
+		sampler := &sampledLogger{inner: log.GetLogger(), burst: *logSampleFlag, seen: make(map[string]int)}
+		log.SetLogger(sampler)
+		go sampler.loop(*logSampleIntervalFlag)
+	}
+	return nil
+}
+
+// jsonLogger writes every log line as a JSON object, for log aggregators.
+type jsonLogger struct {
+	lock sync.Mutex
+	enc  *json.Encoder
+}
+
+func (l *jsonLogger) Log(v ...interface{}) {
+	l.write(fmt.Sprint(v...))
+}
+
+func (l *jsonLogger) Logf(format string, v ...interface{}) {
+	l.write(fmt.Sprintf(format, v...))
+}
+
+func (l *jsonLogger) write(msg string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.enc.Encode(map[string]string{"time": time.Now().UTC().Format(time.RFC3339Nano), "msg": msg})
+}
+
+// sampledLogger passes on a limited number of lines of the same kind per
+// interval, so floods of identical errors (e.g. during a bot attack) don't
+// drown out everything else. Lines are of the same kind if they were logged
+// with the same leading message or format, regardless of the values that
+// follow. The number of suppressed lines is reported once the interval is
+// over.
+type sampledLogger struct {
+	inner log.Logger
+	burst int
+
+	lock sync.Mutex
+	seen map[string]int // Lines per kind in the current interval
+}
+
+// allow counts a line of the given kind and reports whether to pass it on.
+func (l *sampledLogger) allow(kind string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.seen[kind]++
+	return l.seen[kind] <= l.burst
+}
+
+func (l *sampledLogger) Log(v ...interface{}) {
+	// The log package passes the prefix and a separator ahead of the message
+	kind := v
+	if len(kind) > 3 {
+		kind = kind[:3]
+	}
+	if l.allow(fmt.Sprint(kind...)) {
+		l.inner.Log(v...)
+	}
+}
+
+func (l *sampledLogger) Logf(format string, v ...interface{}) {
+	if strings.HasSuffix(format, sampleReport) || l.allow(format) {
+		l.inner.Logf(format, v...)
+	}
+}
+
+// loop starts a new sampling interval every interval, reporting the lines
+// suppressed in the last one.
+func (l *sampledLogger) loop(interval time.Duration) {
+	for range time.Tick(interval) {
+		l.lock.Lock()
+		seen := l.seen
+		l.seen = make(map[string]int)
+		l.lock.Unlock()
+
+		for kind, n := range seen {
+			if n > l.burst {
+				log.Infof(sampleReport, n-l.burst, kind, interval)
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/sunvim/utils/log"
+)
+
+var lookbackFlag = flag.Uint64("cooldown.lookback", 10000, "Blocks to scan on startup for payouts still within their cooldown, so restarts don't reset cooldowns (0 = disabled)")
+
+// lookbackWorkers is the number of blocks fetched concurrently while scanning.
+const lookbackWorkers = 16
+
+// PayoutHistory is implemented by chain backends able to list the transfers
+// the faucet account made, used to restore the cooldowns on startup.
+type PayoutHistory interface {
+	// Payouts calls fn for the plain transfers out of the faucet account
+	// included after the given time, newest first, scanning at most the given
+	// number of blocks back from the head.
+	Payouts(ctx context.Context, since time.Time, blocks uint64, fn func(to string, amount *big.Int, at time.Time)) error
+}
+
+// seedCooldowns restores the cooldowns of the recipients paid out within the
+// longest tier cooldown from the chain, so neither a restart nor a wiped store
+// lets anybody claim twice within their cooldown. The tier of a payout is
+// inferred from its amount: the smallest tier paying at least as much.
+func (s *Server) seedCooldowns() {
+	history, ok := unwrapChain(s.chain).(PayoutHistory)
+	if !ok || *lookbackFlag == 0 || len(payoutTiers) == 0 {
+		return
+	}
+	var longest time.Duration
+	for _, tier := range payoutTiers {
+		if tier.Cooldown > longest {
+			longest = tier.Cooldown
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	now := time.Now()
+	seeded := make(map[string]time.Time)
+	err := history.Payouts(ctx, now.Add(-longest), *lookbackFlag, func(to string, amount *big.Int, at time.Time) {
+		cooldown := payoutTiers[len(payoutTiers)-1].Cooldown
+		for _, tier := range payoutTiers {
+			if tier.Amount.Cmp(amount) >= 0 {
+				cooldown = tier.Cooldown
+				break
+			}
+		}
+		until := at.Add(cooldown - cooldown/288) // Same grace as the rate limiter
+		if until.After(now) && until.After(seeded[to]) {
+			seeded[to] = until
+		}
+	})
+	if err != nil {
+		log.Error("Failed to look back for recent payouts err: ", err)
+	}
+	s.lock.Lock()
+	for address, until := range seeded {
+		if until.After(s.timeouts[address]) {
+			s.timeouts[address] = until
+		}
+	}
+	s.lock.Unlock()
+	log.Info("Restored ", len(seeded), " cooldowns from recent payouts")
+}
+
+// Payouts scans the blocks back from the head for value transfers signed by the
+// faucet key. Contract calls such as token payouts carry their recipient in the
+// call data and are skipped.
+func (b *evmBackend) Payouts(ctx context.Context, since time.Time, blocks uint64, fn func(to string, amount *big.Int, at time.Time)) error {
+	head, err := b.Head(ctx)
+	if err != nil {
+		return err
+	}
+	signer := types.LatestSignerForChainID(b.chainID)
+	for next := head + 1; next > 0 && head+1-next < blocks; {
+		// Fetch the next window of blocks concurrently
+		count := uint64(lookbackWorkers)
+		if count > next {
+			count = next
+		}
+		if left := blocks - (head + 1 - next); count > left {
+			count = left
+		}
+		fetched := make([]*types.Block, count)
+		errs := make([]error, count)
+
+		var wg sync.WaitGroup
+		for i := uint64(0); i < count; i++ {
+			wg.Add(1)
+			go func(i uint64) {
+				defer wg.Done()
+				rctx, cancel := rpcContext(ctx)
+				defer cancel()
+				fetched[i], errs[i] = b.client.BlockByNumber(rctx, new(big.Int).SetUint64(next-1-i))
+			}(i)
+		}
+		wg.Wait()
+		next -= count
+
+		for i, block := range fetched {
+			if errs[i] != nil {
+				return errs[i]
+			}
+			at := time.Unix(int64(block.Time()), 0)
+			if at.Before(since) {
+				return nil
+			}
+			for _, tx := range block.Transactions() {
+				if tx.To() == nil || len(tx.Data()) > 0 || tx.Value().Sign() <= 0 {
+					continue
+				}
+				if from, err := types.Sender(signer, tx); err != nil || from != b.from {
+					continue
+				}
+				fn(tx.To().Hex(), tx.Value(), at)
+			}
+		}
+	}
+	return nil
+}
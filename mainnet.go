@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	mainnetRPCFlag      = flag.String("mainnet.rpc", "", "Mainnet RPC endpoint to require some history of the funded address on (empty = disabled)")
+	mainnetNonceFlag    = flag.Uint64("mainnet.nonce", 1, "Mainnet transactions sufficient for an address to be funded")
+	mainnetBalanceFlag  = flag.Float64("mainnet.balance", 0.001, "Mainnet balance in ether sufficient for an address to be funded")
+	mainnetFailOpenFlag = flag.Bool("mainnet.failopen", false, "Allow claims through if the mainnet RPC is unreachable")
+)
+
+// mainnetVerifiedBucket is the store bucket remembering the addresses found to
+// have mainnet history, sparing the lookups on later claims.
+const mainnetVerifiedBucket = "mainnet-verified"
+
+// mainnet is the lazily dialed client of the mainnet RPC.
+var mainnet struct {
+	once   sync.Once
+	client *ethclient.Client
+	err    error
+}
+
+// mainnetStage requires the funded address to have some history on mainnet, a
+// simple but effective filter against addresses generated en masse.
+func mainnetStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if *mainnetRPCFlag == "" {
+			return next(c)
+		}
+		active, err := hasMainnetHistory(c.ctx, common.HexToAddress(c.Address))
+		if err != nil {
+			log.Error("Failed to check mainnet history err: ", err)
+			if *mainnetFailOpenFlag {
+				return next(c)
+			}
+			return newUserError("Eligibility check unavailable, try again later")
+		}
+		if !active {
+			return newUserError("Address %s has no mainnet activity, only addresses used on mainnet are funded", c.Address)
+		}
+		return next(c)
+	}
+}
+
+// hasMainnetHistory reports whether an address sent enough transactions on
+// mainnet or holds more than dust there.
+func hasMainnetHistory(ctx context.Context, addr common.Address) (bool, error) {
+	if _, err := store.Get(mainnetVerifiedBucket, addr.Hex()); err == nil {
+		return true, nil
+	}
+	mainnet.once.Do(func() {
+		mainnet.client, mainnet.err = ethclient.Dial(*mainnetRPCFlag)
+	})
+	if mainnet.err != nil {
+		return false, mainnet.err
+	}
+	rctx, cancel := rpcContext(ctx)
+	nonce, err := mainnet.client.NonceAt(rctx, addr, nil)
+	cancel()
+	if err != nil {
+		return false, err
+	}
+	active := nonce >= *mainnetNonceFlag
+	if !active {
+		rctx, cancel := rpcContext(ctx)
+		balance, err := mainnet.client.BalanceAt(rctx, addr, nil)
+		cancel()
+		if err != nil {
+			return false, err
+		}
+		active = balance.Cmp(toWei(*mainnetBalanceFlag)) >= 0
+	}
+	if active {
+		if err := store.Put(mainnetVerifiedBucket, addr.Hex(), []byte{1}); err != nil {
+			log.Error("Failed to remember mainnet history err: ", err)
+		}
+	}
+	return active, nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// metric is a single metric family exported in the Prometheus text format.
+type metric struct {
+	name    string
+	help    string
+	kind    string          // "counter" or "gauge"
+	collect func() []sample // Current samples of the family
+}
+
+// sample is a single value of a metric family, with an optional label.
+type sample struct {
+	label string // Label assignment, e.g. `outcome="success"`, if any
+	value float64
+}
+
+// registry holds every metric exported by the faucet, in registration order.
+var registry = struct {
+	lock    sync.Mutex
+	metrics []*metric
+}{}
+
+// register adds a metric family to the exported ones.
+func register(m *metric) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	registry.metrics = append(registry.metrics, m)
+}
+
+// registerGauge exports a gauge whose value is retrieved on every scrape.
+func registerGauge(name string, help string, fn func() float64) {
+	register(&metric{name: name, help: help, kind: "gauge", collect: func() []sample {
+		return []sample{{value: fn()}}
+	}})
+}
+
+// counter is a monotonically increasing metric.
+type counter struct {
+	value uint64
+}
+
+// newCounter creates and exports a counter.
+func newCounter(name string, help string) *counter {
+	c := new(counter)
+	register(&metric{name: name, help: help, kind: "counter", collect: func() []sample {
+		return []sample{{value: float64(atomic.LoadUint64(&c.value))}}
+	}})
+	return c
+}
+
+// Inc increments the counter by one.
+func (c *counter) Inc() {
+	atomic.AddUint64(&c.value, 1)
+}
+
+// counterVec is a family of counters partitioned by the value of a label.
+type counterVec struct {
+	label    string
+	lock     sync.Mutex
+	counters map[string]*counter
+}
+
+// newCounterVec creates and exports a family of counters by label value.
+func newCounterVec(name string, help string, label string) *counterVec {
+	v := &counterVec{label: label, counters: make(map[string]*counter)}
+	register(&metric{name: name, help: help, kind: "counter", collect: v.collect})
+	return v
+}
+
+// With returns the counter of the given label value, creating it if needed.
+func (v *counterVec) With(value string) *counter {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	c, ok := v.counters[value]
+	if !ok {
+		c = new(counter)
+		v.counters[value] = c
+	}
+	return c
+}
+
+func (v *counterVec) collect() []sample {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	samples := make([]sample, 0, len(v.counters))
+	for value, c := range v.counters {
+		samples = append(samples, sample{
+			label: fmt.Sprintf("%s=%q", v.label, value),
+			value: float64(atomic.LoadUint64(&c.value)),
+		})
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].label < samples[j].label })
+	return samples
+}
+
+// onMetrics exports all metrics in the Prometheus text format.
+func onMetrics(w http.ResponseWriter, r *http.Request) {
+	registry.lock.Lock()
+	metrics := append([]*metric{}, registry.metrics...)
+	registry.lock.Unlock()
+
+	var out strings.Builder
+	for _, m := range metrics {
+		fmt.Fprintf(&out, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for _, s := range m.collect() {
+			name := m.name
+			if s.label != "" {
+				name += "{" + s.label + "}"
+			}
+			fmt.Fprintf(&out, "%s %s\n", name, formatValue(s.value))
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(out.String()))
+}
+
+// formatValue formats a sample value as expected by Prometheus.
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return fmt.Sprintf("%g", v)
+}
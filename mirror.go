@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	mirrorURLFlag    = flag.String("mirror.url", "", "Analysis endpoint to POST the metadata of every claim to, fire-and-forget (empty = disabled)")
+	mirrorBufferFlag = flag.Int("mirror.buffer", 1000, "Claims buffered for the analysis endpoint before further ones are dropped")
+)
+
+var (
+	mirrorDropped = newCounter("faucet_mirror_dropped_total", "Claims not mirrored because the buffer was full.")
+	mirrorFailed  = newCounter("faucet_mirror_failed_total", "Claims the analysis endpoint failed to accept.")
+)
+
+// mirrorQueue buffers the claims awaiting delivery to the analysis endpoint.
+var mirrorQueue chan *claimMetadata
+
+// claimMetadata is the full record of a claim mirrored to the analysis endpoint.
+type claimMetadata struct {
+	Time     time.Time              `json:"time"`
+	Duration float64                `json:"duration"` // Seconds spent in the pipeline
+	ID       string                 `json:"id,omitempty"`
+	Address  string                 `json:"address"`
+	Tier     uint                   `json:"tier"`
+	IP       string                 `json:"ip"`
+	Lang     string                 `json:"lang"`
+	Website  bool                   `json:"website"`
+	Subject  string                 `json:"subject,omitempty"`
+	Voucher  string                 `json:"voucher,omitempty"`
+	Referral string                 `json:"referral,omitempty"`
+	Amount   string                 `json:"amount,omitempty"` // Payout in wei, decimal
+	Cooldown float64                `json:"cooldown"`         // Seconds until the next allowance
+	Score    float64                `json:"score"`
+	Risk     float64                `json:"risk"`
+	Tx       string                 `json:"tx,omitempty"`
+	Error    string                 `json:"error,omitempty"` // Why the claim was turned away, if it was
+	Values   map[string]interface{} `json:"values,omitempty"`
+}
+
+// startMirror starts delivering claims to the analysis endpoint, if enabled.
+func startMirror() {
+	if *mirrorURLFlag == "" {
+		return
+	}
+	mirrorQueue = make(chan *claimMetadata, *mirrorBufferFlag)
+	go loopMirror()
+}
+
+// mirrorStage records the outcome of every claim for the analysis endpoint
+// without holding up the claim itself.
+func mirrorStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if mirrorQueue == nil {
+			return next(c)
+		}
+		start := time.Now()
+		err := next(c)
+
+		meta := &claimMetadata{
+			Time:     start,
+			Duration: time.Since(start).Seconds(),
+			ID:       c.ID,
+			Address:  c.Address,
+			Tier:     c.Tier,
+			IP:       remoteHost(c.IP),
+			Lang:     c.Lang,
+			Website:  c.website,
+			Subject:  c.Subject,
+			Voucher:  c.Voucher,
+			Referral: c.Referral,
+			Cooldown: c.Cooldown.Seconds(),
+			Score:    c.Score,
+			Risk:     c.Risk,
+			Values:   make(map[string]interface{}, len(c.Values)),
+		}
+		if c.Amount != nil {
+			meta.Amount = c.Amount.String()
+		}
+		if c.Tx != nil {
+			meta.Tx = c.Tx.ID()
+		}
+		if err != nil {
+			meta.Error = err.Error()
+		}
+		for key, value := range c.Values {
+			meta.Values[key] = value
+		}
+		select {
+		case mirrorQueue <- meta:
+		default:
+			mirrorDropped.Inc()
+		}
+		return err
+	}
+}
+
+// loopMirror delivers the buffered claims to the analysis endpoint one by one.
+func loopMirror() {
+	for meta := range mirrorQueue {
+		if err := postMirror(meta); err != nil {
+			log.Error("Failed to mirror claim err: ", err)
+			mirrorFailed.Inc()
+		}
+	}
+}
+
+// postMirror POSTs the metadata of a single claim to the analysis endpoint.
+func postMirror(meta *claimMetadata) error {
+	blob, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, *mirrorURLFlag, bytes.NewReader(blob))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+	return nil
+}
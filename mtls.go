@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	adminTLSFlag      = flag.Bool("admin.tls", false, "Serve the admin API over TLS with the --crt and --key certificate")
+	adminClientCAFlag = flag.String("admin.clientca", "", "CA bundle (PEM) admin API clients must present a certificate of, implies --admin.tls (empty = no client certificates)")
+	apiClientCAFlag   = flag.String("api.clientca", "", "CA bundle (PEM) website and API clients must present a certificate of, for private deployments served with --https (empty = no client certificates)")
+)
+
+// serverTLS returns the TLS configuration of a listener, requiring clients to
+// present certificates signed by the given CA bundle if set. Nil is returned if
+// the listener is served in plaintext.
+func serverTLS(enabled bool, clientCA string) *tls.Config {
+	if !enabled {
+		return nil
+	}
+	config := new(tls.Config)
+	if clientCA != "" {
+		pem, err := ioutil.ReadFile(clientCA)
+		if err != nil {
+			log.Fatal("Failed to read the client CA bundle: ", err)
+		}
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(pem) {
+			log.Fatal("No certificates found in the client CA bundle ", clientCA)
+		}
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config
+}
+
+// certIdentity identifies the client of a request by its verified certificate,
+// as the subject's common name and the certificate's serial number. It is empty
+// for requests without one.
+func certIdentity(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return ""
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+	return cert.Subject.CommonName + "#" + cert.SerialNumber.Text(16)
+}
+
+// clientIdentity describes the client of a request for the audit trail, by its
+// certificate if it presented one, by its address otherwise.
+func clientIdentity(r *http.Request) string {
+	if identity := certIdentity(r); identity != "" {
+		return identity
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	nftContractFlag = flag.String("nft.contract", "", "ERC-721 contract to mint a test NFT from to every requester besides the funds (empty = disabled)")
+	nftMethodFlag   = flag.String("nft.method", "mint", "Mint function of the NFT contract, taking the recipient address as its only argument")
+	nftTimeoutFlag  = flag.Duration("nft.timeout", time.Minute, "Time to wait for the mint to be mined to report the token ID")
+)
+
+// transferTopic is the topic of the ERC-721 Transfer(from, to, tokenId) event.
+var transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// nftLock serializes the mints of claims paid out in the same batch, as those
+// run concurrently up to the send stage.
+var nftLock sync.Mutex
+
+// mintStage mints a test NFT to the funded address ahead of sending the funds,
+// while the claim still holds the sender.
+func mintStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if *nftContractFlag == "" {
+			return next(c)
+		}
+		method, err := abi.JSON(strings.NewReader(fmt.Sprintf(`[{"type":"function","name":%q,"stateMutability":"nonpayable","inputs":[{"name":"to","type":"address"}],"outputs":[]}]`, *nftMethodFlag)))
+		if err != nil {
+			return err
+		}
+		data, err := method.Pack(*nftMethodFlag, common.HexToAddress(c.Address))
+		if err != nil {
+			return err
+		}
+		nftLock.Lock()
+		c.Mint, err = c.server.sendTx(c.ctx, *nftContractFlag, new(big.Int), data)
+		nftLock.Unlock()
+		if err != nil {
+			log.Error("Failed to mint NFT err: ", err)
+			return newUserError("Failed to mint NFT, try again later")
+		}
+		return next(c)
+	}
+}
+
+// mintedStage waits for the mint of the claim to be mined and extracts the ID
+// of the minted token from its Transfer event.
+func mintedStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if c.Mint == nil {
+			return next(c)
+		}
+		tokenID, err := c.server.mintedToken(c.ctx, c.Mint, c.Address)
+		if err != nil {
+			log.Error("Failed to retrieve minted NFT err: ", err)
+			c.Notes = append(c.Notes, translate(c.Lang, "NFT mint %s pending", c.Mint.ID()))
+			return next(c)
+		}
+		c.TokenID = tokenID
+		c.Notes = append(c.Notes, translate(c.Lang, "Minted NFT #%s", tokenID))
+		return next(c)
+	}
+}
+
+// mintedToken waits for a mint transaction and returns the ID of the token it
+// transferred to the recipient.
+func (s *Server) mintedToken(ctx context.Context, tx ChainTx, to string) (*big.Int, error) {
+	ctx, cancel := context.WithTimeout(ctx, *nftTimeoutFlag)
+	defer cancel()
+
+	receipt, err := s.chain.Confirm(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range receipt.Logs {
+		if common.HexToAddress(entry.Contract) != common.HexToAddress(*nftContractFlag) || len(entry.Topics) != 4 || common.BytesToHash(entry.Topics[0]) != transferTopic {
+			continue
+		}
+		if common.BytesToAddress(entry.Topics[2]).Hex() == to {
+			return new(big.Int).SetBytes(entry.Topics[3]), nil
+		}
+	}
+	return nil, fmt.Errorf("no token transferred by mint %s", tx.ID())
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	nonceRepairFlag = flag.Bool("nonce.repair", false, "Replace stuck and fill gapped transactions of the faucet account on startup")
+	nonceStuckFlag  = flag.Duration("nonce.stuck", 10*time.Minute, "Age from which a journaled transaction not mined yet is considered stuck, if missing from the node pool or underpriced")
+)
+
+// nonceReport describes the transactions of the faucet account not yet mined.
+type nonceReport struct {
+	Mined    uint64   `json:"mined"`    // Next nonce to be mined
+	Pending  uint64   `json:"pending"`  // Next nonce to be used
+	InFlight []uint64 `json:"inFlight"` // Nonces broadcast but not mined yet, and not considered stuck
+	Stuck    []uint64 `json:"stuck"`    // Nonces of old journaled transactions missing from the pool or underpriced
+	Unknown  []uint64 `json:"unknown"`  // Nonces not mined yet of transactions not in the journal
+	Queued   []uint64 `json:"queued"`   // Nonces held back by the node behind a gap
+	Gaps     []uint64 `json:"gaps"`     // Missing nonces holding back the queued ones
+
+	prices  map[uint64]*big.Int        // Gas prices of the known pool transactions
+	journal map[uint64]*journaledNonce // Journaled transactions by nonce
+}
+
+// journaledNonce is the latest journaled transaction of a nonce.
+type journaledNonce struct {
+	id    string
+	entry *journalEntry
+}
+
+// txpoolContent is the part of the txpool_contentFrom response of interest.
+type txpoolContent struct {
+	Pending map[string]*txpoolTx `json:"pending"`
+	Queued  map[string]*txpoolTx `json:"queued"`
+}
+
+// txpoolTx is a pool transaction, as far as its fees go.
+type txpoolTx struct {
+	GasPrice     *hexutil.Big `json:"gasPrice"`
+	MaxFeePerGas *hexutil.Big `json:"maxFeePerGas"`
+}
+
+// nonceRepair replaces transactions through the same queue as the claims, so
+// the repairs don't race the regular payouts for nonces.
+var nonceRepair = NewPipeline(
+	Stage{"enqueue", enqueueStage},
+	Stage{"repair", repairStage},
+)
+
+// journaledNonces indexes the journaled transactions by nonce.
+func journaledNonces() map[uint64]*journaledNonce {
+	nonces := make(map[uint64]*journaledNonce)
+	store.Iterate(journalBucket, func(key string, blob []byte) bool {
+		entry := new(journalEntry)
+		if err := json.Unmarshal(blob, entry); err != nil || entry.Nonce == nil {
+			return true
+		}
+		if known, ok := nonces[*entry.Nonce]; !ok || entry.Time.After(known.entry.Time) {
+			nonces[*entry.Nonce] = &journaledNonce{id: key, entry: entry}
+		}
+		return true
+	})
+	return nonces
+}
+
+// inspectNonces compares the mined and pending nonces of the faucet account and,
+// if the node exposes its pool, looks for transactions held back by gaps.
+//
+// Unmined transactions are only considered stuck if they are in the journal,
+// were sent longer than --nonce.stuck ago and are either missing from the node
+// pool or priced below the current gas price. Anything else is in flight,
+// likely the payout of a claim the requester was told succeeded, and left be.
+// Without access to the pool, the age of the journal entry alone decides.
+func (s *Server) inspectNonces(ctx context.Context) (*nonceReport, error) {
+	backend, ok := unwrapChain(s.chain).(*evmBackend)
+	if !ok {
+		return nil, errors.New("nonce inspection requires the EVM backend")
+	}
+	mined, pending, err := backend.accountNonces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	suggested, err := backend.gasPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report := &nonceReport{
+		Mined: mined, Pending: pending,
+		InFlight: []uint64{}, Stuck: []uint64{}, Unknown: []uint64{}, Queued: []uint64{}, Gaps: []uint64{},
+		prices:  make(map[uint64]*big.Int),
+		journal: journaledNonces(),
+	}
+	content, err := backend.poolContent(ctx)
+	pooled := err == nil
+	if err != nil {
+		log.Info("Node pool not inspectable, skipping gap detection: ", err)
+		content = new(txpoolContent)
+	}
+	queued := make(map[uint64]bool)
+	for _, pool := range []map[string]*txpoolTx{content.Pending, content.Queued} {
+		for key, tx := range pool {
+			nonce, err := strconv.ParseUint(key, 10, 64)
+			if err != nil {
+				continue
+			}
+			if price := tx.MaxFeePerGas; price != nil {
+				report.prices[nonce] = price.ToInt()
+			} else if price := tx.GasPrice; price != nil {
+				report.prices[nonce] = price.ToInt()
+			}
+			if nonce >= pending {
+				queued[nonce] = true
+				report.Queued = append(report.Queued, nonce)
+			}
+		}
+	}
+	for nonce := mined; nonce < pending; nonce++ {
+		journaled, ok := report.journal[nonce]
+		if !ok {
+			report.Unknown = append(report.Unknown, nonce)
+			continue
+		}
+		price, inPool := report.prices[nonce]
+		stale := time.Since(journaled.entry.Time) >= *nonceStuckFlag
+		if stale && (!pooled || !inPool || price.Cmp(suggested) < 0) {
+			report.Stuck = append(report.Stuck, nonce)
+		} else {
+			report.InFlight = append(report.InFlight, nonce)
+		}
+	}
+	sort.Slice(report.Queued, func(i, j int) bool { return report.Queued[i] < report.Queued[j] })
+	if len(report.Queued) > 0 {
+		for nonce := pending; nonce < report.Queued[len(report.Queued)-1]; nonce++ {
+			if !queued[nonce] {
+				report.Gaps = append(report.Gaps, nonce)
+			}
+		}
+	}
+	return report, nil
+}
+
+// accountNonces retrieves the nonce of the faucet account as of the latest block
+// and including the pending transactions.
+func (b *evmBackend) accountNonces(ctx context.Context) (mined uint64, pending uint64, err error) {
+	rctx, cancel := rpcContext(ctx)
+	defer cancel()
+
+	if mined, err = b.client.NonceAt(rctx, b.from, nil); err != nil {
+		return 0, 0, err
+	}
+	if pending, err = b.client.PendingNonceAt(rctx, b.from); err != nil {
+		return 0, 0, err
+	}
+	return mined, pending, nil
+}
+
+// gasPrice retrieves the gas price currently suggested by the node.
+func (b *evmBackend) gasPrice(ctx context.Context) (*big.Int, error) {
+	rctx, cancel := rpcContext(ctx)
+	defer cancel()
+
+	return b.client.SuggestGasPrice(rctx)
+}
+
+// poolContent retrieves the transactions of the faucet account in the pool of
+// the node, if it exposes its pool.
+func (b *evmBackend) poolContent(ctx context.Context) (*txpoolContent, error) {
+	if b.rpc == nil {
+		return nil, errors.New("no raw RPC access")
+	}
+	rctx, cancel := rpcContext(ctx)
+	defer cancel()
+
+	content := new(txpoolContent)
+	if err := b.rpc.CallContext(rctx, content, "txpool_contentFrom", b.from); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// repairStage resends every stuck transaction of the faucet account at a bumped
+// fee, paying out the same recipient as the journaled original, and fills every
+// gap with a zero value self-send, while holding the sender.
+func repairStage(next Handler) Handler {
+	return func(c *Claim) error {
+		s := c.server
+		backend, ok := unwrapChain(s.chain).(*evmBackend)
+		if !ok {
+			return errors.New("nonce repair requires the EVM backend")
+		}
+		report, err := s.inspectNonces(c.ctx)
+		if err != nil {
+			return err
+		}
+		suggested, err := backend.gasPrice(c.ctx)
+		if err != nil {
+			return err
+		}
+		var sent []string
+		for _, nonce := range append(report.Stuck, report.Gaps...) {
+			// Outbid both the market and the replaced transaction by a margin
+			price := new(big.Int).Mul(suggested, big.NewInt(2))
+			if known, ok := report.prices[nonce]; ok {
+				price = outbid(price, known)
+			}
+			replacement, original := types.NewTransaction(nonce, backend.from, new(big.Int), 21000, price, nil), (*journaledNonce)(nil)
+			if journaled, ok := report.journal[nonce]; ok {
+				tx, err := decodeJournaled(backend, journaled.entry)
+				if err != nil || tx.To() == nil {
+					log.Error("Skipping undecodable journaled transaction with nonce ", nonce, " err: ", err)
+					continue
+				}
+				// Keep paying out the claim, only faster
+				price = outbid(price, tx.GasFeeCap())
+				replacement, original = types.NewTransaction(nonce, *tx.To(), tx.Value(), tx.Gas(), price, tx.Data()), journaled
+			}
+			tx, err := backend.Sign(&evmTx{Transaction: replacement, gasPrice: price})
+			if err == nil && original != nil {
+				err = journalTx(tx, original.entry.To, replacement.Value())
+			}
+			if err == nil {
+				err = backend.Broadcast(c.ctx, tx)
+			}
+			if err != nil {
+				log.Error("Failed to replace transaction with nonce ", nonce, " err: ", err)
+				continue
+			}
+			if original != nil {
+				store.Delete(journalBucket, original.id)
+				log.Info("Replaced stuck transaction ", original.id, " with ", tx.ID())
+			}
+			sent = append(sent, tx.ID())
+		}
+		c.Values["repairs"] = sent
+		return next(c)
+	}
+}
+
+// outbid returns the price, raised to a quarter above the known one if needed
+// for the node to accept a replacement.
+func outbid(price, known *big.Int) *big.Int {
+	if bumped := new(big.Int).Div(new(big.Int).Mul(known, big.NewInt(5)), big.NewInt(4)); bumped.Cmp(price) > 0 {
+		return bumped
+	}
+	return price
+}
+
+// decodeJournaled restores the transaction of a journal entry.
+func decodeJournaled(backend *evmBackend, entry *journalEntry) (*types.Transaction, error) {
+	raw, err := hexutil.Decode(entry.Raw)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := backend.DecodeTx(raw)
+	if err != nil {
+		return nil, err
+	}
+	return tx.(*evmTx).Transaction, nil
+}
+
+// repairNonces runs a nonce repair through the sender, returning the hashes of
+// the replacement transactions.
+func (s *Server) repairNonces(ctx context.Context) ([]string, error) {
+	c := &Claim{ctx: ctx, server: s, IP: "repair", Lang: defaultLanguage, Values: make(map[string]interface{})}
+	if err := nonceRepair.Handler()(c); err != nil {
+		return nil, err
+	}
+	sent, _ := c.Values["repairs"].([]string)
+	return sent, nil
+}
+
+// checkNonces looks for stuck or gapped transactions of the faucet account on
+// startup, alerting the operator and repairing them if requested.
+func checkNonces(s *Server) {
+	if s.isPassive() {
+		return // The primary owns the account
+	}
+	report, err := s.inspectNonces(context.Background())
+	if err != nil {
+		log.Error("Failed to inspect faucet nonces err: ", err)
+		return
+	}
+	if len(report.Unknown) > 0 {
+		alert("nonces", "%d transactions of the faucet account not mined yet are missing from the journal (nonce %d mined, %d pending), these are never replaced automatically", len(report.Unknown), report.Mined, report.Pending)
+	}
+	if len(report.Stuck) == 0 && len(report.Gaps) == 0 {
+		return
+	}
+	if !*nonceRepairFlag {
+		alert("nonces", "%d stuck and %d gapped transactions on the faucet account (nonce %d mined, %d pending), repair via the admin API or --nonce.repair", len(report.Stuck), len(report.Gaps), report.Mined, report.Pending)
+		return
+	}
+	sent, err := s.repairNonces(context.Background())
+	if err != nil {
+		log.Error("Failed to repair faucet nonces err: ", err)
+		return
+	}
+	log.Info("Replaced ", len(sent), " stuck or gapped transactions")
+}
+
+// onAdminNonces reports the unmined transactions of the faucet account (GET) or
+// replaces the stuck and gapped ones (POST).
+func (s *Server) onAdminNonces(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		report, err := s.inspectNonces(r.Context())
+		if err != nil {
+			writeJSONError(w, http.StatusBadGateway, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, report)
+
+	case http.MethodPost:
+		sent, err := s.repairNonces(r.Context())
+		if err != nil {
+			writeJSONError(w, http.StatusBadGateway, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string][]string{"replacements": sent})
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
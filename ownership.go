@@ -0,0 +1,128 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	signatureFlag    = flag.Bool("auth.signature", false, "Require requesters to prove ownership of the funded address by signing a challenge")
+	signatureTTLFlag = flag.Duration("auth.signature.ttl", 5*time.Minute, "Validity of an ownership challenge")
+)
+
+// challengeSecret authenticates the ownership challenges issued by this faucet
+// instance, so they don't need to be tracked server side.
+var challengeSecret = func() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	return secret
+}()
+
+// challengeMAC computes the authentication code of a challenge.
+func challengeMAC(address common.Address, issued int64) string {
+	mac := hmac.New(sha256.New, challengeSecret)
+	fmt.Fprintf(mac, "%s:%d", address.Hex(), issued)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newChallenge creates the message a user needs to sign with the key of the
+// address to fund.
+func newChallenge(address common.Address) string {
+	issued := time.Now().Unix()
+	return fmt.Sprintf("%s faucet ownership proof\nAddress: %s\nIssued: %d\nNonce: %s", *apiName, address.Hex(), issued, challengeMAC(address, issued))
+}
+
+// verifyChallenge checks that a challenge was issued by this faucet for the given
+// address and is still valid.
+func verifyChallenge(challenge string, address common.Address) bool {
+	var (
+		issued int64
+		nonce  string
+		owner  string
+	)
+	for _, line := range strings.Split(challenge, "\n") {
+		switch {
+		case strings.HasPrefix(line, "Address: "):
+			owner = strings.TrimPrefix(line, "Address: ")
+		case strings.HasPrefix(line, "Issued: "):
+			issued, _ = strconv.ParseInt(strings.TrimPrefix(line, "Issued: "), 10, 64)
+		case strings.HasPrefix(line, "Nonce: "):
+			nonce = strings.TrimPrefix(line, "Nonce: ")
+		}
+	}
+	if owner != address.Hex() || challenge != fmt.Sprintf("%s faucet ownership proof\nAddress: %s\nIssued: %d\nNonce: %s", *apiName, owner, issued, nonce) {
+		return false
+	}
+	if age := time.Since(time.Unix(issued, 0)); age < 0 || age > *signatureTTLFlag {
+		return false
+	}
+	return hmac.Equal([]byte(nonce), []byte(challengeMAC(address, issued)))
+}
+
+// recoverSigner returns the address that created a personal_sign (EIP-191)
+// signature over the given message.
+func recoverSigner(message string, signature string) (common.Address, error) {
+	sig, err := hexutil.Decode(signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(sig) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("invalid signature length %d", len(sig))
+	}
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27 // Wallets use the legacy 27/28 recovery ids
+	}
+	pubkey, err := crypto.SigToPub(accounts.TextHash([]byte(message)), sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pubkey), nil
+}
+
+// ownershipStage rejects claims that don't carry a valid signature from the
+// funded address over a challenge issued by the faucet, if required.
+func ownershipStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if !*signatureFlag || c.owned {
+			return next(c)
+		}
+		if c.Challenge == "" || c.Signature == "" {
+			return newUserError("Please sign the ownership challenge with your wallet")
+		}
+		if !verifyChallenge(c.Challenge, common.HexToAddress(c.Address)) {
+			return newUserError("Ownership challenge invalid or expired, please retry")
+		}
+		signer, err := recoverSigner(c.Challenge, c.Signature)
+		if err != nil || signer.Hex() != c.Address {
+			log.Info("Invalid ownership proof: ", c.Address, " signer: ", signer.Hex())
+			return newUserError("Signature does not match the address to fund")
+		}
+		return next(c)
+	}
+}
+
+// onChallenge issues an ownership challenge for the address in the query.
+func onChallenge(w http.ResponseWriter, r *http.Request) {
+	address := r.URL.Query().Get("address")
+	if !common.IsHexAddress(address) {
+		writeJSONError(w, http.StatusBadRequest, localizeError(negotiateLanguage(r), newUserError("Invalid address to fund")))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"challenge": newChallenge(common.HexToAddress(address))})
+}
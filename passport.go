@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	passportAPIFlag     = flag.String("passport.api", "https://api.scorer.gitcoin.co", "Gitcoin Passport compatible scorer API to fetch humanity scores from")
+	passportKeyFlag     = flag.String("passport.key", "", "API key of the passport scorer")
+	passportScorerFlag  = flag.String("passport.scorer", "", "Scorer ID to score passports with")
+	passportScoresFlag  = flag.String("passport.scores", "", "Minimum passport score of each funding tier, e.g. 0,15,25 (empty = disabled)")
+	passportTimeoutFlag = flag.Duration("passport.timeout", 10*time.Second, "Deadline for the passport scorer to score a passport")
+)
+
+// passportScores is the minimum passport score required for each funding tier,
+// nil if passports aren't scored. It's parsed on startup.
+var passportScores []float64
+
+// parsePassportScores parses the per tier minimum passport scores, requiring a
+// non-decreasing score for each of the funding tiers.
+func parsePassportScores(spec string, tiers int) ([]float64, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	if *passportScorerFlag == "" {
+		return nil, fmt.Errorf("no scorer configured")
+	}
+	items := strings.Split(spec, ",")
+	if len(items) != tiers {
+		return nil, fmt.Errorf("%d scores for %d funding tiers", len(items), tiers)
+	}
+	scores := make([]float64, len(items))
+	for i, item := range items {
+		score, err := strconv.ParseFloat(strings.TrimSpace(item), 64)
+		if err != nil || score < 0 {
+			return nil, fmt.Errorf("invalid score %q", item)
+		}
+		if i > 0 && score < scores[i-1] {
+			return nil, fmt.Errorf("tier %d: score must not be lower than the one of tier %d", i, i-1)
+		}
+		scores[i] = score
+	}
+	return scores, nil
+}
+
+// passportScore is the score of a passport as reported by the scorer API.
+type passportScore struct {
+	Status string          `json:"status"` // "PROCESSING", "DONE" or "ERROR"
+	Score  json.RawMessage `json:"score"`  // Decimal string or number, depending on the scorer
+	Error  string          `json:"error"`
+}
+
+// passportStage requires the passport of the funded address to reach the score
+// unlocking the requested tier, if passports are scored.
+func passportStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if passportScores == nil || passportScores[c.Tier] == 0 {
+			return next(c)
+		}
+		score, err := scorePassport(c.ctx, c.Address)
+		if err != nil {
+			log.Error("Failed to score passport err: ", err)
+			return newUserError("Passport scoring unavailable, try again later")
+		}
+		if score < passportScores[c.Tier] {
+			return newUserError("Passport score %s too low for this tier, %s required", formatScore(score), formatScore(passportScores[c.Tier]))
+		}
+		c.Values["passport"] = score
+		return next(c)
+	}
+}
+
+// scorePassport submits the passport of an address for scoring and waits for
+// the scorer to come up with its score.
+func scorePassport(ctx context.Context, address string) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, *passportTimeoutFlag)
+	defer cancel()
+
+	blob, err := json.Marshal(map[string]string{"address": address, "scorer_id": *passportScorerFlag})
+	if err != nil {
+		return 0, err
+	}
+	score, err := queryPassport(ctx, http.MethodPost, "/registry/submit-passport", blob)
+	for err == nil && score.Status == "PROCESSING" {
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(time.Second):
+		}
+		score, err = queryPassport(ctx, http.MethodGet, "/registry/score/"+*passportScorerFlag+"/"+address, nil)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if score.Status != "DONE" {
+		return 0, fmt.Errorf("passport scoring failed: %s %s", score.Status, score.Error)
+	}
+	return strconv.ParseFloat(strings.Trim(string(score.Score), `"`), 64)
+}
+
+// queryPassport makes a single request to the passport scorer API.
+func queryPassport(ctx context.Context, method string, path string, body []byte) (*passportScore, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(*passportAPIFlag, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-API-KEY", *passportKeyFlag)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", res.Status)
+	}
+	var score passportScore
+	if err := json.NewDecoder(res.Body).Decode(&score); err != nil {
+		return nil, err
+	}
+	return &score, nil
+}
+
+// formatScore formats a passport score for display.
+func formatScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
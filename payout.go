@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+var (
+	payoutContractFlag = flag.String("payout.contract", "", "Contract to call as the payout action instead of transferring funds (empty = plain transfers)")
+	payoutMethodFlag   = flag.String("payout.method", "", "Signature of the payout function, e.g. transfer(address,uint256)")
+	payoutArgsFlag     = flag.String("payout.args", "", "Comma separated arguments of the payout function, with $address, $amount and $tier standing for those of the claim")
+	payoutValueFlag    = flag.Bool("payout.value", false, "Attach the payout amount as value to the payout call")
+)
+
+// payoutCall is the parsed payout action, nil if claims are paid out with plain
+// transfers. It's parsed on startup.
+var payoutCall *callTemplate
+
+// callTemplate is a contract call with arguments filled in from the claim.
+type callTemplate struct {
+	to     common.Address
+	method abi.Method
+	args   []string // Literal values or claim placeholders, one per input
+}
+
+// parsePayoutCall parses the configured payout action, if any, checking that
+// the arguments fit the function.
+func parsePayoutCall() (*callTemplate, error) {
+	if *payoutContractFlag == "" {
+		return nil, nil
+	}
+	if !common.IsHexAddress(*payoutContractFlag) {
+		return nil, fmt.Errorf("invalid contract address %q", *payoutContractFlag)
+	}
+	if *batchContractFlag != "" || *streamTokenFlag != "" {
+		return nil, errors.New("payout calls can't be batched or streamed")
+	}
+	sig := strings.TrimSpace(*payoutMethodFlag)
+	open := strings.Index(sig, "(")
+	if open <= 0 || !strings.HasSuffix(sig, ")") {
+		return nil, fmt.Errorf("invalid function signature %q", sig)
+	}
+	var inputs abi.Arguments
+	if types := sig[open+1 : len(sig)-1]; types != "" {
+		for i, kind := range strings.Split(types, ",") {
+			typ, err := abi.NewType(strings.TrimSpace(kind), "", nil)
+			if err != nil {
+				return nil, fmt.Errorf("invalid argument type %q: %v", kind, err)
+			}
+			inputs = append(inputs, abi.Argument{Name: fmt.Sprintf("arg%d", i), Type: typ})
+		}
+	}
+	call := &callTemplate{
+		to:     common.HexToAddress(*payoutContractFlag),
+		method: abi.NewMethod(sig[:open], sig[:open], abi.Function, "", false, *payoutValueFlag, inputs, nil),
+	}
+	if strings.TrimSpace(*payoutArgsFlag) != "" {
+		for _, arg := range strings.Split(*payoutArgsFlag, ",") {
+			call.args = append(call.args, strings.TrimSpace(arg))
+		}
+	}
+	if len(call.args) != len(inputs) {
+		return nil, fmt.Errorf("%d arguments for %d function inputs", len(call.args), len(inputs))
+	}
+	// Make sure the literals fit their types before taking any claims
+	if _, err := call.pack(&Claim{Amount: new(big.Int)}); err != nil {
+		return nil, err
+	}
+	return call, nil
+}
+
+// pack assembles the call data of the payout action for a claim.
+func (t *callTemplate) pack(c *Claim) ([]byte, error) {
+	values := make([]interface{}, len(t.args))
+	for i, arg := range t.args {
+		switch arg {
+		case "$address":
+			arg = c.Address
+		case "$amount":
+			arg = c.Amount.String()
+		case "$tier":
+			arg = strconv.FormatUint(uint64(c.Tier), 10)
+		}
+		value, err := parseCallArg(t.method.Inputs[i].Type, arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %v", i, err)
+		}
+		values[i] = value
+	}
+	data, err := t.method.Inputs.Pack(values...)
+	if err != nil {
+		return nil, err
+	}
+	return append(t.method.ID, data...), nil
+}
+
+// parseCallArg converts a textual argument into the Go type the ABI encoder
+// expects for the given Solidity type.
+func parseCallArg(typ abi.Type, arg string) (interface{}, error) {
+	switch typ.T {
+	case abi.AddressTy:
+		if !common.IsHexAddress(arg) {
+			return nil, fmt.Errorf("invalid address %q", arg)
+		}
+		return common.HexToAddress(arg), nil
+
+	case abi.UintTy, abi.IntTy:
+		n, ok := new(big.Int).SetString(arg, 0)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer %q", arg)
+		}
+		if (typ.T == abi.UintTy && (n.Sign() < 0 || n.BitLen() > typ.Size)) || (typ.T == abi.IntTy && n.BitLen() >= typ.Size) {
+			return nil, fmt.Errorf("integer %q out of range", arg)
+		}
+		if typ.Size > 64 {
+			return n, nil
+		}
+		if typ.T == abi.UintTy {
+			return reflect.ValueOf(n.Uint64()).Convert(typ.GetType()).Interface(), nil
+		}
+		return reflect.ValueOf(n.Int64()).Convert(typ.GetType()).Interface(), nil
+
+	case abi.BoolTy:
+		return strconv.ParseBool(arg)
+
+	case abi.StringTy:
+		return arg, nil
+
+	case abi.BytesTy:
+		return hexutil.Decode(arg)
+
+	case abi.FixedBytesTy:
+		blob, err := hexutil.Decode(arg)
+		if err != nil || len(blob) > typ.Size {
+			return nil, fmt.Errorf("invalid bytes%d %q", typ.Size, arg)
+		}
+		value := reflect.New(typ.GetType()).Elem()
+		reflect.Copy(value, reflect.ValueOf(blob))
+		return value.Interface(), nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", typ)
+}
+
+// sendPayoutCall sends the payout action of a claim.
+func sendPayoutCall(c *Claim) (ChainTx, error) {
+	data, err := payoutCall.pack(c)
+	if err != nil {
+		return nil, err
+	}
+	value := new(big.Int)
+	if *payoutValueFlag {
+		value = c.Amount
+	}
+	return c.server.sendTx(c.ctx, payoutCall.to.Hex(), value, data)
+}
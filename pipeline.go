@@ -0,0 +1,572 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+// Claim carries a single funding request through the funding pipeline. Stages
+// may fill in any of the derived fields for the ones running after them.
+type Claim struct {
+	ctx    context.Context
+	server *Server // Faucet the claim is made to
+
+	Address   string            // Account to fund, in the native format of the chain
+	Tier      uint              // Requested funding tier
+	Captcha   string            // Captcha response supplied by the client
+	Voucher   string            // Voucher code to redeem, if any
+	Referral  string            // Referral code of the user who referred the requester
+	Challenge string            // Ownership challenge signed by the requester
+	Token     string            // Claim token issued to the requester
+	JWT       string            // Identity token issued by the operator, if any
+	Subject   string            // Subject of the identity token, set by identity
+	Signature string            // Signature of the challenge by the funded address
+	IP        string            // Remote address of the requester
+	Country   string            // Country of the requester, empty if unknown
+	Trusted   bool              // Whether the requester is on a trusted network
+	Lang      string            // Language to talk to the requester in
+	Honeypot  string            // Hidden form field only bots fill in
+	Terms     bool              // Whether the requester accepted the terms of service
+	Fields    map[string]string // Extra form fields filled in by the requester
+	FormNonce string            // Nonce of the website the claim was submitted from
+	WorldID   *worldIDProof     // Proof of unique personhood, if any
+
+	ID           string // Identifier of the funding job, set by inflight
+	SkipCooldown bool   // Whether the claim is exempt from rate limiting
+
+	Amount   *big.Int      // Amount of wei to pay out, set by validate
+	Cooldown time.Duration // Time until the next allowance, set by validate
+	Score    float64       // Captcha score (1 if unscored), set by verify
+	Risk     float64       // Accumulated risk score, set by risk-score
+	Tx       ChainTx       // Funding transaction, set by send
+	Mint     ChainTx       // NFT mint transaction, set by mint
+	TokenID  *big.Int      // ID of the minted NFT, set by minted
+	Receipt  *ChainReceipt // Funding receipt, set by confirm
+
+	Notes    []string               // Extra information to append to the success message
+	Siblings []string               // Sibling faucets to suggest on failure, set by federation
+	Values   map[string]interface{} // Scratch space for custom stages
+
+	release func()                                // Hands the sender back to the queue once the tx is out
+	notify  func(position int, eta time.Duration) // Reports the queue position to the requester, if set
+	status  func(msg string)                      // Reports the progress of the claim to the requester, if set
+	batch   *payoutBatch                          // Batch the claim is paid out in, if any
+	website bool                                  // Whether the claim was submitted through the website
+	owned   bool                                  // Whether the requester already proved to own the address
+	decoy   bool                                  // Whether the claim was answered with a decoy payout
+	link    bool                                  // Whether the claim was made by opening a signed claim link
+	trace   *claimTrace                           // Captured lifecycle of the claim, if selected for tracing
+}
+
+// Context returns the context of the request, cancelled when the client leaves.
+func (c *Claim) Context() context.Context {
+	return c.ctx
+}
+
+// Handler processes a funding claim, returning an error to be displayed to the
+// user if the claim is rejected.
+type Handler func(c *Claim) error
+
+// Middleware wraps a handler with an additional processing step. It may act on
+// the claim before and after calling into the rest of the pipeline, or refuse
+// the claim by returning an error without calling next.
+type Middleware func(next Handler) Handler
+
+// Stage is a named step of the funding pipeline.
+type Stage struct {
+	Name       string
+	Middleware Middleware
+}
+
+// Pipeline is an ordered list of stages every funding claim is pushed through.
+type Pipeline struct {
+	lock   sync.Mutex
+	stages []Stage
+}
+
+// Funding is the pipeline used to serve funding requests. Builds embedding the
+// faucet may insert their own steps (e.g. a KYC check) from an init function in
+// a separate file, without touching the existing sources:
+//
+//	func init() {
+//		Funding.InsertBefore("send", Stage{Name: "kyc", Middleware: kycCheck})
+//	}
+var Funding = NewPipeline(
+	Stage{"slo", sloStage},
+	Stage{"errors", errorsStage},
+	Stage{"mirror", mirrorStage},
+	Stage{"federation", federationStage},
+	Stage{"standby", standbyStage},
+	Stage{"breaker", breakerStage},
+	Stage{"schedule", scheduleStage},
+	Stage{"bots", botStage},
+	Stage{"token", tokenStage},
+	Stage{"validate", validateStage},
+	Stage{"geo", geoStage},
+	Stage{"trusted", trustedStage},
+	Stage{"terms", termsStage},
+	Stage{"fields", formFieldsStage},
+	Stage{"shadowban", shadowbanStage},
+	Stage{"identity", identityStage},
+	Stage{"ownership", ownershipStage},
+	Stage{"passport", passportStage},
+	Stage{"verify", verifyStage},
+	Stage{"worldid", worldIDStage},
+	Stage{"decay", decayStage},
+	Stage{"velocity", velocityStage},
+	Stage{"freshness", freshnessStage},
+	Stage{"contract", contractStage},
+	Stage{"mainnet", mainnetStage},
+	Stage{"tier-upgrade", tierUpgradeStage},
+	Stage{"voucher", voucherStage},
+	Stage{"referral", referralStage},
+	Stage{"rate-limit", rateLimitStage},
+	Stage{"risk-score", riskScoreStage},
+	Stage{"balance", balanceStage},
+	Stage{"eligibility", eligibilityStage},
+	Stage{"budget", budgetStage},
+	Stage{"inflight", inflightStage},
+	Stage{"enqueue", enqueueStage},
+	Stage{"mint", mintStage},
+	Stage{"send", sendStage},
+	Stage{"minted", mintedStage},
+	Stage{"record", recordStage},
+	Stage{"confirm", confirmStage},
+	Stage{"finality", finalityStage},
+)
+
+// NewPipeline creates a funding pipeline from the given stages.
+func NewPipeline(stages ...Stage) *Pipeline {
+	return &Pipeline{stages: stages}
+}
+
+// Use appends a stage to the end of the pipeline.
+func (p *Pipeline) Use(stage Stage) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.stages = append(p.stages, stage)
+}
+
+// InsertBefore inserts a stage in front of the named one.
+func (p *Pipeline) InsertBefore(name string, stage Stage) error {
+	return p.insert(name, 0, stage)
+}
+
+// InsertAfter inserts a stage right after the named one.
+func (p *Pipeline) InsertAfter(name string, stage Stage) error {
+	return p.insert(name, 1, stage)
+}
+
+func (p *Pipeline) insert(name string, offset int, stage Stage) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for i, s := range p.stages {
+		if s.Name == name {
+			stages := append([]Stage{}, p.stages[:i+offset]...)
+			stages = append(stages, stage)
+			p.stages = append(stages, p.stages[i+offset:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown pipeline stage %q", name)
+}
+
+// Remove drops the named stage from the pipeline.
+func (p *Pipeline) Remove(name string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for i, s := range p.stages {
+		if s.Name == name {
+			p.stages = append(p.stages[:i:i], p.stages[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown pipeline stage %q", name)
+}
+
+// Handler chains all the stages together into a single claim handler, with
+// the claims selected for tracing captured along the way.
+func (p *Pipeline) Handler() Handler {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	handler := Handler(func(c *Claim) error { return nil })
+	for i := len(p.stages) - 1; i >= 0; i-- {
+		handler = traceStage(p.stages[i].Name, p.stages[i].Middleware(handler))
+	}
+	return traceClaims(handler)
+}
+
+// remoteHost strips the port from a remote address, if any.
+func remoteHost(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// validateStage rejects malformed requests and calculates the payout amount.
+func validateStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if c.Tier >= uint(len(payoutTiers)) {
+			return newUserError("Invalid funding tier requested")
+		}
+		if c.Address == "" {
+			return newUserError("Invalid address to fund")
+		}
+		c.Amount = new(big.Int).Set(payoutTiers[c.Tier].Amount)
+		c.Cooldown = payoutTiers[c.Tier].Cooldown
+		c.Score = 1
+		return next(c)
+	}
+}
+
+// verifyStage validates the captcha response of the request, if captchas are
+// configured and the requester isn't on a trusted network. Score based captchas
+// scale the payout and cooldown of the claim along the configured curve instead
+// of a plain pass or fail.
+func verifyStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if *captchaToken == "" || *captchaSecret == "" || c.Trusted {
+			return next(c)
+		}
+		// Claim links are opened without a form to solve a captcha on, their
+		// signature and single use stand in for it
+		if c.link {
+			return next(c)
+		}
+		form := url.Values{}
+		form.Add("secret", *captchaSecret)
+		form.Add("response", c.Captcha)
+		if host := remoteHost(c.IP); host != "" {
+			form.Add("remoteip", host)
+		}
+		ctx, cancel := context.WithTimeout(c.ctx, 10*time.Second)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, *captchaVerifyFlag, strings.NewReader(form.Encode()))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			log.Error("Failed to verify captcha err: ", err)
+			return newUserError("Captcha verification unavailable")
+		}
+		defer res.Body.Close()
+
+		var result struct {
+			Success bool            `json:"success"`
+			Score   *float64        `json:"score"`
+			Errors  json.RawMessage `json:"error-codes"`
+		}
+		if err = json.NewDecoder(res.Body).Decode(&result); err != nil {
+			log.Error("Failed to decode captcha response err: ", err)
+			return newUserError("Captcha verification unavailable")
+		}
+		if !result.Success {
+			log.Info("Captcha verification failed: ", string(result.Errors))
+			return newUserError("Beep-bop, you're a robot!")
+		}
+		if result.Score != nil {
+			c.Score = *result.Score
+		}
+		if !captchaCurve.apply(c, c.Score) {
+			log.Info("Captcha score too low: ", c.Address, " score: ", c.Score)
+			return newUserError("Beep-bop, you're a robot!")
+		}
+		return next(c)
+	}
+}
+
+// throttledError is returned if the requester has used up their allowance and
+// needs to wait before claiming again.
+type throttledError struct {
+	error               // Message to display to the user
+	limit int           // Number of claims allowed per period
+	retry time.Duration // Time until the next claim is allowed
+}
+
+func (e *throttledError) Unwrap() error { return e.error }
+
+// cooldownExpiry returns when a cooldown starting now runs out, leaving a grace
+// period for requesters claiming like clockwork.
+func cooldownExpiry(cooldown time.Duration) time.Time {
+	return time.Now().Add(cooldown - cooldown/288) // 24h timeout => 5m grace
+}
+
+// newThrottledError turns away a claim during a cooldown lasting until expiry.
+func newThrottledError(expiry time.Time) error {
+	return &throttledError{
+		error: newUserError("%s left until next allowance", prettyDuration(time.Until(expiry))),
+		limit: 1,
+		retry: time.Until(expiry),
+	}
+}
+
+// rateLimitStage ensures the user didn't request funds too recently. The slot is
+// reserved up front so concurrent requests for the same account can't slip
+// through, and handed back if the claim fails further down the pipeline. The
+// cooldown of repeat offenders is escalated outside the lock, as looking up
+// their strikes hits the store, and their strikes only cleared once paid out.
+func rateLimitStage(next Handler) Handler {
+	return func(c *Claim) error {
+		s := c.server
+		if c.SkipCooldown {
+			return next(c)
+		}
+		id := c.Address
+		s.lock.Lock()
+		prev, ok := s.timeouts[id]
+		if ok && time.Now().Before(prev) {
+			s.lock.Unlock()
+			recordStrike(c)
+			return newThrottledError(prev)
+		}
+		s.timeouts[id] = cooldownExpiry(c.Cooldown)
+		s.lock.Unlock()
+
+		base := c.Cooldown
+		struck := escalateCooldown(c)
+		if c.Cooldown != base {
+			s.lock.Lock()
+			s.timeouts[id] = cooldownExpiry(c.Cooldown)
+			s.lock.Unlock()
+		}
+		err := next(c)
+		if refundable(c, err) {
+			s.lock.Lock()
+			if ok {
+				s.timeouts[id] = prev
+			} else {
+				delete(s.timeouts, id)
+			}
+			s.lock.Unlock()
+			return err
+		}
+		if struck {
+			clearStrikes(c)
+		}
+		return err
+	}
+}
+
+// RiskScorer rates how likely a claim is to be abusive. Scores of all registered
+// scorers are summed up and the claim is rejected above the configured threshold.
+type RiskScorer func(c *Claim) float64
+
+// riskScorers is the list of scorers consulted by the risk-score stage.
+var riskScorers []RiskScorer
+
+// riskScoreStage rates the claim with every registered scorer and rejects it if
+// the total exceeds the configured threshold.
+func riskScoreStage(next Handler) Handler {
+	return func(c *Claim) error {
+		for _, scorer := range riskScorers {
+			c.Risk += scorer(c)
+		}
+		if c.Risk > *riskThresholdFlag && !(*appealsFlag && riskExempt(c)) {
+			if *shadowbanRiskFlag {
+				// Keep bots from learning what gets them caught
+				for _, target := range []string{c.Address, remoteHost(c.IP)} {
+					if err := addShadowban(target, "risk"); err != nil {
+						log.Error("Failed to shadow-ban risky claim err: ", err)
+					}
+				}
+				return shadowbanned(c)
+			}
+			log.Info("Rejecting risky claim: ", c.Address, " score: ", c.Risk)
+			if *appealsFlag {
+				return newUserError("Request denied, you may appeal the decision")
+			}
+			return newUserError("Request denied")
+		}
+		return next(c)
+	}
+}
+
+// fundJob is a claim waiting in the funding queue for the sender.
+type fundJob struct {
+	claim  *Claim
+	next   Handler
+	done   chan error
+	ticket uint64 // Place in the queue, see queueStats
+	state  int32  // Whether the job is queued, taken or abandoned (atomic)
+}
+
+// States of a queued claim. Once the sender took a claim its transaction may go
+// out at any time, so the requester has to wait for the outcome before rolling
+// back anything reserved for the claim, even if the client left meanwhile.
+const (
+	jobQueued int32 = iota
+	jobTaken
+	jobAbandoned
+)
+
+// take hands the job to the sender, reporting false if the requester gave up
+// on it while it was queued.
+func (j *fundJob) take() bool {
+	return atomic.CompareAndSwapInt32(&j.state, jobQueued, jobTaken)
+}
+
+// abandon withdraws the job from the queue, reporting false if the sender took
+// it already.
+func (j *fundJob) abandon() bool {
+	return atomic.CompareAndSwapInt32(&j.state, jobQueued, jobAbandoned)
+}
+
+// enqueueStage hands the claim over to the single sender goroutine, ensuring
+// transactions are created one at a time with consecutive nonces, and waits for
+// the rest of the pipeline to complete. While waiting, the requester is kept up
+// to date on its queue position. Requesters leaving while their claim is queued
+// abandon it, but once the sender took the claim its outcome is waited for, so
+// the stages before never roll back a claim that was paid out.
+func enqueueStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if err := c.server.checkBusy(); err != nil {
+			return err
+		}
+		job := &fundJob{claim: c, next: next, done: make(chan error, 1)}
+		if !c.server.queue.push(job) {
+			return newBusyError(c.server.queue.len())
+		}
+		job.ticket = queueStats.enter()
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		reported, left := 0, c.ctx.Done()
+		for {
+			if position := queueStats.position(job.ticket); c.notify != nil && position > 0 && position != reported {
+				c.notify(position, queueStats.eta(position))
+				reported = position
+			}
+			select {
+			case err := <-job.done:
+				return err
+			case <-left:
+				if job.abandon() {
+					return c.ctx.Err()
+				}
+				left = nil // Taken by the sender already, wait for the outcome
+			case <-ticker.C:
+			}
+		}
+	}
+}
+
+// loopSender runs the queued claims through the rest of the pipeline, moving on
+// to the next one as soon as the previous transaction was broadcast. If enough
+// claims are waiting, they are paid out together in a single batch.
+func (s *Server) loopSender() {
+	for {
+		job := s.queue.pop()
+		if !job.take() {
+			queueStats.serve() // Abandoned by the requester, skip it
+			continue
+		}
+		jobs := collectBatch(s.queue, job)
+		if len(jobs) > 1 && len(jobs) >= *batchMinFlag {
+			s.runBatch(jobs)
+			continue
+		}
+		for _, job := range jobs {
+			runSingle(job)
+		}
+	}
+}
+
+// runSingle runs a claim through the rest of the pipeline, returning once its
+// transaction was broadcast.
+func runSingle(job *fundJob) {
+	queueStats.serve()
+	start := time.Now()
+
+	released := make(chan struct{})
+	var once sync.Once
+	job.claim.release = func() { once.Do(func() { close(released) }) }
+
+	go func(job *fundJob) {
+		defer job.claim.release()
+		defer func() {
+			if v := recover(); v != nil {
+				job.done <- recoverPanic("sender", job.claim, v)
+			}
+		}()
+		job.done <- job.next(job.claim)
+	}(job)
+	<-released
+	queueStats.sent(time.Since(start))
+}
+
+// sendStage signs and broadcasts the funding transaction, or waits for the batch
+// the claim is paid out in to be broadcast.
+func sendStage(next Handler) Handler {
+	return func(c *Claim) error {
+		var (
+			tx  ChainTx
+			err error
+		)
+		switch {
+		case c.batch != nil:
+			tx, err = c.batch.join(c)
+		case *streamTokenFlag != "":
+			tx, err = sendStream(c)
+		case payoutCall != nil:
+			tx, err = sendPayoutCall(c)
+		default:
+			tx, err = c.server.SendTx(c.ctx, c.Amount, c.Address)
+		}
+		if c.release != nil {
+			c.release()
+		}
+		if err != nil {
+			return err
+		}
+		c.Tx = tx
+		return next(c)
+	}
+}
+
+// confirmStage waits for the funding transaction to be mined, if requested.
+func confirmStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if *receiptTimeoutFlag <= 0 {
+			return next(c)
+		}
+		ctx, cancel := context.WithTimeout(c.ctx, *receiptTimeoutFlag)
+		defer cancel()
+
+		start := time.Now()
+		receipt, err := c.server.chain.Confirm(ctx, c.Tx)
+		if err != nil {
+			return newUserError("Funding transaction %s not confirmed yet", c.Tx.ID())
+		}
+		if !receipt.Success {
+			unjournalTx(c.Tx)
+			log.Error("Funding transaction ", c.Tx.ID(), " failed: ", receipt.Reason)
+			return newTxFailure(c.Tx, receipt.Reason)
+		}
+		if receipt, err = awaitConfirmations(ctx, c, receipt); err != nil {
+			return newUserError("Funding transaction %s not confirmed yet", c.Tx.ID())
+		}
+		unjournalTx(c.Tx)
+		queueStats.confirmed(time.Since(start))
+		c.Receipt = receipt
+		return next(c)
+	}
+}
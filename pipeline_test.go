@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// benchClaim creates a claim of the base tier to a faucet, paid out to the
+// given account.
+func benchClaim(s *Server, i int) *Claim {
+	return &Claim{
+		ctx:      context.Background(),
+		server:   s,
+		Address:  common.BigToAddress(big.NewInt(int64(i) + 1)).Hex(),
+		IP:       fmt.Sprintf("10.%d.%d.%d:4242", i>>16&0xff, i>>8&0xff, i&0xff),
+		Amount:   big.NewInt(1),
+		Cooldown: time.Hour,
+		Values:   make(map[string]interface{}),
+	}
+}
+
+// sentTx is a funding transaction for claims to settle as paid.
+var sentTx = &evmTx{Transaction: types.NewTransaction(0, common.Address{}, new(big.Int), 21000, new(big.Int), nil)}
+
+func BenchmarkRateLimit(b *testing.B) {
+	store, _ = openStore("")
+	pay := rateLimitStage(func(c *Claim) error {
+		c.Tx = sentTx
+		return nil
+	})
+	b.Run("allowed", func(b *testing.B) {
+		s := newServer(nil)
+		claims := make([]*Claim, b.N)
+		for i := range claims {
+			claims[i] = benchClaim(s, i)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for _, c := range claims {
+			if err := pay(c); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("throttled", func(b *testing.B) {
+		s := newServer(nil)
+		if err := pay(benchClaim(s, 0)); err != nil {
+			b.Fatal(err)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := pay(benchClaim(s, 0)); err == nil {
+				b.Fatal("repeated claim not throttled")
+			}
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		s := newServer(nil)
+		var next int64
+		b.ReportAllocs()
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				pay(benchClaim(s, int(atomic.AddInt64(&next, 1))))
+			}
+		})
+	})
+}
+
+func BenchmarkEnqueue(b *testing.B) {
+	s := newServer(nil)
+	s.queue = newJobQueue(b.N + 1)
+	go s.loopSender()
+
+	enqueue := enqueueStage(func(c *Claim) error { return nil })
+	var next int64
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := enqueue(benchClaim(s, int(atomic.AddInt64(&next, 1)))); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var prefetchFlag = flag.Duration("rpc.prefetch", 2*time.Second, "Interval to prefetch the pending nonce and gas price at, so claims are built without waiting on the node (0 = fetch per claim)")
+
+// evmPrefetch caches the pending nonce and gas price of the faucet account,
+// refreshed in the background. The nonce is advanced locally with every
+// broadcast, as the sender is the only one using it.
+type evmPrefetch struct {
+	lock     sync.Mutex
+	nonce    uint64 // Next nonce to use
+	nonceOK  bool   // Whether the nonce is known, false after a failed broadcast
+	gasPrice *big.Int
+	priced   time.Time // Time the gas price was fetched at
+}
+
+// startPrefetch keeps the nonce and gas price cache of the backend warm.
+func (b *evmBackend) startPrefetch(interval time.Duration) {
+	b.prefetch = new(evmPrefetch)
+	go func() {
+		for ; ; time.Sleep(interval) {
+			rctx, cancel := rpcContext(context.Background())
+			nonce, err := b.client.PendingNonceAt(rctx, b.from)
+			cancel()
+			if err != nil {
+				log.Error("Failed to prefetch nonce err: ", err)
+			} else {
+				b.prefetch.fetchedNonce(nonce)
+			}
+			if *l2Flag != "" {
+				continue // L2 transactions are priced by the L2 specific fee logic
+			}
+			rctx, cancel = rpcContext(context.Background())
+			price, err := b.client.SuggestGasPrice(rctx)
+			cancel()
+			if err != nil {
+				log.Error("Failed to prefetch gas price err: ", err)
+				continue
+			}
+			b.prefetch.lock.Lock()
+			b.prefetch.gasPrice, b.prefetch.priced = price, time.Now()
+			b.prefetch.lock.Unlock()
+		}
+	}()
+}
+
+// fetchedNonce folds a pending nonce reported by the node into the cache. The
+// node may lag behind transactions just broadcast, never move backwards.
+func (p *evmPrefetch) fetchedNonce(nonce uint64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if !p.nonceOK || nonce > p.nonce {
+		p.nonce, p.nonceOK = nonce, true
+	}
+}
+
+// pendingNonce returns the nonce of the next transaction, from the cache if
+// warm, from the node otherwise.
+func (b *evmBackend) pendingNonce(ctx context.Context) (uint64, error) {
+	if b.prefetch != nil {
+		b.prefetch.lock.Lock()
+		nonce, ok := b.prefetch.nonce, b.prefetch.nonceOK
+		b.prefetch.lock.Unlock()
+		if ok {
+			return nonce, nil
+		}
+	}
+	rctx, cancel := rpcContext(ctx)
+	defer cancel()
+
+	nonce, err := b.client.PendingNonceAt(rctx, b.from)
+	if err == nil && b.prefetch != nil {
+		b.prefetch.fetchedNonce(nonce)
+	}
+	return nonce, err
+}
+
+// suggestGasPrice returns the gas price to pay, from the cache if it was
+// refreshed recently, from the node otherwise.
+func (b *evmBackend) suggestGasPrice(ctx context.Context) (*big.Int, error) {
+	if b.prefetch != nil {
+		b.prefetch.lock.Lock()
+		price, priced := b.prefetch.gasPrice, b.prefetch.priced
+		b.prefetch.lock.Unlock()
+		if price != nil && time.Since(priced) < 3*(*prefetchFlag) {
+			return new(big.Int).Set(price), nil
+		}
+	}
+	rctx, cancel := rpcContext(ctx)
+	defer cancel()
+
+	return b.client.SuggestGasPrice(rctx)
+}
+
+// broadcasted advances the cached nonce past a transaction accepted by the
+// node, or forgets it if the node rejected one, as it may have been stale.
+func (b *evmBackend) broadcasted(nonce uint64, err error) {
+	if b.prefetch == nil {
+		return
+	}
+	b.prefetch.lock.Lock()
+	defer b.prefetch.lock.Unlock()
+
+	if err != nil {
+		b.prefetch.nonceOK = false
+		return
+	}
+	if b.prefetch.nonceOK && nonce+1 > b.prefetch.nonce {
+		b.prefetch.nonce = nonce + 1
+	}
+}
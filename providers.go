@@ -0,0 +1,444 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var rpcFallbacksFlag = flag.String("rpc.fallbacks", "", "Comma separated further HTTP RPC endpoints of the same chain as --rpc, requests going to the fastest healthy one of them all (empty = none)")
+
+const (
+	providerSamples  = 256              // Recent latencies kept per endpoint for the percentiles
+	providerFailures = 3                // Consecutive failures after which an endpoint is unhealthy
+	providerProbe    = 15 * time.Second // Time between two probes of every pooled endpoint
+	providerSwitch   = 0.75             // Latency ratio below which a faster endpoint is preferred
+	inclusionTTL     = time.Hour        // Time to wait for the inclusion of a broadcast transaction
+)
+
+var (
+	providerRequests  = newCounterVec("faucet_rpc_requests_total", "HTTP requests made to RPC endpoints, by endpoint host.", "provider")
+	providerErrors    = newCounterVec("faucet_rpc_errors_total", "HTTP requests to RPC endpoints that failed or were answered with a server error, by endpoint host.", "provider")
+	providerFailovers = newCounter("faucet_rpc_failovers_total", "Times requests were moved to another RPC endpoint.")
+)
+
+func init() {
+	register(&metric{name: "faucet_rpc_latency_p95_seconds", help: "95th percentile latency of recent requests to RPC endpoints, by endpoint host.", kind: "gauge", collect: func() []sample {
+		var samples []sample
+		for _, report := range providerReports() {
+			samples = append(samples, sample{label: fmt.Sprintf("provider=%q", report.Provider), value: report.Latency.P95})
+		}
+		return samples
+	}})
+	register(&metric{name: "faucet_rpc_inclusion_p95_seconds", help: "95th percentile time from broadcast to inclusion of recent transactions accepted by RPC endpoints, by endpoint host.", kind: "gauge", collect: func() []sample {
+		var samples []sample
+		for _, report := range providerReports() {
+			samples = append(samples, sample{label: fmt.Sprintf("provider=%q", report.Provider), value: report.Inclusion.P95})
+		}
+		return samples
+	}})
+}
+
+// rpcFallbacks returns the configured fallback endpoints of the RPC node.
+func rpcFallbacks() []string {
+	var endpoints []string
+	for _, endpoint := range strings.Split(*rpcFallbacksFlag, ",") {
+		if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
+
+// rpcProvider is an HTTP RPC endpoint along with the statistics of the requests
+// made to it.
+type rpcProvider struct {
+	host string        // Host of the endpoint, as logged and labeled in the metrics
+	url  *url.URL      // Full URL of the endpoint
+	pool *providerPool // Pool the endpoint is part of
+
+	lock       sync.Mutex
+	requests   uint64
+	errors     uint64
+	failures   int             // Consecutive failed requests
+	average    time.Duration   // Moving average of the request latency, 0 if unknown
+	latencies  []time.Duration // Recent request latencies, oldest first
+	inclusions []time.Duration // Recent times from broadcast to inclusion, oldest first
+}
+
+// observe records the outcome of a request to the endpoint.
+func (p *rpcProvider) observe(latency time.Duration, failed bool) {
+	providerRequests.With(p.host).Inc()
+	if failed {
+		providerErrors.With(p.host).Inc()
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.requests++
+	if failed {
+		p.errors++
+		p.failures++
+		return
+	}
+	p.failures = 0
+	if p.average == 0 {
+		p.average = latency
+	} else {
+		p.average = (4*p.average + latency) / 5
+	}
+	p.latencies = appendSample(p.latencies, latency)
+}
+
+// healthy reports whether the endpoint answered its recent requests, along with
+// its average latency.
+func (p *rpcProvider) healthy() (bool, time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.failures < providerFailures, p.average
+}
+
+// appendSample appends a duration to a window of recent samples.
+func appendSample(samples []time.Duration, d time.Duration) []time.Duration {
+	if len(samples) >= providerSamples {
+		samples = append(samples[:0], samples[len(samples)-providerSamples+1:]...)
+	}
+	return append(samples, d)
+}
+
+// rpcProviders are all the HTTP RPC endpoints dialed, in order of dialing.
+var rpcProviders = struct {
+	lock sync.Mutex
+	list []*rpcProvider
+}{}
+
+// providerPool is an HTTP transport spreading the requests of an RPC client
+// over equivalent endpoints. Requests go to the preferred endpoint, failing
+// over to the others in order of their latency; the fastest healthy endpoint
+// becomes preferred once it is clearly faster than the current one.
+type providerPool struct {
+	transport http.RoundTripper
+	providers []*rpcProvider
+
+	preferred int32 // Index of the preferred endpoint
+}
+
+// newProviderPool creates a pool of the given endpoints, the first preferred
+// initially. Endpoints are probed periodically if there are several.
+func newProviderPool(transport http.RoundTripper, endpoints []string) (*providerPool, error) {
+	pool := &providerPool{transport: transport}
+	for _, endpoint := range endpoints {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("invalid HTTP RPC endpoint %q", endpoint)
+		}
+		pool.providers = append(pool.providers, &rpcProvider{host: u.Host, url: u, pool: pool})
+	}
+	rpcProviders.lock.Lock()
+	rpcProviders.list = append(rpcProviders.list, pool.providers...)
+	rpcProviders.lock.Unlock()
+
+	if len(pool.providers) > 1 {
+		go pool.probe()
+	}
+	return pool, nil
+}
+
+// RoundTrip sends an RPC request to the preferred endpoint, retrying it on the
+// others if the endpoint can't be reached or answers with a server error.
+// Retried requests are safe, the node deduplicates resent transactions.
+func (pool *providerPool) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+	var (
+		res *http.Response
+		err error
+	)
+	order := pool.order()
+	for i, provider := range order {
+		out := req.Clone(req.Context())
+		out.URL, out.Host = provider.url, ""
+		out.Body, out.ContentLength = ioutil.NopCloser(bytes.NewReader(body)), int64(len(body))
+
+		start := time.Now()
+		res, err = pool.transport.RoundTrip(out)
+		failed := err != nil || res.StatusCode >= http.StatusInternalServerError || res.StatusCode == http.StatusTooManyRequests
+		if err == nil && failed {
+			traceRPC(req.Context(), body, provider.host, time.Since(start), errors.New(res.Status))
+		} else {
+			traceRPC(req.Context(), body, provider.host, time.Since(start), err)
+		}
+		if req.Context().Err() == nil {
+			provider.observe(time.Since(start), failed) // Don't blame the endpoint for the caller giving up
+		}
+		if !failed {
+			if trace, ok := req.Context().Value(providerTraceKey{}).(*providerTrace); ok {
+				trace.set(provider)
+			}
+			return res, nil
+		}
+		if i == len(order)-1 || req.Context().Err() != nil {
+			break
+		}
+		if res != nil {
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		}
+		log.Info("RPC endpoint failed, retrying on another: ", provider.host)
+	}
+	return res, err
+}
+
+// order returns the endpoints in the order to try them in: the preferred one
+// first, then the healthy ones by latency, then the unhealthy ones. Another
+// endpoint becomes preferred if the current one turned unhealthy or another is
+// clearly faster.
+func (pool *providerPool) order() []*rpcProvider {
+	if len(pool.providers) == 1 {
+		return pool.providers
+	}
+	type ranked struct {
+		provider *rpcProvider
+		healthy  bool
+		latency  time.Duration
+	}
+	ranks := make([]ranked, len(pool.providers))
+	for i, provider := range pool.providers {
+		healthy, latency := provider.healthy()
+		ranks[i] = ranked{provider, healthy, latency}
+	}
+	preferred := atomic.LoadInt32(&pool.preferred)
+	current := ranks[preferred]
+
+	sort.SliceStable(ranks, func(i, j int) bool {
+		switch {
+		case ranks[i].healthy != ranks[j].healthy:
+			return ranks[i].healthy
+		case ranks[i].latency == 0 || ranks[j].latency == 0:
+			return ranks[j].latency == 0 && ranks[i].latency != 0 // Unknown latencies last
+		default:
+			return ranks[i].latency < ranks[j].latency
+		}
+	})
+	best := ranks[0]
+	if best.provider != current.provider && best.healthy && best.latency > 0 &&
+		(!current.healthy || float64(best.latency) < providerSwitch*float64(current.latency)) {
+		if atomic.CompareAndSwapInt32(&pool.preferred, preferred, int32(indexOf(pool.providers, best.provider))) {
+			log.Info("Preferring RPC endpoint ", best.provider.host, " over ", current.provider.host)
+			providerFailovers.Inc()
+		}
+		current = best
+	}
+	order := []*rpcProvider{current.provider}
+	for _, rank := range ranks {
+		if rank.provider != current.provider {
+			order = append(order, rank.provider)
+		}
+	}
+	return order
+}
+
+// indexOf returns the index of an endpoint in a list.
+func indexOf(providers []*rpcProvider, provider *rpcProvider) int {
+	for i, p := range providers {
+		if p == provider {
+			return i
+		}
+	}
+	return -1
+}
+
+// probe periodically measures every endpoint of the pool, so the latencies of
+// the ones not preferred stay current and unhealthy ones get to recover.
+func (pool *providerPool) probe() {
+	const request = `{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`
+
+	for range time.Tick(providerProbe) {
+		for _, provider := range pool.providers {
+			ctx, cancel := rpcContext(context.Background())
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.url.String(), strings.NewReader(request))
+			if err != nil {
+				cancel()
+				continue
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			start := time.Now()
+			res, err := pool.transport.RoundTrip(req)
+			if err == nil {
+				io.Copy(ioutil.Discard, res.Body)
+				res.Body.Close()
+			}
+			provider.observe(time.Since(start), err != nil || res.StatusCode >= http.StatusInternalServerError || res.StatusCode == http.StatusTooManyRequests)
+			cancel()
+		}
+	}
+}
+
+// providerTraceKey is the context key of the endpoint a request was served by.
+type providerTraceKey struct{}
+
+// providerTrace records the endpoint that served the requests of a context.
+type providerTrace struct {
+	lock     sync.Mutex
+	provider *rpcProvider
+}
+
+func (t *providerTrace) set(provider *rpcProvider) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.provider = provider
+}
+
+func (t *providerTrace) get() *rpcProvider {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.provider
+}
+
+// withProviderTrace derives a context recording the endpoint serving requests.
+func withProviderTrace(ctx context.Context) (context.Context, *providerTrace) {
+	trace := new(providerTrace)
+	return context.WithValue(ctx, providerTraceKey{}, trace), trace
+}
+
+// broadcasts tracks the transactions awaiting inclusion by the endpoints that
+// accepted them, to measure the time to inclusion of every endpoint.
+var broadcasts = struct {
+	lock    sync.Mutex
+	pending map[string]*pendingBroadcast // By transaction hash
+}{
+	pending: make(map[string]*pendingBroadcast),
+}
+
+// pendingBroadcast is a transaction accepted by some endpoints, not yet included.
+type pendingBroadcast struct {
+	sent      time.Time
+	providers []*rpcProvider
+}
+
+// trackBroadcast records that an endpoint accepted a transaction.
+func trackBroadcast(hash string, provider *rpcProvider) {
+	if provider == nil {
+		return
+	}
+	broadcasts.lock.Lock()
+	defer broadcasts.lock.Unlock()
+
+	for id, pending := range broadcasts.pending {
+		if time.Since(pending.sent) > inclusionTTL {
+			delete(broadcasts.pending, id) // Never confirmed, e.g. replaced
+		}
+	}
+	pending, ok := broadcasts.pending[hash]
+	if !ok {
+		pending = &pendingBroadcast{sent: time.Now()}
+		broadcasts.pending[hash] = pending
+	}
+	pending.providers = append(pending.providers, provider)
+}
+
+// trackInclusion records the time to inclusion of a transaction for every
+// endpoint that accepted it.
+func trackInclusion(hash string) {
+	broadcasts.lock.Lock()
+	pending, ok := broadcasts.pending[hash]
+	delete(broadcasts.pending, hash)
+	broadcasts.lock.Unlock()
+
+	if !ok {
+		return
+	}
+	elapsed := time.Since(pending.sent)
+	for _, provider := range pending.providers {
+		provider.lock.Lock()
+		provider.inclusions = appendSample(provider.inclusions, elapsed)
+		provider.lock.Unlock()
+	}
+}
+
+// providerReport are the statistics of an RPC endpoint, as listed by the admin
+// API for operators to rank their providers.
+type providerReport struct {
+	Provider  string  `json:"provider"`
+	Preferred bool    `json:"preferred"` // Whether requests currently go to the endpoint
+	Healthy   bool    `json:"healthy"`
+	Requests  uint64  `json:"requests"`
+	Errors    uint64  `json:"errors"`
+	ErrorRate float64 `json:"errorRate"`
+
+	Latency struct {
+		P50 float64 `json:"p50"` // Seconds
+		P95 float64 `json:"p95"` // Seconds
+	} `json:"latency"`
+
+	Inclusion struct {
+		Included int     `json:"included"` // Recent transactions included after the endpoint accepted them
+		P50      float64 `json:"p50"`      // Seconds
+		P95      float64 `json:"p95"`      // Seconds
+	} `json:"inclusion"`
+}
+
+// providerReports compiles the statistics of every RPC endpoint.
+func providerReports() []*providerReport {
+	rpcProviders.lock.Lock()
+	providers := append([]*rpcProvider{}, rpcProviders.list...)
+	rpcProviders.lock.Unlock()
+
+	reports := []*providerReport{}
+	for _, provider := range providers {
+		provider.lock.Lock()
+		report := &providerReport{
+			Provider:  provider.host,
+			Preferred: provider.pool.providers[atomic.LoadInt32(&provider.pool.preferred)] == provider,
+			Healthy:   provider.failures < providerFailures,
+			Requests:  provider.requests,
+			Errors:    provider.errors,
+		}
+		latencies, inclusions := seconds(provider.latencies), seconds(provider.inclusions)
+		provider.lock.Unlock()
+
+		if report.Requests > 0 {
+			report.ErrorRate = float64(report.Errors) / float64(report.Requests)
+		}
+		report.Latency.P50, report.Latency.P95 = percentile(latencies, 0.5), percentile(latencies, 0.95)
+		report.Inclusion.Included = len(inclusions)
+		report.Inclusion.P50, report.Inclusion.P95 = percentile(inclusions, 0.5), percentile(inclusions, 0.95)
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// seconds converts durations into sorted seconds.
+func seconds(durations []time.Duration) []float64 {
+	out := make([]float64, len(durations))
+	for i, d := range durations {
+		out[i] = d.Seconds()
+	}
+	sort.Float64s(out)
+	return out
+}
+
+// onAdminProviders lists the statistics of the RPC endpoints.
+func onAdminProviders(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, providerReports())
+}
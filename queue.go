@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var busyDepthFlag = flag.Int("queue.busy", 0, "Queue depth from which new requests are turned away as busy (0 = when full)")
+
+// Metrics of the funding queue, e.g. for autoscaling decisions.
+var busyRejections = newCounter("faucet_queue_busy_total", "Requests turned away because the funding queue was full.")
+
+// registerQueueMetrics exports the depth and capacity of the funding queue of
+// the faucet.
+func registerQueueMetrics(s *Server) {
+	registerGauge("faucet_queue_depth", "Number of requests waiting for the sender.", func() float64 {
+		return float64(s.queue.len())
+	})
+	registerGauge("faucet_queue_capacity", "Maximum number of requests waiting for the sender.", func() float64 {
+		return float64(s.queue.cap())
+	})
+}
+
+// busyError is returned if the funding queue is too deep to take on more work.
+type busyError struct {
+	error               // Message to display to the user
+	retry time.Duration // Suggested time to wait before retrying
+}
+
+func (e *busyError) Unwrap() error { return e.error }
+
+// checkBusy returns a busy error if the funding queue reached the configured
+// depth, suggesting to retry once the queue has had time to drain.
+func (s *Server) checkBusy() error {
+	depth, limit := s.queue.len(), s.queue.cap()
+	if *busyDepthFlag > 0 && *busyDepthFlag < limit {
+		limit = *busyDepthFlag
+	}
+	if depth < limit {
+		return nil
+	}
+	return newBusyError(depth)
+}
+
+// newBusyError creates the error turning a request away from a queue of the
+// given depth, suggesting to retry once the queue has had time to drain.
+func newBusyError(depth int) error {
+	busyRejections.Inc()
+	retry := queueStats.eta(depth).Round(time.Second)
+	if retry <= 0 {
+		retry = 30 * time.Second
+	}
+	return &busyError{
+		error: newUserError("Faucet is busy, please retry in %s", retry),
+		retry: retry,
+	}
+}
+
+// queueStats tracks the progress of the funding queue to tell waiting users
+// where they stand.
+var queueStats = new(queueTracker)
+
+// queueTracker hands out tickets to queued claims and keeps moving averages of
+// the time spent per claim, from which the wait of a queued claim is estimated.
+type queueTracker struct {
+	enqueued uint64 // Number of claims ever added to the queue (atomic)
+	served   uint64 // Number of claims ever picked up by the sender (atomic)
+
+	lock    sync.Mutex
+	service time.Duration // Average time the sender spends per claim
+	confirm time.Duration // Average time for a funding transaction to be mined
+}
+
+// enter registers a newly queued claim, returning its ticket.
+func (q *queueTracker) enter() uint64 {
+	return atomic.AddUint64(&q.enqueued, 1)
+}
+
+// serve registers that the sender picked up the next claim.
+func (q *queueTracker) serve() {
+	atomic.AddUint64(&q.served, 1)
+}
+
+// position returns the number of claims to be served up to and including the
+// one holding the ticket, or 0 if it is no longer waiting.
+func (q *queueTracker) position(ticket uint64) int {
+	served := atomic.LoadUint64(&q.served)
+	if ticket <= served {
+		return 0
+	}
+	return int(ticket - served)
+}
+
+// sent records the time the sender spent on a single claim.
+func (q *queueTracker) sent(elapsed time.Duration) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.service = average(q.service, elapsed)
+}
+
+// confirmed records the time a funding transaction took to be mined.
+func (q *queueTracker) confirmed(elapsed time.Duration) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.confirm = average(q.confirm, elapsed)
+}
+
+// eta estimates the time until a claim at the given queue position is funded,
+// or returns 0 if there's no data to base the estimate on yet.
+func (q *queueTracker) eta(position int) time.Duration {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.service == 0 {
+		return 0
+	}
+	return time.Duration(position)*q.service + q.confirm
+}
+
+// average folds a new sample into an exponentially weighted moving average,
+// favouring recent samples so the estimate follows the state of the chain.
+func average(avg time.Duration, sample time.Duration) time.Duration {
+	if avg == 0 {
+		return sample
+	}
+	return (4*avg + sample) / 5
+}
+
+// queueMessage describes the queue position of a claim to the user.
+func queueMessage(lang string, position int, eta time.Duration) string {
+	if eta <= 0 {
+		return translate(lang, "Position %d in the funding queue", position)
+	}
+	return translate(lang, "Position %d in the funding queue, about %s left", position, eta.Round(time.Second))
+}
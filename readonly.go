@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	readOnlyFlag        = flag.Bool("readonly", false, "Serve only the website, info, stats and activity feed from the store shared with the sending instance, without loading the key or sending (requires --store.path)")
+	readOnlyFaucetFlag  = flag.String("readonly.faucet", "", "Public URL of the sending faucet, linked from the website of read-only mirrors for claims")
+	readOnlyPublishFlag = flag.Bool("readonly.publish", false, "Publish the balance and reliability statistics of this instance into the store for read-only mirrors to serve")
+)
+
+// statusBucket is the store bucket holding the status published for mirrors.
+const statusBucket = "status"
+
+// errReadOnly is returned by the store of read-only mirrors on modifications.
+var errReadOnly = errors.New("store is read-only")
+
+// publishedStatus is the state of the sending instance that read-only mirrors
+// can't derive from the store themselves.
+type publishedStatus struct {
+	Updated time.Time `json:"updated"`
+	Address string    `json:"address"`           // Faucet account paying out the claims
+	Balance string    `json:"balance,omitempty"` // Balance in wei, decimal, if known
+	Stats   *sloStats `json:"stats"`
+}
+
+// readOnlyStore is the shared store as seen by read-only mirrors, refusing any
+// modification so a mirror never overwrites the state of the sending instance.
+type readOnlyStore struct {
+	*fileStore
+}
+
+func (s *readOnlyStore) Put(bucket, key string, value []byte) error { return errReadOnly }
+func (s *readOnlyStore) Delete(bucket, key string) error            { return errReadOnly }
+
+func (s *readOnlyStore) Update(bucket, key string, fn func(value []byte) ([]byte, error)) error {
+	return errReadOnly
+}
+
+func (s *readOnlyStore) Batch(fn func(tx Store) error) error { return errReadOnly }
+
+// setupReadOnly validates the configuration of read-only mirrors and locks
+// down their store.
+func setupReadOnly() error {
+	if *readOnlyFaucetFlag != "" && !*readOnlyFlag {
+		return fmt.Errorf("--readonly.faucet requires --readonly")
+	}
+	if !*readOnlyFlag {
+		return nil
+	}
+	if *readOnlyPublishFlag || *standbyPrimaryFlag != "" || *k8sLeaseFlag != "" {
+		return fmt.Errorf("read-only mirrors can neither publish their status nor take over sending")
+	}
+	files, ok := store.(*fileStore)
+	if !ok {
+		return fmt.Errorf("read-only mode requires --store.path on storage shared with the sending instance")
+	}
+	store = &readOnlyStore{files}
+	return nil
+}
+
+// startStatusSync either keeps the store of a read-only mirror in sync with the
+// sending instance, or publishes the status of the sending instance for them.
+func startStatusSync(s *Server) {
+	switch {
+	case *readOnlyFlag:
+		log.Info("Running as read-only mirror")
+		go syncMirror(store.(*readOnlyStore).fileStore)
+	case *readOnlyPublishFlag:
+		go s.publishStatus()
+	}
+}
+
+// syncMirror periodically reloads the store written by the sending instance.
+func syncMirror(files *fileStore) {
+	for range time.Tick(*standbyIntervalFlag) {
+		if err := files.reload(); err != nil {
+			log.Error("Failed to sync store from sending instance err: ", err)
+		}
+	}
+}
+
+// publishStatus periodically publishes the status of this instance for the
+// read-only mirrors.
+func (s *Server) publishStatus() {
+	ticker := time.NewTicker(*standbyIntervalFlag)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		status := &publishedStatus{Updated: time.Now(), Address: s.address.Hex(), Stats: sloReport()}
+
+		ctx, cancel := context.WithTimeout(context.Background(), *standbyIntervalFlag)
+		if balance, err := s.faucetBalance(ctx); err == nil {
+			status.Balance = balance.String()
+		}
+		cancel()
+
+		if err := putJSON(store, statusBucket, "latest", status); err != nil {
+			log.Error("Failed to publish status err: ", err)
+		}
+	}
+}
+
+// mirroredStatus retrieves the status last published by the sending instance.
+func mirroredStatus() (*publishedStatus, error) {
+	status := new(publishedStatus)
+	if err := getJSON(store, statusBucket, "latest", status); err != nil {
+		if err == errNotFound {
+			return nil, errors.New("no status published, run the sending instance with --readonly.publish")
+		}
+		return nil, err
+	}
+	return status, nil
+}
+
+// mirroredBalance returns the faucet balance last published by the sending
+// instance.
+func mirroredBalance() (*big.Int, error) {
+	status, err := mirroredStatus()
+	if err != nil {
+		return nil, err
+	}
+	balance, ok := new(big.Int).SetString(status.Balance, 10)
+	if !ok {
+		return nil, errors.New("no balance published")
+	}
+	return balance, nil
+}
+
+// faucetAddress returns the faucet account paying out the claims, as published
+// by the sending instance on read-only mirrors.
+func (s *Server) faucetAddress() string {
+	if *readOnlyFlag {
+		if status, err := mirroredStatus(); err == nil {
+			return status.Address
+		}
+		return ""
+	}
+	return s.address.Hex()
+}
+
+// faucetURL returns the public URL of an endpoint claims are made on, which is
+// on the sending faucet if this is a read-only mirror linking to it.
+func faucetURL(r *http.Request, path string) string {
+	if *readOnlyFaucetFlag != "" {
+		return strings.TrimSuffix(*readOnlyFaucetFlag, "/") + path
+	}
+	return publicURL(r, path)
+}
+
+// onMirroredStats serves the reliability statistics last published by the
+// sending instance (GET /api/stats on read-only mirrors).
+func onMirroredStats(w http.ResponseWriter, r *http.Request) {
+	status, err := mirroredStatus()
+	if err != nil {
+		writeJSONError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	w.Header().Set("Last-Modified", status.Updated.UTC().Format(http.TimeFormat))
+	writeJSON(w, http.StatusOK, status.Stats)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	ethrpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	rpcConnsFlag = flag.Int("rpc.conns", 16, "Idle HTTP connections to keep open to the RPC node for reuse")
+	rpcBatchFlag = flag.Int("rpc.batch", 100, "Maximum receipts to poll for in a single batched RPC request (0 = poll each transaction separately)")
+)
+
+// dialRPC connects to the RPC node, over a pool of kept alive connections if
+// it is served over HTTP. Requests to HTTP nodes are measured per endpoint and
+// fail over to the fallback endpoints, if any.
+func dialRPC(url string, fallbacks ...string) (*ethrpc.Client, error) {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		if len(fallbacks) > 0 {
+			return nil, fmt.Errorf("fallback endpoints require an HTTP RPC endpoint")
+		}
+		return ethrpc.Dial(url)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = *rpcConnsFlag
+	transport.MaxIdleConnsPerHost = *rpcConnsFlag
+
+	pool, err := newProviderPool(transport, append([]string{url}, fallbacks...))
+	if err != nil {
+		return nil, err
+	}
+	return ethrpc.DialHTTPWithClient(url, &http.Client{Transport: pool})
+}
+
+// receiptPoller polls for the receipts of all transactions awaiting one with a
+// single batched request per interval, instead of a request per transaction.
+type receiptPoller struct {
+	client *ethrpc.Client
+
+	lock    sync.Mutex
+	waiting map[common.Hash][]chan *types.Receipt
+}
+
+// newReceiptPoller starts polling for receipts every interval.
+func newReceiptPoller(client *ethrpc.Client, interval time.Duration) *receiptPoller {
+	p := &receiptPoller{client: client, waiting: make(map[common.Hash][]chan *types.Receipt)}
+	go func() {
+		for range time.Tick(interval) {
+			p.poll()
+		}
+	}()
+	return p
+}
+
+// wait blocks until the receipt of the transaction was retrieved, or the
+// context is cancelled.
+func (p *receiptPoller) wait(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	ch := make(chan *types.Receipt, 1)
+
+	p.lock.Lock()
+	p.waiting[hash] = append(p.waiting[hash], ch)
+	p.lock.Unlock()
+
+	select {
+	case receipt := <-ch:
+		return receipt, nil
+	case <-ctx.Done():
+		p.lock.Lock()
+		defer p.lock.Unlock()
+		for i, waiter := range p.waiting[hash] {
+			if waiter == ch {
+				p.waiting[hash] = append(p.waiting[hash][:i], p.waiting[hash][i+1:]...)
+				break
+			}
+		}
+		if len(p.waiting[hash]) == 0 {
+			delete(p.waiting, hash)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// poll retrieves the receipts of the awaited transactions in batches, handing
+// out the ones already included.
+func (p *receiptPoller) poll() {
+	p.lock.Lock()
+	hashes := make([]common.Hash, 0, len(p.waiting))
+	for hash := range p.waiting {
+		hashes = append(hashes, hash)
+	}
+	p.lock.Unlock()
+
+	for len(hashes) > 0 {
+		chunk := hashes
+		if len(chunk) > *rpcBatchFlag {
+			chunk = chunk[:*rpcBatchFlag]
+		}
+		hashes = hashes[len(chunk):]
+
+		receipts := make([]*types.Receipt, len(chunk))
+		batch := make([]ethrpc.BatchElem, len(chunk))
+		for i, hash := range chunk {
+			batch[i] = ethrpc.BatchElem{Method: "eth_getTransactionReceipt", Args: []interface{}{hash}, Result: &receipts[i]}
+		}
+		ctx, cancel := rpcContext(context.Background())
+		err := p.client.BatchCallContext(ctx, batch)
+		cancel()
+		if err != nil {
+			log.Error("Failed to retrieve receipts err: ", err)
+			return
+		}
+		p.lock.Lock()
+		for i, elem := range batch {
+			if elem.Error != nil {
+				log.Error("Failed to retrieve receipt err: ", elem.Error)
+				continue
+			}
+			if receipts[i] == nil {
+				continue // Not included yet
+			}
+			for _, ch := range p.waiting[chunk[i]] {
+				ch <- receipts[i]
+			}
+			delete(p.waiting, chunk[i])
+		}
+		p.lock.Unlock()
+	}
+}
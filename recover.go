@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/sunvim/utils/log"
+)
+
+// panicsRecovered counts the panics recovered from by where they occurred.
+var panicsRecovered = newCounterVec("faucet_panics_total", "Panics recovered from, by where they occurred.", "where")
+
+// internalError is returned for claims that failed due to a bug in the faucet,
+// i.e. a panic, which is logged and reported rather than taking the faucet down.
+type internalError struct {
+	error
+}
+
+// recoverPanic logs the stack of a recovered panic, counts and reports it and
+// returns the error to answer the request with. It must be called from the
+// deferred function recovering the panic.
+func recoverPanic(where string, c *Claim, v interface{}) error {
+	err, ok := v.(error)
+	if !ok {
+		err = fmt.Errorf("%v", v)
+	}
+	log.Error("Recovered from panic in ", where, ": ", err, "\n", string(debug.Stack()))
+	panicsRecovered.With(where).Inc()
+	reportError(c, err, true, 4)
+
+	return &internalError{newUserError("Internal error, please try again later")}
+}
+
+// recoverPanics wraps an HTTP handler, answering requests whose handler panics
+// with an internal error instead of dropping the connection.
+func recoverPanics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v) // Deliberate abort of the response, not a bug
+			}
+			err := recoverPanic("http", nil, v)
+			writeJSONError(w, http.StatusInternalServerError, localizeError(negotiateLanguage(r), err))
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"strings"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	referralFlag          = flag.Bool("referral.enabled", false, "Hand out referral codes that shorten the cooldown of the referrer")
+	referralReductionFlag = flag.Float64("referral.reduction", 0.5, "Fraction of the remaining cooldown a successful referral removes")
+	referralCapFlag       = flag.Int("referral.cap", 5, "Maximum number of rewarded referrals per referrer per day")
+)
+
+// Store buckets holding the referral graph.
+const (
+	referralCodesBucket = "referral-codes" // code -> referrer address
+	referrersBucket     = "referrers"      // referrer address -> referrer record
+	refereesBucket      = "referees"       // referee address -> referee record
+)
+
+// referrer is the referral record of a funded user.
+type referrer struct {
+	Code     string    `json:"code"`
+	Total    int       `json:"total"`    // Total number of rewarded referrals
+	Window   time.Time `json:"window"`   // Start of the current daily cap window
+	Windowed int       `json:"windowed"` // Rewarded referrals within the window
+}
+
+// referee records who referred a user, so nobody can be referred twice.
+type referee struct {
+	Referrer string    `json:"referrer"`
+	Time     time.Time `json:"time"`
+}
+
+// referralStage credits the referrer of first-time claimants and hands out a
+// referral code to every funded user.
+func referralStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if !*referralFlag {
+			return next(c)
+		}
+		// Referrals only count for users the faucet has never seen before
+		id := c.Address
+
+		c.server.lock.RLock()
+		_, seen := c.server.timeouts[id]
+		c.server.lock.RUnlock()
+
+		var ref referee
+		if getJSON(store, refereesBucket, id, &ref) == nil {
+			seen = true
+		}
+		if err := next(c); err != nil || c.decoy {
+			return err
+		}
+		if code := strings.ToLower(strings.TrimSpace(c.Referral)); code != "" && !seen {
+			c.server.creditReferral(code, id)
+		}
+		code, err := referralCode(id)
+		if err != nil {
+			log.Error("Failed to assign referral code err: ", err)
+			return nil
+		}
+		c.Notes = append(c.Notes, translate(c.Lang, "Share referral code %s to shorten your next cooldown", code))
+		return nil
+	}
+}
+
+// referralCode returns the referral code of a user, assigning a new one if they
+// don't have one yet.
+func referralCode(address string) (string, error) {
+	var ref referrer
+	err := getJSON(store, referrersBucket, address, &ref)
+	if err == nil {
+		return ref.Code, nil
+	}
+	if err != errNotFound {
+		return "", err
+	}
+	raw := make([]byte, 5)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	ref.Code = hex.EncodeToString(raw)
+
+	if err := store.Put(referralCodesBucket, ref.Code, []byte(address)); err != nil {
+		return "", err
+	}
+	if err := putJSON(store, referrersBucket, address, &ref); err != nil {
+		return "", err
+	}
+	return ref.Code, nil
+}
+
+// creditReferral records that the newcomer was brought in by the owner of the
+// given code, and shortens the referrer's cooldown unless they hit their cap.
+func (s *Server) creditReferral(code string, newcomer string) {
+	blob, err := store.Get(referralCodesBucket, code)
+	if err != nil {
+		return // Unknown code, ignore
+	}
+	address := string(blob)
+	if address == newcomer {
+		return
+	}
+	if err := putJSON(store, refereesBucket, newcomer, &referee{Referrer: address, Time: time.Now()}); err != nil {
+		log.Error("Failed to record referee err: ", err)
+		return
+	}
+	var ref referrer
+	if err := getJSON(store, referrersBucket, address, &ref); err != nil {
+		log.Error("Failed to load referrer err: ", err)
+		return
+	}
+	if time.Since(ref.Window) > 24*time.Hour {
+		ref.Window, ref.Windowed = time.Now(), 0
+	}
+	if ref.Windowed >= *referralCapFlag {
+		log.Info("Referral cap reached: ", address)
+		return
+	}
+	ref.Total++
+	ref.Windowed++
+	if err := putJSON(store, referrersBucket, address, &ref); err != nil {
+		log.Error("Failed to update referrer err: ", err)
+		return
+	}
+	// Cut the remaining cooldown of the referrer
+	s.lock.Lock()
+	if timeout, ok := s.timeouts[address]; ok {
+		if remaining := time.Until(timeout); remaining > 0 {
+			s.timeouts[address] = time.Now().Add(remaining - time.Duration(float64(remaining)*(*referralReductionFlag)))
+		}
+	}
+	s.lock.Unlock()
+
+	log.Info("Referral credited: ", address, " referee: ", newcomer)
+}
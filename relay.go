@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethrpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/sunvim/utils/log"
+)
+
+var relaysFlag = flag.String("rpc.relays", "", "Comma separated RPC endpoints (e.g. private transaction APIs) to broadcast signed transactions through in parallel with --rpc (empty = none)")
+
+// relayTimeout bounds the submission of a transaction to a relay.
+const relayTimeout = 30 * time.Second
+
+// relayFailures counts the transactions relays failed to accept.
+var relayFailures = newCounterVec("faucet_relay_failures_total", "Transactions a broadcast relay failed to accept, by relay host.", "relay")
+
+// txRelay is an additional endpoint signed transactions are broadcast through.
+type txRelay struct {
+	host   string // Host of the endpoint, as logged and labeled in the metrics
+	client *ethrpc.Client
+}
+
+// relays are the configured broadcast relays, set up on startup.
+var relays []*txRelay
+
+// setupRelays connects to the broadcast relays, if any.
+func setupRelays() error {
+	for _, endpoint := range strings.Split(*relaysFlag, ",") {
+		if endpoint = strings.TrimSpace(endpoint); endpoint == "" {
+			continue
+		}
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("invalid relay endpoint %q", endpoint)
+		}
+		client, err := dialRPC(endpoint)
+		if err != nil {
+			return err
+		}
+		relays = append(relays, &txRelay{host: u.Host, client: client})
+	}
+	return nil
+}
+
+// relayTx submits a signed transaction to every relay in the background. The
+// returned channel yields whether any of them accepted it, as soon as one did
+// or all failed.
+func relayTx(hash string, raw []byte) <-chan bool {
+	accepted := make(chan bool, 1)
+	if len(relays) == 0 {
+		accepted <- false
+		return accepted
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), relayTimeout)
+		defer cancel()
+
+		results := make(chan bool, len(relays))
+		for _, relay := range relays {
+			go func(relay *txRelay) {
+				tctx, trace := withProviderTrace(ctx)
+				err := relay.client.CallContext(tctx, nil, "eth_sendRawTransaction", hexutil.Encode(raw))
+				if err != nil && !strings.Contains(err.Error(), "already known") {
+					log.Info("Relay failed to accept transaction: ", relay.host, " err: ", err)
+					relayFailures.With(relay.host).Inc()
+					results <- false
+					return
+				}
+				trackBroadcast(hash, trace.get())
+				results <- true
+			}(relay)
+		}
+		answered := false
+		for range relays {
+			if <-results && !answered {
+				accepted <- true
+				answered = true
+			}
+		}
+		if !answered {
+			accepted <- false
+		}
+	}()
+	return accepted
+}
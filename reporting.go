@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	sentryDSNFlag   = flag.String("errors.sentry", "", "Sentry DSN to report panics and unexpected errors of the funding pipeline to (empty = disabled)")
+	sentryEnvFlag   = flag.String("errors.environment", "production", "Environment reported along with the errors")
+	errorsQueueFlag = flag.Int("errors.queue", 100, "Error reports buffered for delivery, further ones are dropped until the backlog is sent")
+)
+
+// ErrorReport describes a panic or an unexpected error while serving a claim.
+type ErrorReport struct {
+	Time    time.Time
+	Err     error
+	Panic   bool              // Whether the error is a recovered panic
+	Stack   []runtime.Frame   // Call stack leading to the error, innermost first
+	Tags    map[string]string // Indexed context, e.g. the release and chain
+	Context map[string]string // Details of the claim that failed
+}
+
+// ErrorHooks receive every error report. Builds embedding the faucet may add
+// their own (e.g. to forward errors to another tracker) from an init function:
+//
+//	func init() {
+//		ErrorHooks = append(ErrorHooks, func(report *ErrorReport) { ... })
+//	}
+//
+// Hooks are called synchronously, so they must not block for long.
+var ErrorHooks []func(report *ErrorReport)
+
+// errorReportsDropped counts the error reports dropped as the delivery backlog
+// was full.
+var errorReportsDropped = newCounter("faucet_error_reports_dropped_total", "Error reports dropped for a full delivery backlog.")
+
+// setupErrorReporting hooks up the error tracker, if configured.
+func setupErrorReporting() error {
+	if *sentryDSNFlag == "" {
+		return nil
+	}
+	sentry, err := newSentryClient(*sentryDSNFlag)
+	if err != nil {
+		return err
+	}
+	ErrorHooks = append(ErrorHooks, sentry.enqueue)
+	go sentry.loop()
+	return nil
+}
+
+// errorsStage reports unexpected errors of the claims passing through, i.e.
+// anything but rejections and overload, and turns panics into internal errors.
+func errorsStage(next Handler) Handler {
+	return func(c *Claim) (err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				err = recoverPanic("pipeline", c, v)
+			}
+		}()
+		if err = next(c); unexpectedError(err) {
+			reportError(c, err, false, 1)
+		}
+		return err
+	}
+}
+
+// unexpectedError reports whether an error returned by the funding pipeline
+// points at a problem of the faucet rather than the claim.
+func unexpectedError(err error) bool {
+	var (
+		uerr      *userError
+		throttled *throttledError
+	)
+	switch {
+	case err == nil, errors.As(err, &uerr), errors.As(err, &throttled):
+		return false
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	}
+	return true
+}
+
+// reportError hands an error of the given claim to the error hooks, skipping
+// the given number of frames of the reporting machinery.
+func reportError(c *Claim, err error, panicked bool, skip int) {
+	if len(ErrorHooks) == 0 {
+		return
+	}
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(skip+1, pcs)])
+
+	report := &ErrorReport{
+		Time:  time.Now(),
+		Err:   err,
+		Panic: panicked,
+		Tags: map[string]string{
+			"release":  version,
+			"chain_id": strconv.FormatInt(*chainID, 10),
+			"chain":    newWalletChain().ChainName,
+		},
+	}
+	for {
+		frame, more := frames.Next()
+		report.Stack = append(report.Stack, frame)
+		if !more {
+			break
+		}
+	}
+	if c != nil {
+		report.Context = map[string]string{
+			"id":      c.ID,
+			"address": c.Address,
+			"tier":    strconv.FormatUint(uint64(c.Tier), 10),
+			"website": strconv.FormatBool(c.website),
+		}
+		if c.Tx != nil {
+			report.Context["tx"] = c.Tx.ID()
+		}
+	}
+	for _, hook := range ErrorHooks {
+		hook(report)
+	}
+}
+
+// sentryClient delivers error reports to Sentry through its store endpoint.
+type sentryClient struct {
+	endpoint string
+	auth     string
+	queue    chan *sentryEvent
+}
+
+// newSentryClient creates a client for the project the DSN points to, e.g.
+// https://<key>@o0.ingest.sentry.io/<project>.
+func newSentryClient(dsn string) (*sentryClient, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+	project := strings.Trim(u.Path, "/")
+	if u.User == nil || u.User.Username() == "" || project == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid Sentry DSN, expected <scheme>://<key>@<host>/<project>")
+	}
+	prefix := ""
+	if i := strings.LastIndex(project, "/"); i >= 0 {
+		prefix, project = "/"+project[:i], project[i+1:]
+	}
+	return &sentryClient{
+		endpoint: fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, prefix, project),
+		auth:     fmt.Sprintf("Sentry sentry_version=7, sentry_client=faucet/%s, sentry_key=%s", version, u.User.Username()),
+		queue:    make(chan *sentryEvent, *errorsQueueFlag),
+	}, nil
+}
+
+// sentryEvent is an event in the Sentry event payload format.
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Platform    string            `json:"platform"`
+	Level       string            `json:"level"`
+	Logger      string            `json:"logger"`
+	Release     string            `json:"release"`
+	Environment string            `json:"environment,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Tags        map[string]string `json:"tags"`
+	Extra       map[string]string `json:"extra,omitempty"`
+	Exception   struct {
+		Values []sentryException `json:"values"`
+	} `json:"exception"`
+}
+
+type sentryException struct {
+	Type       string `json:"type"`
+	Value      string `json:"value"`
+	Stacktrace struct {
+		Frames []sentryFrame `json:"frames"`
+	} `json:"stacktrace"`
+}
+
+type sentryFrame struct {
+	Function string `json:"function"`
+	File     string `json:"abs_path"`
+	Line     int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+// enqueue converts a report into a Sentry event and queues it for delivery.
+// Panics are delivered right away, as the process is likely to go down.
+func (s *sentryClient) enqueue(report *ErrorReport) {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	event := &sentryEvent{
+		EventID:     hex.EncodeToString(id),
+		Timestamp:   report.Time.UTC().Format(time.RFC3339Nano),
+		Platform:    "go",
+		Level:       "error",
+		Logger:      "faucet",
+		Release:     "faucet@" + report.Tags["release"],
+		Environment: *sentryEnvFlag,
+		Tags:        report.Tags,
+		Extra:       report.Context,
+	}
+	event.ServerName, _ = os.Hostname()
+
+	exception := sentryException{Type: fmt.Sprintf("%T", report.Err), Value: report.Err.Error()}
+	if report.Panic {
+		event.Level, exception.Type = "fatal", "panic"
+	}
+	for i := len(report.Stack) - 1; i >= 0; i-- { // Sentry wants the innermost frame last
+		frame := report.Stack[i]
+		exception.Stacktrace.Frames = append(exception.Stacktrace.Frames, sentryFrame{
+			Function: frame.Function,
+			File:     frame.File,
+			Line:     frame.Line,
+			InApp:    strings.HasPrefix(frame.Function, "main."),
+		})
+	}
+	event.Exception.Values = []sentryException{exception}
+
+	if report.Panic {
+		s.deliver(event)
+		return
+	}
+	select {
+	case s.queue <- event:
+	default:
+		errorReportsDropped.Inc()
+	}
+}
+
+// loop delivers the queued events one at a time.
+func (s *sentryClient) loop() {
+	for event := range s.queue {
+		s.deliver(event)
+	}
+}
+
+// deliver posts an event to Sentry.
+func (s *sentryClient) deliver(event *sentryEvent) {
+	blob, err := json.Marshal(event)
+	if err != nil {
+		log.Error("Failed to encode error report err: ", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(blob))
+	if err != nil {
+		log.Error("Failed to create error report request err: ", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Error("Failed to deliver error report err: ", err)
+		return
+	}
+	res.Body.Close()
+	if res.StatusCode >= 300 {
+		log.Error("Sentry rejected error report: ", res.Status)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package main
+
+// setupRLimit is a no-op on platforms the open file limit isn't managed on.
+func setupRLimit(target uint64) {}
@@ -0,0 +1,33 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package main
+
+import (
+	"syscall"
+
+	"github.com/sunvim/utils/log"
+)
+
+// setupRLimit raises the open file limit of the process to the requested target,
+// or as high as the hard limit permits if zero. Failures are only warned about,
+// as restricted containers may not allow changing the limit at all.
+func setupRLimit(target uint64) {
+	var rlimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
+		log.Error("Failed to retrieve the open file limit: ", err)
+		return
+	}
+	want := rlimit.Max
+	if target != 0 && target < want {
+		want = target
+	}
+	if rlimit.Cur >= want {
+		return
+	}
+	prev := rlimit.Cur
+	rlimit.Cur = want
+	if err := syscall.Setrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
+		log.Error("Failed to raise the open file limit, keeping ", prev, ": ", err)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	hoursFlag   = flag.String("faucet.hours", "", `Operating windows separated by ';', e.g. "mon-fri 09:00-18:00" (empty = always open)`)
+	hoursTZFlag = flag.String("faucet.hours.tz", "UTC", "Time zone the operating windows are defined in")
+)
+
+// weekdays maps the day names accepted in the operating windows to Go weekdays.
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// window is a recurring weekly period during which the faucet is open. Windows
+// ending before they start wrap around midnight into the following day.
+type window struct {
+	days  [7]bool
+	start time.Duration // Offset from midnight the window opens at
+	end   time.Duration // Offset from midnight the window closes at
+}
+
+// schedule is the set of operating windows of the faucet. An empty schedule
+// means the faucet is always open.
+type schedule struct {
+	windows []window
+	loc     *time.Location
+	spec    string
+}
+
+// hours is the operating schedule parsed from the command line flags.
+var hours = new(schedule)
+
+// parseSchedule parses a list of operating windows in the form of
+// "<days> <HH:MM>-<HH:MM>", separated by semicolons. Days may be a single day
+// name, a range like "mon-fri", a comma separated list, or "daily".
+func parseSchedule(spec string, tz string) (*schedule, error) {
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, err
+	}
+	s := &schedule{loc: loc, spec: strings.TrimSpace(spec)}
+	for _, part := range strings.Split(spec, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		fields := strings.Fields(part)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid operating window %q", part)
+		}
+		var w window
+		if err := parseDays(strings.ToLower(fields[0]), &w.days); err != nil {
+			return nil, fmt.Errorf("invalid operating window %q: %v", part, err)
+		}
+		span := strings.Split(fields[1], "-")
+		if len(span) != 2 {
+			return nil, fmt.Errorf("invalid operating window %q: bad time span", part)
+		}
+		if w.start, err = parseClock(span[0]); err != nil {
+			return nil, fmt.Errorf("invalid operating window %q: %v", part, err)
+		}
+		if w.end, err = parseClock(span[1]); err != nil {
+			return nil, fmt.Errorf("invalid operating window %q: %v", part, err)
+		}
+		if w.start == w.end {
+			return nil, fmt.Errorf("invalid operating window %q: empty time span", part)
+		}
+		s.windows = append(s.windows, w)
+	}
+	return s, nil
+}
+
+// parseDays sets the days of a window from a day specifier.
+func parseDays(spec string, days *[7]bool) error {
+	if spec == "daily" || spec == "*" {
+		for i := range days {
+			days[i] = true
+		}
+		return nil
+	}
+	for _, item := range strings.Split(spec, ",") {
+		bounds := strings.Split(item, "-")
+		first, ok := weekdays[bounds[0]]
+		if !ok || len(bounds) > 2 {
+			return fmt.Errorf("unknown day %q", item)
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, ok = weekdays[bounds[1]]; !ok {
+				return fmt.Errorf("unknown day %q", item)
+			}
+		}
+		for day := first; ; day = (day + 1) % 7 {
+			days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// parseClock parses a HH:MM time of day into an offset from midnight.
+func parseClock(clock string) (time.Duration, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		if clock == "24:00" {
+			return 24 * time.Hour, nil
+		}
+		return 0, fmt.Errorf("invalid time of day %q", clock)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// midnight returns the start of the day of t.
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// clockTime returns the wall clock time the given offset from midnight stands
+// for on the day of t. Days changing the clocks for daylight saving time last
+// 23 or 25 hours, so the offset is applied to the clock, not to midnight.
+func clockTime(t time.Time, offset time.Duration) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), int(offset/time.Hour), int(offset%time.Hour/time.Minute), 0, 0, t.Location())
+}
+
+// open reports whether the faucet is open at the given time.
+func (s *schedule) open(t time.Time) bool {
+	if len(s.windows) == 0 {
+		return true
+	}
+	t = t.In(s.loc)
+	today, yesterday := t.Weekday(), (t.Weekday()+6)%7
+
+	for _, w := range s.windows {
+		start, end := clockTime(t, w.start), clockTime(t, w.end)
+		if w.start < w.end {
+			if w.days[today] && !t.Before(start) && t.Before(end) {
+				return true
+			}
+			continue
+		}
+		// Window wrapping around midnight
+		if (w.days[today] && !t.Before(start)) || (w.days[yesterday] && t.Before(end)) {
+			return true
+		}
+	}
+	return false
+}
+
+// next returns the time the faucet opens next after t.
+func (s *schedule) next(t time.Time) time.Time {
+	t = t.In(s.loc)
+
+	var next time.Time
+	for i := 0; i <= 7; i++ {
+		day := midnight(t).AddDate(0, 0, i)
+		for _, w := range s.windows {
+			if !w.days[day.Weekday()] {
+				continue
+			}
+			if start := clockTime(day, w.start); start.After(t) && (next.IsZero() || start.Before(next)) {
+				next = start
+			}
+		}
+	}
+	return next
+}
+
+// describe returns a human readable summary of the operating windows, or an
+// empty string if the faucet is always open.
+func (s *schedule) describe() string {
+	if len(s.windows) == 0 {
+		return ""
+	}
+	return strings.ReplaceAll(s.spec, ";", ",") + " (" + s.loc.String() + ")"
+}
+
+// closedError returns the error to show users if the faucet is currently closed,
+// or nil if it's open.
+func (s *schedule) closedError() error {
+	now := time.Now()
+	if s.open(now) {
+		return nil
+	}
+	next := s.next(now)
+	if next.IsZero() {
+		return newUserError("Faucet is closed")
+	}
+	return newUserError("Faucet is closed, reopens at %s (in %s)", next.Format("Mon 15:04 MST"), prettyDuration(time.Until(next)))
+}
+
+// scheduleStage rejects all claims outside of the operating windows.
+func scheduleStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if err := hours.closedError(); err != nil {
+			return err
+		}
+		return next(c)
+	}
+}
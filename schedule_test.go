@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleDST(t *testing.T) {
+	s, err := parseSchedule("daily 09:00-18:00; sat 22:00-02:00", "Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	berlin := s.loc
+	tests := []struct {
+		time time.Time
+		open bool
+		next time.Time // Zero if open
+	}{
+		// Sunday the clocks were put forward, 2024-03-31 02:00 CET -> 03:00 CEST
+		{time.Date(2024, 3, 31, 8, 59, 0, 0, berlin), false, time.Date(2024, 3, 31, 9, 0, 0, 0, berlin)},
+		{time.Date(2024, 3, 31, 9, 0, 0, 0, berlin), true, time.Time{}},
+		{time.Date(2024, 3, 31, 17, 59, 0, 0, berlin), true, time.Time{}},
+		{time.Date(2024, 3, 31, 18, 0, 0, 0, berlin), false, time.Date(2024, 4, 1, 9, 0, 0, 0, berlin)},
+		{time.Date(2024, 3, 31, 1, 59, 0, 0, berlin), true, time.Time{}},
+		{time.Date(2024, 3, 31, 3, 0, 0, 0, berlin), false, time.Date(2024, 3, 31, 9, 0, 0, 0, berlin)},
+
+		// Sunday the clocks were put back, 2024-10-27 03:00 CEST -> 02:00 CET
+		{time.Date(2024, 10, 27, 8, 59, 0, 0, berlin), false, time.Date(2024, 10, 27, 9, 0, 0, 0, berlin)},
+		{time.Date(2024, 10, 27, 9, 0, 0, 0, berlin), true, time.Time{}},
+		{time.Date(2024, 10, 27, 18, 0, 0, 0, berlin), false, time.Date(2024, 10, 28, 9, 0, 0, 0, berlin)},
+		{time.Date(2024, 10, 26, 22, 0, 0, 0, berlin), true, time.Time{}},
+		{time.Date(2024, 10, 27, 2, 0, 0, 0, berlin).Add(time.Hour), false, time.Date(2024, 10, 27, 9, 0, 0, 0, berlin)},
+	}
+	for _, tt := range tests {
+		if open := s.open(tt.time); open != tt.open {
+			t.Errorf("open at %v: %v, want %v", tt.time, open, tt.open)
+		}
+		if tt.open {
+			continue
+		}
+		if next := s.next(tt.time); !next.Equal(tt.next) {
+			t.Errorf("next opening after %v: %v, want %v", tt.time, next, tt.next)
+		}
+	}
+}
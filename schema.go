@@ -0,0 +1,174 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var schemaFlag = flag.Bool("api.schema", true, "Serve a JSON Schema of the API messages under /api/schema, for custom frontends to generate their types from")
+
+// jsonSchema is a JSON Schema, or a fragment of one.
+type jsonSchema map[string]interface{}
+
+// schemaBuilder derives JSON Schemas from the Go types of the API messages, so
+// the published schema can't drift from what the faucet actually sends. Named
+// struct types are collected as definitions and referenced.
+type schemaBuilder struct {
+	defs  map[string]jsonSchema
+	names map[reflect.Type]string
+}
+
+// define adds a type as definition of the given name and returns a reference.
+func (b *schemaBuilder) define(name string, value interface{}) jsonSchema {
+	t := reflect.TypeOf(value)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	b.names[t] = name
+	return b.schema(t)
+}
+
+// schema returns the schema of a type.
+func (b *schemaBuilder) schema(t reflect.Type) jsonSchema {
+	if t == reflect.TypeOf(time.Time{}) {
+		return jsonSchema{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return b.schema(t.Elem())
+	case reflect.Bool:
+		return jsonSchema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return jsonSchema{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return jsonSchema{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return jsonSchema{"type": "number"}
+	case reflect.String:
+		return jsonSchema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return jsonSchema{"type": "string", "contentEncoding": "base64"}
+		}
+		return jsonSchema{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return jsonSchema{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		name, ok := b.names[t]
+		if !ok {
+			name = exportedName(t.Name())
+			b.names[t] = name
+		}
+		if _, ok := b.defs[name]; !ok {
+			b.defs[name] = jsonSchema{} // Placeholder for recursive types
+			b.defs[name] = b.object(t)
+		}
+		return jsonSchema{"$ref": "#/$defs/" + name}
+	}
+	return jsonSchema{} // Anything goes
+}
+
+// object returns the schema of a struct, honoring the JSON field tags. Fields
+// without omitempty are always present, and thus required.
+func (b *schemaBuilder) object(t reflect.Type) jsonSchema {
+	properties, required := jsonSchema{}, []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue // Unexported
+		}
+		tag := strings.Split(field.Tag.Get("json"), ",")
+		name := tag[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = b.schema(field.Type)
+		if !contains(tag[1:], "omitempty") {
+			required = append(required, name)
+		}
+	}
+	schema := jsonSchema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// contains reports whether a list of strings holds the given one.
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// exportedName capitalizes a Go type name for use as definition name.
+func exportedName(name string) string {
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// apiSchema assembles the schema of the API messages. Beside the definitions,
+// it maps the REST endpoints to their request and response bodies and lists
+// the messages exchanged over the websocket API.
+func apiSchema(r *http.Request) jsonSchema {
+	b := &schemaBuilder{defs: make(map[string]jsonSchema), names: make(map[reflect.Type]string)}
+
+	request := b.define("FundRequest", fundRequest{})
+	b.defs["FundRequest"]["additionalProperties"] = false // Decoded strictly
+	delete(b.defs["FundRequest"], "required")             // Missing fields are left empty
+	errorBody := b.define("Error", apiError{})
+	rest := jsonSchema{
+		"GET /api/info":   jsonSchema{"response": b.define("Info", faucetInfo{})},
+		"POST /api/claim": jsonSchema{"request": request, "response": b.define("ClaimResponse", claimResponse{}), "error": errorBody},
+	}
+	if *readOnlyFlag {
+		delete(rest, "POST /api/claim") // Claims go to the sending instance
+	}
+	if *statsFlag {
+		rest["GET /api/stats"] = jsonSchema{"response": b.define("Stats", sloStats{})}
+	}
+	if terms.text != "" {
+		rest["GET /api/terms"] = jsonSchema{"response": b.define("Terms", termsDocument{})}
+	}
+	if *activityFlag {
+		rest["GET /api/activity"] = jsonSchema{"response": b.define("Activity", activityReport{})}
+	}
+	server := []jsonSchema{
+		b.define("ErrorMessage", wsErrorMessage{}),
+		b.define("SuccessMessage", wsSuccessMessage{}),
+		b.define("QueueMessage", wsQueueMessage{}),
+		b.define("StatusMessage", wsStatusMessage{}),
+		b.define("SessionMessage", wsSessionMessage{}),
+		b.define("FundedMessage", wsFundedMessage{}),
+	}
+	return jsonSchema{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$id":     publicURL(r, "/api/schema"),
+		"title":   *apiName + " faucet API",
+		"$defs":   b.defs,
+		"rest":    rest,
+		"websocket": jsonSchema{
+			"client": request,
+			"server": jsonSchema{"anyOf": server},
+		},
+	}
+}
+
+// onSchema serves the JSON Schema of the API messages.
+func onSchema(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, apiSchema(r))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// secretSources records the environment variable each secret loaded from the
+// environment came from.
+var secretSources = make(map[string]string)
+
+// secretEnv returns the environment variable a secret flag may be supplied by,
+// e.g. FAUCET_ADMIN_TOKEN for --admin.token.
+func secretEnv(name string) string {
+	return "FAUCET_" + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(name))
+}
+
+// loadSecrets fills the secret flags not given on the command line from the
+// environment, either from the file named by the variable suffixed with _FILE
+// (as mounted by Docker and Kubernetes secrets) or from the variable itself.
+// Keeping secrets off the command line keeps them out of process listings.
+func loadSecrets() error {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	for name := range secretFlags {
+		if set[name] {
+			continue
+		}
+		env := secretEnv(name)
+		if path := os.Getenv(env + "_FILE"); path != "" {
+			blob, err := ioutil.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("%s: %v", env+"_FILE", err)
+			}
+			if err := flag.Set(name, strings.TrimRight(string(blob), "\r\n")); err != nil {
+				return fmt.Errorf("%s: %v", env+"_FILE", err)
+			}
+			secretSources[name] = env + "_FILE"
+			continue
+		}
+		if value, ok := os.LookupEnv(env); ok {
+			if err := flag.Set(name, value); err != nil {
+				return fmt.Errorf("%s: %v", env, err)
+			}
+			secretSources[name] = env
+		}
+	}
+	return nil
+}
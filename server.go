@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"time"
+)
+
+var (
+	httpReadHeaderTimeoutFlag = flag.Duration("http.timeout.header", 10*time.Second, "Time allowed to read the headers of an HTTP request")
+	httpReadTimeoutFlag       = flag.Duration("http.timeout.read", 30*time.Second, "Time allowed to read an entire HTTP request, body included")
+	httpWriteTimeoutFlag      = flag.Duration("http.timeout.write", 5*time.Minute, "Time allowed to answer an HTTP request, long enough for claims awaiting their confirmations (websockets are exempt)")
+	httpIdleTimeoutFlag       = flag.Duration("http.timeout.idle", 2*time.Minute, "Time to keep idle keep-alive connections open")
+	httpMaxHeaderFlag         = flag.Int("http.maxheader", 64*1024, "Maximum size of the headers of an HTTP request in bytes")
+	httpH2CFlag               = flag.Bool("http.h2c", false, "Accept HTTP/2 without TLS (h2c), for trusted reverse proxies speaking it to the faucet")
+)
+
+// newHTTPServer creates an HTTP server with the configured limits. HTTP/2 is
+// negotiated over TLS by default, and enabled in cleartext on request.
+func newHTTPServer(handler http.Handler) *http.Server {
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: *httpReadHeaderTimeoutFlag,
+		ReadTimeout:       *httpReadTimeoutFlag,
+		WriteTimeout:      *httpWriteTimeoutFlag,
+		IdleTimeout:       *httpIdleTimeoutFlag,
+		MaxHeaderBytes:    *httpMaxHeaderFlag,
+	}
+	if *httpH2CFlag {
+		enableH2C(srv)
+	}
+	return srv
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	sessionTTLFlag = flag.Duration("ws.session.ttl", 2*time.Minute, "Time a websocket claim keeps going after its client dropped, waiting for it to reconnect and resume (0 = claims abort on disconnect)")
+	wsPingFlag     = flag.Duration("ws.ping", 30*time.Second, "Interval of the websocket heartbeat, dropping clients that miss two in a row (0 = disabled)")
+)
+
+// claimSession tracks a websocket claim independently of the connection it was
+// submitted on, so clients losing their connection (e.g. on mobile networks)
+// can reconnect, present the session token and keep receiving the updates of
+// the claim.
+type claimSession struct {
+	token  string
+	cancel context.CancelFunc // Aborts the claim once the session expires
+
+	lock    sync.Mutex
+	conn    *wsConn     // Connection the updates are relayed to, nil while detached
+	last    interface{} // Last update, replayed on resume
+	done    bool        // Whether the claim completed
+	expired *time.Timer // Expires the session while detached or done
+}
+
+// sessions tracks the claim sessions by token.
+var sessions = struct {
+	lock    sync.Mutex
+	byToken map[string]*claimSession
+}{
+	byToken: make(map[string]*claimSession),
+}
+
+// newClaimSession starts a session for a claim submitted on the given
+// connection and hands its token to the client. The returned context aborts
+// the claim only if no client resumes the session in time.
+func newClaimSession(conn *wsConn) (*claimSession, context.Context, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &claimSession{token: hex.EncodeToString(raw), cancel: cancel, conn: conn}
+
+	sessions.lock.Lock()
+	sessions.byToken[s.token] = s
+	sessions.lock.Unlock()
+
+	s.send(&wsSessionMessage{Session: s.token}, time.Second)
+	return s, ctx, nil
+}
+
+// send relays an update of the claim to the attached client, if any. Failing
+// connections are detached rather than failing the claim.
+func (s *claimSession) send(value interface{}, timeout time.Duration) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.last = value
+	if s.conn == nil {
+		return nil
+	}
+	if err := s.conn.send(value, timeout); err != nil {
+		log.Info("Detaching claim session after failed update: ", err)
+		s.detachLocked()
+	}
+	return nil
+}
+
+// finish marks the claim complete, keeping its outcome around for clients
+// resuming late until the session expires.
+func (s *claimSession) finish() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.done = true
+	if s.expired == nil {
+		s.expired = time.AfterFunc(*sessionTTLFlag, s.expire)
+	}
+}
+
+// detach disconnects the session from a connection that went away, starting
+// the countdown for a client to resume it.
+func (s *claimSession) detach(conn *wsConn) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.conn == conn {
+		s.detachLocked()
+	}
+}
+
+func (s *claimSession) detachLocked() {
+	s.conn = nil
+	if s.expired == nil {
+		s.expired = time.AfterFunc(*sessionTTLFlag, s.expire)
+	}
+}
+
+// expire forgets the session, aborting its claim if still in progress.
+func (s *claimSession) expire() {
+	sessions.lock.Lock()
+	delete(sessions.byToken, s.token)
+	sessions.lock.Unlock()
+
+	s.cancel()
+}
+
+// resumeSession attaches a connection to the session with the given token and
+// replays the latest update of the claim. It reports whether the session is
+// still known.
+func resumeSession(token string, conn *wsConn) bool {
+	sessions.lock.Lock()
+	s, ok := sessions.byToken[token]
+	sessions.lock.Unlock()
+	if !ok {
+		return false
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !s.done && s.expired != nil {
+		s.expired.Stop()
+		s.expired = nil
+	}
+	s.conn = conn
+	if s.last != nil {
+		if err := conn.send(s.last, time.Second); err != nil {
+			log.Info("Detaching claim session after failed replay: ", err)
+			s.detachLocked()
+		}
+	}
+	return true
+}
+
+// detachSessions detaches all sessions from a connection that went away.
+func detachSessions(conn *wsConn) {
+	sessions.lock.Lock()
+	attached := make([]*claimSession, 0, len(sessions.byToken))
+	for _, s := range sessions.byToken {
+		attached = append(attached, s)
+	}
+	sessions.lock.Unlock()
+
+	for _, s := range attached {
+		s.detach(conn)
+	}
+}
+
+// keepAlive pings the client at the heartbeat interval until the context is
+// done, and drops the connection once two pings went unanswered.
+func keepAlive(ctx context.Context, conn *wsConn) {
+	if *wsPingFlag <= 0 {
+		return
+	}
+	extend := func(string) error {
+		return conn.conn.SetReadDeadline(time.Now().Add(2 * *wsPingFlag))
+	}
+	extend("")
+	conn.conn.SetPongHandler(extend)
+
+	go func() {
+		ticker := time.NewTicker(*wsPingFlag)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := conn.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second)); err != nil {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"flag"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sunvim/utils/log"
+)
+
+var shadowbanRiskFlag = flag.Bool("risk.shadowban", false, "Shadow-ban the address and IP of risky claims instead of rejecting them")
+
+// shadowbansBucket is the store bucket holding the shadow-banned addresses and
+// IPs, keyed by "addr:<address>" or "ip:<ip>".
+const shadowbansBucket = "shadowbans"
+
+// shadowban is a single shadow-banned address or IP. Claims from it appear to
+// succeed, but are never paid out.
+type shadowban struct {
+	Target  string    `json:"target"` // Address or IP
+	Reason  string    `json:"reason,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+// shadowbannedClaims counts the claims dropped due to a shadow-ban.
+var shadowbannedClaims = newCounter("faucet_shadowbanned_claims_total", "Claims answered as funded without paying out due to a shadow-ban.")
+
+// shadowbannedWei sums up the payouts withheld from shadow-banned claims.
+var shadowbannedWei = struct {
+	lock  sync.Mutex
+	total *big.Int
+}{
+	total: new(big.Int),
+}
+
+func init() {
+	register(&metric{name: "faucet_shadowbanned_units_total", help: "Units withheld from shadow-banned claims.", kind: "counter", collect: func() []sample {
+		shadowbannedWei.lock.Lock()
+		defer shadowbannedWei.lock.Unlock()
+
+		units, _ := new(big.Float).Quo(new(big.Float).SetInt(shadowbannedWei.total), big.NewFloat(float64(ether))).Float64()
+		return []sample{{value: units}}
+	}})
+	registerGauge("faucet_shadowbans", "Shadow-banned addresses and IPs.", func() float64 {
+		count := 0
+		store.Iterate(shadowbansBucket, func(key string, value []byte) bool {
+			count++
+			return true
+		})
+		return float64(count)
+	})
+}
+
+// shadowbanKey returns the store key of a shadow-ban target, an address or an
+// IP, or false if the target is neither.
+func shadowbanKey(target string) (string, bool) {
+	target = strings.TrimSpace(target)
+	if common.IsHexAddress(target) {
+		return "addr:" + common.HexToAddress(target).Hex(), true
+	}
+	if ip := net.ParseIP(target); ip != nil {
+		return "ip:" + ip.String(), true
+	}
+	return "", false
+}
+
+// addShadowban shadow-bans an address or IP.
+func addShadowban(target string, reason string) error {
+	key, ok := shadowbanKey(target)
+	if !ok {
+		return errors.New("target must be an address or an IP")
+	}
+	log.Info("Shadow-banning ", target, " reason: ", reason)
+	return putJSON(store, shadowbansBucket, key, &shadowban{Target: key[strings.Index(key, ":")+1:], Reason: reason, Created: time.Now()})
+}
+
+// isShadowbanned reports whether the address or IP of a claim is shadow-banned.
+func isShadowbanned(c *Claim) bool {
+	for _, target := range []string{c.Address, remoteHost(c.IP)} {
+		if key, ok := shadowbanKey(target); ok {
+			if _, err := store.Get(shadowbansBucket, key); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// shadowbanned answers a claim as if it was funded, without paying out.
+func shadowbanned(c *Claim) error {
+	shadowbannedClaims.Inc()
+
+	if c.Amount != nil {
+		shadowbannedWei.lock.Lock()
+		shadowbannedWei.total.Add(shadowbannedWei.total, c.Amount)
+		shadowbannedWei.lock.Unlock()
+	}
+	return decoyPayout(c)
+}
+
+// decoyTx is the made up transaction of a decoy payout, never broadcast.
+type decoyTx common.Hash
+
+func (tx decoyTx) ID() string { return common.Hash(tx).Hex() }
+
+func (tx decoyTx) MarshalBinary() ([]byte, error) {
+	return nil, errors.New("decoy transaction")
+}
+
+// decoyPayout answers a claim shaped like a real payout without paying out: it
+// is given a job ID and a random transaction hash once a real payout would have
+// been sent, and if claims wait for their receipt, fails once that times out as
+// an unconfirmed payout would. The claim is marked as a decoy, so the stages it
+// passed through leave out the side effects of a real payout.
+func decoyPayout(c *Claim) error {
+	var hash common.Hash
+	if _, err := rand.Read(hash[:]); err != nil {
+		return err
+	}
+	if c.ID == "" {
+		c.ID = newJobID()
+	}
+	if c.Amount == nil && c.Tier < uint(len(payoutTiers)) {
+		c.Amount = new(big.Int).Set(payoutTiers[c.Tier].Amount)
+	}
+	c.Tx, c.decoy = decoyTx(hash), true
+
+	queueStats.lock.Lock()
+	delay := queueStats.service
+	queueStats.lock.Unlock()
+	if *receiptTimeoutFlag > 0 {
+		delay = *receiptTimeoutFlag
+	}
+	select {
+	case <-time.After(delay):
+	case <-c.ctx.Done():
+		return c.ctx.Err()
+	}
+	if *receiptTimeoutFlag > 0 {
+		return newUserError("Funding transaction %s not confirmed yet", c.Tx.ID())
+	}
+	return nil
+}
+
+// shadowbanStage silently drops claims of shadow-banned addresses and IPs. They
+// are subject to the cooldown like any other, lest repeated claims give it away.
+func shadowbanStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if !isShadowbanned(c) {
+			return next(c)
+		}
+		if !c.SkipCooldown {
+			id := c.Address
+			c.server.lock.Lock()
+			if expiry, ok := c.server.timeouts[id]; ok && time.Now().Before(expiry) {
+				c.server.lock.Unlock()
+				return newThrottledError(expiry)
+			}
+			c.server.timeouts[id] = cooldownExpiry(c.Cooldown)
+			c.server.lock.Unlock()
+		}
+		log.Info("Dropping shadow-banned claim: ", c.Address, " ip: ", remoteHost(c.IP))
+		return shadowbanned(c)
+	}
+}
+
+// onAdminShadowbans lists (GET), adds (POST {"target", "reason"}) and lifts
+// (DELETE ?target=) shadow-bans.
+func onAdminShadowbans(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		bans := []*shadowban{}
+		err := store.Iterate(shadowbansBucket, func(key string, blob []byte) bool {
+			ban := new(shadowban)
+			if err := json.Unmarshal(blob, ban); err != nil {
+				log.Error("Failed to decode shadow-ban err: ", err)
+				return true
+			}
+			bans = append(bans, ban)
+			return true
+		})
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, bans)
+
+	case http.MethodPost:
+		var req struct {
+			Target string `json:"target"`
+			Reason string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		if req.Reason == "" {
+			req.Reason = "admin"
+		}
+		if err := addShadowban(req.Target, req.Reason); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case http.MethodDelete:
+		key, ok := shadowbanKey(r.URL.Query().Get("target"))
+		if !ok {
+			writeJSONError(w, http.StatusBadRequest, errors.New("target must be an address or an IP"))
+			return
+		}
+		if err := store.Delete(shadowbansBucket, key); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		log.Info("Lifted shadow-ban of ", key)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	ethrpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	chainBackendFlag = flag.String("chain.backend", "evm", "Chain to pay out on: evm (the node at --rpc) or sim (an in-process simulated chain for demos and development)")
+	simBlockTimeFlag = flag.Duration("sim.blocktime", 5*time.Second, "Time between two empty blocks of the simulated chain, transactions are mined right away")
+)
+
+// simChainID is the chain ID of go-ethereum's simulated backend.
+var simChainID = params.AllEthashProtocolChanges.ChainID
+
+// dialSimulated starts an in-process simulated chain funding the faucet key
+// and returns an RPC client talking to it, so the rest of the faucet runs
+// exactly as against a real node.
+func dialSimulated(key *ecdsa.PrivateKey) *ethrpc.Client {
+	alloc := core.GenesisAlloc{
+		crypto.PubkeyToAddress(key.PublicKey): {Balance: new(big.Int).Mul(big.NewInt(1_000_000_000), big.NewInt(params.Ether))},
+	}
+	sim := backends.NewSimulatedBackend(alloc, 30_000_000)
+	go func() {
+		for range time.Tick(*simBlockTimeFlag) {
+			sim.Commit()
+		}
+	}()
+	server := ethrpc.NewServer()
+	if err := server.RegisterName("eth", &simService{sim: sim}); err != nil {
+		log.Fatal("Failed to start simulated chain: ", err)
+	}
+	return ethrpc.DialInProc(server)
+}
+
+// simService serves the eth namespace methods used by the faucet from the
+// simulated backend.
+type simService struct {
+	sim *backends.SimulatedBackend
+}
+
+// simCallArgs are the arguments of eth_call and eth_estimateGas.
+type simCallArgs struct {
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to"`
+	Gas      hexutil.Uint64  `json:"gas"`
+	GasPrice *hexutil.Big    `json:"gasPrice"`
+	Value    *hexutil.Big    `json:"value"`
+	Data     hexutil.Bytes   `json:"data"`
+	Input    hexutil.Bytes   `json:"input"`
+}
+
+func (args *simCallArgs) message() ethereum.CallMsg {
+	msg := ethereum.CallMsg{From: args.From, To: args.To, Gas: uint64(args.Gas), Data: args.Data}
+	if args.Input != nil {
+		msg.Data = args.Input
+	}
+	if args.GasPrice != nil {
+		msg.GasPrice = args.GasPrice.ToInt()
+	}
+	if args.Value != nil {
+		msg.Value = args.Value.ToInt()
+	}
+	return msg
+}
+
+// block resolves a block number or tag, the simulated chain having no notion
+// of safe or finalized blocks beyond its head.
+func (s *simService) block(ctx context.Context, number string) (*types.Block, error) {
+	switch number {
+	case "latest", "pending", "safe", "finalized":
+		return s.sim.BlockByNumber(ctx, nil)
+	case "earliest":
+		return s.sim.BlockByNumber(ctx, common.Big0)
+	}
+	n, err := hexutil.DecodeBig(number)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block number %q", number)
+	}
+	return s.sim.BlockByNumber(ctx, n)
+}
+
+// head makes sure state queries target the head, the only state retained.
+func (s *simService) head(ctx context.Context, number string) error {
+	if number == "latest" || number == "pending" {
+		return nil
+	}
+	block, err := s.block(ctx, number)
+	if err != nil {
+		return err
+	}
+	if head, _ := s.sim.BlockByNumber(ctx, nil); block.NumberU64() != head.NumberU64() {
+		return errors.New("simulated chain only retains the latest state")
+	}
+	return nil
+}
+
+func (s *simService) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(simChainID)
+}
+
+func (s *simService) BlockNumber(ctx context.Context) (hexutil.Uint64, error) {
+	block, err := s.sim.BlockByNumber(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(block.NumberU64()), nil
+}
+
+func (s *simService) GasPrice(ctx context.Context) (*hexutil.Big, error) {
+	price, err := s.sim.SuggestGasPrice(ctx)
+	return (*hexutil.Big)(price), err
+}
+
+func (s *simService) MaxPriorityFeePerGas(ctx context.Context) (*hexutil.Big, error) {
+	tip, err := s.sim.SuggestGasTipCap(ctx)
+	return (*hexutil.Big)(tip), err
+}
+
+func (s *simService) GetBalance(ctx context.Context, addr common.Address, number string) (*hexutil.Big, error) {
+	if err := s.head(ctx, number); err != nil {
+		return nil, err
+	}
+	balance, err := s.sim.BalanceAt(ctx, addr, nil)
+	return (*hexutil.Big)(balance), err
+}
+
+func (s *simService) GetTransactionCount(ctx context.Context, addr common.Address, number string) (hexutil.Uint64, error) {
+	if number == "pending" {
+		nonce, err := s.sim.PendingNonceAt(ctx, addr)
+		return hexutil.Uint64(nonce), err
+	}
+	if err := s.head(ctx, number); err != nil {
+		return 0, err
+	}
+	nonce, err := s.sim.NonceAt(ctx, addr, nil)
+	return hexutil.Uint64(nonce), err
+}
+
+func (s *simService) GetCode(ctx context.Context, addr common.Address, number string) (hexutil.Bytes, error) {
+	if err := s.head(ctx, number); err != nil {
+		return nil, err
+	}
+	return s.sim.CodeAt(ctx, addr, nil)
+}
+
+func (s *simService) EstimateGas(ctx context.Context, args simCallArgs) (hexutil.Uint64, error) {
+	gas, err := s.sim.EstimateGas(ctx, args.message())
+	return hexutil.Uint64(gas), err
+}
+
+func (s *simService) Call(ctx context.Context, args simCallArgs, number string) (hexutil.Bytes, error) {
+	if number == "pending" {
+		return s.sim.PendingCallContract(ctx, args.message())
+	}
+	if err := s.head(ctx, number); err != nil {
+		return nil, err
+	}
+	return s.sim.CallContract(ctx, args.message(), nil)
+}
+
+// SendRawTransaction submits a transaction and mines it right away.
+func (s *simService) SendRawTransaction(ctx context.Context, raw hexutil.Bytes) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return common.Hash{}, err
+	}
+	if err := s.sim.SendTransaction(ctx, tx); err != nil {
+		return common.Hash{}, err
+	}
+	s.sim.Commit()
+	return tx.Hash(), nil
+}
+
+func (s *simService) GetTransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	receipt, err := s.sim.TransactionReceipt(ctx, hash)
+	if errors.Is(err, ethereum.NotFound) {
+		return nil, nil
+	}
+	return receipt, err
+}
+
+func (s *simService) GetTransactionByHash(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+	tx, pending, err := s.sim.TransactionByHash(ctx, hash)
+	if errors.Is(err, ethereum.NotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	fields, err := jsonFields(tx)
+	if err != nil {
+		return nil, err
+	}
+	from, _ := types.Sender(types.LatestSignerForChainID(simChainID), tx)
+	fields["from"] = from
+	if !pending {
+		if receipt, err := s.sim.TransactionReceipt(ctx, hash); err == nil {
+			fields["blockHash"] = receipt.BlockHash
+			fields["blockNumber"] = (*hexutil.Big)(receipt.BlockNumber)
+			fields["transactionIndex"] = hexutil.Uint64(receipt.TransactionIndex)
+		}
+	}
+	return fields, nil
+}
+
+func (s *simService) GetBlockByNumber(ctx context.Context, number string, full bool) (map[string]interface{}, error) {
+	block, err := s.block(ctx, number)
+	if err != nil {
+		if errors.Is(err, ethereum.NotFound) || strings.Contains(err.Error(), "not found") {
+			return nil, nil
+		}
+		return nil, err
+	}
+	fields, err := jsonFields(block.Header())
+	if err != nil {
+		return nil, err
+	}
+	txs := make([]interface{}, len(block.Transactions()))
+	for i, tx := range block.Transactions() {
+		if !full {
+			txs[i] = tx.Hash()
+			continue
+		}
+		txFields, err := jsonFields(tx)
+		if err != nil {
+			return nil, err
+		}
+		from, _ := types.Sender(types.LatestSignerForChainID(simChainID), tx)
+		txFields["from"] = from
+		txFields["blockHash"] = block.Hash()
+		txFields["blockNumber"] = (*hexutil.Big)(block.Number())
+		txFields["transactionIndex"] = hexutil.Uint64(i)
+		txs[i] = txFields
+	}
+	fields["transactions"] = txs
+	fields["uncles"] = []common.Hash{}
+	return fields, nil
+}
+
+// jsonFields returns the JSON object fields v encodes to, for extending the
+// RPC encoding of go-ethereum types.
+func jsonFields(v interface{}) (map[string]interface{}, error) {
+	blob, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	return fields, json.Unmarshal(blob, &fields)
+}
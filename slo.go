@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	sloSuccessFlag = flag.Float64("slo.success", 0.99, "Target ratio of claims paid out among those not rejected for cause of the user")
+	sloLatencyFlag = flag.Duration("slo.latency", time.Minute, "Target 95th percentile time from request to confirmation of the funding transaction")
+	sloWindowFlag  = flag.Duration("slo.window", 24*time.Hour, "Rolling window the SLIs are computed over")
+	statsFlag      = flag.Bool("api.stats", true, "Serve the reliability statistics of the faucet under /api/stats")
+)
+
+// maxSLOSamples bounds the confirmation latencies kept within the SLO window.
+const maxSLOSamples = 10000
+
+// claimOutcomes counts the claims by outcome: "success", "failure" (the faucet
+// failed to pay out) or "rejected" (turned away for cause of the user).
+var claimOutcomes = newCounterVec("faucet_claims_total", "Claims by outcome: success, failure (counting against the SLO) or rejected.", "outcome")
+
+// sloEvent is a single claim outcome within the SLO window.
+type sloEvent struct {
+	time    time.Time
+	failed  bool
+	latency time.Duration // Time to confirmation, 0 if not confirmed
+}
+
+// slo tracks the claim outcomes within the SLO window, oldest first.
+var slo = struct {
+	lock   sync.Mutex
+	events []sloEvent
+}{}
+
+func init() {
+	registerGauge("faucet_slo_success_ratio", "Ratio of claims paid out over the SLO window, user rejections excluded.", func() float64 {
+		return sloReport().SuccessRatio
+	})
+	registerGauge("faucet_slo_success_target", "Target success ratio.", func() float64 {
+		return *sloSuccessFlag
+	})
+	registerGauge("faucet_slo_error_budget_remaining", "Share of the error budget of the SLO window left, negative once exceeded.", func() float64 {
+		return sloReport().ErrorBudget
+	})
+	registerGauge("faucet_slo_confirmation_p50_seconds", "Median time from request to confirmation over the SLO window.", func() float64 {
+		return sloReport().Latency.P50
+	})
+	registerGauge("faucet_slo_confirmation_p95_seconds", "95th percentile time from request to confirmation over the SLO window.", func() float64 {
+		return sloReport().Latency.P95
+	})
+	registerGauge("faucet_slo_confirmation_target_seconds", "Target 95th percentile time to confirmation.", func() float64 {
+		return sloLatencyFlag.Seconds()
+	})
+}
+
+// sloStage records the outcome and time to confirmation of every claim. Claims
+// rejected for cause of the user (invalid requests, cooldowns, failed checks)
+// or abandoned by the user don't count against the SLO; overload and failures
+// to pay out do.
+func sloStage(next Handler) Handler {
+	return func(c *Claim) error {
+		start := time.Now()
+		err := next(c)
+
+		var (
+			busy     *busyError
+			failed   *txFailedError
+			internal *internalError
+			uerr     *userError
+		)
+		event := sloEvent{time: time.Now()}
+		switch {
+		case c.decoy:
+			claimOutcomes.With("rejected").Inc() // Shadow-banned, nothing was sent
+			return err
+		case err == nil:
+			if c.Receipt != nil {
+				event.latency = time.Since(start)
+			}
+		case c.Tx != nil, errors.As(err, &busy), errors.As(err, &failed), errors.As(err, &internal):
+			event.failed = true // Sent but not confirmed, overloaded or crashed
+		case errors.As(err, &uerr), errors.Is(err, context.Canceled):
+			claimOutcomes.With("rejected").Inc()
+			return err
+		default:
+			event.failed = true
+		}
+		if event.failed {
+			claimOutcomes.With("failure").Inc()
+		} else {
+			claimOutcomes.With("success").Inc()
+		}
+		slo.lock.Lock()
+		slo.events = append(slo.events, event)
+		pruneSLO(time.Now())
+		slo.lock.Unlock()
+
+		return err
+	}
+}
+
+// pruneSLO drops the events that left the SLO window. The caller must hold the
+// slo lock.
+func pruneSLO(now time.Time) {
+	i := sort.Search(len(slo.events), func(i int) bool {
+		return now.Sub(slo.events[i].time) < *sloWindowFlag
+	})
+	if len(slo.events)-i > maxSLOSamples {
+		i = len(slo.events) - maxSLOSamples
+	}
+	if i > 0 {
+		slo.events = append(slo.events[:0], slo.events[i:]...)
+	}
+}
+
+// sloStats are the reliability statistics of the faucet over the SLO window.
+type sloStats struct {
+	Window        string  `json:"window"`
+	Claims        int     `json:"claims"` // Claims counting towards the SLO
+	Failed        int     `json:"failed"`
+	SuccessRatio  float64 `json:"successRatio"`
+	SuccessTarget float64 `json:"successTarget"`
+	ErrorBudget   float64 `json:"errorBudgetRemaining"` // Share of allowed failures left
+
+	Latency struct {
+		Confirmed int     `json:"confirmed"` // Claims with a measured time to confirmation
+		P50       float64 `json:"p50"`       // Seconds
+		P95       float64 `json:"p95"`       // Seconds
+		Target    float64 `json:"target"`    // Target p95 in seconds
+		Met       bool    `json:"met"`
+	} `json:"latency"`
+}
+
+// sloReport computes the reliability statistics over the SLO window.
+func sloReport() *sloStats {
+	slo.lock.Lock()
+	pruneSLO(time.Now())
+	events := append([]sloEvent{}, slo.events...)
+	slo.lock.Unlock()
+
+	stats := &sloStats{Window: sloWindowFlag.String(), Claims: len(events), SuccessRatio: 1, SuccessTarget: *sloSuccessFlag, ErrorBudget: 1}
+	var latencies []float64
+	for _, event := range events {
+		if event.failed {
+			stats.Failed++
+		}
+		if event.latency > 0 {
+			latencies = append(latencies, event.latency.Seconds())
+		}
+	}
+	if stats.Claims > 0 {
+		stats.SuccessRatio = 1 - float64(stats.Failed)/float64(stats.Claims)
+		if allowed := (1 - *sloSuccessFlag) * float64(stats.Claims); allowed > 0 {
+			stats.ErrorBudget = 1 - float64(stats.Failed)/allowed
+		} else if stats.Failed > 0 {
+			stats.ErrorBudget = -1
+		}
+	}
+	sort.Float64s(latencies)
+	stats.Latency.Confirmed = len(latencies)
+	stats.Latency.P50 = percentile(latencies, 0.5)
+	stats.Latency.P95 = percentile(latencies, 0.95)
+	stats.Latency.Target = sloLatencyFlag.Seconds()
+	stats.Latency.Met = stats.Latency.P95 <= stats.Latency.Target
+	return stats
+}
+
+// percentile returns the given percentile of sorted values, 0 if there are none.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[int(p*float64(len(sorted)-1)+0.5)]
+}
+
+// onStats serves the reliability statistics of the faucet (GET /api/stats).
+func onStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, sloReport())
+}
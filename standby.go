@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	standbyPrimaryFlag  = flag.String("standby.primary", "", "Health URL of the primary faucet (e.g. its /api/info), running this instance as a passive standby taking over when it fails")
+	standbyLeaseFlag    = flag.Bool("standby.lease", false, "Publish a sender lease in the store for standby instances to watch")
+	standbyIntervalFlag = flag.Duration("standby.interval", 5*time.Second, "Time between two lease renewals, primary health checks and store syncs")
+	standbyFailuresFlag = flag.Int("standby.failures", 3, "Consecutive failed health checks of the primary before taking over")
+)
+
+// standbyBucket is the store bucket holding the sender lease.
+const standbyBucket = "standby"
+
+// senderLease is the heartbeat of the instance currently allowed to send, and
+// thus to use the nonces of the faucet account.
+type senderLease struct {
+	Owner   string    `json:"owner"`
+	Renewed time.Time `json:"renewed"`
+}
+
+// registerStandbyMetrics exports whether the faucet is a passive standby.
+func registerStandbyMetrics(s *Server) {
+	registerGauge("faucet_standby_passive", "Whether this instance is a passive standby (1) or sending (0).", func() float64 {
+		return float64(atomic.LoadUint32(&s.passive))
+	})
+}
+
+// isPassive reports whether this instance is a standby leaving claims to the primary.
+func (s *Server) isPassive() bool {
+	return atomic.LoadUint32(&s.passive) == 1
+}
+
+// leaseOwner identifies this instance in the sender lease.
+func leaseOwner() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s/%d", host, os.Getpid())
+}
+
+// startStandby either starts mirroring the primary as a passive standby, or
+// competing for a Kubernetes lease, or publishing the sender lease if requested.
+func startStandby(s *Server) {
+	if *k8sLeaseFlag != "" {
+		if *standbyPrimaryFlag != "" {
+			log.Fatal("Standby of a primary can't be combined with a Kubernetes lease")
+		}
+		startK8sLease(s)
+		return
+	}
+	if *standbyPrimaryFlag == "" {
+		if *standbyLeaseFlag {
+			go renewLease()
+		}
+		return
+	}
+	files, ok := store.(*fileStore)
+	if !ok {
+		log.Fatal("Standby mode requires --store.path on storage shared with the primary")
+	}
+	if !fileLocking {
+		log.Fatal("Standby mode requires file locking, unavailable on this platform")
+	}
+	atomic.StoreUint32(&s.passive, 1)
+	log.Info("Running as standby of ", *standbyPrimaryFlag)
+	go s.watchPrimary(files)
+}
+
+// watchPrimary mirrors the store written by the primary and health checks it,
+// taking over sending once it failed repeatedly and its lease expired.
+func (s *Server) watchPrimary(files *fileStore) {
+	ticker := time.NewTicker(*standbyIntervalFlag)
+	defer ticker.Stop()
+
+	failures := 0
+	for range ticker.C {
+		if err := files.reload(); err != nil {
+			log.Error("Failed to sync store from primary err: ", err)
+		}
+		if err := checkPrimary(); err != nil {
+			failures++
+			log.Error("Primary health check failed (", failures, "/", *standbyFailuresFlag, ") err: ", err)
+		} else {
+			failures = 0
+		}
+		if failures < *standbyFailuresFlag {
+			continue
+		}
+		// The primary looks dead, but only take over if it stopped sending too,
+		// otherwise a network split would have both use the same nonces
+		var lease senderLease
+		if err := getJSON(store, standbyBucket, "lease", &lease); err == nil && time.Since(lease.Renewed) < 3*(*standbyIntervalFlag) {
+			log.Error("Primary unhealthy but its lease is still renewed by ", lease.Owner, ", not taking over")
+			continue
+		}
+		atomic.StoreUint32(&s.passive, 0)
+		alert("failover", "primary %s unhealthy, standby %s took over sending", *standbyPrimaryFlag, leaseOwner())
+		renewLease()
+		return
+	}
+}
+
+// checkPrimary probes the health URL of the primary.
+func checkPrimary() error {
+	ctx, cancel := context.WithTimeout(context.Background(), *standbyIntervalFlag)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, *standbyPrimaryFlag, nil)
+	if err != nil {
+		return err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("unhealthy status %s", res.Status)
+	}
+	return nil
+}
+
+// renewLease periodically publishes the sender lease of this instance.
+func renewLease() {
+	ticker := time.NewTicker(*standbyIntervalFlag)
+	defer ticker.Stop()
+
+	owner := leaseOwner()
+	for ; ; <-ticker.C {
+		if err := putJSON(store, standbyBucket, "lease", &senderLease{Owner: owner, Renewed: time.Now()}); err != nil {
+			log.Error("Failed to renew sender lease err: ", err)
+		}
+	}
+}
+
+// standbyStage leaves claims to the primary while this instance is a passive
+// standby, so only one instance ever sends from the faucet account.
+func standbyStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if c.server.isPassive() {
+			return &busyError{error: newUserError("Faucet is on standby, please retry shortly"), retry: *standbyIntervalFlag}
+		}
+		return next(c)
+	}
+}
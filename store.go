@@ -0,0 +1,369 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+var storePathFlag = flag.String("store.path", "", "File to persist the faucet state into (empty = in-memory only)")
+
+// errNotFound is returned by stores if the requested key doesn't exist.
+var errNotFound = errors.New("not found")
+
+// Store is a persistent key-value store partitioned into buckets, holding all
+// the state of the faucet that needs to survive restarts.
+type Store interface {
+	// Get retrieves the value of a key, or errNotFound if it doesn't exist.
+	Get(bucket, key string) ([]byte, error)
+
+	// Put inserts or overwrites the value of a key.
+	Put(bucket, key string, value []byte) error
+
+	// Delete removes a key, doing nothing if it doesn't exist.
+	Delete(bucket, key string) error
+
+	// Update atomically replaces the value of a key with the one returned by fn,
+	// which receives nil if the key doesn't exist yet. If fn returns a nil value
+	// the key is deleted; if it returns an error the update is aborted.
+	Update(bucket, key string, fn func(value []byte) ([]byte, error)) error
+
+	// Iterate calls fn for every key in the bucket in lexicographic order until
+	// it returns false.
+	Iterate(bucket string, fn func(key string, value []byte) bool) error
+
+	// Batch applies the changes fn makes through the given view of the store
+	// atomically and, for persistent stores, in a single write. The view must
+	// not be used beyond fn. If fn returns an error, persistent stores discard
+	// the changes.
+	Batch(fn func(tx Store) error) error
+
+	// Close flushes and releases the store.
+	Close() error
+}
+
+// store is the persistence layer of the faucet, opened on startup.
+var store Store = newMemoryStore()
+
+// openStore opens the store configured on the command line.
+func openStore(path string) (Store, error) {
+	if path == "" {
+		return newMemoryStore(), nil
+	}
+	return newFileStore(path)
+}
+
+// getJSON retrieves and decodes a JSON value from the store.
+func getJSON(s Store, bucket, key string, value interface{}) error {
+	blob, err := s.Get(bucket, key)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(blob, value)
+}
+
+// putJSON encodes and inserts a JSON value into the store.
+func putJSON(s Store, bucket, key string, value interface{}) error {
+	blob, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return s.Put(bucket, key, blob)
+}
+
+// memoryStore is a Store keeping everything in memory.
+type memoryStore struct {
+	lock    sync.RWMutex
+	buckets map[string]map[string][]byte
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{buckets: make(map[string]map[string][]byte)}
+}
+
+func (s *memoryStore) Get(bucket, key string) ([]byte, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	value, ok := s.buckets[bucket][key]
+	if !ok {
+		return nil, errNotFound
+	}
+	return append([]byte{}, value...), nil
+}
+
+func (s *memoryStore) Put(bucket, key string, value []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.put(bucket, key, value)
+	return nil
+}
+
+func (s *memoryStore) put(bucket, key string, value []byte) {
+	if s.buckets[bucket] == nil {
+		s.buckets[bucket] = make(map[string][]byte)
+	}
+	s.buckets[bucket][key] = append([]byte{}, value...)
+}
+
+func (s *memoryStore) Delete(bucket, key string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.buckets[bucket], key)
+	return nil
+}
+
+func (s *memoryStore) Update(bucket, key string, fn func(value []byte) ([]byte, error)) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.update(bucket, key, fn)
+}
+
+func (s *memoryStore) update(bucket, key string, fn func(value []byte) ([]byte, error)) error {
+	var old []byte
+	if value, ok := s.buckets[bucket][key]; ok {
+		old = append([]byte{}, value...)
+	}
+	value, err := fn(old)
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		delete(s.buckets[bucket], key)
+		return nil
+	}
+	s.put(bucket, key, value)
+	return nil
+}
+
+func (s *memoryStore) Iterate(bucket string, fn func(key string, value []byte) bool) error {
+	s.lock.RLock()
+	keys := make([]string, 0, len(s.buckets[bucket]))
+	for key := range s.buckets[bucket] {
+		keys = append(keys, key)
+	}
+	s.lock.RUnlock()
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, err := s.Get(bucket, key)
+		if err == errNotFound {
+			continue // Deleted meanwhile
+		}
+		if !fn(key, value) {
+			break
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) Batch(fn func(tx Store) error) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return fn(lockedStore{s})
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
+
+// lockedStore is a view of a memoryStore whose lock is held by the caller, for
+// the changes of a batch.
+type lockedStore struct {
+	*memoryStore
+}
+
+func (s lockedStore) Get(bucket, key string) ([]byte, error) {
+	value, ok := s.buckets[bucket][key]
+	if !ok {
+		return nil, errNotFound
+	}
+	return append([]byte{}, value...), nil
+}
+
+func (s lockedStore) Put(bucket, key string, value []byte) error {
+	s.put(bucket, key, value)
+	return nil
+}
+
+func (s lockedStore) Delete(bucket, key string) error {
+	delete(s.buckets[bucket], key)
+	return nil
+}
+
+func (s lockedStore) Update(bucket, key string, fn func(value []byte) ([]byte, error)) error {
+	return s.update(bucket, key, fn)
+}
+
+func (s lockedStore) Iterate(bucket string, fn func(key string, value []byte) bool) error {
+	keys := make([]string, 0, len(s.buckets[bucket]))
+	for key := range s.buckets[bucket] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if value, ok := s.buckets[bucket][key]; ok && !fn(key, append([]byte{}, value...)) {
+			break
+		}
+	}
+	return nil
+}
+
+func (s lockedStore) Batch(fn func(tx Store) error) error {
+	return fn(s)
+}
+
+// fileStore is a memoryStore that snapshots its entire content into a JSON file
+// after every modification. It's plenty for the write rates of a faucet.
+//
+// Several instances may share the file (e.g. a primary and its standby): every
+// modification happens under an exclusive lock of a sibling lock file and first
+// picks up the snapshot written by the others, so none of their writes are lost.
+type fileStore struct {
+	*memoryStore
+	path  string
+	flock *os.File    // Lock file serializing writers across processes
+	stamp os.FileInfo // Snapshot on disk the content was last synced with
+}
+
+func newFileStore(path string) (*fileStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	flock, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	s := &fileStore{memoryStore: newMemoryStore(), path: path, flock: flock}
+	if err := s.reload(); err != nil {
+		flock.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fileStore) Put(bucket, key string, value []byte) error {
+	return s.modify(func() error {
+		s.put(bucket, key, value)
+		return nil
+	})
+}
+
+func (s *fileStore) Delete(bucket, key string) error {
+	return s.modify(func() error {
+		delete(s.buckets[bucket], key)
+		return nil
+	})
+}
+
+func (s *fileStore) Update(bucket, key string, fn func(value []byte) ([]byte, error)) error {
+	return s.modify(func() error {
+		return s.update(bucket, key, fn)
+	})
+}
+
+func (s *fileStore) Batch(fn func(tx Store) error) error {
+	return s.modify(func() error {
+		return fn(lockedStore{s.memoryStore})
+	})
+}
+
+func (s *fileStore) Close() error {
+	return s.flock.Close()
+}
+
+// modify applies a change to the content and flushes it, holding the lock file
+// from reading the current snapshot until the new one replaced it.
+func (s *fileStore) modify(change func() error) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := lockFile(s.flock); err != nil {
+		return err
+	}
+	defer unlockFile(s.flock)
+
+	if err := s.sync(); err != nil {
+		return err
+	}
+	if err := change(); err != nil {
+		s.stamp = nil // Reload the snapshot on disk, discarding a partial change
+		return err
+	}
+	return s.flush()
+}
+
+// sync loads the snapshot on disk if it was replaced since last synced with. The
+// caller must hold the write lock.
+func (s *fileStore) sync() error {
+	info, err := os.Stat(s.path)
+	switch {
+	case os.IsNotExist(err):
+		return nil
+	case err != nil:
+		return err
+	}
+	// Snapshots are replaced by renaming, so any write shows as a new file
+	if s.stamp != nil && os.SameFile(info, s.stamp) && info.ModTime().Equal(s.stamp.ModTime()) && info.Size() == s.stamp.Size() {
+		return nil
+	}
+	blob, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	buckets := make(map[string]map[string][]byte)
+	if err := json.Unmarshal(blob, &buckets); err != nil {
+		return err
+	}
+	s.buckets, s.stamp = buckets, info
+	return nil
+}
+
+// flush atomically replaces the snapshot on disk with the current content. The
+// caller must hold the write lock and the lock file.
+func (s *fileStore) flush() error {
+	blob, err := json.Marshal(s.buckets)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(blob); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	s.stamp = info
+	return nil
+}
+
+// reload replaces the content of the store with the snapshot on disk, to mirror
+// the state written by another faucet instance sharing the file.
+func (s *fileStore) reload() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.sync()
+}
@@ -0,0 +1,16 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package main
+
+import "os"
+
+// fileLocking reports whether lockFile serializes access across processes.
+const fileLocking = false
+
+// lockFile is a no-op on platforms without advisory file locks, the store file
+// may then only be used by a single process.
+func lockFile(f *os.File) error { return nil }
+
+// unlockFile is a no-op on platforms without advisory file locks.
+func unlockFile(f *os.File) error { return nil }
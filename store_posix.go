@@ -0,0 +1,22 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileLocking reports whether lockFile serializes access across processes.
+const fileLocking = true
+
+// lockFile waits for an exclusive advisory lock of the file.
+func lockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+}
+
+// unlockFile releases the lock taken by lockFile.
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	streamTokenFlag     = flag.String("stream.token", "", "Super token to stream payouts in instead of paying them out at once (empty = disabled)")
+	streamForwarderFlag = flag.String("stream.forwarder", "0xcfA132E353cB4E398080B9700609bb008eceB125", "Superfluid CFAv1Forwarder (or compatible) contract opening the streams")
+	streamDurationFlag  = flag.Duration("stream.duration", 24*time.Hour, "Time over which each payout is streamed to the recipient")
+)
+
+// streamsBucket is the store bucket tracking when the open payout streams are
+// due to be closed.
+const streamsBucket = "streams"
+
+// forwarderABI is the interface of the forwarder contract managing the streams.
+var forwarderABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(`[{"type":"function","name":"setFlowrate","stateMutability":"nonpayable","inputs":[{"name":"token","type":"address"},{"name":"receiver","type":"address"},{"name":"flowrate","type":"int96"}],"outputs":[{"name":"","type":"bool"}]}]`))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// streamClosing closes expired streams through the same queue as the claims, so
+// they don't race the regular payouts for nonces.
+var streamClosing = NewPipeline(
+	Stage{"enqueue", enqueueStage},
+	Stage{"send", sendStage},
+)
+
+// startStreams starts closing expired payout streams, if payouts are streamed.
+func startStreams(s *Server) {
+	if *streamTokenFlag == "" {
+		return
+	}
+	if !common.IsHexAddress(*streamTokenFlag) || !common.IsHexAddress(*streamForwarderFlag) {
+		log.Fatal("Invalid stream token or forwarder address")
+	}
+	if *batchContractFlag != "" {
+		log.Fatal("Streamed payouts can't be batched, drop --batch.contract")
+	}
+	log.Info("Streaming payouts over ", common.PrettyDuration(*streamDurationFlag))
+	go s.loopStreams()
+}
+
+// sendStream opens a stream paying out the claim over the configured duration,
+// replacing any stream already open to the recipient. A zero amount closes the
+// stream instead.
+func sendStream(c *Claim) (ChainTx, error) {
+	seconds := big.NewInt(int64(streamDurationFlag.Seconds()))
+	flowrate := new(big.Int).Div(c.Amount, seconds)
+	if c.Amount.Sign() > 0 && flowrate.Sign() == 0 {
+		return nil, newUserError("Payout too small to stream")
+	}
+	data, err := forwarderABI.Pack("setFlowrate", common.HexToAddress(*streamTokenFlag), c.Address, flowrate)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := c.server.sendTx(c.ctx, *streamForwarderFlag, new(big.Int), data)
+	if err != nil || c.Amount.Sign() == 0 {
+		return tx, err
+	}
+	until, err := time.Now().Add(*streamDurationFlag).MarshalText()
+	if err == nil {
+		err = store.Put(streamsBucket, c.Address, until)
+	}
+	if err != nil {
+		log.Error("Failed to track payout stream err: ", err)
+	}
+	c.Notes = append(c.Notes, translate(c.Lang, "Streaming over %s", prettyDuration(*streamDurationFlag)))
+	return tx, nil
+}
+
+// loopStreams periodically closes the payout streams that ran their course.
+func (s *Server) loopStreams() {
+	handle := streamClosing.Handler()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		if s.isPassive() {
+			continue // Leave the streams to the primary
+		}
+		var expired []string
+		store.Iterate(streamsBucket, func(key string, value []byte) bool {
+			var until time.Time
+			if err := until.UnmarshalText(value); err != nil || time.Now().After(until) {
+				expired = append(expired, key)
+			}
+			return true
+		})
+		for _, addr := range expired {
+			c := &Claim{ctx: context.Background(), server: s, Address: addr, Amount: new(big.Int), IP: "stream", Lang: defaultLanguage}
+			if err := handle(c); err != nil {
+				log.Error("Failed to close payout stream to ", addr, " err: ", err)
+				continue
+			}
+			if err := store.Delete(streamsBucket, addr); err != nil {
+				log.Error("Failed to untrack payout stream err: ", err)
+			}
+			log.Info("Closed payout stream to ", addr)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sunvim/utils/log"
+)
+
+// listenFdsStart is the first file descriptor passed by socket activation.
+const listenFdsStart = 3
+
+var (
+	activatedOnce sync.Once
+	activated     map[string]net.Listener // Listeners inherited from systemd by name
+)
+
+// activatedListeners returns the listeners inherited via systemd socket
+// activation, keyed by their FileDescriptorName (or position if unnamed).
+func activatedListeners() map[string]net.Listener {
+	activatedOnce.Do(func() {
+		activated = make(map[string]net.Listener)
+
+		// Listeners are either passed by systemd, or by an earlier faucet process
+		// handing over to an upgraded binary
+		pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+		if err != nil || pid != os.Getpid() {
+			if ppid, err := strconv.Atoi(os.Getenv(upgradeParentEnv)); err != nil || ppid != os.Getppid() {
+				return
+			}
+		}
+		count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+		if err != nil || count <= 0 {
+			return
+		}
+		names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+		// Don't let the variables leak into any child process
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+		os.Unsetenv(upgradeParentEnv)
+
+		for i := 0; i < count; i++ {
+			name := strconv.Itoa(i)
+			if i < len(names) && names[i] != "" {
+				name = names[i]
+			}
+			file := os.NewFile(uintptr(listenFdsStart+i), name)
+			listener, err := net.FileListener(file)
+			file.Close()
+			if err != nil {
+				log.Error("Failed to inherit activated socket ", name, ": ", err)
+				continue
+			}
+			activated[name] = listener
+		}
+	})
+	return activated
+}
+
+// listen returns the inherited listener of the given name if there is one, or
+// starts listening on the address otherwise. The public API also takes over an
+// unnamed or otherwise unclaimed inherited listener.
+func listen(name string, address string) (net.Listener, error) {
+	inherited := activatedListeners()
+	if listener, ok := inherited[name]; ok {
+		delete(inherited, name)
+		log.Info("Using inherited listener ", name, " on ", listener.Addr())
+		return listener, nil
+	}
+	if name == "api" {
+		for key, listener := range inherited {
+			if key != "admin" {
+				delete(inherited, key)
+				log.Info("Using inherited listener ", key, " on ", listener.Addr())
+				return listener, nil
+			}
+		}
+	}
+	return net.Listen("tcp", address)
+}
+
+// sdNotify sends a state update to the service manager, if the faucet is run
+// under systemd with Type=notify.
+func sdNotify(state string) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return
+	}
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:] // Abstract namespace socket
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		log.Error("Failed to notify systemd: ", err)
+		return
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		log.Error("Failed to notify systemd ", state, ": ", err)
+	}
+}
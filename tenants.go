@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var tenantsFlag = flag.String("tenants", "", "JSON file of faucets to host in a single deployment, routed by hostname or path prefix (empty = single faucet)")
+
+// tenant is a faucet hosted by the tenant router. Every tenant runs in its own
+// process with its own flags, so keys, tokens, branding, limits, stores and
+// metrics are all isolated from the other tenants.
+type tenant struct {
+	Name   string            `json:"name"`
+	Hosts  []string          `json:"hosts"`  // Hostnames routed to the tenant (empty = any)
+	Prefix string            `json:"prefix"` // Path prefix routed to the tenant (empty = any)
+	Args   []string          `json:"args"`   // Command line flags of the tenant faucet
+	Env    map[string]string `json:"env"`    // Environment variables of the tenant faucet, e.g. its secrets
+
+	listener net.Listener // Loopback listener handed to the tenant process
+	proxy    http.Handler
+}
+
+// loadTenants reads and validates the tenant configurations.
+func loadTenants(path string) ([]*tenant, error) {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config struct {
+		Tenants []*tenant `json:"tenants"`
+	}
+	if err := json.Unmarshal(blob, &config); err != nil {
+		return nil, err
+	}
+	if len(config.Tenants) == 0 {
+		return nil, fmt.Errorf("no tenants configured")
+	}
+	names := make(map[string]bool)
+	for _, t := range config.Tenants {
+		if t.Name == "" || names[t.Name] {
+			return nil, fmt.Errorf("tenant names must be unique and non-empty: %q", t.Name)
+		}
+		names[t.Name] = true
+
+		if t.Prefix != "" && (!strings.HasPrefix(t.Prefix, "/") || strings.HasSuffix(t.Prefix, "/")) {
+			return nil, fmt.Errorf("tenant %s: prefix must start but not end with a slash: %q", t.Name, t.Prefix)
+		}
+		for i, host := range t.Hosts {
+			t.Hosts[i] = strings.ToLower(host)
+		}
+		if !t.hasKey() {
+			return nil, fmt.Errorf("tenant %s: no signing key of its own, set --pri_key in its args or %s(_FILE) in its env", t.Name, secretEnv("pri_key"))
+		}
+	}
+	return config.Tenants, nil
+}
+
+// hasKey reports whether the tenant is configured with a signing key of its
+// own. Tenants sharing a key would collide on nonces and drain each other.
+func (t *tenant) hasKey() bool {
+	for _, arg := range t.Args {
+		if name := strings.SplitN(strings.TrimLeft(arg, "-"), "=", 2)[0]; name == "pri_key" && strings.HasPrefix(arg, "-") {
+			return true
+		}
+	}
+	env := secretEnv("pri_key")
+	return t.Env[env] != "" || t.Env[env+"_FILE"] != ""
+}
+
+// tenantEnvs are the variables of the router environment passed on to tenants,
+// which only configure the runtime. Anything else, in particular the secrets of
+// the router, is left out, and has to be given to each tenant in its env.
+var tenantEnvs = []string{"PATH", "HOME", "TMPDIR", "TZ", "LANG", "SSL_CERT_FILE", "SSL_CERT_DIR", "HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy"}
+
+// tenantEnv assembles the minimal environment of a tenant process.
+func tenantEnv(t *tenant) []string {
+	var env []string
+	for _, name := range tenantEnvs {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, "LC_") {
+			env = append(env, kv)
+		}
+	}
+	for name, value := range t.Env {
+		env = append(env, name+"="+value)
+	}
+	return env
+}
+
+// matches reports whether a request is routed to the tenant.
+func (t *tenant) matches(r *http.Request) bool {
+	if t.Prefix != "" && r.URL.Path != t.Prefix && !strings.HasPrefix(r.URL.Path, t.Prefix+"/") {
+		return false
+	}
+	if len(t.Hosts) == 0 {
+		return true
+	}
+	host := strings.ToLower(remoteHost(r.Host))
+	for _, h := range t.Hosts {
+		if h == host {
+			return true
+		}
+	}
+	return false
+}
+
+// runTenants hosts every configured faucet in a child process, routing the
+// public API to them by hostname and path prefix.
+func runTenants() {
+	tenants, err := loadTenants(*tenantsFlag)
+	if err != nil {
+		log.Fatal("Invalid tenants configuration: ", err)
+	}
+	binary, err := os.Executable()
+	if err != nil {
+		log.Fatal("Failed to locate the faucet binary: ", err)
+	}
+	var (
+		lock     sync.Mutex
+		children = make(map[string]*os.Process)
+		stopping bool
+	)
+	for _, t := range tenants {
+		if t.listener, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+			log.Fatal("Failed to listen for tenant ", t.Name, ": ", err)
+		}
+		target := &url.URL{Scheme: "http", Host: t.listener.Addr().String()}
+		t.proxy = httputil.NewSingleHostReverseProxy(target)
+		if t.Prefix != "" {
+			t.proxy = http.StripPrefix(t.Prefix, t.proxy)
+		}
+		// Supervise the tenant process, restarting it whenever it dies. The
+		// listener stays open meanwhile, so requests just wait for the restart.
+		go func(t *tenant) {
+			for {
+				cmd, err := startTenant(binary, t)
+				if err != nil {
+					log.Error("Failed to start tenant ", t.Name, ": ", err)
+				} else {
+					lock.Lock()
+					children[t.Name] = cmd.Process
+					lock.Unlock()
+
+					err = cmd.Wait()
+					log.Error("Tenant ", t.Name, " exited: ", err)
+				}
+				lock.Lock()
+				done := stopping
+				lock.Unlock()
+				if done {
+					return
+				}
+				time.Sleep(time.Second)
+			}
+		}(t)
+	}
+	// Stop the tenants along with the router
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-sigs
+		log.Info("Stopping tenants on ", sig)
+
+		lock.Lock()
+		stopping = true
+		for _, proc := range children {
+			proc.Signal(sig)
+		}
+		lock.Unlock()
+		time.Sleep(time.Second)
+		os.Exit(0)
+	}()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var match *tenant
+		for _, t := range tenants {
+			if t.matches(r) && (match == nil || len(t.Prefix) > len(match.Prefix)) {
+				match = t
+			}
+		}
+		if match == nil {
+			http.NotFound(w, r)
+			return
+		}
+		if match.Prefix != "" && r.URL.Path == match.Prefix {
+			http.Redirect(w, r, match.Prefix+"/", http.StatusMovedPermanently)
+			return
+		}
+		match.proxy.ServeHTTP(w, r)
+	})
+	address := strings.Join([]string{*apiAddr, ":", *apiPort}, "")
+	listener, err := listen("api", address)
+	if err != nil {
+		log.Fatal("Failed to listen for API connections: ", err)
+	}
+	log.Infof("tenant router booting with %s \n", listener.Addr())
+
+	sdNotify("READY=1")
+	if err = serve("api", listener, handler, serverTLS(*apiHttps, *apiClientCAFlag)); err != http.ErrServerClosed {
+		log.Fatal("API server failed: ", err)
+	}
+}
+
+// startTenant starts the faucet process of a tenant, handing it its listener
+// the same way as upgrades do.
+func startTenant(binary string, t *tenant) (*exec.Cmd, error) {
+	file, err := t.listener.(*net.TCPListener).File()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	args := append([]string{"--api.proxy", "--api.prefix=" + t.Prefix}, t.Args...)
+	cmd := exec.Command(binary, args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	cmd.ExtraFiles = []*os.File{file}
+
+	// Tenants get a minimal environment of their own, without the secrets of
+	// the router, and only the router talks to the service manager
+	cmd.Env = append(tenantEnv(t),
+		upgradeParentEnv+"="+strconv.Itoa(os.Getpid()),
+		"LISTEN_FDS=1",
+		"LISTEN_FDNAMES=api",
+	)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	log.Info("Started tenant ", t.Name, " on ", t.listener.Addr())
+	return cmd, nil
+}
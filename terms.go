@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	termsFileFlag    = flag.String("tos.file", "", "Plain text file with terms of service requesters have to accept with every claim (empty = no terms)")
+	termsVersionFlag = flag.String("tos.version", "", "Version of the terms of service recorded with acceptances (empty = derived from the text)")
+)
+
+// termsBucket is the store bucket holding the acceptances of the terms of
+// service, keyed by "<address>:<version>".
+const termsBucket = "terms"
+
+// terms are the terms of service requesters have to accept, if any.
+var terms struct {
+	text    string
+	version string
+}
+
+// termsAcceptance records that the terms of service of a version were accepted
+// for an address.
+type termsAcceptance struct {
+	Address  string    `json:"address"`
+	Version  string    `json:"version"`
+	Accepted time.Time `json:"accepted"` // First acceptance
+}
+
+// loadTerms reads the terms of service, if configured.
+func loadTerms() error {
+	if *termsFileFlag == "" {
+		return nil
+	}
+	blob, err := os.ReadFile(*termsFileFlag)
+	if err != nil {
+		return err
+	}
+	terms.text = strings.TrimSpace(string(blob))
+	terms.version = *termsVersionFlag
+	if terms.version == "" {
+		hash := sha256.Sum256([]byte(terms.text))
+		terms.version = hex.EncodeToString(hash[:4])
+	}
+	log.Info("Requiring acceptance of the terms of service version ", terms.version)
+	return nil
+}
+
+// termsStage rejects claims not accepting the terms of service, if any, and
+// records the acceptances of the others.
+func termsStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if terms.text == "" {
+			return next(c)
+		}
+		if !c.Terms {
+			return newUserError("Please accept the terms of service")
+		}
+		address := c.Address
+		err := store.Update(termsBucket, address+":"+terms.version, func(value []byte) ([]byte, error) {
+			if value != nil {
+				return value, nil // Accepted before
+			}
+			return json.Marshal(&termsAcceptance{Address: address, Version: terms.version, Accepted: time.Now()})
+		})
+		if err != nil {
+			log.Error("Failed to record terms acceptance err: ", err)
+			return newUserError("Terms of service unavailable, please try again later")
+		}
+		return next(c)
+	}
+}
+
+// termsDocument is the body of /api/terms.
+type termsDocument struct {
+	Version string `json:"version"`
+	Text    string `json:"text"`
+}
+
+// onTerms serves the terms of service along with their version.
+func onTerms(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, &termsDocument{Version: terms.version, Text: terms.text})
+}
+
+// onAdminTerms lists the recorded acceptances of the terms of service of an
+// address (GET ?address=).
+func (s *Server) onAdminTerms(w http.ResponseWriter, r *http.Request) {
+	address, err := s.chain.ParseAddress(r.URL.Query().Get("address"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, errors.New("invalid address"))
+		return
+	}
+	prefix := address + ":"
+
+	acceptances := []*termsAcceptance{}
+	err = store.Iterate(termsBucket, func(key string, blob []byte) bool {
+		if strings.HasPrefix(key, prefix) {
+			a := new(termsAcceptance)
+			if err := json.Unmarshal(blob, a); err != nil {
+				log.Error("Failed to decode terms acceptance err: ", err)
+				return true
+			}
+			acceptances = append(acceptances, a)
+		}
+		return true
+	})
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, acceptances)
+}
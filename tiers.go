@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var tierListFlag = flag.String("faucet.tierlist", "", "Funding tiers as amount:cooldown pairs, e.g. 0.1:24h,0.5:72h (empty = derived from --faucet.amount, --faucet.start, --faucet.minutes and --faucet.tiers)")
+
+// payoutTier is a single funding tier users may pick from.
+type payoutTier struct {
+	Amount   *big.Int      // Payout in wei
+	Cooldown time.Duration // Time until the next allowance
+}
+
+// payoutTiers is the authoritative tier table, used both to display the tiers
+// and to pay them out. It's parsed on startup.
+var payoutTiers []payoutTier
+
+// parseTiers parses the configured tier table, falling back to the tiers derived
+// from the legacy payout flags, and validates it.
+func parseTiers(spec string) ([]payoutTier, error) {
+	var tiers []payoutTier
+	if strings.TrimSpace(spec) == "" {
+		for i := 0; i < *tiersFlag; i++ {
+			tiers = append(tiers, payoutTier{
+				Amount:   toWei((*payoutFlag + float64(i)) * (*startFlag)),
+				Cooldown: time.Duration(*minutesFlag*int(math.Pow(3, float64(i)))) * time.Minute,
+			})
+		}
+	} else {
+		for _, item := range strings.Split(spec, ",") {
+			parts := strings.Split(strings.TrimSpace(item), ":")
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("invalid tier %q, want amount:cooldown", item)
+			}
+			amount, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid tier amount %q", parts[0])
+			}
+			cooldown, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid tier cooldown %q", parts[1])
+			}
+			tiers = append(tiers, payoutTier{Amount: toWei(amount), Cooldown: cooldown})
+		}
+	}
+	if len(tiers) == 0 {
+		return nil, fmt.Errorf("no funding tiers configured")
+	}
+	for i, tier := range tiers {
+		if tier.Amount.Sign() <= 0 {
+			return nil, fmt.Errorf("tier %d: amount must be positive", i)
+		}
+		if tier.Cooldown <= 0 || tier.Cooldown%time.Minute != 0 {
+			return nil, fmt.Errorf("tier %d: cooldown must be a positive number of whole minutes", i)
+		}
+		if i > 0 && tier.Amount.Cmp(tiers[i-1].Amount) <= 0 {
+			return nil, fmt.Errorf("tier %d: amount must be larger than the one of tier %d", i, i-1)
+		}
+	}
+	return tiers, nil
+}
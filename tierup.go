@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	tierTxsFlag       = flag.Uint64("tiers.activity.txs", 0, "Transactions an address must have sent since its last claim to unlock the tiers above the first (0 = all tiers open)")
+	tierContractsFlag = flag.String("tiers.activity.contracts", "", "Comma separated contracts whose events naming the address since its last claim also unlock the tiers above the first")
+)
+
+// tierActivityBucket is the store bucket holding the on-chain state of every
+// address as of its last claim.
+const tierActivityBucket = "tier-activity"
+
+// tierBaseline is the state of an address when it was last funded, against
+// which its activity since is measured.
+type tierBaseline struct {
+	Nonce uint64    `json:"nonce"` // Transactions sent by the address
+	Block uint64    `json:"block"` // Head at the time of the claim
+	Time  time.Time `json:"time"`
+}
+
+// parseTierContracts parses the contracts whose use unlocks higher tiers.
+func parseTierContracts(spec string) ([]string, error) {
+	var contracts []string
+	for _, item := range strings.Split(spec, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		if !common.IsHexAddress(item) {
+			return nil, fmt.Errorf("invalid contract address %q", item)
+		}
+		contracts = append(contracts, common.HexToAddress(item).Hex())
+	}
+	return contracts, nil
+}
+
+// tierContracts are the contracts whose use unlocks higher tiers.
+var tierContracts []string
+
+// tierUpgradeStage reserves the tiers above the first for addresses that put
+// the funds of their previous claim to use on chain, rewarding developers
+// actually building with bigger payouts.
+func tierUpgradeStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if *tierTxsFlag == 0 {
+			return next(c)
+		}
+		if c.Tier > 0 {
+			var baseline tierBaseline
+			if err := getJSON(store, tierActivityBucket, c.Address, &baseline); err == errNotFound {
+				return newUserError("Please claim the first tier before the higher ones")
+			} else if err != nil {
+				log.Error("Failed to load tier baseline err: ", err)
+				return newUserError("Eligibility check unavailable, try again later")
+			}
+			if err := c.server.checkTierActivity(c.ctx, c.Address, &baseline); err != nil {
+				return err
+			}
+		}
+		err := next(c)
+		if c.Tx != nil && !c.decoy {
+			c.server.recordTierBaseline(c.Address)
+		}
+		return err
+	}
+}
+
+// checkTierActivity verifies the address sent enough transactions or used one
+// of the configured contracts since the baseline.
+func (s *Server) checkTierActivity(ctx context.Context, addr string, baseline *tierBaseline) error {
+	nonce, err := s.chain.TxCount(ctx, addr, nil)
+	if err != nil {
+		log.Error("Failed to retrieve nonce err: ", err)
+		return newUserError("Eligibility check unavailable, try again later")
+	}
+	sent := uint64(0)
+	if nonce > baseline.Nonce {
+		sent = nonce - baseline.Nonce
+	}
+	if sent >= *tierTxsFlag {
+		return nil
+	}
+	if history, ok := unwrapChain(s.chain).(EventHistory); ok && len(tierContracts) > 0 {
+		used, err := history.Involved(ctx, tierContracts, addr, baseline.Block)
+		if err != nil {
+			log.Error("Failed to retrieve contract events err: ", err)
+			return newUserError("Eligibility check unavailable, try again later")
+		}
+		if used {
+			return nil
+		}
+	}
+	return newUserError("Higher tiers unlock after %d transactions since your last claim, %d so far", *tierTxsFlag, sent)
+}
+
+// EventHistory is implemented by chain backends able to search the events of
+// contracts, so that using the configured ones unlocks higher tiers.
+type EventHistory interface {
+	// Involved reports whether any of the contracts emitted an event naming
+	// the account as one of its first two indexed parameters (e.g. the sender
+	// or recipient of a transfer) since the given block.
+	Involved(ctx context.Context, contracts []string, addr string, since uint64) (bool, error)
+}
+
+// Involved searches the logs of the contracts for the hex address as the first
+// or second topic after the event signature.
+func (b *evmBackend) Involved(ctx context.Context, contracts []string, addr string, since uint64) (bool, error) {
+	addresses := make([]common.Address, len(contracts))
+	for i, contract := range contracts {
+		addresses[i] = common.HexToAddress(contract)
+	}
+	topic := common.BytesToHash(common.HexToAddress(addr).Bytes())
+	for _, topics := range [][][]common.Hash{{nil, {topic}}, {nil, nil, {topic}}} {
+		rctx, cancel := rpcContext(ctx)
+		logs, err := b.client.FilterLogs(rctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(since + 1),
+			Addresses: addresses,
+			Topics:    topics,
+		})
+		cancel()
+		if err != nil {
+			return false, err
+		}
+		if len(logs) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// recordTierBaseline stores the on-chain state of a just funded address.
+func (s *Server) recordTierBaseline(addr string) {
+	ctx := context.Background()
+
+	nonce, err := s.chain.TxCount(ctx, addr, nil)
+	if err != nil {
+		log.Error("Failed to retrieve nonce err: ", err)
+		return
+	}
+	head, err := s.chain.Head(ctx)
+	if err != nil {
+		log.Error("Failed to retrieve head err: ", err)
+		return
+	}
+	if err := putJSON(store, tierActivityBucket, addr, &tierBaseline{Nonce: nonce, Block: head, Time: time.Now()}); err != nil {
+		log.Error("Failed to record tier baseline err: ", err)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// prettyDuration is a duration rendered in mixed units (e.g. "1 day 12 hours")
+// in the language of the message it's formatted into.
+type prettyDuration time.Duration
+
+// String renders the duration in the default language.
+func (d prettyDuration) String() string {
+	return d.localize(defaultLanguage)
+}
+
+// localize renders the duration in the requested language. Durations under a
+// minute are rounded up to whole seconds, longer ones to whole minutes, with
+// the units that come out zero left out.
+func (d prettyDuration) localize(lang string) string {
+	duration := time.Duration(d)
+	if duration < time.Minute {
+		secs := int((duration + time.Second - 1) / time.Second)
+		if secs < 0 {
+			secs = 0
+		}
+		return translate(lang, pluralUnit(secs, "sec"), secs)
+	}
+	mins := int(duration.Round(time.Minute) / time.Minute)
+
+	var parts []string
+	if days := mins / (24 * 60); days > 0 {
+		parts = append(parts, translate(lang, pluralUnit(days, "day"), days))
+	}
+	if hours := mins / 60 % 24; hours > 0 {
+		parts = append(parts, translate(lang, pluralUnit(hours, "hour"), hours))
+	}
+	if mins%60 > 0 {
+		parts = append(parts, translate(lang, pluralUnit(mins%60, "min"), mins%60))
+	}
+	return strings.Join(parts, " ")
+}
+
+// pluralUnit returns the message key of a count of the given unit.
+func pluralUnit(n int, unit string) string {
+	if n != 1 {
+		unit += "s"
+	}
+	return "%d " + unit
+}
+
+// isoDuration renders a duration in ISO 8601 form (e.g. "PT36H"), as used by
+// the datetime attribute of HTML time elements.
+func isoDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d <= 0 {
+		return "PT0S"
+	}
+	var b strings.Builder
+	b.WriteString("PT")
+	if hours := d / time.Hour; hours > 0 {
+		fmt.Fprintf(&b, "%dH", hours)
+	}
+	if mins := d % time.Hour / time.Minute; mins > 0 {
+		fmt.Fprintf(&b, "%dM", mins)
+	}
+	if secs := d % time.Minute / time.Second; secs > 0 {
+		fmt.Fprintf(&b, "%dS", secs)
+	}
+	return b.String()
+}
+
+// marshalAPI encodes a message of the HTTP or websocket API. Timestamps are
+// normalized to UTC, as records persisted by the faucet carry the offset of
+// the server's time zone at the time they were written.
+func marshalAPI(value interface{}) ([]byte, error) {
+	blob, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return utcTimestamps(blob), nil
+}
+
+// utcTimestamps rewrites the RFC 3339 timestamps among the strings of a JSON
+// document to UTC.
+func utcTimestamps(blob []byte) []byte {
+	var out *bytes.Buffer
+	last := 0
+	for i := 0; i < len(blob); i++ {
+		if blob[i] != '"' {
+			continue
+		}
+		end := i + 1
+		for end < len(blob) && blob[end] != '"' {
+			if blob[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if s := blob[i+1 : end]; len(s) >= len("2006-01-02T15:04:05Z") && len(s) <= len(time.RFC3339Nano) && s[10] == 'T' && s[len(s)-1] != 'Z' {
+			if t, err := time.Parse(time.RFC3339Nano, string(s)); err == nil {
+				if out == nil {
+					out = new(bytes.Buffer)
+				}
+				out.Write(blob[last : i+1])
+				out.WriteString(t.UTC().Format(time.RFC3339Nano))
+				last = end
+			}
+		}
+		i = end
+	}
+	if out == nil {
+		return blob
+	}
+	out.Write(blob[last:])
+	return out.Bytes()
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	claimTokenFlag    = flag.Bool("auth.token", false, "Require claims to carry a short-lived token issued to the requester's IP")
+	claimTokenTTLFlag = flag.Duration("auth.token.ttl", 10*time.Minute, "Validity of a claim token")
+)
+
+// claimTokenCookie is the cookie a claim token is handed to browsers in, so
+// form posts without JavaScript carry one too.
+const claimTokenCookie = "faucet-token"
+
+// tokenSecret authenticates the claim tokens issued by this faucet instance.
+var tokenSecret = func() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	return secret
+}()
+
+// tokenMAC computes the authentication code of a claim token.
+func tokenMAC(host string, issued int64) string {
+	mac := hmac.New(sha256.New, tokenSecret)
+	fmt.Fprintf(mac, "%s:%d", host, issued)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newClaimToken issues a claim token bound to the given remote address.
+func newClaimToken(addr string) string {
+	issued := time.Now().Unix()
+	return fmt.Sprintf("%d.%s", issued, tokenMAC(remoteHost(addr), issued))
+}
+
+// verifyClaimToken checks that a claim token was issued by this faucet to the
+// given remote address and is still valid.
+func verifyClaimToken(token string, addr string) bool {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	issued, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := time.Since(time.Unix(issued, 0)); age < 0 || age > *claimTokenTTLFlag {
+		return false
+	}
+	return hmac.Equal([]byte(parts[1]), []byte(tokenMAC(remoteHost(addr), issued)))
+}
+
+// setClaimToken hands a fresh claim token to the browser in a cookie.
+func setClaimToken(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     claimTokenCookie,
+		Value:    newClaimToken(r.RemoteAddr),
+		Path:     publicPrefix() + "/",
+		MaxAge:   int(claimTokenTTLFlag.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// requestToken returns the claim token carried by a request in its cookies.
+func requestToken(r *http.Request) string {
+	if cookie, err := r.Cookie(claimTokenCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// tokenStage rejects claims without a valid claim token, if required.
+func tokenStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if !*claimTokenFlag || c.Trusted {
+			return next(c)
+		}
+		if !verifyClaimToken(c.Token, c.IP) {
+			log.Info("Rejecting claim with invalid token: ", c.Address, " ip: ", remoteHost(c.IP))
+			return newUserError("Session expired, please reload the page")
+		}
+		return next(c)
+	}
+}
+
+// onClaimToken issues a fresh claim token to the requester.
+func onClaimToken(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"token": newClaimToken(r.RemoteAddr)})
+}
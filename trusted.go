@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+var (
+	trustedNetsFlag     = flag.String("trusted.cidrs", "", "Comma separated networks of trusted requesters, e.g. an office VPN or CI runners, exempt from captchas and claim tokens (empty = none)")
+	trustedCooldownFlag = flag.Float64("trusted.cooldown", 0.1, "Cooldown multiplier of claims from trusted networks (0 = no cooldown)")
+)
+
+// trustedClaims counts the claims from trusted networks.
+var trustedClaims = newCounter("faucet_trusted_claims_total", "Claims from trusted networks, exempt from captchas and with relaxed cooldowns.")
+
+// trustedNets are the networks of trusted requesters, parsed on startup.
+var trustedNets []*net.IPNet
+
+// setupTrusted parses the trusted networks. Single addresses are accepted as
+// networks of their own.
+func setupTrusted() error {
+	if *trustedCooldownFlag < 0 {
+		return fmt.Errorf("negative cooldown multiplier %v", *trustedCooldownFlag)
+	}
+	if strings.TrimSpace(*trustedNetsFlag) == "" {
+		return nil
+	}
+	for _, spec := range strings.Split(*trustedNetsFlag, ",") {
+		spec = strings.TrimSpace(spec)
+		if !strings.Contains(spec, "/") {
+			ip := net.ParseIP(spec)
+			if ip == nil {
+				return fmt.Errorf("invalid network %q", spec)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			trustedNets = append(trustedNets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(spec)
+		if err != nil {
+			return fmt.Errorf("invalid network %q", spec)
+		}
+		trustedNets = append(trustedNets, network)
+	}
+	return nil
+}
+
+// trustedRemote reports whether a remote address is on a trusted network. The
+// requester is authenticated by its address alone, so behind a reverse proxy
+// this relies on --api.proxy reporting the real client.
+func trustedRemote(addr string) bool {
+	ip := net.ParseIP(remoteHost(addr))
+	if ip == nil {
+		return false
+	}
+	for _, network := range trustedNets {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// trustedCooldown relaxes the cooldown of a claim from a trusted network,
+// keeping it at whole minutes as the tiers are.
+func trustedCooldown(cooldown time.Duration) time.Duration {
+	return time.Duration(float64(cooldown) * *trustedCooldownFlag).Round(time.Minute)
+}
+
+// trustedStage relaxes the cooldown of claims from trusted networks. Their
+// captchas and claim tokens are skipped by the respective stages.
+func trustedStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if c.Trusted {
+			c.Cooldown = trustedCooldown(c.Cooldown)
+			trustedClaims.Inc()
+		}
+		return next(c)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var upgradeTimeoutFlag = flag.Duration("upgrade.timeout", 10*time.Minute, "Time to keep serving pending requests after handing the listeners to an upgraded binary")
+
+// Environment variables an upgraded child process is started with, on top of
+// the socket activation ones describing the handed over listeners.
+const (
+	upgradeParentEnv = "FAUCET_UPGRADE_PPID"  // PID of the process handing over its listeners
+	upgradeReadyEnv  = "FAUCET_UPGRADE_READY" // File descriptor to signal readiness on
+)
+
+// servers tracks the HTTP servers along with the listeners they serve, so they
+// can be handed over to an upgraded binary.
+var servers = struct {
+	lock  sync.Mutex
+	names []string
+	lns   []net.Listener
+	srvs  []*http.Server
+}{}
+
+// pending counts the claims being processed, which are allowed to complete
+// after the listeners were handed over. New claims are turned away meanwhile.
+var (
+	pending  int64
+	draining int32
+)
+
+// serve serves HTTP requests on the listener until it is handed over to an
+// upgraded binary, in which case http.ErrServerClosed is returned. The listener
+// is served over TLS if a configuration is given.
+func serve(name string, listener net.Listener, handler http.Handler, config *tls.Config) error {
+	srv := newHTTPServer(handler)
+	srv.TLSConfig = config
+
+	servers.lock.Lock()
+	servers.names = append(servers.names, name)
+	servers.lns = append(servers.lns, listener)
+	servers.srvs = append(servers.srvs, srv)
+	servers.lock.Unlock()
+
+	if config != nil {
+		return srv.ServeTLS(listener, *crt, *key)
+	}
+	return srv.Serve(listener)
+}
+
+// trackPending wraps a funding handler to track the claims in progress.
+func trackPending(handler Handler) Handler {
+	return func(c *Claim) error {
+		atomic.AddInt64(&pending, 1)
+		defer atomic.AddInt64(&pending, -1)
+
+		if atomic.LoadInt32(&draining) != 0 {
+			return newUserError("Faucet is restarting, please retry")
+		}
+		return handler(c)
+	}
+}
+
+// upgrade starts the faucet binary anew, handing it the listeners of this one.
+// Once the new process is ready, this one stops accepting connections and the
+// API server returns, leaving waitPending to let the running claims complete.
+func upgrade() error {
+	servers.lock.Lock()
+	defer servers.lock.Unlock()
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	var files []*os.File
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+	for _, listener := range servers.lns {
+		filer, ok := listener.(interface{ File() (*os.File, error) })
+		if !ok {
+			return fmt.Errorf("listener %s can't be handed over", listener.Addr())
+		}
+		file, err := filer.File()
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+	}
+	ready, signal, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer ready.Close()
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	cmd.ExtraFiles = append(files, signal)
+	cmd.Env = append(os.Environ(),
+		"LISTEN_FDS="+strconv.Itoa(len(files)),
+		"LISTEN_FDNAMES="+strings.Join(servers.names, ":"),
+		upgradeParentEnv+"="+strconv.Itoa(os.Getpid()),
+		upgradeReadyEnv+"="+strconv.Itoa(listenFdsStart+len(files)),
+	)
+	err = cmd.Start()
+	signal.Close()
+	if err != nil {
+		return err
+	}
+	// Wait for the child to report ready, it dies trying otherwise
+	ready.SetReadDeadline(time.Now().Add(time.Minute))
+	if _, err := ready.Read(make([]byte, 1)); err != nil {
+		cmd.Process.Kill()
+		return errors.New("upgraded process failed to start")
+	}
+	go cmd.Wait()
+	log.Info("Handed listeners over to upgraded process ", cmd.Process.Pid)
+
+	atomic.StoreInt32(&draining, 1)
+	for _, srv := range servers.srvs {
+		go srv.Shutdown(context.Background())
+	}
+	return nil
+}
+
+// upgradeReady tells the process that started this one that it may stop
+// accepting connections, if this is an upgraded binary.
+func upgradeReady() {
+	fd, err := strconv.Atoi(os.Getenv(upgradeReadyEnv))
+	if err != nil {
+		return
+	}
+	os.Unsetenv(upgradeReadyEnv)
+
+	signal := os.NewFile(uintptr(fd), "upgrade")
+	defer signal.Close()
+	if _, err := signal.Write([]byte{1}); err != nil {
+		log.Error("Failed to signal readiness to the parent process: ", err)
+	}
+	// Take over as the main process of the service if run by systemd
+	sdNotify(fmt.Sprintf("MAINPID=%d", os.Getpid()))
+}
+
+// waitPending waits for the claims in progress to complete, bounded by the
+// upgrade timeout.
+func waitPending() {
+	deadline := time.Now().Add(*upgradeTimeoutFlag)
+	for atomic.LoadInt64(&pending) > 0 {
+		if time.Now().After(deadline) {
+			log.Error("Timed out waiting for pending claims, exiting")
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	log.Info("Pending claims completed, exiting")
+}
@@ -0,0 +1,7 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package main
+
+// watchUpgrades is a no-op on platforms listeners can't be handed over on.
+func watchUpgrades() {}
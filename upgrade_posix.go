@@ -0,0 +1,31 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/sunvim/utils/log"
+)
+
+// watchUpgrades hands the listeners over to a freshly started faucet binary
+// whenever SIGUSR2 is received.
+func watchUpgrades() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGUSR2)
+
+	go func() {
+		for range sigs {
+			log.Info("Upgrade requested, starting new process")
+			if err := upgrade(); err != nil {
+				log.Error("Failed to upgrade: ", err)
+				continue
+			}
+			signal.Stop(sigs)
+			return
+		}
+	}()
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"flag"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	velocityAmountFlag    = flag.Float64("velocity.amount", 0, "Payouts per hour (in token units) above which the faucet tightens its limits (0 = unlimited)")
+	velocityAddressesFlag = flag.Int("velocity.addresses", 0, "Funded addresses per hour above which the faucet tightens its limits (0 = unlimited)")
+	velocityPayoutFlag    = flag.Float64("velocity.payout", 0.5, "Payout multiplier while the limits are tightened")
+	velocityCooldownFlag  = flag.Float64("velocity.cooldown", 2, "Cooldown multiplier while the limits are tightened")
+	velocityCaptchaFlag   = flag.Float64("velocity.captcha", 0, "Minimum reCAPTCHA v3 score while the limits are tightened (0 = unchanged)")
+)
+
+// velocityRelax is the fraction of the velocity bands the dispense velocity
+// must fall below for tightened limits to be relaxed again, so the faucet
+// doesn't flap between the two around the bands.
+const velocityRelax = 0.8
+
+// velocity tracks the payouts of the past hour to detect the faucet being
+// drained faster than expected.
+var velocity struct {
+	lock      sync.Mutex
+	payouts   []velocityPayout
+	tightened bool
+}
+
+// velocityPayout is a single payout within the velocity window.
+type velocityPayout struct {
+	time    time.Time
+	address string
+	amount  *big.Int
+}
+
+func init() {
+	registerGauge("faucet_velocity_amount", "Payouts over the past hour in token units.", func() float64 {
+		amount, _ := dispenseVelocity()
+		f, _ := new(big.Float).Quo(new(big.Float).SetInt(amount), new(big.Float).SetInt(toWei(1))).Float64()
+		return f
+	})
+	registerGauge("faucet_velocity_addresses", "Addresses funded over the past hour.", func() float64 {
+		_, addresses := dispenseVelocity()
+		return float64(addresses)
+	})
+	registerGauge("faucet_velocity_tightened", "Whether limits are tightened due to high dispense velocity (1) or not (0).", func() float64 {
+		if velocityTightened() {
+			return 1
+		}
+		return 0
+	})
+}
+
+// startVelocity periodically reevaluates the dispense velocity, so tightened
+// limits are relaxed again once payouts leave the window, if enabled.
+func startVelocity() {
+	if !velocityEnabled() {
+		return
+	}
+	go func() {
+		for range time.Tick(time.Minute) {
+			checkVelocity()
+		}
+	}()
+}
+
+// velocityEnabled reports whether any velocity band is configured.
+func velocityEnabled() bool {
+	return *velocityAmountFlag > 0 || *velocityAddressesFlag > 0
+}
+
+// velocityTightened reports whether limits are currently tightened.
+func velocityTightened() bool {
+	velocity.lock.Lock()
+	defer velocity.lock.Unlock()
+	return velocity.tightened
+}
+
+// dispenseVelocity returns the total paid out and the number of distinct
+// addresses funded over the past hour.
+func dispenseVelocity() (*big.Int, int) {
+	velocity.lock.Lock()
+	defer velocity.lock.Unlock()
+
+	cutoff := time.Now().Add(-time.Hour)
+	for len(velocity.payouts) > 0 && velocity.payouts[0].time.Before(cutoff) {
+		velocity.payouts = velocity.payouts[1:]
+	}
+	amount, addresses := new(big.Int), make(map[string]struct{})
+	for _, payout := range velocity.payouts {
+		amount.Add(amount, payout.amount)
+		addresses[payout.address] = struct{}{}
+	}
+	return amount, len(addresses)
+}
+
+// checkVelocity tightens the limits if the dispense velocity exceeds any of
+// the bands, and relaxes them once it fell back well below all of them.
+func checkVelocity() {
+	if !velocityEnabled() {
+		return
+	}
+	amount, addresses := dispenseVelocity()
+	units, _ := new(big.Float).Quo(new(big.Float).SetInt(amount), new(big.Float).SetInt(toWei(1))).Float64()
+
+	exceeds := func(value, band, scale float64) bool {
+		return band > 0 && value > band*scale
+	}
+	velocity.lock.Lock()
+	defer velocity.lock.Unlock()
+
+	switch {
+	case !velocity.tightened && (exceeds(units, *velocityAmountFlag, 1) || exceeds(float64(addresses), float64(*velocityAddressesFlag), 1)):
+		velocity.tightened = true
+		alert("velocity", "dispensed %s %s to %d addresses over the past hour, tightening limits", fromWei(amount), *UnitFlag, addresses)
+	case velocity.tightened && !exceeds(units, *velocityAmountFlag, velocityRelax) && !exceeds(float64(addresses), float64(*velocityAddressesFlag), velocityRelax):
+		velocity.tightened = false
+		log.Info("Dispense velocity back to normal, relaxing limits")
+	}
+}
+
+// velocityStage tightens the limits of claims while the faucet is drained
+// faster than the configured bands allow, and tracks the payouts made.
+func velocityStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if !velocityEnabled() {
+			return next(c)
+		}
+		if velocityTightened() {
+			if *velocityCaptchaFlag > 0 && *captchaV3Flag && c.Score < *velocityCaptchaFlag {
+				log.Info("Captcha score too low under high velocity: ", c.Address, " score: ", c.Score)
+				return newUserError("Beep-bop, you're a robot!")
+			}
+			c.Amount, _ = new(big.Float).Mul(new(big.Float).SetInt(c.Amount), big.NewFloat(*velocityPayoutFlag)).Int(nil)
+			c.Cooldown = time.Duration(float64(c.Cooldown) * *velocityCooldownFlag)
+		}
+		// Payouts that failed on chain are refunded and drain nothing, unlike
+		// those sent but unconfirmed, which may still be mined
+		err := next(c)
+		if c.Tx != nil && !c.decoy && !refundable(c, err) {
+			velocity.lock.Lock()
+			velocity.payouts = append(velocity.payouts, velocityPayout{time: time.Now(), address: c.Address, amount: c.Amount})
+			velocity.lock.Unlock()
+			checkVelocity()
+		}
+		return err
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sunvim/utils/log"
+)
+
+// vouchersBucket is the store bucket holding the issued vouchers by code.
+const vouchersBucket = "vouchers"
+
+// maxVoucherBatch is the maximum number of vouchers generated in one go.
+const maxVoucherBatch = 10000
+
+// voucherReservationTTL is how long a voucher stays reserved by a claim before
+// the reservation is considered left behind by a crash and released. Claims
+// take far less, even when waiting in a deep queue for their receipt.
+const voucherReservationTTL = time.Hour
+
+// errVoucherExists is returned if a freshly generated voucher code is taken.
+var errVoucherExists = errors.New("voucher code collision")
+
+// voucher is a single-use claim code redeemable for a fixed payout, bypassing
+// the cooldown of the requester.
+type voucher struct {
+	Code       string    `json:"code"`
+	Batch      string    `json:"batch,omitempty"`
+	Amount     string    `json:"amount"` // Payout in wei, decimal
+	Created    time.Time `json:"created"`
+	Reserved   bool      `json:"reserved,omitempty"` // Redemption in progress
+	ReservedAt time.Time `json:"reservedAt,omitempty"`
+	Redeemed   time.Time `json:"redeemed,omitempty"`
+	RedeemedBy string    `json:"redeemedBy,omitempty"`
+	Tx         string    `json:"tx,omitempty"`
+}
+
+// newVoucherCode generates a random, easy to type voucher code.
+func newVoucherCode() (string, error) {
+	raw := make([]byte, 10)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	code := base32.StdEncoding.EncodeToString(raw)
+	return code[:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:16], nil
+}
+
+// normalizeVoucherCode canonicalizes a user supplied voucher code.
+func normalizeVoucherCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// voucherStage redeems the voucher attached to a claim, if any. The voucher is
+// reserved for the duration of the claim so it can't be spent twice, and only
+// marked redeemed once the funds were sent. Reservations older than
+// voucherReservationTTL were left behind by a crash and are released.
+func voucherStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if c.Voucher == "" {
+			return next(c)
+		}
+		code := normalizeVoucherCode(c.Voucher)
+
+		var v voucher
+		err := store.Update(vouchersBucket, code, func(blob []byte) ([]byte, error) {
+			if blob == nil {
+				return nil, errNotFound
+			}
+			if err := json.Unmarshal(blob, &v); err != nil {
+				return nil, err
+			}
+			if !v.Redeemed.IsZero() || (v.Reserved && time.Since(v.ReservedAt) < voucherReservationTTL) {
+				return nil, newUserError("Voucher already redeemed")
+			}
+			if v.Reserved {
+				log.Info("Releasing stale voucher reservation: ", v.Code, " reserved: ", v.ReservedAt)
+			}
+			v.Reserved, v.ReservedAt = true, time.Now()
+			return json.Marshal(&v)
+		})
+		switch {
+		case err == errNotFound:
+			return newUserError("Invalid voucher code")
+		case err != nil:
+			return err
+		}
+		c.Amount, _ = new(big.Int).SetString(v.Amount, 10)
+		c.SkipCooldown = true
+
+		// The claim is waited for even if the requester left once it was taken
+		// by the sender, so a voucher is never released after paying out
+		err = next(c)
+
+		v.Reserved, v.ReservedAt = false, time.Time{}
+		if !refundable(c, err) && !c.decoy {
+			v.Redeemed, v.RedeemedBy = time.Now(), c.Address
+			if c.Tx != nil {
+				v.Tx = c.Tx.ID()
+			}
+		}
+		if perr := putJSON(store, vouchersBucket, code, &v); perr != nil {
+			log.Error("Failed to update voucher err: ", perr)
+		}
+		return err
+	}
+}
+
+// onAdminVouchers lists the vouchers on GET (optionally filtered by the batch
+// query parameter) and generates a new batch on POST.
+func onAdminVouchers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		batch := r.URL.Query().Get("batch")
+
+		vouchers := []*voucher{}
+		err := store.Iterate(vouchersBucket, func(key string, blob []byte) bool {
+			v := new(voucher)
+			if err := json.Unmarshal(blob, v); err != nil {
+				log.Error("Failed to decode voucher err: ", err)
+				return true
+			}
+			if batch == "" || v.Batch == batch {
+				vouchers = append(vouchers, v)
+			}
+			return true
+		})
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, vouchers)
+
+	case http.MethodPost:
+		var req struct {
+			Count  int     `json:"count"`
+			Amount float64 `json:"amount"` // Payout in token units
+			Batch  string  `json:"batch"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		if req.Count <= 0 || req.Count > maxVoucherBatch {
+			writeJSONError(w, http.StatusBadRequest, fmt.Errorf("count must be between 1 and %d", maxVoucherBatch))
+			return
+		}
+		if req.Amount <= 0 {
+			writeJSONError(w, http.StatusBadRequest, errors.New("amount must be positive"))
+			return
+		}
+		amount := toWei(req.Amount)
+
+		codes := make([]string, 0, req.Count)
+		for len(codes) < req.Count {
+			code, err := newVoucherCode()
+			if err != nil {
+				writeJSONError(w, http.StatusInternalServerError, err)
+				return
+			}
+			v := &voucher{Code: code, Batch: req.Batch, Amount: amount.String(), Created: time.Now()}
+			blob, err := json.Marshal(v)
+			if err != nil {
+				writeJSONError(w, http.StatusInternalServerError, err)
+				return
+			}
+			err = store.Update(vouchersBucket, code, func(old []byte) ([]byte, error) {
+				if old != nil {
+					return nil, errVoucherExists
+				}
+				return blob, nil
+			})
+			if err == errVoucherExists {
+				continue // Astronomically unlikely, just roll a new code
+			}
+			if err != nil {
+				writeJSONError(w, http.StatusInternalServerError, err)
+				return
+			}
+			codes = append(codes, code)
+		}
+		log.Info("Generated vouchers: ", len(codes), " batch: ", req.Batch)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"batch": req.Batch, "codes": codes})
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
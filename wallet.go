@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+var (
+	chainNameFlag     = flag.String("chain.name", "", "Network name suggested to wallets (empty = faucet name)")
+	chainRPCFlag      = flag.String("chain.rpc", "", "Public RPC endpoint suggested to wallets (empty = none, only offering to switch to networks the wallet knows)")
+	chainExplorerFlag = flag.String("chain.explorer", "", "Block explorer URL suggested to wallets")
+
+	tokenAddressFlag  = flag.String("token.address", "", "ERC-20 token contract to offer adding to wallets (empty = none)")
+	tokenSymbolFlag   = flag.String("token.symbol", "", "Ticker symbol of the ERC-20 token (empty = --unit)")
+	tokenDecimalsFlag = flag.Int("token.decimals", 18, "Number of decimals of the ERC-20 token")
+	tokenImageFlag    = flag.String("token.image", "", "Logo URL of the ERC-20 token")
+)
+
+// walletChain is the network definition handed to wallet_addEthereumChain as
+// specified by EIP-3085.
+type walletChain struct {
+	ChainID           string               `json:"chainId"`
+	ChainName         string               `json:"chainName"`
+	NativeCurrency    walletNativeCurrency `json:"nativeCurrency"`
+	RPCURLs           []string             `json:"rpcUrls,omitempty"`
+	BlockExplorerURLs []string             `json:"blockExplorerUrls,omitempty"`
+}
+
+// walletNativeCurrency describes the native currency of a network to wallets.
+type walletNativeCurrency struct {
+	Name     string `json:"name"`
+	Symbol   string `json:"symbol"`
+	Decimals int    `json:"decimals"`
+}
+
+// walletAsset is the token definition handed to wallet_watchAsset as specified
+// by EIP-747.
+type walletAsset struct {
+	Type    string             `json:"type"`
+	Options walletAssetOptions `json:"options"`
+}
+
+// walletAssetOptions holds the ERC-20 metadata of a watched asset.
+type walletAssetOptions struct {
+	Address  string `json:"address"`
+	Symbol   string `json:"symbol"`
+	Decimals int    `json:"decimals"`
+	Image    string `json:"image,omitempty"`
+}
+
+// newWalletChain assembles the network definition of the funded chain from the
+// configuration flags. The definition is public, so it only suggests the RPC
+// endpoint explicitly configured as such, never the faucet's own --rpc, whose
+// URL may embed provider API keys.
+func newWalletChain() *walletChain {
+	name := *chainNameFlag
+	if name == "" {
+		name = *apiName
+	}
+	chain := &walletChain{
+		ChainID:   hexutil.EncodeUint64(uint64(*chainID)),
+		ChainName: name,
+		NativeCurrency: walletNativeCurrency{
+			Name:     *UnitFlag,
+			Symbol:   *UnitFlag,
+			Decimals: 18,
+		},
+	}
+	if *chainRPCFlag != "" {
+		chain.RPCURLs = []string{*chainRPCFlag}
+	}
+	if *chainExplorerFlag != "" {
+		chain.BlockExplorerURLs = []string{strings.TrimSuffix(*chainExplorerFlag, "/")}
+	}
+	return chain
+}
+
+// newWalletAsset assembles the ERC-20 token definition from the configuration
+// flags, or returns nil if no token is configured.
+func newWalletAsset() *walletAsset {
+	if !common.IsHexAddress(*tokenAddressFlag) {
+		return nil
+	}
+	symbol := *tokenSymbolFlag
+	if symbol == "" {
+		symbol = *UnitFlag
+	}
+	return &walletAsset{
+		Type: "ERC20",
+		Options: walletAssetOptions{
+			Address:  common.HexToAddress(*tokenAddressFlag).Hex(),
+			Symbol:   symbol,
+			Decimals: *tokenDecimalsFlag,
+			Image:    *tokenImageFlag,
+		},
+	}
+}
@@ -78,7 +78,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var _faucetHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xb4\x3a\xeb\x93\xe3\x34\xf2\x9f\x87\xbf\xa2\xf1\x6f\x21\xce\x6f\xc6\x76\xb2\xc3\xab\x92\x38\x57\x7b\x0b\x47\xed\x55\x1d\x6c\x1d\x50\x77\x57\xc0\x07\xc5\xea\xc4\x9a\x91\x25\x23\xc9\xc9\x84\x54\xfe\xf7\x2b\xc9\x8f\xf8\x95\x61\x81\xbd\x2f\xbb\xb2\xba\xd5\xef\x6e\x75\x2b\xb3\xfa\xf0\xcb\x6f\x5f\x7f\xff\x9f\xb7\x5f\x41\x6a\x32\xbe\xfe\x60\x65\xff\x03\x4e\xc4\x2e\xf6\x50\x78\xeb\x0f\x00\x56\x29\x12\x6a\x17\x00\xab\x0c\x0d\x81\x24\x25\x4a\xa3\x89\xbd\xc2\x6c\x83\x2f\x3c\x88\xda\xc0\xd4\x98\x3c\xc0\x5f\x0a\xb6\x8f\xbd\x7f\x07\x3f\xbc\x0a\x5e\xcb\x2c\x27\x86\x6d\x38\x7a\x90\x48\x61\x50\x98\xd8\x7b\xf3\x55\x8c\x74\x87\xbd\xb3\x82\x64\x18\x7b\x7b\x86\x87\x5c\x2a\xd3\x42\x3f\x30\x6a\xd2\x98\xe2\x9e\x25\x18\xb8\x8f\x3b\x60\x82\x19\x46\x78\xa0\x13\xc2\x31\x9e\x3b\x52\x25\x2d\xc3\x0c\xc7\xf5\xe9\x04\xe1\x37\x24\x43\x38\x9f\xe1\x6f\xa4\x48\xd0\xac\xa2\x12\x52\xa1\x71\x26\x1e\xdd\x0a\x20\x55\xb8\x8d\x3d\x2b\xba\x5e\x44\x51\x42\xc5\x83\x0e\x13\x2e\x0b\xba\xe5\x44\x61\x98\xc8\x2c\x22\x0f\xe4\x29\xe2\x6c\xa3\x23\x73\x60\xc6\xa0\x0a\x36\x52\x1a\x6d\x14\xc9\xa3\xfb\xf0\x3e\xfc\x3c\x4a\xb4\x8e\x9a\xbd\x30\x63\x22\x4c\xb4\xf6\x2a\x0e\x0a\x79\xec\x69\x73\xe4\xa8\x53\x44\x53\x6e\xd7\xca\xff\x51\x49\xb6\x52\x98\x80\x1c\x50\xcb\x0c\xa3\x4f\xc2\xcf\xc3\x99\x13\xa2\xbd\xfd\xae\x72\x94\x82\xe8\x44\xb1\xdc\x80\x56\xc9\x3b\xcb\xf0\xf0\x4b\x81\xea\x18\xdd\x87\xf3\x70\x5e\x7d\x38\x9e\x0f\xda\x5b\xaf\xa2\x92\xe0\xfa\x4f\x52\x0f\x84\x34\xc7\xe8\x65\xf8\x49\x38\x8f\x72\x92\x3c\x92\x1d\xd2\x9a\x97\x05\x85\xf5\xe6\x7b\xe4\x7c\xcd\xcb\x0f\x7d\x27\xbf\x1f\x76\x99\xcc\x50\x98\xf0\x41\x47\x2f\xc3\xf9\x17\xe1\xac\xde\x18\x72\xa8\x58\x58\x17\xae\x2b\xa7\x86\x7b\x54\x86\x25\x84\x07\x09\x0a\x83\x0a\x4e\x15\x00\x20\x63\x22\x48\x91\xed\x52\xb3\x80\xf9\x6c\xf6\xd1\xf2\x1a\x64\x9f\x5e\x40\x94\xe9\x9c\x93\xe3\x02\xb6\x1c\x9f\x2e\xdb\x84\xb3\x9d\x08\x98\xc1\x4c\x2f\xa0\xe4\x54\x03\xcf\xb5\x24\xb9\x92\x3b\x85\x5a\xb7\x44\xc8\xa5\x66\x86\x49\xb1\xb0\xc1\x47\x0c\xdb\xe3\xf5\x53\x3a\x27\x62\xf4\x28\xd9\x68\xc9\x0b\x83\x23\x42\x6e\xb8\x4c\x1e\x2f\xfb\xae\x3c\xf4\x95\x4d\x24\x97\x6a\x01\x87\x94\x99\x01\xf7\x5c\x61\x9b\x25\xa1\x94\x89\xdd\x02\x3e\xcb\x5b\xaa\x67\x44\xed\x98\x58\xc0\xac\x7b\x78\x15\x35\x7e\x58\x45\x65\x99\xb4\xcb\x8d\xa4\xc7\x2a\x14\x28\xdb\x43\xc2\x89\xd6\xb1\xd7\x73\x92\x57\x7b\xaf\x8d\x63\x2b\x1e\x61\xa2\x05\xed\xc2\x95\x3c\x78\xe0\x78\xc6\x5e\x29\x53\xb0\x91\xc6\xc8\x6c\x01\xf3\xcf\xf2\xa7\xd6\xa9\x3e\x5d\x1e\xf0\x5d\x30\x7f\xd9\xc1\xb0\xb5\x7d\x5e\x93\x33\xf8\x64\x02\xe7\xe2\xda\xb9\x3d\x5c\x80\x15\xab\xe9\x6d\x09\x6c\x49\xb0\x21\x26\xf5\x80\x28\x46\x82\x94\x51\x8a\x22\xf6\x8c\x2a\xd0\x46\x2b\xeb\x9f\x1d\x96\xe3\xae\x20\x51\x3a\xef\x08\x1f\x51\xb6\x6f\xd9\xa0\xf7\xd9\x33\xc9\x6f\xa8\xfd\x05\x54\x0b\xb9\xdd\x6a\x34\xc1\xc0\x0a\xad\x23\x4c\xe4\x85\x09\x76\x4a\x16\xf9\x88\xfe\x16\xd8\xdb\x04\x60\x34\xf6\x0a\xc5\xbd\x01\xa0\xbc\xcf\x46\x41\xe6\x98\x57\x36\x1f\xc2\x6a\x1b\x4b\x95\x05\x36\x20\x94\x1c\x21\x90\x73\x92\x60\x2a\x39\x45\x15\x7b\x6f\x39\x12\x8d\xe0\xc4\x83\xa3\x2c\x14\x1c\x08\xe7\x68\x80\x50\x6a\xf3\x2a\x0c\xc3\x3e\x85\x68\xa0\x9c\xcb\xbd\xa1\x15\x82\x8d\x11\x03\x4b\xd8\x20\x2f\x8c\x91\x62\xb0\xdf\x88\xbf\x31\x02\x36\x46\x04\x14\xb7\xa4\xe0\x06\xa8\x92\x39\x95\x07\x11\x18\xb9\xdb\x71\x1c\x6a\x54\x1b\xa5\x24\x3c\x06\xa7\xc4\x90\xea\x78\xec\xd5\xf4\xc6\x10\xcb\x90\x24\x3a\x97\x79\x91\x57\x41\x79\x0d\x0d\x9f\x72\x22\x28\x52\x1b\xd4\x5c\x8f\xe0\x0d\x75\x07\xf8\x9a\xed\x11\x32\x1c\x81\xf4\x73\x24\x21\x0a\x4d\xe0\x04\x7d\xc7\x4c\xb1\xd1\x5e\xda\x60\x04\x52\xf0\x9a\x7c\x63\xcf\x0c\x45\x01\x9d\xaf\x40\xd9\xb2\x3e\xe2\x34\x9b\x86\x8a\x88\x1d\xc2\x0b\x46\x9f\xee\xe0\x05\xc9\x64\x21\x0c\x2c\x62\x08\x5f\xb9\xa5\x3e\x9f\xc7\x94\xe2\x23\x52\x3a\x00\x19\xdd\x86\x67\x8a\xca\x95\x03\x52\x24\x9c\x25\x8f\xb1\x67\x18\xaa\xf8\x74\xb2\x02\x9e\xcf\x4b\x38\x9d\xd8\x16\x5e\x84\xff\xc4\x84\xe4\x26\x49\xc9\xf9\xbc\x53\xf5\x3a\xc4\x27\x4c\x0a\x83\xfe\xf4\x74\x42\xae\xf1\x7c\xd6\xc5\x26\x63\xc6\xaf\x8f\xdb\x7d\x41\xcf\xe7\x6b\x4c\xd7\xa7\x53\x65\x82\xf3\x19\x22\xcb\x4b\x50\x7c\x82\x17\xe1\x5b\x54\x4c\x52\x0d\x25\x99\x55\x34\xae\xe6\x98\x4d\x56\xd1\xb8\xad\x2a\x49\x46\x7c\x5d\xf0\x41\x2a\x46\x36\x17\x7b\x35\xaa\x5b\x01\x4b\x92\x6c\x0b\x6d\xcb\x0c\x8a\x5a\x8f\x6e\x15\x39\xbb\xa0\xb1\x60\xdf\x30\x2e\xc3\x34\x33\xf8\x88\xc7\xd8\x3b\x9d\xda\xd4\x47\x71\x13\xc2\xf9\x86\x58\xbf\x95\xa6\xbf\x42\xf0\x57\xb4\x35\x65\xcf\xb4\x9b\x03\x3a\x38\xeb\x51\xcd\xfa\xc6\x7a\xee\x3e\x68\x7d\xb4\x97\xed\x96\x0c\x20\x8a\xe0\x6b\x2e\x37\x84\xc3\xde\xa6\xe1\x86\xa3\x06\x23\xc1\x56\x50\x30\x29\x42\x52\x28\x85\xc2\x80\x36\xc4\x14\x1a\xe4\xd6\xed\x6e\xdb\x37\xd5\x9e\x28\x20\xc6\x60\x96\x1b\x88\x2f\xad\x80\xdd\xd6\xa8\xf6\x97\x6e\xc8\xee\xd8\x30\xee\x63\x29\xfc\xa5\x40\x6d\x34\xc4\xf0\xe3\xcf\xcb\x0f\x2e\x92\x7d\x89\x5b\x26\x10\x08\x6c\x0b\x91\xd8\x86\x07\x4c\x4a\x0c\x24\x0a\x89\x41\x0d\x09\x97\xba\x50\xa5\xc0\x36\xd1\xc1\x0a\x5d\x13\x6b\xd1\xb7\xb0\xdc\xb1\xad\xe9\xf8\x29\xd1\xe9\xb4\x69\x6f\x6e\x14\x9a\x42\x89\x0b\xb8\x05\xba\xd9\x4a\x05\xbe\x25\xc3\xe2\xd9\x12\xd8\xaa\x66\x10\x72\x14\x3b\x93\x2e\x81\xdd\xde\xb6\xf1\x6f\xd8\x16\xfc\x1a\xe9\x47\xf6\x73\x68\x9e\x42\xcb\x0e\xe2\x18\x7a\x6c\x6f\x6e\x6e\x1a\x6a\x3a\xe7\x2c\x41\x9f\xdd\xc1\x7c\xba\x6c\x21\x6c\x14\x92\xc7\xd6\xc6\xb9\x59\x36\xab\x7a\x71\x5e\x0e\x4c\xe7\x9c\xd5\x31\x5e\x19\x8f\x1a\x08\xec\x98\x36\x50\x28\x6e\xcd\x67\xf1\x4a\x67\xb5\xbd\xe7\x50\xdb\x66\x1b\xa4\x56\xb5\xa8\x02\xb3\xa5\x5a\x49\x2c\xd4\x28\xa8\xff\xf7\xef\xbe\xfd\x26\xd4\x46\x31\xb1\x63\xdb\xa3\x7f\x2a\x14\x5f\xc0\x0b\xdf\xfb\x3f\xdb\x02\x4c\x7f\x9c\xfd\x1c\xee\x09\x2f\xf0\xce\x05\xc7\xc2\xfd\x3b\x60\x74\x07\xd5\x72\x01\x5d\x9e\xe7\xe9\x74\x79\x2d\xe3\x6f\x5a\x35\x51\xa1\x46\xe3\x5b\xdc\x76\x0e\x8d\x98\x8c\x40\x86\x26\x95\xd4\x9a\x45\x61\x22\x85\xc0\xc4\x40\x91\x4b\x51\x59\x08\xb8\xd4\xba\x13\xbe\x35\x52\x3c\x1a\x41\xd5\xa9\x18\x04\x1e\xe0\x5f\xb8\xf9\x4e\x26\x8f\x68\x7c\xdf\x3f\x30\x41\xe5\x21\xe4\x32\x21\xf6\x8c\x6d\xf7\x8d\x4c\x24\x87\x38\x8e\xa1\x9a\x90\xbc\x29\xfc\x05\xbc\x83\xb6\xb3\x92\x07\x0b\xbb\xb4\xab\x29\xdc\x42\xff\x78\x2a\xb5\x81\x5b\xf0\x22\x92\x33\x6f\xda\xe4\x51\xed\x0a\x29\x32\xd4\x9a\xec\xb0\x2d\x26\xee\x51\x98\x76\x44\x5a\x85\x32\xbd\x83\x18\x9c\xd7\x72\xa2\x34\x96\x58\xa1\xad\x59\xad\xd0\xb4\x61\xee\x30\xe3\x18\x44\xc1\x79\x27\xae\xcb\x84\x5a\xb6\x62\xb5\x7f\x2e\x44\xa5\xa4\x82\x0f\xe3\x18\x0a\x41\x9d\xe9\x69\x87\x84\x9d\x60\xfd\x13\x27\x47\x59\x98\x05\x4c\x8c\xcc\x5f\xbb\x9b\x72\x72\x07\xf6\xfa\x5c\x40\x43\xe4\xce\x75\x48\x0b\x98\xb8\x2f\x0b\x67\x19\xba\x53\x9f\xce\x66\xb3\x3b\xa8\xc7\xa8\xbf\x12\x1b\x5d\xaa\xc0\xf3\x74\x39\xcc\xa2\x46\x30\x5d\x24\x89\x1d\xba\xfe\xa4\x68\x15\x99\x46\xb8\xea\xfb\x4f\x8b\xd7\x54\xcb\x8e\x7c\xf0\xf1\xc7\x30\x80\x0e\xdc\x12\x45\xf0\x0f\xa2\x1e\x81\x70\x6e\xe7\xbb\x3d\x93\x85\xbe\x94\xdf\x8c\x69\xcd\xc4\x0e\x88\x06\x2a\x05\x5e\x8e\xfd\xee\x12\x38\x10\xb6\xc2\x84\x35\xcc\xfa\x92\xda\xfc\x6f\x95\xc8\x91\xca\xd9\x25\x3d\xa8\x88\x2d\x1b\x8d\x14\x5f\x96\x21\x7c\x18\x83\xe7\xf5\xa8\x0c\x90\x2c\x4e\x9b\xea\x8d\x46\xf3\x7d\xe9\x29\xbf\xba\x41\xc6\xca\xfa\xf4\x0e\xee\x67\xb3\xd9\xf4\x8a\x40\xe7\x8e\xf1\x5f\xe5\x39\x0a\x0a\x44\x1c\x5d\x31\x68\x2c\xcf\x84\x91\x60\xe7\x13\x9b\xcc\xdc\x8e\x65\x1c\x5d\x82\x5e\x4e\x5b\xf3\x27\x32\xcb\xa4\x80\x18\x82\xf9\x72\xfc\xbe\x69\xd9\xb9\xab\x6f\xdf\x85\x23\xce\x19\x71\x63\xd7\x9c\x3d\xfc\x60\xde\x71\x5c\xc7\xa7\x57\x9d\x77\xd3\xe8\xc0\x3a\xc6\x1e\x7a\xb5\xe3\xd6\x71\x8b\xb6\x94\x2a\xc9\xde\xce\xdf\x5d\xb7\x06\x23\x2f\x74\xea\xf7\xa4\x9f\x8e\xde\xb8\xd6\x85\x6f\x0c\x2a\x62\x10\xa4\xad\xe9\xd6\x65\x28\x0c\x53\x38\xf0\x1c\x10\x61\x9b\x91\x40\xa1\xa0\xa8\xea\x8b\x98\xda\xfc\x32\xb6\xcd\xea\x7b\xd6\x3d\xe9\xf6\x83\xb0\xa5\xe0\xc0\xf8\x4b\x60\xb0\xb6\x9d\x14\xb0\x20\xe8\xaa\xe6\x9a\x1e\x29\xd0\x7e\xf7\x32\xca\x45\x7a\x3f\xd4\x2d\x3e\x72\x92\x6b\xa4\x10\x43\xf9\xc4\xe6\x4f\xc3\x42\xb0\x27\x7f\x1a\x54\xdf\x7d\x32\x35\xfc\x72\xd1\x38\xef\x96\x7a\xdc\xc6\xe0\xad\x8c\x02\x46\xe3\x89\x07\xb7\x63\x59\x6d\xaf\xaa\xc9\xba\x23\x47\xfb\x34\xc0\xca\xd0\xb5\x7b\x82\x28\x87\xa6\x9f\x3c\xdb\x50\xdb\xd9\x5b\xd0\x85\xed\x5b\xfc\x01\x65\xb2\x27\x86\x28\x47\x78\xba\x84\x0b\xba\xeb\xb4\x17\x90\x58\x9f\x2d\xab\x97\xb0\xfb\x97\xf9\xd3\x12\xea\x97\xbe\xf2\x6b\x23\x15\x45\x15\x28\x42\x59\xa1\x17\xf0\x49\xfe\xb4\xfc\xc9\xab\x1a\xf1\x55\x64\xe8\x6f\x4a\x9b\x2b\x5c\x0f\x84\x4a\x12\x37\x4c\xde\x82\xb7\x8a\x2c\xc2\x3b\x50\x6a\x54\x6e\xbf\xda\xc1\x70\x6a\x5c\x42\xf3\x7a\x56\xed\x67\x8c\x52\x8e\x56\xec\x0e\x07\x9b\xc7\x36\x22\x7a\xd9\xd8\x65\x0c\x2e\x42\x91\x76\x4e\x9e\xc1\x8e\x90\xcf\x1f\x2b\xdf\x48\xac\xaf\x6d\x60\x04\xd6\x02\xcc\x79\xa1\x1a\xad\xdc\xb6\x9a\x38\xd3\x54\x0f\xb8\xb4\x50\xae\x6b\xf1\x83\x2a\xf0\xee\x60\xa2\x6d\x23\x45\xf5\x64\x1a\xa6\x45\x46\x04\xfb\x15\x7d\x7b\x1d\x4e\x4b\xd3\xb9\xd9\xcf\xbb\x56\xf7\x07\x22\x5d\xde\xae\x26\xf5\x05\x3b\xa9\xcc\x3a\xa9\xbd\x6e\x1d\xdc\x7a\xc3\x9c\xfc\x21\x9b\x8d\xf3\x0a\x36\x44\x41\xfb\x23\xa8\xef\x7f\x50\xd2\xca\x50\xc3\x36\x44\x4d\xca\x17\x10\xd7\x04\x0b\x79\x88\x27\xf7\xb3\x46\xd4\x32\x00\x9c\xff\x27\x55\x24\x8e\xb9\xc7\xca\x5a\x67\xf0\x1a\xee\x67\xef\x49\x66\x4a\xc4\x0e\xfb\x7a\x18\xc5\x72\xa4\x40\x12\xc3\xf6\xf8\xbf\x51\xe7\xfd\x18\xfc\x77\x0b\x6a\xe3\xb3\xb6\xa2\x0b\xdf\x8e\xd4\x16\xda\x18\xf9\xff\x6d\x4e\x42\xe4\x4c\x7d\x0b\xde\x35\x75\x9e\x8b\xd0\x21\x7a\xaf\x0e\x3c\x5b\x27\x56\x91\x51\x1d\x68\x8b\x97\x9d\xad\xea\x12\xe4\x4d\xc3\xd4\x64\xdc\xf7\x56\xc6\xbd\xbf\x5b\x2d\x1a\x3a\x8e\x4c\xb9\x3d\xd6\x73\x9e\x07\x23\x84\x1d\xb9\xb1\x37\xe7\x40\xab\x51\x6a\x66\xa1\xba\x2b\xba\x4c\x58\xe7\xcb\xa4\xf5\x9d\x21\xca\x00\x81\x1f\xde\x40\x91\x53\x62\xec\xfd\x28\xc1\xde\xc3\xee\x9e\x6c\x7e\xf6\xd8\x10\xa5\x61\x2b\xd5\x81\x28\x0a\x85\x30\x8c\x5b\xf8\x11\x88\xc2\x76\x87\xaa\xd1\xbc\xb1\xd5\x70\x4f\xb8\x3f\x36\x80\xbd\xf0\x27\x61\x3b\x32\x26\xd3\x10\x49\x92\x8e\xe2\xba\xbb\xb0\x11\x20\x86\x6f\x8a\x6c\x83\xca\x7f\xe1\x9b\x94\xe9\x69\x48\x8c\x51\xfe\xa4\x13\x36\x93\xa9\x8d\x80\x79\x77\x30\x6a\x28\xac\xfa\xc9\xf8\x1c\xa5\xcb\x2c\xd0\xee\x3f\xea\x13\x89\xd6\x7e\x19\x8a\x93\xbb\x16\x87\x6e\x24\x4e\x3e\x9a\xb4\x3d\x79\xa9\x0e\x17\x9d\xe2\x6b\x22\x75\x18\x4c\x6c\x92\x4e\xc6\xe4\x20\x94\xbe\xb6\xb9\xe7\x7b\x23\xb5\x62\x3c\x8e\xa6\x6d\x57\x94\x97\xc1\x6f\xf9\xa0\x7c\x72\xbc\xe2\x00\x46\x27\xd3\x50\x17\x9b\xf2\x51\xc1\xff\x74\xda\xe2\x5a\x63\xba\xa8\xef\xdf\x36\x83\x5e\xc6\x72\xe9\xf6\x33\x41\xaf\xff\x79\xe6\x62\xba\x70\x6d\x34\x3c\xdf\x59\x77\xcc\xa6\xed\x67\xac\xaf\xb4\xed\xf8\x98\x4e\x81\xc0\x01\x37\xda\xcd\xff\x50\x25\x8a\x7b\x98\x29\x1f\x60\x5e\xbd\x7d\xd3\x7d\x84\x69\xb2\xc9\xaf\x38\x75\x7f\x50\x1d\x7f\xf6\x18\xfd\x99\xf5\x70\x38\x84\x3b\x29\x77\xbc\xfc\x81\xb5\x79\x16\x89\x48\xce\xc2\x07\xed\x01\xd1\x47\x91\x00\xc5\x2d\xaa\x75\x9f\x4b\xfd\x60\xb2\x8a\xca\x5f\xf0\x56\x51\xf9\x47\x11\xff\x0d\x00\x00\xff\xff\xef\xb3\x09\x4c\x25\x21\x00\x00")
+var _faucetHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\xad\x5b\x6d\x93\xdb\xc4\x96\xfe\x4c\x7e\x45\x47\x84\x8b\x5d\x8c\x64\x4f\x92\x0d\x5c\x67\x3c\x6c\x76\x08\x5c\x6e\x01\x99\x62\x02\xec\x56\x2a\x4b\xb5\xa5\xb6\xdd\x19\x59\x2d\xf4\x32\x1e\x33\x77\x7e\xd7\xfd\xbe\xbf\x6c\xcf\x39\xdd\x2d\xb5\xa4\xb6\xe3\x00\x54\x91\xb1\xd5\x6f\xe7\xfd\x3c\xe7\xb4\x7c\xf6\xf0\xab\x57\x17\xaf\xff\xe7\xf2\x25\x5b\x57\x9b\xf4\xfc\xc1\x19\xfe\x61\x29\xcf\x56\xf3\xe0\xee\x8e\x45\xdf\xc1\x27\x76\x7f\x1f\x9c\x3f\x60\xec\x6c\x2d\x78\x82\x1f\xe0\xe3\x46\x54\x9c\xc5\x6b\x5e\x94\xa2\x9a\x07\x75\xb5\x0c\xbf\x08\xd8\xc4\x1d\x5c\x57\x55\x1e\x8a\xdf\x6a\x79\x33\x0f\xfe\x3b\xfc\xe9\x45\x78\xa1\x36\x39\xaf\xe4\x22\x15\x01\x8b\x55\x56\x89\x0c\x56\x7e\xfb\x72\x2e\x92\x95\xe8\xad\xcd\xf8\x46\xcc\x83\x1b\x29\xb6\xb9\x2a\x2a\x67\xfa\x56\x26\xd5\x7a\x9e\x88\x1b\x19\x8b\x90\xbe\x9c\x30\x99\xc9\x4a\xf2\x34\x2c\x63\x9e\x8a\xf9\x29\x6d\xa5\xf7\xaa\x64\x95\x8a\x73\x60\xe3\x35\x0b\x3e\x29\xd9\xd7\xbc\x8e\x05\xec\x16\xfd\x00\xdb\x03\x53\x67\x13\x3d\xc1\xcc\x4e\x65\x76\x4d\x9f\x18\x5b\x17\x62\x39\x0f\x90\x83\x72\x36\x99\xc4\x49\xf6\xae\x8c\xe2\x54\xd5\xc9\x32\xe5\x85\x88\x62\xb5\x99\xf0\x77\xfc\x76\x92\xca\x45\x39\xa9\xb6\xb2\xaa\x44\x11\x2e\x94\xaa\xca\xaa\xe0\xf9\xe4\x49\xf4\x24\xfa\x7c\x12\x97\xe5\xa4\x79\x16\x6d\x64\x16\xc1\x93\xc0\x9c\x50\x88\x74\x1e\x94\xd5\x2e\x15\xe5\x5a\x00\x51\xf4\xd8\xca\xe0\x8f\x52\xb2\x04\x31\x85\x7c\x2b\x4a\xb5\x11\x93\xa7\xd1\xe7\xd1\x94\x88\x70\x1f\x1f\x4b\x87\x26\xa4\x8c\x0b\x99\x57\xac\x2c\xe2\xa3\x69\x78\xf7\x5b\x2d\x8a\x1d\x88\xe0\x34\x3a\x35\x5f\xe8\xcc\x77\x65\x70\x7e\x36\xd1\x1b\x9e\xff\xc9\xdd\xc3\x4c\x55\xbb\xc9\xe3\xe8\x29\x1c\x91\xf3\xf8\x9a\xaf\x44\x62\xcf\xc2\xa1\xc8\x3e\xf4\x9c\x6c\x8e\x46\x8e\xcf\x8d\x0c\xa2\x1b\x51\x54\x12\xac\x27\x8c\xc1\xc8\x44\xc1\xee\xcc\x00\x63\xb0\x3e\x5c\x0b\xb9\x5a\x57\x33\x76\x3a\x9d\x7e\xf2\x7c\xdf\xc8\xcd\xba\x1d\x4a\x64\x99\xa7\x7c\x37\x63\xcb\x54\xdc\xb6\x8f\x79\x2a\x57\x59\x28\x2b\xb1\x29\x67\x4c\x9f\xd4\x0e\xe6\x3c\x49\x64\xb6\x82\xbd\x9e\xe5\xb7\x6c\x6a\x07\xee\xf7\x91\x78\xce\x22\x74\x0a\x2e\xb3\x0e\xbd\xe4\x12\x5d\x52\xed\x16\xeb\x53\x67\x5e\x25\x6e\xc1\x24\x90\xa0\x21\x29\x1b\x5e\xac\x80\xb9\x85\xaa\x2a\xb5\x99\xb1\xc7\x4f\x73\x87\x89\xad\x2a\x92\x70\x0b\x06\x3d\x63\x8b\x42\xf0\xeb\x10\x1f\x0c\xa8\xad\xa4\x28\x4a\xe7\xb8\x05\x4c\x12\xc5\xac\xe5\xcb\x61\x78\xda\x3f\x19\xc8\x7f\xec\xca\xe0\x10\xb5\xbd\x13\x53\xb1\x12\x59\x72\xf8\x60\xf2\x86\x52\xfe\x2e\x66\x10\x39\xd6\xa2\x90\xd5\x5e\xd6\x9f\xb5\x9c\xf7\x0f\xe2\x0b\x91\x3a\xe7\x34\x2a\x97\x19\x38\xaf\x08\x17\xa9\x8a\xaf\x87\x8c\x81\x28\xd9\x17\xae\x38\x89\x98\xad\x31\xa3\x4c\x15\x1b\x9e\xb6\x83\x71\x5d\x94\x0a\x88\xcf\x95\xf4\xf2\x0c\x52\x29\x78\xb8\x94\x22\x75\x59\x36\x3c\x54\x0a\x54\xf4\x85\x8f\x01\x51\x6c\xca\xc1\xfc\xbe\xd0\xbb\xd3\x43\xd4\x40\x67\xcd\x6d\x6b\xfc\xcf\xa6\x2e\x47\x0a\x2c\x75\x99\xaa\x6d\x08\xc2\xe0\x75\xa5\x1c\xd3\x59\x83\xed\x87\x25\xf8\x26\x88\x3e\x2f\x04\x99\x91\xc7\x20\x3a\x02\xb2\x0a\x3c\x05\xe2\x4a\x95\xca\x84\x7d\x9c\x24\x49\x7f\x3c\x2c\x78\x22\xeb\x92\x04\xec\xd5\x34\x32\xb7\x47\x12\x7d\x55\xba\xf2\x7b\xf6\x97\xe9\x4a\x9b\x8d\xcc\xf2\xba\x9a\x2d\x55\x5c\x97\xec\x33\x06\xa2\xc8\x4e\xcc\x04\xae\x9f\xda\xaf\x8b\x1a\x2c\x30\xeb\x3e\x73\x17\xb7\xe4\xaa\xba\x42\x8b\x9b\xb1\x27\xad\x84\x1e\xf3\x67\x4f\xff\xfe\xec\x79\x7f\x4e\xa8\x96\x4b\x48\xd7\xe0\xd2\x43\x61\x7c\x0c\xd2\x2f\x44\xe9\xee\x4c\xfc\x2e\xf9\x46\xa6\xa0\xca\x8d\xca\x14\xa9\x6e\xc0\x59\x59\xf1\xaa\x2e\xf7\x08\xd0\x2b\x77\xbd\x62\x26\x36\x79\xb5\xf3\xf9\x50\xa6\xb2\xe1\x31\x5b\x9e\xa6\xa2\xfa\xb0\x10\xb6\xd7\x07\xcc\x66\xd1\xa2\xca\x3c\x9e\xf0\xd4\xb3\x62\x09\x89\xbc\x13\x69\xff\xcc\xf1\x66\x33\x7e\xd2\x7b\x00\x48\x41\x01\xdc\x3a\x3a\xac\x34\x2e\x83\x39\xc3\x43\xf5\x7f\x6e\x44\x22\x39\x1b\xa1\xbb\x9a\xcc\xf0\xf9\xb3\xcf\xf3\xdb\xb1\x73\xc4\x81\xe4\xd7\x4b\x59\x98\xcd\x42\xd0\x5d\xe1\x04\xcc\xfb\xe6\x53\x27\xbd\x74\x7c\xef\x71\xc7\x8b\xda\x15\x11\x19\x74\xb8\x2a\x54\x9d\x9f\x78\x9f\xa2\x60\x8a\x4d\x88\x89\xae\x50\xa9\x7f\x4e\xd8\xd5\xa1\x23\xb3\x9e\xb0\xbc\xc9\x71\x1f\x3d\xb4\xeb\x79\xdf\x40\xf6\x6c\xb1\x57\xe1\xfb\x76\xef\xf2\x35\x5b\xca\xa2\xac\xc2\x78\x2d\x3b\x71\xfc\x70\x6c\xbb\xef\xa8\x1a\xe0\x8d\x05\x34\x67\x13\x8d\xd2\xf1\xe3\x42\x25\x3b\x83\xb5\x00\xac\xa7\xbc\x2c\x01\xeb\x15\xa1\xca\xd2\x1d\x33\x7f\x43\x8a\x27\x9c\x40\xb9\xc6\x9a\x36\x12\x04\x06\x38\x5f\x5d\xcb\x9c\x55\x8a\x55\x6b\xc1\x96\x75\x86\x06\xc7\x90\xfc\x80\x10\x34\xb7\xb0\x1d\x90\x88\x3d\xa2\x67\x51\x81\xc5\x59\x67\x89\xbc\xb1\x73\x1a\xf0\xd2\x8c\x62\x7d\x71\x7a\xee\xb0\x7f\x26\xed\xe4\x25\x67\x4b\x1e\x2e\x78\xb5\x0e\x18\x2f\x24\x0f\xd7\x32\x49\x44\x36\x0f\xaa\xa2\x16\x08\xee\xa4\xbb\x6e\x2f\xde\x6f\x0f\x9a\xb8\x27\xb9\x64\x15\x6a\x1b\x74\x68\xe8\x90\x9c\x86\xb7\x65\x78\xfa\x98\xe1\xa7\x72\x13\x9e\x4e\xed\x27\x1d\x58\xc3\x53\xfa\xbe\x49\xc2\x2f\xec\x07\x33\xf0\xb8\xb3\x29\x92\x28\x97\x2c\xfa\x11\x14\xf5\x0a\x74\xe0\x50\x46\x67\xe6\xf6\x44\x8a\x31\x9b\xba\x12\x49\xc0\x48\xc1\xe6\x51\x27\xec\xf4\xb6\xb6\xfc\xbf\x5e\xcb\x12\x02\xc4\x4a\x00\x52\x2d\x0a\x05\xc9\x07\x15\x68\x82\xb5\x5a\x6a\x75\x92\x80\x4e\xf0\x38\x09\x99\x10\xe0\x36\xc4\x5e\x00\xe4\xa2\xb8\x11\x09\x03\x6c\x24\x22\xd4\xf2\xdd\x1d\x54\x38\x6b\x16\x69\x79\xf6\xa8\x25\xdb\xd2\x96\x83\xf5\x22\xd5\x8a\x9a\x82\x6f\x54\x63\x36\x46\x11\xda\x60\xee\xee\x00\xa3\xf5\x79\x9e\xe4\x7d\x09\x89\xb4\x14\xfd\x59\x68\x77\x4c\x26\xa0\x0b\xa4\x38\x60\x50\x2c\xae\x15\x7c\xcd\x55\x09\xdb\xf3\xb8\x92\x2a\xd3\x74\x5c\x02\x45\xf2\x16\x4e\x9c\x04\xc0\xd3\x0d\x48\x2c\xe1\x95\xe8\x8b\xea\x8c\x9c\x92\x55\xbb\x1c\x44\xab\x8d\x2a\x30\xc5\x27\x96\xc0\x01\x83\x85\xb5\xe8\x56\xc2\x50\x1d\x69\xf5\xfd\x03\xd2\xd4\x2e\x57\x1e\x81\xa0\xd1\x18\x7d\x01\x65\x12\xa9\x02\x30\xb4\x80\xec\x0c\xba\x7c\x0e\x28\x75\x09\x49\x18\xcc\x07\xfe\xcb\x6f\xbd\x26\xdd\xdb\x11\x6b\x42\x82\x29\x20\x00\x28\x82\xc5\x02\x36\x85\x59\xbf\xe8\x0f\x67\x13\x1a\xf4\x2c\xd2\xec\xa1\xc0\xec\x1a\xc3\x5d\xf3\x55\xb3\x8e\x56\x05\x9f\xf9\x42\x66\x89\xb8\x9d\x07\x21\x54\xd1\x08\xde\xa0\xf8\xca\x21\x51\xc2\x0c\x30\xe4\xa6\x46\x77\xd5\x06\xac\x7a\x15\xda\xa5\xd8\x86\x94\x5e\x10\xb2\x96\xf2\x8b\x4e\xc7\xcd\x2c\xb4\x13\x2f\x4b\xae\x3b\x3a\xf1\x34\xd8\xc7\xfa\xe0\x31\x23\x61\xd8\x83\x3c\xc3\x5a\x3c\x75\x91\xfa\x06\x1d\x61\x79\x46\x6d\xb0\x72\x42\xbb\xc6\x6c\x61\xba\xf2\xcd\x87\x2c\x15\x8b\xb5\x4a\x21\xc6\x93\x85\x81\x20\x2e\x53\xc1\x4b\xa1\x57\xb1\x9d\xaa\x0b\xb6\xed\x88\x26\x8a\xc8\x21\x7d\xbb\x0d\xd5\xb5\x6f\x12\xcf\x65\x05\xfe\xf0\xfb\xfe\x69\x65\x2e\xd2\x34\x5e\x8b\xf8\x1a\x63\x2f\xf8\xa1\x6f\x52\x81\xbd\x9d\x42\x24\x3e\xce\x38\x36\x44\xc0\x98\xff\x77\x7a\xfb\x66\x1a\xfe\x1d\x8a\x94\x17\xe1\xd7\x6f\xef\x9e\x4e\xef\x1f\x79\xc9\x42\x07\x48\x04\x96\xe8\x0b\x91\x2c\x76\xd8\x91\xc0\x38\x35\x9c\x3b\xf1\x68\x1a\x61\xb4\xc7\x28\x30\x85\x7b\x0c\x03\xd3\x22\x81\x6b\x1d\x47\x54\x96\x89\xb8\x6a\x0c\x13\xf3\x3d\xfc\x0f\xc4\x40\xcc\x4a\x2b\xfa\x0c\xda\x33\x9a\xd7\x0b\x9b\x58\xdc\x01\xac\xde\xa3\x86\x49\x2c\x4f\xeb\xd5\x31\x49\xac\x1f\xce\x2f\x34\xa1\xc6\x1e\x02\x36\x70\x37\xed\x8e\x9a\xc2\xf7\x71\x5d\xd6\x8b\x8d\x1c\x32\x9d\x17\x12\x80\xcc\xae\xc7\xb4\x99\x7c\x88\xb8\x6f\xe4\x0d\xa4\x19\xf1\xc1\x54\x01\x6c\x01\xdd\xf9\x83\x4a\xff\x21\x95\xb9\x90\x4b\x9b\xdc\x88\x05\x95\x37\x50\x52\x0f\xc0\x44\x96\x8b\xb5\x52\xe0\x50\x60\x20\x7c\xa3\xea\xcc\xe4\x20\x33\xe5\xc1\x90\x9b\x02\x82\xbc\x60\x8f\x64\x72\x7b\xc2\x1e\xe9\x25\x6c\x36\x67\xd1\x0b\xfa\x58\x7a\xf8\x3b\xdb\x13\x7b\x7b\xc9\x05\x41\x9c\xb2\xd1\x17\x69\x77\x73\x0b\x9e\x47\xa9\x85\x12\x8b\xf8\x4d\x3f\x98\xde\xdf\x93\x0f\x8a\xc4\x04\x58\x9f\xf5\x1b\xfb\x47\x76\x2d\xbd\x38\x11\x7b\x9f\x80\x79\x30\xe7\xe1\x07\x3a\x85\x62\x3b\x7b\x14\x7d\x55\x17\x1c\x53\x52\xd9\x9c\x7b\xee\x8c\x5e\x8a\x42\xaa\xa4\x19\xc3\x1e\xe9\x06\x7b\xa8\x04\x01\xec\xa4\xab\x58\x41\x38\xa2\x39\x70\xda\xc8\x24\xfe\xb1\xa1\xd3\xaf\x56\x54\xec\x1e\x59\xed\x49\x20\x13\xab\xf3\xf3\x03\xa9\xe5\x46\xd5\x20\xa5\x62\x5f\x6a\xf9\x59\x0f\x03\x1e\x4b\x04\x1b\xa9\x1c\x19\xe7\xe9\xf8\x50\x8e\xf1\x67\x0e\xf4\x1b\x7b\xd6\x03\x7f\xd6\xd8\x3b\x7c\x28\x6f\x78\xb2\xc6\x70\x92\x27\x55\x1c\x60\x6c\xb8\xfe\x88\xe4\xd0\x4f\x0d\xd8\xdb\x07\x40\x85\x4e\xf6\xe0\x83\xf3\xc3\x64\x88\x49\xb5\x67\x45\x5f\x93\x4a\x0f\x82\x05\xd2\x7a\x88\x36\x65\x70\xbb\x57\xb7\x80\xc7\x70\xc5\x1f\x51\xe3\xf0\x80\x3d\x0a\xa5\x89\xd1\xc1\x89\x1f\xa8\x5a\xe6\xf4\xec\x86\x0b\xc0\x5d\xcd\x98\x86\x9c\x7b\x4f\xed\xd9\x03\xa2\xd1\xcb\xf6\x11\x22\xf6\xce\x03\xc2\xf0\x1a\x4f\xbb\x92\x33\x6e\x37\xdc\x7e\xc3\x6f\x53\x91\xad\xaa\xb5\x26\xe3\x7b\x7e\xfb\x1d\x7d\x45\x5a\xcc\x69\x3a\xc3\x7b\xe2\x61\x93\xfb\x89\x06\xfd\x45\xaf\xa3\xc3\x6c\xe5\xa3\xb1\x83\x67\xbd\x85\x15\xfe\x98\xe0\x31\x2c\x6f\xe8\xf0\xe1\x7a\x54\x3d\x54\x05\xa2\x28\x78\x6a\x23\x71\xfb\xdd\x44\xe3\xc0\x77\x04\x92\xfc\x1a\x9b\x85\x7e\xa8\xdf\x54\x6b\x30\xc3\x97\x91\xfa\x73\xc2\x3e\xdc\x9e\x06\x0c\x6c\x83\xc8\x59\x49\x04\x18\x84\x0f\xc8\xac\xad\xbb\xd3\xf1\x58\xb7\x61\x71\x26\x63\xca\xb5\xda\x0f\xf4\x08\xfa\x81\x27\x6d\x1e\x91\x9f\x50\x2c\x9a\x74\x23\x13\x1e\xc7\x22\xaf\x5e\xeb\x47\x5a\x84\xe4\xeb\x0b\x75\xdb\x88\x89\x30\x4b\xa3\x2b\x7f\x62\xd2\x84\x7f\xcb\xf4\x7e\x54\x08\x56\x1e\x2e\x8e\x4d\x13\x5e\xfe\xfc\xea\xb7\x46\x06\xf1\xac\x82\x38\x66\xac\x0e\x4b\xdb\xe8\xe7\x27\x5e\x1d\xee\xf3\xdd\x55\x58\xd8\x5d\xf6\xb8\x2b\x96\x51\xd7\x62\xa7\xed\xbd\x39\xd2\xeb\xb5\x34\x3f\x06\x00\xb7\xe0\x18\x39\x0d\xb0\xda\xb7\x2d\x06\x61\x99\xdd\xc8\x92\x6e\x4c\x7b\xb3\xce\x0f\x56\x5e\x99\x72\xef\xd9\x0e\xf4\x16\xb6\xbc\xc8\x24\xd6\xb9\x07\xba\x0b\x9d\xca\x44\x64\xd8\x2a\x62\xff\xe4\x37\xfc\x4a\xdf\xde\x41\x89\x9f\xc3\x86\xa4\x5e\x2b\x29\xb2\xc6\x7c\xa8\xc0\x7d\x74\xf9\x3b\x02\x18\x37\x07\x4d\x81\xe1\x3c\xf4\x2e\x02\xb5\xba\x64\x68\x72\x85\xf9\xaa\x1d\xcb\x7e\xd3\x8e\x05\x70\x15\x2b\xf3\x94\x2a\x60\x7a\xc4\x2b\xb5\x91\xb1\xc5\xe2\xda\x7e\x5e\x62\xdb\x04\x38\x71\xdc\x97\xa7\xa2\x80\x62\x0c\xff\x0d\x13\x4c\x68\x5a\x3e\x7a\x6a\xe3\x83\x9d\x50\x77\x55\x83\xf9\x97\xe5\xfe\x7d\x4a\x3d\x41\x6f\x64\x66\xf7\xb7\xf2\xd8\x7e\xc3\xb7\xad\x08\xcc\xd6\xf6\xeb\x51\x35\x8a\x5b\x14\x40\x89\x19\x66\xa2\xda\xaa\xe2\x7a\x5f\x39\xd4\xab\x83\x7c\x55\x77\xb7\xda\x41\xe7\xd8\xf0\xfc\xf8\x82\x47\x1b\xdb\x8b\x24\x61\x9f\x94\xcc\x50\x83\x26\xf6\xbd\xa8\xf8\xf7\xbc\x04\xca\xa2\x8b\x35\x97\x99\xfe\xb7\xdf\xd7\x3b\x5c\x6f\x68\x7d\xbc\x28\x4b\x5f\x0f\xab\x27\x88\x4a\x5d\x63\xca\xf8\x8b\xc4\x00\x45\x5f\x19\xc6\xb2\x88\x53\xf1\x07\x45\xd1\x15\x01\xf1\x10\xbd\x22\xcc\x57\x46\x57\xbb\xcd\x42\xa5\x1f\x22\x07\x9f\xb7\x0d\x0c\xcc\x36\xb9\xea\xa2\xfc\x73\x0d\xca\xe7\xfd\xf6\xf8\xd0\x0c\x7b\xf2\x8a\xb1\x73\x1f\xaa\x63\x65\xa5\x25\xf5\x2a\x17\x19\x88\x2a\x30\x34\xb3\x23\x3a\x8c\x43\x31\x64\xfc\xa6\x85\x6d\x74\x27\xe3\xb2\x38\x4c\xcb\xd8\x14\xac\xf9\xca\xa4\xe3\x07\xfb\xca\x49\x28\x26\x53\xaa\x23\xed\xfc\xd2\x84\x87\x47\x12\xea\xa7\xff\xfb\x37\x73\x43\x06\xd6\x7f\x69\x74\x81\xb8\xfe\x11\x2d\x00\xff\x37\x37\x43\x44\x40\x5c\x17\x05\xbd\x0c\xa3\x93\x70\xf3\xae\x8e\x5d\x64\x80\x01\x7c\x6d\xde\x73\xd1\xcb\xcf\x2d\x08\x6c\x3a\xb6\x5f\xd2\xe2\xee\x5a\xef\x86\x34\xff\x98\x93\x78\x1b\xfd\x7c\x56\x06\xf2\xed\xb4\xd7\xbb\x66\xd7\xf9\xea\x7c\x39\x9b\xe0\xf5\x42\xe7\xad\x11\x3b\x6b\x32\x61\xdf\xa4\x6a\xc1\x53\x40\x26\x20\x1c\x48\x4e\xe4\x2c\x88\x7b\x75\x4a\xd2\xc2\xf2\x36\xbe\xcd\x16\xb0\x50\xb7\xbc\x9b\x1b\x3c\x7c\x62\x91\x21\x9b\x43\x1c\xda\xb2\x9f\x7e\xfc\xee\x4a\xf0\x22\x5e\x5f\x42\x59\xb4\x29\x47\x5b\x80\x6e\x6a\x1b\x81\xa1\x52\x2d\x1d\x95\x34\x38\x8e\x56\xa2\x1a\x21\xaa\x0c\xc6\xec\x5f\xff\x62\x41\x60\xb7\x7c\x34\x0a\x3e\x6e\xc0\xe6\x38\x02\x18\x35\xb2\x5f\xc7\xee\xb1\xef\xb6\xd5\x91\x27\xae\x79\xb9\x8e\xca\x14\x50\xd4\xe8\x74\x6c\x0e\xe6\x94\x3d\x7e\xd5\xd1\xab\xa1\xa0\x15\xd5\x57\x62\x29\x33\xc1\x38\x5e\xe4\x50\xb3\x1c\x65\x55\x08\x7c\xc9\x8a\xe4\xa2\xea\x0a\xca\x44\x81\x62\xe2\x84\xee\x44\x59\xb1\x85\x02\xe4\x0f\x38\xa4\x86\xcc\xb2\x63\x3c\x4b\xda\xfd\x60\x35\xf8\x8a\x2c\x2b\x6c\x00\x55\x22\x5e\x67\x2a\x55\x2b\x89\x3a\x58\x17\xaa\x5e\xad\x69\x57\xcc\xb7\x56\x01\x1a\xe1\x76\xe4\x4c\xa7\xcf\x1b\x92\x46\xd7\xc0\xe8\x09\xf9\x5d\x7b\x4f\xf9\x11\x4e\xd5\x59\x73\x8e\xb2\xc4\xec\x77\x0e\x72\x84\x58\x7d\x81\xee\x3a\xea\xa4\xd4\x80\x7d\xc6\x68\x1b\x36\x9f\xb3\x40\x60\x72\x0e\xd8\x97\x2c\x30\x29\x9b\xcd\x58\x60\xb3\x2e\x48\x0e\x4f\x1a\xd1\x71\x56\x11\x1f\xa1\xb6\x0c\x64\x18\x47\x74\x4f\x3d\x82\xb3\x72\x88\x30\xc9\x88\x8e\x68\xa7\xe2\xfb\x46\xa3\x3b\x48\xb1\x20\xbb\x19\xfb\x14\x62\xdc\x05\x45\xbd\x4f\x35\x0b\x33\xfa\xf7\x84\x32\xc6\x8c\x19\xd6\xe4\x46\xd0\xec\xff\x98\x4e\xa7\x27\x2c\x2f\xd4\x0a\x9b\xbb\xff\xc5\x0b\x98\x0d\x3e\x7d\xdf\xee\x0e\xe1\xa0\x65\xa4\xa1\xb9\x15\xcb\x47\x50\xcb\x8b\x62\xd4\x2e\x68\xee\x05\x9f\xb7\x5a\x7a\x85\x73\xd0\xa6\x50\xb7\x05\xf9\x07\x36\xd2\xea\xbc\xb9\xd3\x03\x08\x6f\x93\x2d\xe8\x97\x91\xfd\x30\x99\xe1\xb8\xb4\x3d\x68\x47\x67\x74\xa8\xab\x32\x87\x22\xa4\xd8\x98\xaa\xa8\xf0\x0a\xa9\xde\xb8\xf4\xa2\x64\x0d\x44\x19\x47\xe5\x5a\x6d\x0f\xd1\x8e\x93\x5d\x58\x32\x8e\xe0\xac\x20\x06\x9b\xbf\x0e\x4e\xbc\xa7\x23\x75\x31\x42\x03\xa0\x0e\xf1\x14\xc1\x04\x08\x4c\xcd\x19\x20\x8e\x1f\xf4\x6e\x25\xc0\xd5\x8c\xd1\x5d\xe8\x42\x60\x83\x1d\x84\xc0\x53\x8c\xb2\xd4\xea\x82\xb1\x1f\x2f\x2f\x00\xf0\x26\xf4\x4a\xc7\x09\x3d\x05\xad\x81\x63\xe4\xf5\x02\x48\x70\x76\x04\x68\x45\xa2\x34\xcd\xfa\x0d\xdf\x31\x10\xb4\xc0\x5b\x56\x58\x15\xaf\xc9\x4f\x1a\x09\xcb\x8a\x5d\x67\x6a\x5b\xc2\x69\x85\xe0\xc9\xce\xa5\xdd\xfa\xdc\x5c\x73\x11\x15\x79\xfc\x53\x91\x96\x60\xbc\x77\xfa\xae\x0b\x4c\x57\x9f\xf2\x2b\x50\xfc\xd2\x08\x98\xb8\x04\x89\xe4\x14\x29\x66\xec\x0d\x2d\x7e\x7b\x0f\x86\x3e\x58\xa6\x29\xda\xbb\xf2\x8e\x96\x7e\x0b\x0b\x34\x01\xe6\xeb\xfd\xdb\x56\x84\x3d\xed\x46\x86\xe6\x91\xf9\x3b\x8e\x20\x40\xc5\xeb\x51\xa3\x1e\xf0\x3f\xd7\x02\xb4\xc3\x8f\x8c\x5b\x9e\x30\xf8\x1b\x6d\xc0\xac\x21\x2b\x3a\xa6\xd0\x7c\x6c\x3f\x59\x6b\x30\xd1\xed\xbd\xb6\xb0\x8f\xce\x81\x48\xb6\x48\x2f\x61\x2a\x47\x12\x68\x3d\xf4\x0c\xac\xe7\xfe\xaf\xe7\xa9\x57\x1c\x98\x01\xeb\xcd\xdd\xe4\xd9\xb8\x30\x98\xd1\x52\xa6\xa9\xf1\x4c\x7b\x29\xc4\x96\x85\xda\xd0\x83\x1a\xd2\xd8\xa7\xa5\x35\x43\x49\xb9\xae\x10\xf0\x04\x0c\xd4\xbe\x44\x74\xd0\x3d\x51\xc4\xf6\x4a\xc4\xba\xe7\x7b\xe5\x7c\x84\xa0\xe1\xef\xaf\xe6\xe9\x8b\x38\xa6\x56\x7a\x00\x42\x85\x05\x59\x2b\x53\x6e\x46\xdc\xad\x29\x9c\xd8\x81\x48\x37\xa5\xd8\x39\x9b\x76\xe6\x7c\x64\x2c\x83\xee\xf4\x74\x6e\xb5\x4b\xde\x4c\xdf\x8e\x81\x9e\x8d\xba\x11\x2f\xaa\xaa\x80\x34\x81\x08\x0a\xca\x6a\xbc\x10\x0e\x5a\xdd\x98\x4d\x4c\x49\x3e\x8e\xe8\xad\x88\x91\x3b\x7e\xdf\x7c\x7c\xaf\x35\x1c\x67\x0e\x8e\x3d\xb8\xa6\x71\x30\x59\xaf\x79\xc5\x20\xef\xbb\xc1\xa6\x04\xa4\x8d\xc1\x4a\x6d\x33\x88\xed\x6b\x99\xa3\xe3\xa6\x28\x29\x81\xed\x54\x27\x57\x3b\x16\x83\x86\x54\x63\x2a\x92\x2e\x1e\xb2\xfd\x1c\xd7\x5e\x30\x28\x41\x82\x82\x04\xee\x84\x7b\xb3\x8d\xc3\xb3\x31\x67\xa0\x05\x92\x66\x01\x10\x13\xf5\xf6\xf0\x40\x1e\x28\x04\xcc\xcb\xd8\x25\x98\xae\x2c\x05\xa8\xe8\x1d\xd8\xdc\x08\x71\x0f\xd5\xd2\xa3\x4e\xd7\x81\x78\x24\x7c\xe2\x61\x92\xa2\xb5\x73\x57\x8a\x50\x7c\x3c\x4c\x2a\xf6\xc4\x47\x88\x94\xfe\x79\xf5\xea\x87\x51\xe7\x35\x01\x40\x0c\xc1\x84\xe7\x72\xd2\x6c\x0c\x7a\xbb\x33\x8c\xce\xac\xe0\x4e\x08\x1c\xeb\xe0\x60\xde\x07\x18\x98\x31\xcc\xf3\x30\xfa\x7e\x1f\xc9\x81\x33\x6c\xe1\xff\x8a\xdc\xba\x21\x19\x36\x8c\x1a\xaa\x4e\x2c\x29\x6f\x07\x07\x97\x56\xfa\x3d\x5b\xa4\xf3\xef\x9a\x1d\x66\xac\xb7\x61\xb3\x6e\xd6\x7e\xbc\xdf\x67\xa6\xb6\x84\x18\xe8\xaf\x54\xe9\x8d\x18\xdd\xdd\xf7\x83\x97\x0b\x44\xf6\x58\xf4\x52\x80\x37\x81\xd5\xc1\x73\xd8\x67\xad\x5f\x47\xd1\x20\xa4\x67\xa1\xed\x56\x7b\x4c\x95\xd7\x20\xcd\x42\xfe\x2e\xf6\xa0\x13\xd3\xcb\xc5\xad\x1b\x1e\xde\x67\x11\x36\xdf\x1c\xa3\x66\x14\x2d\xcd\xff\x00\x99\x05\xc1\x21\x99\x5d\x28\xd0\x53\xac\xe1\x39\x5d\x1e\xd0\xcb\x57\x4c\x5f\x8d\xe1\x0f\x23\x96\x72\x55\x63\x0f\x76\xb1\xd3\x2e\x02\x76\xc4\xab\xc6\xf1\x51\x28\x66\xee\x7c\x2f\x62\xa2\xa6\x2e\x4e\xb8\xbb\x77\x21\xb0\xfb\x82\x31\xe2\x60\xee\xc6\x3c\x97\xf9\x66\x0f\x02\xe7\xd5\x5a\x96\x3a\x0a\x83\xcc\x0a\xb9\x71\xc3\x28\x86\x05\x9a\xd9\xb1\x51\x7d\xfe\x1b\xbb\x14\x1b\xa0\xa3\xc0\x1c\xfb\x16\xf6\xa4\x71\xc7\x22\x87\x61\xd3\xca\x5f\xef\xf4\x7c\xbf\xed\x19\xc4\xeb\x58\x9f\x8e\xf9\xa5\xb6\x4a\x7a\xbb\xcd\x82\x30\xf3\xf6\x92\xae\x0d\xdd\x62\x91\x56\xb8\xf2\x1c\x34\x9c\xcd\x07\xa3\xd6\x7e\x1d\x63\xa2\xf0\x9c\x0d\x13\xd7\x40\x64\xd6\x5a\xc0\x5d\x47\x6f\x28\x12\x37\xe1\xf7\xa4\x35\xf7\xd1\xf8\xad\xc7\x40\xeb\xb4\x9b\x4d\x4d\x30\x87\xea\x6e\xce\xcc\x30\x24\xc8\x56\xb0\x9a\x53\xa8\x65\xa1\xcc\x41\x87\x88\x4a\x20\x26\x5b\xc9\x25\x54\x38\xad\xb6\xea\x22\x6d\x03\x62\xfb\x18\x2f\xb5\x67\xec\x87\x7a\xb3\x00\x08\x03\x8c\xd1\xed\xc2\x1b\xba\x52\xc0\xa1\xb7\x33\x73\x99\x6d\x19\xc5\xea\x74\x3a\x76\x36\x30\xd7\xa7\x33\x92\x8a\xbd\x4b\xed\x4a\xe5\xc4\x8d\x6b\xba\x7a\x9e\x35\x95\xba\x33\xe8\x04\x3b\xe2\xd7\x09\x77\xed\x24\x27\xee\xe9\x49\xcd\x03\x97\x2b\x74\xe6\x59\x23\xad\xd3\xb7\xce\x18\xd4\xea\x33\x2c\xd8\x9d\x47\xe6\xbd\x2a\xcd\x83\x7d\xc9\xca\x61\x38\x30\xf7\x67\xf6\x1a\xd4\x59\xaa\xbd\x74\x66\xbc\x75\x34\xee\x74\x91\xcd\xed\x93\x33\xdd\xb9\xa5\xd1\xa7\xe9\x3b\x9c\x71\x24\xa1\x18\x6e\x65\xdd\xbb\x76\x6b\x0c\xd4\x15\x96\x7e\x36\x63\x5d\xa3\x75\x12\x80\x9b\x01\x3e\x10\x09\xe3\x38\xfb\xdb\xdf\x3a\x10\x08\x05\x71\x45\x96\x45\xab\x7b\xf9\x05\x08\xc5\x1a\xd4\xb9\x4b\x19\x99\x9b\x9b\x71\x4b\xd4\xaa\xb9\x96\xc1\x30\x2a\xaa\xd1\xa1\x10\xfa\x9a\x5f\x0b\xfa\x5d\x82\x0e\x9d\xe4\xbe\x65\x49\x6f\x40\xa8\x2c\x36\xa5\x1b\xe8\x4a\x81\xc8\x00\x36\x97\x50\x19\x03\xd2\x05\x83\xc1\x60\x80\x77\x35\x54\xbf\xb5\xfb\xe5\x29\x16\x95\xb4\x17\xbe\x78\xc8\x14\x26\xf5\x2d\x38\xa9\x53\xa8\xe8\x17\x14\x35\x78\x36\xa8\xd2\x41\xcf\xe2\x46\x64\x55\xaf\x68\x7e\xa8\xbd\x0f\x85\x63\xfc\x90\xaa\xc3\xab\x8a\x57\x82\x3d\x84\xfa\xff\x17\xb1\xb8\x22\x12\xa3\x57\x97\x2f\x7f\x18\x26\x9f\x21\xe4\xa1\x63\xa2\xbc\xa0\xbf\x5f\xe9\xe6\xf5\xa8\xdb\x5c\x78\xd8\x09\x41\x10\x0d\x22\x32\x9d\x9f\x11\x1f\x4b\x6c\x7b\xf4\x8b\xf7\x36\x5c\xf1\x21\xa0\x3e\x61\xba\x29\xe9\x01\xd0\x7d\xc3\x30\xb7\x83\x7a\x61\x1f\x98\x12\x86\x7b\xfe\x5e\xfe\xba\xf4\x1c\x01\xf3\xfd\x17\x83\x74\x29\xd8\xb1\x28\x90\xbb\x3f\xd3\x39\xb3\xc4\xad\x88\xeb\x4a\x8c\xfa\xf7\x7e\x08\x1b\x63\xfd\xca\xa7\x79\x4d\xd5\x22\x35\x6d\x07\x5e\x38\xe5\xd9\xb7\xb1\x68\x3b\x47\x2f\x1f\x5a\xfa\xd8\x87\xb0\x34\xac\xd4\xed\x3d\x53\xd1\x81\x59\x43\xce\x33\x56\x96\x2a\x44\xb3\x18\xd6\x36\x68\xe5\xd4\x2a\x25\xd8\x25\x33\xc7\xd0\x4d\x67\x8a\x91\x4f\xee\x3a\xfd\x52\x72\x20\x6c\x55\xd6\x69\xda\xed\x9b\xda\xe3\xfc\x78\xc3\x9c\xaf\x7b\x9c\x8d\x49\x8f\x46\x83\xfe\x26\x1c\x8e\x2f\xad\xa4\x6c\x8e\xbd\x2f\xfd\xfb\xc0\x60\x8c\x6d\xbc\x6d\x89\xbf\x14\xa4\x36\xde\x96\x3e\x8d\x01\xab\x0d\xda\xa3\xe8\x99\x9f\x31\x2f\xa4\xd3\x6d\xef\xc0\x0c\x1b\x20\xdf\xea\xc5\xb8\x9f\xca\x14\x5e\x29\xf8\xd9\x20\xe7\x31\x52\xe8\x60\x99\x43\x39\x94\xc4\x8d\x30\x5b\xaf\x73\x03\xab\x03\x6a\x9a\x86\x6d\x4b\x88\x89\x9c\x2e\x2d\xfd\x10\x42\xc9\x7d\x53\xae\x60\x0e\x1d\x9b\xe3\x4f\x73\xf5\x2c\x02\x54\x3d\x10\x46\x33\xe7\x5a\x7d\x9e\x7a\xc1\x43\x97\x5d\x16\x59\xdd\x63\x4c\xc2\xde\x21\x5a\x5c\xd2\x93\x81\xb5\x0e\x67\xfe\xa1\x2d\x29\x28\x74\x37\xc4\x38\x48\xab\xcd\x8d\xe8\x31\xa7\xb9\xb6\x78\xe4\x31\x07\xeb\xf6\x66\xcd\xf8\xa0\x3c\xde\x4b\xa1\xdd\xd7\x36\x70\x4f\x5c\xce\x0e\xee\x0d\x40\xb4\x16\x43\xc1\xe8\x73\x75\x47\xbd\x3b\x08\xa9\xd6\xa5\x69\xee\x1b\xd4\x62\xe8\x0c\x75\xc2\xfc\xc1\xf6\xf7\x7b\x9b\xef\x32\x5b\xaa\xc0\x74\xd5\x5b\x32\xfd\xa6\x3e\x30\xf4\x38\xc5\xd7\x35\xbb\x2e\x87\xcd\xea\xd7\xba\x65\x3e\x6a\xa2\xcb\x09\x7b\x32\x9d\x4e\xc7\xcf\xdb\x44\x6f\x37\x6b\xdf\xf5\x68\x7f\x5f\x01\xb1\xec\x25\xd0\xb1\x48\x25\x94\x97\xdc\x49\xf6\x66\x37\x73\x13\x82\x51\xf0\xc5\xe5\xb7\x5d\xc4\xdf\x1c\xd9\x8b\xbc\xdd\x1f\x33\x0f\x32\xcb\xfe\x9f\x38\x6f\xb7\xdb\x68\xa5\xd4\x2a\xd5\x3f\x6e\x6e\x22\x3f\x46\xa6\xe8\x5d\xd9\xa6\xa4\x2f\x0b\x38\x4d\x14\xf3\x7e\x86\xb1\xef\x6d\x31\x5e\xee\xb2\x98\x25\x88\x7f\xcf\xfb\xe4\x58\x42\xcf\x26\xfa\x57\x40\x67\x13\xfd\x03\xff\xff\x07\x0d\x60\xf9\x5b\xf1\x3f\x00\x00")
 
 func faucetHtmlBytes() ([]byte, error) {
 	return bindataRead(
@@ -93,7 +93,7 @@ func faucetHtml() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "faucet.html", size: 8485, mode: os.FileMode(420), modTime: time.Unix(1650596342, 0)}
+	info := bindataFileInfo{name: "faucet.html", size: 16369, mode: os.FileMode(420), modTime: time.Unix(1792154205, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sunvim/utils/log"
+)
+
+var (
+	worldIDAppFlag    = flag.String("worldid.app", "", "World ID app ID whose proofs of personhood every claim must carry (empty = disabled)")
+	worldIDActionFlag = flag.String("worldid.action", "faucet-claim", "World ID action the proofs must be generated for")
+	worldIDVerifyFlag = flag.String("worldid.verify", "https://developer.worldcoin.org/api/v2/verify", "World ID proof verification endpoint, the app ID is appended")
+)
+
+// nullifiersBucket is the store bucket tracking when the humans behind World ID
+// nullifiers are next allowed to claim.
+const nullifiersBucket = "nullifiers"
+
+// worldIDProof is the proof of unique personhood produced by IDKit, with the
+// funded address as its signal.
+type worldIDProof struct {
+	MerkleRoot        string `json:"merkle_root"`
+	NullifierHash     string `json:"nullifier_hash"`
+	Proof             string `json:"proof"`
+	VerificationLevel string `json:"verification_level"`
+}
+
+// worldIDVerification is the request made to the verification endpoint.
+type worldIDVerification struct {
+	worldIDProof
+	Action     string `json:"action"`
+	SignalHash string `json:"signal_hash"`
+}
+
+// worldIDStage requires every claim to carry a valid World ID proof, allowing
+// each human a single claim per cooldown, no matter the addresses they control.
+func worldIDStage(next Handler) Handler {
+	return func(c *Claim) error {
+		if *worldIDAppFlag == "" {
+			return next(c)
+		}
+		if c.WorldID == nil || c.WorldID.NullifierHash == "" {
+			return newUserError("Please verify with World ID to request funds")
+		}
+		nullifier, ok := new(big.Int).SetString(c.WorldID.NullifierHash, 0)
+		if !ok {
+			return newUserError("World ID proof invalid")
+		}
+		id := fmt.Sprintf("%#x", nullifier)
+
+		// Reserve the allowance up front so concurrent claims can't share a proof
+		var prev []byte
+		err := store.Update(nullifiersBucket, id, func(blob []byte) ([]byte, error) {
+			if blob != nil {
+				var until time.Time
+				if err := until.UnmarshalText(blob); err == nil && time.Now().Before(until) {
+					return nil, &throttledError{
+						error: newUserError("%s left until next allowance", prettyDuration(time.Until(until))),
+						limit: 1,
+						retry: time.Until(until),
+					}
+				}
+			}
+			prev = blob
+			return time.Now().Add(c.Cooldown).MarshalText()
+		})
+		if err != nil {
+			return err
+		}
+		if err = verifyWorldID(c.ctx, c.WorldID, common.HexToAddress(c.Address)); err == nil {
+			err = next(c)
+		}
+		if refundable(c, err) {
+			var rerr error
+			if prev != nil {
+				rerr = store.Put(nullifiersBucket, id, prev)
+			} else {
+				rerr = store.Delete(nullifiersBucket, id)
+			}
+			if rerr != nil {
+				log.Error("Failed to release World ID nullifier err: ", rerr)
+			}
+		}
+		return err
+	}
+}
+
+// verifyWorldID checks a World ID proof with the verification endpoint, making
+// sure it was generated for the faucet's action and the funded address.
+func verifyWorldID(ctx context.Context, proof *worldIDProof, addr common.Address) error {
+	// IDKit hashes signals into the field by dropping the low byte of the keccak
+	signal := new(big.Int).Rsh(new(big.Int).SetBytes(crypto.Keccak256(addr.Bytes())), 8)
+
+	blob, err := json.Marshal(&worldIDVerification{
+		worldIDProof: *proof,
+		Action:       *worldIDActionFlag,
+		SignalHash:   fmt.Sprintf("0x%064x", signal),
+	})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, *worldIDVerifyFlag+"/"+*worldIDAppFlag, bytes.NewReader(blob))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Error("Failed to verify World ID proof err: ", err)
+		return newUserError("World ID verification unavailable, try again later")
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusOK:
+		return nil
+	case res.StatusCode == http.StatusBadRequest:
+		var failure struct {
+			Code   string `json:"code"`
+			Detail string `json:"detail"`
+		}
+		json.NewDecoder(res.Body).Decode(&failure)
+		log.Info("Rejecting invalid World ID proof: ", failure.Code, " ", failure.Detail)
+		return newUserError("World ID proof invalid")
+	default:
+		log.Error("Failed to verify World ID proof, status: ", res.Status)
+		return newUserError("World ID verification unavailable, try again later")
+	}
+}
@@ -1,202 +1,414 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"crypto/ecdsa"
+	"encoding/json"
 	"errors"
-	"fmt"
-	"math"
+	"io"
 	"math/big"
 	"net/http"
+	"reflect"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	ethrpc "github.com/ethereum/go-ethereum/rpc"
 	"github.com/gorilla/websocket"
 	"github.com/sunvim/utils/log"
 )
 
-// wsConn wraps a websocket connection with a write mutex as the underlying
-// websocket library does not synchronize access to the stream.
-type wsConn struct {
-	conn  *websocket.Conn
-	wlock sync.Mutex
-}
-
-var (
-	faucet = struct {
-		lock     sync.RWMutex
-		conns    []*wsConn
-		timeouts map[string]time.Time
-		client   *ethclient.Client
-	}{
-		conns:    make([]*wsConn, 0, 1024),
-		timeouts: make(map[string]time.Time),
-	}
-	err         error
-	privateKey  *ecdsa.PrivateKey
-	fromAddress common.Address
-)
+// fundRequest is a funding request as submitted by the websocket client.
+type fundRequest struct {
+	URL       string `json:"url"`
+	Tier      uint   `json:"tier"`
+	Captcha   string `json:"captcha"`
+	Voucher   string `json:"voucher"`
+	Referral  string `json:"referral"`
+	Challenge string `json:"challenge"`
+	Signature string `json:"signature"`
+	Token     string `json:"token"`
+	JWT       string `json:"jwt"`
+	Website   string `json:"website"` // Honeypot field, left empty by humans
+	Resume    string `json:"resume"`  // Token of a claim session to resume instead
+	Terms     bool   `json:"acceptTerms"`
 
-func initFaucet() {
-	faucet.client, err = ethclient.Dial(*rpc)
-	if err != nil {
-		log.Fatal("init chain connect: ", err)
+	Fields map[string]string `json:"fields"` // Extra form fields, see --form.fields
+
+	WorldID *worldIDProof `json:"worldid"`
+}
+
+// decodeFundRequest strictly decodes a single funding request, rejecting unknown
+// fields, mistyped values and trailing data instead of silently ignoring them.
+func decodeFundRequest(r io.Reader) (*fundRequest, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	var msg fundRequest
+	if err := dec.Decode(&msg); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &typeErr) && typeErr.Field != "":
+			switch typeErr.Type.Kind() {
+			case reflect.String:
+				return nil, newUserError("Invalid request, field %s must be a string", typeErr.Field)
+			case reflect.Struct, reflect.Map:
+				return nil, newUserError("Invalid request, field %s must be an object", typeErr.Field)
+			case reflect.Bool:
+				return nil, newUserError("Invalid request, field %s must be true or false", typeErr.Field)
+			}
+			return nil, newUserError("Invalid request, field %s must be a non-negative integer", typeErr.Field)
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			return nil, newUserError("Invalid request, unknown field %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+		}
+		return nil, newUserError("Invalid request, malformed JSON")
 	}
-	privateKey, err = crypto.HexToECDSA(*priKey)
-	if err != nil {
-		log.Fatal(err)
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, newUserError("Invalid request, malformed JSON")
 	}
+	return &msg, nil
+}
+
+// wsConn wraps a websocket connection with a send queue drained by a single
+// writer, as the underlying websocket library does not synchronize access to
+// the stream and slow clients must not hold up their senders.
+type wsConn struct {
+	conn *websocket.Conn
+	cbor bool // Whether messages are CBOR encoded instead of JSON
+
+	queue   chan *wsMessage // Messages waiting to be written
+	closing chan struct{}   // Closed once the connection is torn down
+	closed  chan struct{}   // Closed once the writer flushed the queue
+}
+
+// Server is a faucet paying out of a single account: its signing key, the
+// connections to the chain, the funding queue and the cooldowns it enforces.
+type Server struct {
+	lock     sync.RWMutex
+	timeouts map[string]time.Time // Cooldown expiry by IP or address
 
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		log.Fatal("cannot assert type: publicKey is not of type *ecdsa.PublicKey")
+	key     *ecdsa.PrivateKey // Signing key of the faucet account
+	address common.Address    // Faucet account paying out the claims
+
+	chain  ChainBackend
+	queue  *jobQueue
+	handle Handler
+
+	websites struct {
+		lock  sync.Mutex
+		pages map[string]*cachedWebsite // Rendered website by language
 	}
+	passive uint32 // Set while claims are left to the primary, see standby (atomic)
+}
 
-	fromAddress = crypto.PubkeyToAddress(*publicKeyECDSA)
+// newServer creates a faucet paying out of the account of the given key. It
+// doesn't pay out anything until started on a chain backend.
+func newServer(key *ecdsa.PrivateKey) *Server {
+	s := &Server{timeouts: make(map[string]time.Time), key: key}
+	s.websites.pages = make(map[string]*cachedWebsite)
+	if key != nil {
+		s.address = crypto.PubkeyToAddress(key.PublicKey)
+	}
+	return s
 }
 
-func SendTx(amount int64, toAddress string) error {
-	ctx := context.Background()
-	nonce, err := faucet.client.PendingNonceAt(ctx, fromAddress)
+// initFaucet connects to the configured chain and starts paying out claims.
+func initFaucet() *Server {
+	key, err := crypto.HexToECDSA(*priKey)
 	if err != nil {
-		log.Error(err)
-		return err
+		log.Fatal(err)
 	}
+	var client *ethrpc.Client
+	if *chainBackendFlag == "sim" {
+		client = dialSimulated(key)
+	} else if client, err = dialRPC(*rpc, rpcFallbacks()...); err != nil {
+		log.Fatal("init chain connect: ", err)
+	}
+	s := newServer(key)
 
-	gasLimit := uint64(21000) // in units
-	gasPrice, err := faucet.client.SuggestGasPrice(context.Background())
-	if err != nil {
-		log.Error(err)
-		return err
+	backend := newEVMBackend(ethclient.NewClient(client), key, big.NewInt(*chainID))
+	backend.rpc = client
+	if *prefetchFlag > 0 {
+		backend.startPrefetch(*prefetchFlag)
 	}
-	to := common.HexToAddress(toAddress)
-	var data []byte
-	tx := types.NewTransaction(nonce, to, big.NewInt(amount), gasLimit, gasPrice, data)
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(big.NewInt(*chainID)), privateKey)
-	if err != nil {
-		log.Error(err)
-		return err
+	if *rpcBatchFlag > 0 {
+		backend.receipts = newReceiptPoller(client, time.Second)
 	}
+	s.start(backend)
+	return s
+}
 
-	log.Info("tx hash: ", signedTx.Hash().Hex())
+// start starts paying out claims on the given chain backend. It is split from
+// initFaucet so the faucet can be run against e.g. a simulated chain.
+func (s *Server) start(chain ChainBackend) {
+	s.chain = chain
+	if *breakerFailuresFlag > 0 {
+		s.chain = &breakerBackend{chain}
+	}
+	s.queue = newJobQueue(*queueSizeFlag)
+	s.handle = trackPending(Funding.Handler())
+	go s.loopSender()
+}
 
-	return faucet.client.SendTransaction(ctx, signedTx)
+// rpcContext derives a context for a single RPC call from the request context,
+// bounded by the configured per-call deadline.
+func rpcContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if *rpcTimeoutFlag <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, *rpcTimeoutFlag)
 }
 
-func OnWebsocket(w http.ResponseWriter, r *http.Request) {
+// onWebsocket serves the websocket API, funding the requests of the client as
+// they come in and reporting their progress and outcome.
+func (s *Server) onWebsocket(w http.ResponseWriter, r *http.Request) {
 	upgrader := websocket.Upgrader{}
+	if *apiCBORFlag {
+		upgrader.Subprotocols = []string{cborSubprotocol}
+	}
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return
 	}
 
+	// Oversized messages are refused with a close frame by the websocket library
+	conn.SetReadLimit(*maxMessageFlag)
+
 	// Start tracking the connection and drop at the end
-	defer conn.Close()
+	wsconn := newWSConn(conn, conn.Subprotocol() == cborSubprotocol)
+	defer wsconn.close()
 
-	faucet.lock.Lock()
-	wsconn := &wsConn{conn: conn}
-	faucet.conns = append(faucet.conns, wsconn)
-	faucet.lock.Unlock()
+	hub.add(wsconn)
+	defer hub.remove(wsconn)
 
+	// Let the user know right away if the faucet is currently closed
+	lang := negotiateLanguage(r)
 	defer func() {
-		faucet.lock.Lock()
-		for i, c := range faucet.conns {
-			if c.conn == conn {
-				faucet.conns = append(faucet.conns[:i], faucet.conns[i+1:]...)
-				break
-			}
+		if v := recover(); v != nil {
+			sendError(wsconn, localizeError(lang, recoverPanic("websocket", nil, v)))
 		}
-		faucet.lock.Unlock()
 	}()
-
-	for {
-		// Fetch the next funding request and validate against github
-		var msg struct {
-			URL     string `json:"url"`
-			Tier    uint   `json:"tier"`
-			Captcha string `json:"captcha"`
-		}
-		if err = conn.ReadJSON(&msg); err != nil {
+	if err := hours.closedError(); err != nil {
+		if err = sendError(wsconn, localizeError(lang, err)); err != nil {
+			log.Error("Failed to send schedule notice to client err: ", err)
 			return
 		}
-		if msg.Tier >= uint(*tiersFlag) {
-			//lint:ignore ST1005 This error is to be displayed in the browser
-			if err = sendError(wsconn, errors.New("Invalid funding tier requested")); err != nil {
-				log.Error("Failed to send tier error to client", "err", err)
+	}
+	// The request context is cancelled as soon as the client goes away, which
+	// aborts any RPC calls still in flight on its behalf, unless the claim is
+	// kept going for the client to resume it.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	keepAlive(ctx, wsconn)
+
+	reqs := make(chan fundRequest)
+	go func() {
+		defer cancel()
+		defer close(reqs)
+		defer detachSessions(wsconn)
+		defer func() {
+			if v := recover(); v != nil {
+				recoverPanic("websocket", nil, v)
+			}
+		}()
+		for {
+			_, reader, err := conn.NextReader()
+			if err != nil {
 				return
 			}
-			continue
-		}
-		log.Info("Faucet funds requested: ", "url: ", msg.URL, " tier: ", msg.Tier)
-		// Ensure the user didn't request funds too recently
-		faucet.lock.Lock()
-		var (
-			fund    bool
-			timeout time.Time
-		)
-		if timeout = faucet.timeouts[msg.URL]; time.Now().After(timeout) {
-			// User wasn't funded recently, create the funding transaction
-			p := (*payoutFlag + float64(msg.Tier)) * (*startFlag) * float64(ether)
-			amount, _ := big.NewFloat(p).Int64()
-
-			// Submit the transaction and mark as funded if successful
-			if err := SendTx(amount, msg.URL); err != nil {
-				faucet.lock.Unlock()
-				if err = sendError(wsconn, err); err != nil {
-					log.Error("Failed to send transaction transmission error to client err", err)
+			msg, err := wsconn.readRequest(reader)
+			if err != nil {
+				if err = sendError(wsconn, localizeError(lang, err)); err != nil {
+					log.Error("Failed to send request error to client err: ", err)
 					return
 				}
 				continue
 			}
-			timeout := time.Duration(*minutesFlag*int(math.Pow(3, float64(msg.Tier)))) * time.Minute
-			grace := timeout / 288 // 24h timeout => 5m grace
+			select {
+			case reqs <- *msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
 
-			faucet.timeouts[msg.URL] = time.Now().Add(timeout - grace)
-			fund = true
+	// Push every funding request through the pipeline and report the outcome
+	for msg := range reqs {
+		if msg.Resume != "" {
+			if !resumeSession(msg.Resume, wsconn) {
+				if err := sendError(wsconn, localizeError(lang, newUserError("Claim session expired, please check your balance before claiming again"))); err != nil {
+					log.Error("Failed to send session error to client err: ", err)
+					return
+				}
+			}
+			continue
 		}
-		faucet.lock.Unlock()
+		log.Info("Faucet funds requested: ", "url: ", msg.URL, " tier: ", msg.Tier)
 
-		// Send an error if too frequent funding, othewise a success
-		if !fund {
-			if err = sendError(wsconn, fmt.Errorf("%s left until next allowance", common.PrettyDuration(time.Until(timeout)))); err != nil { // nolint: gosimple
-				log.Error("Failed to send funding error to client err: ", err)
+		// Relay the updates through a session the client may resume, if enabled
+		var (
+			out      wsSender = wsconn
+			claimCtx          = ctx
+			session  *claimSession
+		)
+		if *sessionTTLFlag > 0 {
+			if session, claimCtx, err = newClaimSession(wsconn); err != nil {
+				log.Error("Failed to start claim session err: ", err)
 				return
 			}
-			continue
+			out = session
 		}
-		if err = sendSuccess(wsconn, fmt.Sprintf("Funding request accepted for Faucet into %s", msg.URL)); err != nil {
-			log.Error("Failed to send funding success to client err", err)
+		claim := s.newClaim(claimCtx, r, &msg, lang)
+		claim.website = true
+		claim.notify = func(position int, eta time.Duration) {
+			if err := sendQueue(out, position, eta, queueMessage(lang, position, eta)); err != nil {
+				log.Error("Failed to send queue position to client err: ", err)
+			}
+		}
+		claim.status = func(msg string) {
+			if err := out.send(&wsStatusMessage{Status: msg}, time.Second); err != nil {
+				log.Error("Failed to send claim status to client err: ", err)
+			}
+		}
+		if err = s.handle(claim); err != nil {
+			err = sendError(out, errors.New(localizeError(lang, err).Error()+siblingsNote(claim)))
+		} else {
+			err = sendSuccess(out, successMessage(claim), signClaimReceipt(claim))
+		}
+		if session != nil {
+			session.finish()
+		}
+		if err != nil {
+			log.Error("Failed to send funding outcome to client err: ", err)
 			return
 		}
 	}
+}
 
+// newClaim creates a funding claim from a request submitted by a client.
+func (s *Server) newClaim(ctx context.Context, r *http.Request, msg *fundRequest, lang string) *Claim {
+	claim := &Claim{
+		ctx:       ctx,
+		server:    s,
+		Tier:      msg.Tier,
+		Captcha:   msg.Captcha,
+		Voucher:   msg.Voucher,
+		Referral:  msg.Referral,
+		Challenge: msg.Challenge,
+		Signature: msg.Signature,
+		Token:     msg.Token,
+		JWT:       msg.JWT,
+		WorldID:   msg.WorldID,
+		Honeypot:  msg.Website,
+		Terms:     msg.Terms,
+		Fields:    msg.Fields,
+		FormNonce: formNonce(r),
+		IP:        r.RemoteAddr,
+		Country:   requestCountry(r),
+		Trusted:   trustedRemote(r.RemoteAddr),
+		Lang:      lang,
+		Values:    make(map[string]interface{}),
+	}
+	if claim.Token == "" {
+		claim.Token = requestToken(r)
+	}
+	if claim.JWT == "" {
+		claim.JWT = bearerToken(r)
+	}
+	if addr, err := s.chain.ParseAddress(msg.URL); err == nil {
+		claim.Address = addr
+	}
+	return claim
 }
 
+// successMessage assembles the message to show the user after a successful claim.
+func successMessage(c *Claim) string {
+	success := translate(c.Lang, "Funding request accepted for Faucet into %s", c.Address)
+	for _, note := range c.Notes {
+		success += ". " + note
+	}
+	return success
+}
+
+// wsSender is the destination of websocket messages, either a connection or a
+// claim session relaying them to the connection of its client.
+type wsSender interface {
+	send(value interface{}, timeout time.Duration) error
+}
+
+// The messages sent to websocket clients.
+type (
+	wsErrorMessage struct {
+		Error string `json:"error"`
+	}
+	wsSuccessMessage struct {
+		Success string        `json:"success"`
+		Receipt *claimReceipt `json:"receipt,omitempty"`
+	}
+	wsQueueMessage struct {
+		Queue  int    `json:"queue"` // Position in the funding queue
+		ETA    int    `json:"eta"`   // Estimated wait in seconds
+		Status string `json:"status"`
+	}
+	wsStatusMessage struct {
+		Status string `json:"status"`
+	}
+	wsSessionMessage struct {
+		Session string `json:"session"` // Token to resume the claim with
+	}
+	wsFundedMessage struct {
+		Funded *activityClaim `json:"funded"`
+	}
+)
+
 // sendError transmits an error to the remote end of the websocket, also setting
 // the write deadline to 1 second to prevent waiting forever.
-func sendError(conn *wsConn, err error) error {
-	return send(conn, map[string]string{"error": err.Error()}, time.Second)
+func sendError(conn wsSender, err error) error {
+	return conn.send(&wsErrorMessage{Error: err.Error()}, time.Second)
 }
 
-// sendSuccess transmits a success message to the remote end of the websocket, also
-// setting the write deadline to 1 second to prevent waiting forever.
-func sendSuccess(conn *wsConn, msg string) error {
-	return send(conn, map[string]string{"success": msg}, time.Second)
+// sendSuccess transmits a success message to the remote end of the websocket,
+// along with the signed claim receipt if any, also setting the write deadline
+// to 1 second to prevent waiting forever.
+func sendSuccess(conn wsSender, msg string, receipt *claimReceipt) error {
+	return conn.send(&wsSuccessMessage{Success: msg, Receipt: receipt}, time.Second)
 }
 
-// sends transmits a data packet to the remote end of the websocket, but also
-// setting a write deadline to prevent waiting forever on the node.
-func send(conn *wsConn, value interface{}, timeout time.Duration) error {
-	if timeout == 0 {
-		timeout = 60 * time.Second
+// sendQueue transmits the queue position of a pending request to the remote end
+// of the websocket, also setting the write deadline to 1 second to prevent waiting
+// forever.
+func sendQueue(conn wsSender, position int, eta time.Duration, msg string) error {
+	return conn.send(&wsQueueMessage{Queue: position, ETA: int(eta.Seconds()), Status: msg}, time.Second)
+}
+
+// readRequest decodes a funding request in the encoding negotiated for the
+// connection.
+func (c *wsConn) readRequest(r io.Reader) (*fundRequest, error) {
+	if !c.cbor {
+		return decodeFundRequest(r)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	blob, err := cborToJSON(data)
+	if err != nil {
+		return nil, newUserError("Invalid request, malformed CBOR")
+	}
+	return decodeFundRequest(bytes.NewReader(blob))
+}
+
+// send queues a data packet for the remote end of the websocket, to be written
+// within the given deadline to prevent waiting forever on the node.
+func (conn *wsConn) send(value interface{}, timeout time.Duration) error {
+	msg, err := conn.encode(value, timeout)
+	if err != nil {
+		return err
 	}
-	conn.wlock.Lock()
-	defer conn.wlock.Unlock()
-	conn.conn.SetWriteDeadline(time.Now().Add(timeout))
-	return conn.conn.WriteJSON(value)
+	return conn.enqueue(msg)
 }
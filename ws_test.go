@@ -0,0 +1,140 @@
+//go:build go1.18
+// +build go1.18
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func FuzzDecodeFundRequest(f *testing.F) {
+	for _, seed := range []string{
+		`{"url":"0x0000000000000000000000000000000000000001"}`,
+		`{"url":"0x0000000000000000000000000000000000000001","tier":2,"captcha":"x","acceptTerms":true}`,
+		`{"url":"x","fields":{"team":"a"},"worldid":{"merkle_root":"0x1","proof":"0x2"}}`,
+		`{"url":"x"} {"url":"y"}`,
+		`{"tier":-1}`,
+		`{"tier":"1"}`,
+		`{"acceptTerms":"yes"}`,
+		`{"fields":[]}`,
+		`{"address":"0x1"}`,
+		`{"url":`,
+		`[]`,
+		``,
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		msg, err := decodeFundRequest(bytes.NewReader(data))
+		if err != nil {
+			// Malformed requests are the fault of the requester, never internal
+			var uerr *userError
+			if !errors.As(err, &uerr) {
+				t.Fatalf("decoding %q failed with non-user error %v", data, err)
+			}
+			if msg != nil {
+				t.Fatalf("decoding %q failed but returned %+v", data, msg)
+			}
+			return
+		}
+		// Anything accepted must survive a round trip unchanged
+		blob, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatalf("failed to encode decoded request %+v: %v", msg, err)
+		}
+		again, err := decodeFundRequest(bytes.NewReader(blob))
+		if err != nil {
+			t.Fatalf("re-decoding %s failed: %v", blob, err)
+		}
+		if !reflect.DeepEqual(msg, again) {
+			t.Fatalf("round trip of %q changed the request: %+v != %+v", data, msg, again)
+		}
+	})
+}
+
+func FuzzClaimAddress(f *testing.F) {
+	for _, seed := range []string{
+		"0x0000000000000000000000000000000000000000",
+		"0x0000000000000000000000000000000000000001",
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+		"0X5AAEB6053F3E94C9B9A09F33669435E7EF1BEAED",
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAe",
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAedd",
+		"0xZZAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"https://twitter.com/someone/status/1",
+		"",
+	} {
+		f.Add(seed)
+	}
+	key, _ := crypto.GenerateKey()
+	s := newServer(key)
+	s.chain = newEVMBackend(nil, key, big.NewInt(1))
+
+	f.Fuzz(func(t *testing.T, url string) {
+		r := httptest.NewRequest("POST", "/api/claim", nil)
+		claim := s.newClaim(context.Background(), r, &fundRequest{URL: url}, "en")
+
+		if claim.Address == "" {
+			if common.IsHexAddress(url) && common.HexToAddress(url) != (common.Address{}) {
+				t.Fatalf("valid address %q not picked up", url)
+			}
+			return
+		}
+		if !common.IsHexAddress(url) {
+			t.Fatalf("invalid address %q accepted as %s", url, claim.Address)
+		}
+		// The accepted address must be the one requested, whatever the case
+		if !strings.EqualFold(strings.TrimPrefix(strings.TrimPrefix(url, "0x"), "0X"), claim.Address[2:]) {
+			t.Fatalf("address %q parsed as %s", url, claim.Address)
+		}
+	})
+}
+
+func FuzzCBORToJSON(f *testing.F) {
+	for _, seed := range []string{
+		"a16375726c782a307830303030303030303030303030303030303030303030303030303030303030303030303030303031",
+		"a26375726c61786474696572fb3ff8000000000000",
+		"a1666669656c6473a1647465616d6161",
+		"1bffffffffffffffff",
+		"3b7fffffffffffffff",
+		"4401020304",
+		"9f01ff",
+		"c0c0c0c000",
+		"f93e00",
+		"8181818181818181818181818181818181818100",
+		"",
+	} {
+		blob, _ := hex.DecodeString(seed)
+		f.Add(blob)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		blob, err := cborToJSON(data)
+		if err != nil {
+			if blob != nil {
+				t.Fatalf("decoding %x failed but returned %s", data, blob)
+			}
+			return
+		}
+		// Anything decoded must be valid JSON, which decodes the same again
+		if !json.Valid(blob) {
+			t.Fatalf("decoding %x returned invalid JSON %s", data, blob)
+		}
+		again, err := cborToJSON(data)
+		if err != nil || !bytes.Equal(blob, again) {
+			t.Fatalf("decoding %x is not deterministic: %s != %s (%v)", data, blob, again, err)
+		}
+	})
+}